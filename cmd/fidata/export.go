@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg"
+	"github.com/epifi/fi-mcp-lite/pkg/categorize"
+	"github.com/epifi/fi-mcp-lite/pkg/export"
+	"github.com/epifi/fi-mcp-lite/pkg/journal"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const exportUsage = `usage: fidata export -phone <phone number> -tool <tool> [-format csv|xlsx] [-sheet name] [-dir test_data_dir] [-o file]
+       fidata export -phone <phone number> -format ledger|hledger|beancount [-rules file] [-asof YYYY-MM-DD] [-dir test_data_dir] [-o file]
+
+tools: %s
+`
+
+// exportData writes the response of a data tool of a persona as a spreadsheet,
+// or all of its transactions and balances as a journal, to stdout unless -o
+// names a file
+func exportData(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, exportUsage, strings.Join(export.Tools(), ", "))
+		fs.PrintDefaults()
+	}
+	phone := fs.String("phone", "", "phone number of the persona to export")
+	tool := fs.String("tool", "", "data tool whose response is exported")
+	format := fs.String("format", export.FormatCSV, "csv, holding one sheet, xlsx, holding all of them, or the ledger, hledger or beancount journal of the persona")
+	sheet := fs.String("sheet", "", "sheet written to a CSV, the first one when empty")
+	rules := fs.String("rules", pkg.GetRulesFile(), "categorisation rules naming the Income and Expenses accounts of bank rows in a journal, none when empty")
+	asOf := fs.String("asof", "", "date of the scheme and holding NAVs in a journal, the latest transaction date when empty")
+	dir := fs.String("dir", "test_data_dir", "directory holding one directory of tool responses per phone number")
+	out := fs.String("o", "", "file to write instead of stdout")
+	fs.Parse(args)
+	if *phone == "" || *tool == "" && !journal.Supported(*format) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var b bytes.Buffer
+	if journal.Supported(*format) {
+		j, err := buildJournal(filepath.Join(*dir, *phone), *rules, *asOf)
+		if err != nil {
+			return err
+		}
+		j.Title = "Persona " + *phone
+		if err := journal.Write(&b, *format, j); err != nil {
+			return err
+		}
+	} else {
+		data, err := os.ReadFile(filepath.Join(*dir, *phone, *tool+".json"))
+		if err != nil {
+			return err
+		}
+		sheets, err := export.Sheets(*tool, data)
+		if err != nil {
+			return err
+		}
+		if err := export.Write(&b, *format, sheets, *sheet); err != nil {
+			return err
+		}
+	}
+	if *out == "" {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, b.Bytes(), 0o644)
+}
+
+// buildJournal reads the transactions and net worth of the persona in dir into
+// a journal. Missing responses are left out.
+func buildJournal(dir, rules, asOf string) (*journal.Journal, error) {
+	var src journal.Sources
+	for _, f := range []struct {
+		tool   string
+		target any
+	}{
+		{"fetch_bank_transactions", &src.Bank},
+		{"fetch_mf_transactions", &src.MF},
+		{"fetch_stock_transactions", &src.Stocks},
+		{"fetch_net_worth", &src.NetWorth},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, f.tool+".json"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, f.target); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.tool, err)
+		}
+	}
+
+	var opt journal.Options
+	if asOf != "" {
+		t, err := time.Parse(models.DateLayout, asOf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -asof %q, use YYYY-MM-DD", asOf)
+		}
+		opt.AsOf = t
+	}
+	if rules != "" {
+		engine := categorize.NewEngine(rules)
+		if _, err := engine.Reload(); err != nil {
+			return nil, err
+		}
+		opt.Category = func(t models.BankTxn) string {
+			if c := engine.Categorize(t).Category; c != categorize.Uncategorised {
+				return c
+			}
+			return ""
+		}
+	}
+	return journal.Build(src, opt), nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/aa"
+	"github.com/epifi/fi-mcp-lite/pkg/bureau"
+	"github.com/epifi/fi-mcp-lite/pkg/cas"
+	"github.com/epifi/fi-mcp-lite/pkg/epfo"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+	"github.com/epifi/fi-mcp-lite/pkg/validate"
+)
+
+// importer replaces the fixtures covered by the statements in paths and returns
+// the tools whose responses changed, with anything it skipped
+type importer func(f *persona.Fixtures, paths []string) (tools, warnings []string, err error)
+
+var importers = map[string]importer{
+	"aa":     importAA,
+	"bureau": importBureau,
+	"cas":    importCAS,
+	"epfo":   importEPFO,
+}
+
+const importUsage = `usage: fidata import <format> -phone <phone number> [-out test_data_dir] files...
+
+formats:
+  aa      ReBIT Account Aggregator FI data (XML or JSON) of deposit, term_deposit,
+          recurring_deposit, mutual_funds and equities accounts
+  bureau  Experian credit reports (XML, JSON or fetch_credit_report JSON)
+  cas     CAMS and KFintech consolidated account statements, as text (pdftotext
+          -layout), casparser JSON or CSV
+  epfo    EPFO member passbooks, as text (pdftotext -layout) or CSV
+`
+
+// importData converts statement files into the tool responses of a persona.
+// Only the responses the statements cover are rewritten.
+func importData(args []string) error {
+	if len(args) == 0 || importers[args[0]] == nil {
+		fmt.Fprint(os.Stderr, importUsage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("import "+args[0], flag.ExitOnError)
+	phone := fs.String("phone", "", "phone number of the persona to write")
+	out := fs.String("out", "test_data_dir", "directory the <phone number>/<tool>.json files are written to")
+	fs.Parse(args[1:])
+	if *phone == "" || fs.NArg() == 0 {
+		fmt.Fprint(os.Stderr, importUsage)
+		os.Exit(2)
+	}
+
+	dir := filepath.Join(*out, *phone)
+	f, err := persona.Load(dir)
+	if err != nil {
+		return err
+	}
+	tools, warnings, err := importers[args[0]](f, fs.Args())
+	if err != nil {
+		return err
+	}
+	sort.Strings(tools)
+	tools = slices.Compact(tools)
+	if err := f.WriteTools(dir, tools...); err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	fmt.Printf("%s: wrote %v\n", dir, tools)
+
+	// statements trimmed to a date range may not add up, point that out without failing
+	violations, err := validate.Persona(dir)
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, "warning:", v)
+	}
+	return nil
+}
+
+func importAA(f *persona.Fixtures, paths []string) ([]string, []string, error) {
+	var accounts []aa.Account
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := aa.Parse(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		accounts = append(accounts, found...)
+	}
+	r := aa.Convert(accounts)
+	if len(r.Types) == 0 {
+		return nil, r.Warnings, errors.New("no account of a supported FI type found")
+	}
+	return r.Apply(f), r.Warnings, nil
+}
+
+func importCAS(f *persona.Fixtures, paths []string) ([]string, []string, error) {
+	var statements []*cas.Statement
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		st, err := cas.Parse(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		statements = append(statements, st)
+	}
+	st := cas.Merge(statements...)
+	f.SetMutualFunds(st.Schemes, st.AsOf)
+	return []string{"fetch_mf_transactions", "fetch_net_worth"}, st.Warnings, nil
+}
+
+// importBureau replaces the credit reports with those of the files, latest
+// first as the credit tools read the first one. Reports the fetch_credit_report
+// schema would reject are refused.
+func importBureau(f *persona.Fixtures, paths []string) ([]string, []string, error) {
+	var reports []models.CreditReport
+	var warnings []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := bureau.Parse(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, r := range found {
+			errs, warns := bureau.Check(r)
+			if len(errs) > 0 {
+				return nil, nil, fmt.Errorf("%s: %s", path, strings.Join(errs, "; "))
+			}
+			for _, w := range warns {
+				warnings = append(warnings, path+": "+w)
+			}
+		}
+		reports = append(reports, found...)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].CreditReportData.CreditProfileHeader.ReportDate > reports[j].CreditReportData.CreditProfileHeader.ReportDate
+	})
+	f.CreditReport = &models.CreditReportResponse{CreditReports: reports}
+	return []string{"fetch_credit_report"}, warnings, nil
+}
+
+// importEPFO rebuilds the EPF details from member passbooks, one or more per
+// member id, and the EPF account from their balance
+func importEPFO(f *persona.Fixtures, paths []string) ([]string, []string, error) {
+	var statements []*epfo.Statement
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		st, err := epfo.Parse(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i, w := range st.Warnings {
+			st.Warnings[i] = path + ": " + w
+		}
+		statements = append(statements, st)
+	}
+	st := epfo.Merge(statements...)
+	f.SetEPF(st.Details(), st.AsOf)
+	return []string{"fetch_epf_details", "fetch_net_worth"}, st.Warnings, nil
+}
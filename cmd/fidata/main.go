@@ -0,0 +1,151 @@
+// Command fidata maintains the dummy data under test_data_dir.
+//
+//	fidata generate [-spec personas] [-out test_data_dir]
+//	fidata validate [-dir test_data_dir] [-json] [phone number...]
+//	fidata import <format> -phone <phone number> [-out test_data_dir] files...
+//	fidata export -phone <phone number> -tool <tool> [-format csv|xlsx] [-o file]
+//	fidata export -phone <phone number> -format ledger|hledger|beancount [-o file]
+//
+// generate writes the six tool responses of every persona spec in personas/.
+// validate reports the responses of a persona that disagree with each other.
+// import converts statements, such as Account Aggregator FI data, into the
+// responses of a persona. export writes a response as a CSV or XLSX spreadsheet,
+// or the transactions and balances of a persona as an accounting journal.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+	"github.com/epifi/fi-mcp-lite/pkg/validate"
+)
+
+const usage = `usage: fidata <command> [flags]
+
+commands:
+  generate   write the tool responses of persona specs to the test data dir
+  validate   check the tool responses of every persona for cross-file consistency
+  import     convert statements into the tool responses of a persona
+  export     write a tool response of a persona as a spreadsheet, or a persona as a journal
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "validate":
+		err = validateData(os.Args[2:])
+	case "import":
+		err = importData(os.Args[2:])
+	case "export":
+		err = exportData(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fidata:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	specPath := fs.String("spec", "personas", "persona spec, or a directory of *.yaml specs")
+	out := fs.String("out", "test_data_dir", "directory the <phone number>/<tool>.json files are written to")
+	fs.Parse(args)
+
+	paths, err := specPaths(*specPath)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		spec, err := persona.LoadSpec(path)
+		if err != nil {
+			return err
+		}
+		fixtures, err := persona.Generate(spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		dir := filepath.Join(*out, spec.PhoneNumber)
+		if err := fixtures.Write(dir); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s -> %s (%s)\n", path, dir, spec.Name)
+	}
+	return nil
+}
+
+// errViolations is returned by validate when it found violations, after printing them
+var errViolations = errors.New("found violations")
+
+func validateData(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dir := fs.String("dir", "test_data_dir", "directory holding one directory of tool responses per phone number")
+	asJSON := fs.Bool("json", false, "print one JSON object per violation")
+	fs.Parse(args)
+
+	var violations []validate.Violation
+	if fs.NArg() == 0 {
+		all, err := validate.All(*dir)
+		if err != nil {
+			return err
+		}
+		violations = all
+	}
+	for _, phone := range fs.Args() {
+		found, err := validate.Persona(filepath.Join(*dir, phone))
+		if err != nil {
+			return err
+		}
+		violations = append(violations, found...)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, v := range violations {
+		if *asJSON {
+			enc.Encode(v)
+		} else {
+			fmt.Println(v)
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %d", errViolations, len(violations))
+	}
+	return nil
+}
+
+// specPaths returns the spec itself, or the *.yaml specs of a directory in name order
+func specPaths(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && (strings.HasSuffix(e.Name(), ".yaml") || strings.HasSuffix(e.Name(), ".yml")) {
+			paths = append(paths, filepath.Join(path, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
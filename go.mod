@@ -8,6 +8,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/mark3labs/mcp-go v0.33.0
 	github.com/samber/lo v1.51.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/allocation"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var analyzeAssetAllocationTool = server.ServerTool{
+	Tool: mcp.NewTool("analyze_asset_allocation",
+		mcp.WithDescription("Analyze the user's asset allocation across equity, debt, gold, cash, real estate, international and other assets. Mutual funds are classified by scheme asset class and category, ETFs, REITs, InvITs and deposits by holding. Returns the value and share of each asset class, concentration metrics (largest holding, top five share, HHI, AMC concentration), and the drift from a target allocation when one is given. Liabilities are not included."),
+		mcp.WithObject("target_allocation",
+			mcp.Description("Optional target allocation as percentages summing to 100, keyed by asset class: equity, debt, gold, cash, real_estate, international, other. Example: {\"equity\": 60, \"debt\": 30, \"gold\": 10}."),
+		),
+	),
+	Handler: analyzeAssetAllocation,
+}
+
+func analyzeAssetAllocation(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	rawTarget, err := floatMapArg(req, "target_allocation")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	target, err := allocation.ParseTarget(rawTarget)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	return jsonResult(allocation.Analyze(netWorth, target))
+}
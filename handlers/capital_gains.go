@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/capgains"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var computeCapitalGainsTool = server.ServerTool{
+	Tool: mcp.NewTool("compute_capital_gains",
+		mcp.WithDescription("Compute realised capital gains for an Indian financial year and unrealised gains on current holdings from the user's mutual fund and Indian stock transactions. Lots are matched FIFO and gains are classified as short or long term using the Indian holding period rules for equity, debt and other funds, including bonus, split and 31-Jan-2018 grandfathering. Figures are estimates and not tax advice."),
+		mcp.WithString("financial_year",
+			mcp.Required(),
+			mcp.Description("Indian financial year, e.g. 2024-25"),
+		),
+		mcp.WithString("as_of_date",
+			mcp.Description("Optional date in YYYY-MM-DD format at which unrealised gains and their holding periods are computed. Defaults to the latest balanceDate of the accounts in fetch_net_worth, the day the current prices apply, or the latest transaction date when no account has one."),
+		),
+		mcp.WithString("source",
+			mcp.Description("Transactions to include"),
+			mcp.Enum("all", "mutual_funds", "stocks"),
+			mcp.DefaultString("all"),
+		),
+		mcp.WithObject("grandfathered_fmv",
+			mcp.Description("Optional fair market value per unit on 31-Jan-2018 keyed by ISIN, used for equity units acquired on or before that date"),
+		),
+	),
+	Handler: computeCapitalGains,
+}
+
+func computeCapitalGains(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fyArg, err := req.RequireString("financial_year")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fy, err := models.ParseFinancialYear(fyArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	asOf, err := dateArg(req, "as_of_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fmv, err := floatMapArg(req, "grandfathered_fmv")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	source := req.GetString("source", "all")
+
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	if asOf.IsZero() {
+		asOf, _ = netWorth.ValuationDate()
+	}
+	var securities []capgains.Security
+	if source != "stocks" {
+		txns, err := loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions")
+		if err != nil {
+			return internalError("error reading mf transactions", err)
+		}
+		mf, err := capgains.FromMF(txns, netWorth.MFSchemeAnalytics)
+		if err != nil {
+			return internalError("error reading mf transactions", err)
+		}
+		securities = append(securities, mf...)
+	}
+	if source != "mutual_funds" {
+		txns, err := loadToolData[models.StockTransactionsResponse](phoneNumber, "fetch_stock_transactions")
+		if err != nil {
+			return internalError("error reading stock transactions", err)
+		}
+		stocks, err := capgains.FromStocks(txns, netWorth.AccountDetailsBulkResponse)
+		if err != nil {
+			return internalError("error reading stock transactions", err)
+		}
+		securities = append(securities, stocks...)
+	}
+
+	return jsonResult(capgains.Compute(securities, capgains.Options{
+		FinancialYear:    fy,
+		AsOf:             asOf,
+		GrandfatheredFMV: fmv,
+	}))
+}
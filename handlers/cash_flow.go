@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var summarizeCashFlowTool = server.ServerTool{
+	Tool: mcp.NewTool("summarize_cash_flow",
+		mcp.WithDescription("Summarize the money flowing in and out of the user's bank accounts. Returns monthly inflow, outflow and net, detected salary credits, recurring debits such as SIPs, EMIs, rent, card bills and subscriptions grouped by normalised counterparty, and the end of day balance trajectory of every account with the rows where the reported balance breaks. Every figure lists the JSON pointers of the fetch_bank_transactions rows it was computed from; cite them rather than estimating. Only covers the period the bank transactions are available for."),
+		mcp.WithString("from_date",
+			mcp.Description("Optional start date in YYYY-MM-DD format. Defaults to the earliest transaction."),
+		),
+		mcp.WithString("to_date",
+			mcp.Description("Optional end date in YYYY-MM-DD format. Defaults to the latest transaction."),
+		),
+	),
+	Handler: summarizeCashFlow,
+}
+
+func summarizeCashFlow(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	from, err := dateArg(req, "from_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	to, err := dateArg(req, "to_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return mcp.NewToolResultError("to_date must not be before from_date"), nil
+	}
+	txns, err := loadToolData[models.BankTransactionsResponse](phoneNumber, "fetch_bank_transactions")
+	if errors.Is(err, os.ErrNotExist) || err == nil && len(txns.BankTransactions) == 0 {
+		return mcp.NewToolResultError("no bank transactions available for this user"), nil
+	}
+	if err != nil {
+		return internalError("error reading bank transactions", err)
+	}
+	return jsonResult(banking.SummarizeCashFlow(txns, from, to))
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var fetchCreditReportDecodedTool = server.ServerTool{
+	Tool: mcp.NewTool("fetch_credit_report_decoded",
+		mcp.WithDescription("Fetch the user's credit report with the bureau codes decoded. Account types, account statuses, portfolio types and payment ratings are returned with their labels, dates are in YYYY-MM-DD format, the 36 month payment history of each account is expanded into a month by month days-past-due timeline, and the utilisation of each revolving account is computed from its credit limit. Prefer this tool over fetch_credit_report when explaining the report to the user."),
+	),
+	Handler: fetchCreditReportDecoded,
+}
+
+// decodedCreditReports is the response of fetch_credit_report_decoded
+type decodedCreditReports struct {
+	CreditReports []credit.Report `json:"creditReports"`
+}
+
+func fetchCreditReportDecoded(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	report, err := loadToolData[models.CreditReportResponse](phoneNumber, "fetch_credit_report")
+	if err != nil {
+		return internalError("error reading credit report", err)
+	}
+	resp := decodedCreditReports{CreditReports: []credit.Report{}}
+	for _, r := range report.CreditReports {
+		resp.CreditReports = append(resp.CreditReports, credit.Decode(r))
+	}
+	return jsonResult(resp)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var simulateCreditChangeTool = server.ServerTool{
+	Tool: mcp.NewTool("simulate_credit_change",
+		mcp.WithDescription("Simulate how hypothetical actions change the user's credit health, such as paying down a card, closing an account or applying for new credit. Returns a heuristic credit health index from 0 to 100 before and after the actions, broken down into payment history, utilisation, credit age, credit mix and recent enquiries, with the points gained or lost per factor. The index is NOT a bureau score and must not be presented as one. Call without actions to explain the current credit health. Account indexes are those of fetch_credit_report_decoded."),
+		mcp.WithArray("actions",
+			mcp.Description("Hypothetical actions applied in order. Each action is an object with a type of close_account (needs account_index), pay_down (needs account_index and amount) or new_enquiry (optional count, defaults to 1)."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type":          map[string]any{"type": "string", "enum": []string{credit.ActionCloseAccount, credit.ActionPayDown, credit.ActionNewEnquiry}},
+					"account_index": map[string]any{"type": "integer"},
+					"amount":        map[string]any{"type": "number"},
+					"count":         map[string]any{"type": "integer"},
+				},
+				"required": []string{"type"},
+			}),
+		),
+	),
+	Handler: simulateCreditChange,
+}
+
+func simulateCreditChange(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	actions, err := creditActionsArg(req, "actions")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	report, err := loadToolData[models.CreditReportResponse](phoneNumber, "fetch_credit_report")
+	if err != nil {
+		return internalError("error reading credit report", err)
+	}
+	if len(report.CreditReports) == 0 {
+		return mcp.NewToolResultError("no credit report available for this user"), nil
+	}
+	simulation, err := credit.Simulate(credit.NewProfile(credit.Decode(report.CreditReports[0])), actions)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(simulation)
+}
+
+// creditActionsArg decodes the list of simulated actions
+func creditActionsArg(req mcp.CallToolRequest, key string) ([]credit.Action, error) {
+	raw, ok := req.GetArguments()[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an array of actions", key)
+	}
+	var actions []credit.Action
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("%s must be an array of actions: %w", key, err)
+	}
+	return actions, nil
+}
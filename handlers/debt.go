@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/debt"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var analyzeDebtTool = server.ServerTool{
+	Tool: mcp.NewTool("analyze_debt",
+		mcp.WithDescription("Analyze the user's open loans and credit cards. Matches each loan of the credit report with its EMI debits in the bank transactions, estimates the remaining tenure and interest, computes the debt-to-income ratio from detected salary credits, and builds avalanche (highest rate first) and snowball (smallest balance first) payoff schedules. Every EMI states whether it was found in the bank transactions, estimated from the loan tenure or assumed as a card minimum due. Account indexes are those of fetch_credit_report_decoded."),
+		mcp.WithNumber("extra_monthly_payment",
+			mcp.Description("Amount paid every month on top of the EMIs and minimum dues in the payoff schedules, defaults to 0"),
+			mcp.Min(0),
+		),
+	),
+	Handler: analyzeDebt,
+}
+
+func analyzeDebt(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	extra := req.GetFloat("extra_monthly_payment", 0)
+	if extra < 0 {
+		return mcp.NewToolResultError("extra_monthly_payment must not be negative"), nil
+	}
+	report, err := loadToolData[models.CreditReportResponse](phoneNumber, "fetch_credit_report")
+	if err != nil {
+		return internalError("error reading credit report", err)
+	}
+	if len(report.CreditReports) == 0 {
+		return mcp.NewToolResultError("no credit report available for this user"), nil
+	}
+	// debts can still be analyzed without bank transactions, using tenure estimates
+	txns, err := loadToolData[models.BankTransactionsResponse](phoneNumber, "fetch_bank_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading bank transactions", err)
+	}
+	return jsonResult(debt.Analyze(credit.Decode(report.CreditReports[0]), txns, debt.Options{ExtraMonthlyPayment: extra}))
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/epf"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var projectEPFTool = server.ServerTool{
+	Tool: mcp.NewTool("project_epf",
+		mcp.WithDescription("Project the user's EPF balance year by year to retirement. Starts from the current PF balance across all UANs, infers the monthly basic salary from the employee share credited by the current employer unless one is given, and starts on the date of the EPF data: the latest passbook entry or else the latest balance or transaction date of the user's accounts, and applies salary growth and a configurable interest rate schedule. Also lists every employer with its service period and flags dormant accounts, balances left with previous employers, overlapping service periods, duplicate UANs and balance mismatches. The pension (EPS) balance is reported but not projected."),
+		mcp.WithNumber("current_age",
+			mcp.Required(),
+			mcp.Description("Current age of the user in years"),
+		),
+		mcp.WithNumber("retirement_age",
+			mcp.Description("Age at which contributions stop, defaults to 58"),
+		),
+		mcp.WithNumber("monthly_basic_salary",
+			mcp.Description("Current monthly basic salary plus dearness allowance. Inferred from the current employer's contributions when omitted"),
+		),
+		mcp.WithNumber("employee_contribution_percent",
+			mcp.Description("Employee contribution as a percent of basic salary including any voluntary PF, defaults to 12"),
+		),
+		mcp.WithNumber("salary_growth_percent",
+			mcp.Description("Yearly growth of the basic salary in percent, defaults to 5"),
+		),
+		mcp.WithObject("interest_rates",
+			mcp.Description("Interest rate schedule keyed by financial year, each rate applying until the next configured year. Years before the first entry use 8.25. Example: {\"2025-26\": 8.25, \"2030-31\": 7.5}."),
+		),
+	),
+	Handler: projectEPF,
+}
+
+func projectEPF(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	currentAge, err := req.RequireFloat("current_age")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	assumptions := epf.Assumptions{
+		CurrentAge:          int(currentAge),
+		RetirementAge:       int(req.GetFloat("retirement_age", epf.DefaultRetirementAge)),
+		MonthlyBasicSalary:  req.GetFloat("monthly_basic_salary", 0),
+		EmployeeRatePercent: req.GetFloat("employee_contribution_percent", epf.DefaultEmployeeRate),
+		SalaryGrowthPercent: req.GetFloat("salary_growth_percent", epf.DefaultSalaryGrowth),
+	}
+	rates, err := floatMapArg(req, "interest_rates")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(rates) > 0 {
+		assumptions.InterestRates = make(map[int]float64, len(rates))
+		for key, rate := range rates {
+			fy, err := models.ParseFinancialYear(key)
+			if err != nil {
+				return mcp.NewToolResultError("interest_rates: " + err.Error()), nil
+			}
+			assumptions.InterestRates[fy.StartYear] = rate
+		}
+	}
+	details, err := loadToolData[models.EPFDetailsResponse](phoneNumber, "fetch_epf_details")
+	if err != nil {
+		return internalError("error reading EPF details", err)
+	}
+	if len(details.UANAccounts) == 0 {
+		return mcp.NewToolResultError("no EPF account connected for this user"), nil
+	}
+	asOf, err := epfAsOf(phoneNumber, details)
+	if err != nil {
+		return internalError("error dating EPF details", err)
+	}
+	if asOf.IsZero() {
+		return mcp.NewToolResultError("the EPF details of this user have no date to project them from"), nil
+	}
+	projection, err := epf.Project(epf.Summarize(details, asOf), assumptions, asOf)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(projection)
+}
+
+// epfAsOf dates the EPF details of the phone number with epf.AsOf, falling back
+// on the date of the current net worth
+func epfAsOf(phoneNumber string, details *models.EPFDetailsResponse) (time.Time, error) {
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if errors.Is(err, os.ErrNotExist) {
+		netWorth = &models.FetchNetWorthResponse{}
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	valued, err := currentNetWorthDate(phoneNumber, netWorth)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return epf.AsOf(details, valued), nil
+}
+
+var fetchEPFPassbookTool = server.ServerTool{
+	Tool: mcp.NewTool("fetch_epf_passbook",
+		mcp.WithDescription("Fetch the month by month EPF passbook history of the user: every contribution with its employee, employer and pension shares, interest credits, transfers between employers and withdrawals, with the running employee and employer balances and totals per financial year. Only EPF details imported from EPFO passbooks carry this history, as for the test user 9999999999; other member ids are listed without entries."),
+		mcp.WithString("member_id",
+			mcp.Description("Member id of one employer, all member ids when omitted"),
+		),
+		mcp.WithString("from",
+			mcp.Description("Start date in YYYY-MM-DD format, inclusive"),
+		),
+		mcp.WithString("to",
+			mcp.Description("End date in YYYY-MM-DD format, inclusive"),
+		),
+	),
+	Handler: fetchEPFPassbook,
+}
+
+func fetchEPFPassbook(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	from, err := dateArg(req, "from")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	to, err := dateArg(req, "to")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	details, err := loadToolData[models.EPFDetailsResponse](phoneNumber, "fetch_epf_details")
+	if err != nil {
+		return internalError("error reading EPF details", err)
+	}
+	if len(details.UANAccounts) == 0 {
+		return mcp.NewToolResultError("no EPF account connected for this user"), nil
+	}
+	memberID := req.GetString("member_id", "")
+	passbook := epf.History(details, memberID, from, to)
+	if memberID != "" && len(passbook.Accounts) == 0 {
+		return mcp.NewToolResultError("no EPF account with member id " + memberID), nil
+	}
+	return jsonResult(passbook)
+}
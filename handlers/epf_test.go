@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/epf"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/taxsaving"
+)
+
+// TestEPFAsOf checks that the EPF details are dated by the data, so the inferred
+// basic salary doesn't change from day to day
+func TestEPFAsOf(t *testing.T) {
+	details, err := loadToolData[models.EPFDetailsResponse]("7777777777", "fetch_epf_details")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asOf, err := epfAsOf("7777777777", details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := epf.Project(epf.Summarize(details, asOf), epf.Assumptions{CurrentAge: 30, RetirementAge: 31}, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asOf.Format(models.DateLayout) != "2024-07-22" || p.Contribution.MonthlyBasicSalary != 17241.38 {
+		t.Errorf("as of %s, contribution %+v", asOf.Format(models.DateLayout), p.Contribution)
+	}
+	// the tax saving report counts the same monthly credit
+	fy, _ := models.ParseFinancialYear("2024-25")
+	report := taxsaving.Build(taxsaving.Data{EPF: details, EPFAsOf: asOf}, fy, fy.End())
+	found := false
+	for _, item := range report.Items {
+		found = found || strings.Contains(item.Description, "at 2068.97 a month")
+	}
+	if !found {
+		t.Errorf("tax saving items = %+v, want a monthly credit of 2068.97", report.Items)
+	}
+}
+
+// TestEPFPassbookPersona checks that a served persona carries passbook entries
+// for fetch_epf_passbook
+func TestEPFPassbookPersona(t *testing.T) {
+	details, err := loadToolData[models.EPFDetailsResponse]("9999999999", "fetch_epf_details")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passbook := epf.History(details, "", time.Time{}, time.Time{})
+	entries := 0
+	for _, a := range passbook.Accounts {
+		entries += len(a.Entries)
+	}
+	if len(passbook.Accounts) != 2 || entries == 0 {
+		t.Errorf("passbook has %d accounts with %d entries", len(passbook.Accounts), entries)
+	}
+}
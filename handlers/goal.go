@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/goal"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var planGoalTool = server.ServerTool{
+	Tool: mcp.NewTool("plan_goal",
+		mcp.WithDescription("Plan a financial goal such as a house, education or a car. Inflates the goal amount to the horizon, earmarks existing investable assets suited to the horizon (EPF and NPS are never earmarked), detects the user's active SIPs from mutual fund transactions and returns the monthly SIP required to reach the goal, the probability of success from a seeded Monte Carlo simulation and the SIP needed for 50%, 75% and 90% confidence. Goals less than 3 years away are planned with the conservative profile and those less than 5 years away with at most the moderate one. Use the figures it returns instead of computing your own."),
+		mcp.WithNumber("goal_amount",
+			mcp.Required(),
+			mcp.Description("Cost of the goal in today's rupees"),
+		),
+		mcp.WithNumber("horizon_years",
+			mcp.Required(),
+			mcp.Description("Years until the money is needed, fractions allowed"),
+		),
+		mcp.WithNumber("inflation_percent",
+			mcp.Description("Yearly inflation of the goal cost in percent, defaults to 6"),
+		),
+		mcp.WithString("risk_profile",
+			mcp.Description("Risk profile of the goal portfolio, which sets the assumed return and volatility"),
+			mcp.Enum(string(goal.RiskConservative), string(goal.RiskModerate), string(goal.RiskAggressive)),
+			mcp.DefaultString(string(goal.RiskModerate)),
+		),
+		mcp.WithNumber("simulations",
+			mcp.Description("Number of Monte Carlo runs, defaults to 10000"),
+		),
+		mcp.WithNumber("seed",
+			mcp.Description("Random seed of the simulation, the same seed gives the same result. Defaults to 42"),
+		),
+	),
+	Handler: planGoal,
+}
+
+func planGoal(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	amount, err := req.RequireFloat("goal_amount")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	horizon, err := req.RequireFloat("horizon_years")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	risk, err := goal.ParseRiskProfile(req.GetString("risk_profile", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	g := goal.Goal{
+		Amount:           amount,
+		HorizonYears:     horizon,
+		InflationPercent: req.GetFloat("inflation_percent", goal.DefaultInflation),
+		Risk:             risk,
+		Simulations:      int(req.GetFloat("simulations", goal.DefaultSimulations)),
+		Seed:             int64(req.GetFloat("seed", goal.DefaultSeed)),
+	}
+	if err := g.Validate(); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	mf, err := loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading mutual fund transactions", err)
+	}
+	plan, err := goal.Build(g, netWorth, mf)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(plan)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/middlewares"
+	"github.com/epifi/fi-mcp-lite/pkg"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// ToolList is the list of analytics tools, which compute their response from the data tools in pkg.ToolList
+var ToolList = []server.ServerTool{
+	computeMFReturnsTool,
+	computeCapitalGainsTool,
+	getStockHoldingsTool,
+	fetchNetWorthHistoryTool,
+	analyzeAssetAllocationTool,
+	fetchCreditReportDecodedTool,
+	simulateCreditChangeTool,
+	analyzeDebtTool,
+	projectEPFTool,
+	fetchEPFPassbookTool,
+	summarizeCashFlowTool,
+	planGoalTool,
+	analyzeMFPortfolioTool,
+	taxSavingReportTool,
+	liquidityAnalysisTool,
+}
+
+var errNoPhoneNumber = errors.New("phone number missing from context")
+
+// phoneNumber returns the logged-in phone number set by the auth middleware
+func phoneNumber(ctx context.Context) (string, error) {
+	phoneNumber, ok := middlewares.PhoneNumberFromContext(ctx)
+	if !ok {
+		return "", errNoPhoneNumber
+	}
+	return phoneNumber, nil
+}
+
+// loadToolData reads and decodes the response of a data tool for the phone number
+func loadToolData[T any](phoneNumber, toolName string) (*T, error) {
+	data, err := pkg.ReadToolData(phoneNumber, toolName)
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", toolName, err)
+	}
+	return &v, nil
+}
+
+// dateArg parses an optional YYYY-MM-DD argument, returning the zero time when it is not set
+func dateArg(req mcp.CallToolRequest, key string) (time.Time, error) {
+	value := req.GetString(key, "")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(models.DateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date in YYYY-MM-DD format", key)
+	}
+	return date, nil
+}
+
+// floatMapArg reads an optional object argument whose values are numbers
+func floatMapArg(req mcp.CallToolRequest, key string) (map[string]float64, error) {
+	raw, ok := req.GetArguments()[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
+	values := make(map[string]float64, len(obj))
+	for k, v := range obj {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a number", key, k)
+		}
+		values[k] = f
+	}
+	return values, nil
+}
+
+// jsonResult encodes v as the text content of the tool result
+func jsonResult(v any) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("error encoding tool response", err)
+		return mcp.NewToolResultError("error encoding tool response"), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// internalError logs err and returns a generic tool error so that internals are not leaked to the client
+func internalError(msg string, err error) (*mcp.CallToolResult, error) {
+	log.Println(msg, err)
+	return mcp.NewToolResultError(msg), nil
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain runs the tests from the repository root, where the data cache finds test_data_dir
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/liquidity"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var liquidityAnalysisTool = server.ServerTool{
+	Tool: mcp.NewTool("liquidity_analysis",
+		mcp.WithDescription("Analyze how long the user's liquid money would last. Classifies every asset into liquidity tiers: savings and current accounts, liquid and overnight mutual funds, fixed and recurring deposits (with a premature withdrawal penalty before maturity), market-linked investments and locked retirement savings such as EPF and NPS. Combines them with the average monthly outflow from the bank transactions, less SIP and other investment debits, to report months of runway per tier and the emergency fund target and shortfall."),
+		mcp.WithNumber("target_months",
+			mcp.Description("Months of expenses the emergency fund should cover, defaults to 6"),
+		),
+		mcp.WithNumber("monthly_expenses",
+			mcp.Description("Monthly expenses to measure the runway in. Computed from the bank transactions when omitted"),
+		),
+		mcp.WithNumber("premature_withdrawal_penalty_percent",
+			mcp.Description("Part of a deposit lost by breaking it before maturity, defaults to 1"),
+		),
+		mcp.WithString("as_of_date",
+			mcp.Description("Optional date in YYYY-MM-DD format against which deposit maturities are judged. Defaults to the latest balanceDate of the accounts in fetch_net_worth, the day the balances were taken, or the latest transaction date when no account has one."),
+		),
+	),
+	Handler: liquidityAnalysis,
+}
+
+func liquidityAnalysis(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	opts := liquidity.Options{
+		TargetMonths:            req.GetFloat("target_months", liquidity.DefaultTargetMonths),
+		MonthlyExpenses:         req.GetFloat("monthly_expenses", 0),
+		PrematurePenaltyPercent: req.GetFloat("premature_withdrawal_penalty_percent", liquidity.DefaultPrematurePenaltyPercent),
+	}
+	switch {
+	case opts.TargetMonths <= 0:
+		return mcp.NewToolResultError("target_months must be positive"), nil
+	case opts.MonthlyExpenses < 0:
+		return mcp.NewToolResultError("monthly_expenses must not be negative"), nil
+	case opts.PrematurePenaltyPercent < 0 || opts.PrematurePenaltyPercent > 100:
+		return mcp.NewToolResultError("premature_withdrawal_penalty_percent must be between 0 and 100"), nil
+	}
+	asOf, err := dateArg(req, "as_of_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	txns, err := loadToolData[models.BankTransactionsResponse](phoneNumber, "fetch_bank_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading bank transactions", err)
+	}
+	if asOf.IsZero() {
+		var ok bool
+		if asOf, ok = netWorth.ValuationDate(); !ok {
+			if asOf, err = currentNetWorthDate(phoneNumber, netWorth); err != nil {
+				return internalError("error dating net worth", err)
+			}
+		}
+	}
+	if asOf.IsZero() {
+		return mcp.NewToolResultError("the data of this user has no balance date or transactions to judge deposit maturities by, pass as_of_date"), nil
+	}
+	return jsonResult(liquidity.Analyze(netWorth, txns, opts, asOf))
+}
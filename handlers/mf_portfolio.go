@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/mfportfolio"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var analyzeMFPortfolioTool = server.ServerTool{
+	Tool: mcp.NewTool("analyze_mf_portfolio",
+		mcp.WithDescription("Review the user's mutual fund portfolio for category duplication (several funds of the same kind, e.g. three large cap funds, with balanced advantage and dynamic asset allocation treated as one category), regular plans that leak distributor commission with an estimate of the yearly cost, and funds whose XIRR trails the median of the other funds of their category in the portfolio. Categories come from a category table shipped with the server."),
+		mcp.WithNumber("underperformance_margin_percent",
+			mcp.Description("Percentage points a fund's XIRR may trail its category median before it is flagged, defaults to 1"),
+			mcp.Min(0),
+		),
+	),
+	Handler: analyzeMFPortfolio,
+}
+
+func analyzeMFPortfolio(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	margin := req.GetFloat("underperformance_margin_percent", mfportfolio.DefaultUnderperformanceMargin)
+	if margin < 0 {
+		return mcp.NewToolResultError("underperformance_margin_percent must not be negative"), nil
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	txns, err := loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading mutual fund transactions", err)
+	}
+	return jsonResult(mfportfolio.Analyze(netWorth.MFSchemeAnalytics, txns, margin))
+}
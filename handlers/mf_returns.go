@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/returns"
+)
+
+var computeMFReturnsTool = server.ServerTool{
+	Tool: mcp.NewTool("compute_mf_returns",
+		mcp.WithDescription("Compute XIRR and absolute returns of the user's mutual fund investments from their MF transactions, valued at the NAV of each scheme in the net worth analytics, which applies on the latest balanceDate of the connected accounts. Returns figures per scheme, per folio and for the whole portfolio, along with the precomputed XIRR reported by the fund house for comparison."),
+		mcp.WithString("as_of_date",
+			mcp.Description("Optional valuation date in YYYY-MM-DD format. Only transactions up to this date are considered. Defaults to nav_date."),
+		),
+		mcp.WithString("nav_date",
+			mcp.Description("Optional date in YYYY-MM-DD format on which the current scheme NAV applies. Holdings valued before this date use the last transaction price instead. Defaults to the latest balanceDate of the accounts in fetch_net_worth, or the latest MF transaction date when no account has one."),
+		),
+	),
+	Handler: computeMFReturns,
+}
+
+func computeMFReturns(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	asOf, err := dateArg(req, "as_of_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	navDate, err := dateArg(req, "nav_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	txns, err := loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions")
+	if err != nil {
+		return internalError("error reading mf transactions", err)
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	if navDate.IsZero() {
+		navDate, _ = netWorth.ValuationDate()
+	}
+	result, err := returns.Compute(txns, netWorth.MFSchemeAnalytics, returns.Options{AsOf: asOf, NAVDate: navDate})
+	if err != nil {
+		return internalError("error computing mf returns", err)
+	}
+	return jsonResult(result)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/networth"
+)
+
+var fetchNetWorthHistoryTool = server.ServerTool{
+	Tool: mcp.NewTool("fetch_net_worth_history",
+		mcp.WithDescription("Fetch the user's net worth over time, built from dated net worth snapshots and the current net worth. Returns a series of points with totals and per asset class and liability values, the change between consecutive points and over the whole range. Dates between two snapshots are linearly interpolated and each point states how its value was derived."),
+		mcp.WithString("from_date",
+			mcp.Description("Optional start date in YYYY-MM-DD format. Defaults to the earliest snapshot."),
+		),
+		mcp.WithString("to_date",
+			mcp.Description("Optional end date in YYYY-MM-DD format. Defaults to the latest of the snapshot dates and the date of the current net worth, which is the latest balanceDate of the connected accounts or transaction date."),
+		),
+		mcp.WithString("granularity",
+			mcp.Description("Spacing of the points in the series."),
+			mcp.Enum(string(networth.GranularityDaily), string(networth.GranularityWeekly), string(networth.GranularityMonthly), string(networth.GranularityQuarterly)),
+			mcp.DefaultString(string(networth.GranularityMonthly)),
+		),
+	),
+	Handler: fetchNetWorthHistory,
+}
+
+func fetchNetWorthHistory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	from, err := dateArg(req, "from_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	to, err := dateArg(req, "to_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	granularity, err := networth.ParseGranularity(req.GetString("granularity", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	snapshots, notes, err := loadNetWorthSnapshots(phoneNumber, to)
+	if errors.Is(err, errUndatedNetWorth) {
+		return mcp.NewToolResultError(err.Error()), nil
+	} else if err != nil {
+		return internalError("error reading net worth snapshots", err)
+	}
+	if len(snapshots) == 0 {
+		return mcp.NewToolResultError("no net worth data available for this user"), nil
+	}
+	history, err := networth.BuildHistory(snapshots, from, to, granularity)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	history.Notes = notes
+	return jsonResult(history)
+}
+
+// loadNetWorthSnapshots reads the dated net worth snapshots of the phone number
+// and adds the current net worth, dated by currentNetWorthDate or else by
+// undated. A snapshot is never replaced: on the date of one the current net worth
+// is left out, and snapshots later than the current net worth are kept. Both
+// cases are explained in the notes returned.
+func loadNetWorthSnapshots(phoneNumber string, undated time.Time) ([]networth.Snapshot, []string, error) {
+	var snapshots []networth.Snapshot
+	for _, date := range pkg.ListSnapshotDates(phoneNumber) {
+		data, err := pkg.ReadSnapshotToolData(phoneNumber, date, "fetch_net_worth")
+		if err != nil {
+			return nil, nil, err
+		}
+		var resp models.FetchNetWorthResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, nil, fmt.Errorf("snapshot %s: %w", date, err)
+		}
+		if resp.NetWorthResponse == nil {
+			continue
+		}
+		t, _ := time.Parse(models.DateLayout, date)
+		snapshots = append(snapshots, networth.Snapshot{Date: t, NetWorth: resp.NetWorthResponse})
+	}
+	current, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return nil, nil, err
+	}
+	if current.NetWorthResponse == nil {
+		return snapshots, nil, nil
+	}
+	date, err := currentNetWorthDate(phoneNumber, current)
+	if err != nil {
+		return nil, nil, err
+	}
+	if date.IsZero() {
+		if undated.IsZero() {
+			return nil, nil, errUndatedNetWorth
+		}
+		date = undated
+	}
+
+	var notes, later []string
+	for _, s := range snapshots {
+		day := s.Date.Format(models.DateLayout)
+		switch {
+		case s.Date.Equal(date):
+			notes = append(notes, fmt.Sprintf("the snapshot of %s is used for that date rather than the current net worth of the same date", day))
+		case s.Date.After(date):
+			later = append(later, day)
+		}
+	}
+	if len(later) > 0 {
+		notes = append(notes, fmt.Sprintf("the snapshots of %s are later than the current net worth, which is dated %s by its balance and transaction dates", strings.Join(later, ", "), date.Format(models.DateLayout)))
+	}
+	if slices.ContainsFunc(snapshots, func(s networth.Snapshot) bool { return s.Date.Equal(date) }) {
+		return snapshots, notes, nil
+	}
+	return append(snapshots, networth.Snapshot{Date: date, NetWorth: current.NetWorthResponse}), notes, nil
+}
+
+// errUndatedNetWorth is returned when nothing in the data dates the current net worth
+var errUndatedNetWorth = errors.New("the current net worth of this user has no balance date or transactions to date it by, pass to_date")
+
+// currentNetWorthDate dates the current fetch_net_worth from its own data: the
+// latest of the day its account balances were taken and the transaction dates.
+// Snapshots don't date it, they are states of their own. It is zero when the
+// data has none of them.
+func currentNetWorthDate(phoneNumber string, current *models.FetchNetWorthResponse) (time.Time, error) {
+	latest, _ := current.ValuationDate()
+	later := func(date string) {
+		if t, err := time.Parse(models.DateLayout, date); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	bank, err := loadToolData[models.BankTransactionsResponse](phoneNumber, "fetch_bank_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, err
+	}
+	if bank != nil {
+		for _, b := range bank.BankTransactions {
+			for _, t := range b.Txns {
+				later(t.Date)
+			}
+		}
+	}
+	mf, err := loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, err
+	}
+	if mf != nil {
+		for _, s := range mf.MFTransactions {
+			for _, t := range s.Txns {
+				later(t.Date)
+			}
+		}
+	}
+	stocks, err := loadToolData[models.StockTransactionsResponse](phoneNumber, "fetch_stock_transactions")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, err
+	}
+	if stocks != nil {
+		for _, s := range stocks.StockTransactions {
+			for _, t := range s.Txns {
+				later(t.Date)
+			}
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// TestLoadNetWorthSnapshotsKeepsLaterSnapshots checks a persona whose snapshots
+// are later than its current data: the current net worth is dated by the data and
+// every snapshot keeps its own figures
+func TestLoadNetWorthSnapshotsKeepsLaterSnapshots(t *testing.T) {
+	snapshots, notes, err := loadNetWorthSnapshots("1313131313", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := map[string]float64{}
+	for _, s := range snapshots {
+		totals[s.Date.Format(models.DateLayout)] = s.NetWorth.TotalNetWorthValue.Float()
+	}
+	if len(totals) != 4 || totals["2025-06-01"] != 6150493 {
+		t.Errorf("snapshots = %v", totals)
+	}
+	if _, ok := totals["2024-12-28"]; !ok {
+		t.Errorf("the current net worth is not dated 2024-12-28: %v", totals)
+	}
+	if len(notes) != 1 {
+		t.Errorf("notes = %q", notes)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/holdings"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var getStockHoldingsTool = server.ServerTool{
+	Tool: mcp.NewTool("get_stock_holdings",
+		mcp.WithDescription("Reconstruct the user's current Indian stock, ETF, REIT and InvIT positions by replaying their BUY, SELL, BONUS and SPLIT transactions per ISIN into quantity, average cost, realised and unrealised P&L. The positions are reconciled against the holdings reported by the connected demat accounts and any mismatches, such as duplicated rows, are listed."),
+	),
+	Handler: getStockHoldings,
+}
+
+type stockHoldingsResponse struct {
+	Positions      []holdings.Position     `json:"positions"`
+	Reconciliation holdings.Reconciliation `json:"reconciliation"`
+}
+
+func getStockHoldings(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	txns, err := loadToolData[models.StockTransactionsResponse](phoneNumber, "fetch_stock_transactions")
+	if err != nil {
+		return internalError("error reading stock transactions", err)
+	}
+	netWorth, err := loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth")
+	if err != nil {
+		return internalError("error reading net worth", err)
+	}
+	positions, err := holdings.Reconstruct(txns, netWorth.AccountDetailsBulkResponse)
+	if err != nil {
+		return internalError("error reconstructing stock holdings", err)
+	}
+	if positions == nil {
+		positions = []holdings.Position{}
+	}
+	return jsonResult(stockHoldingsResponse{
+		Positions:      positions,
+		Reconciliation: holdings.Reconcile(positions, txns, netWorth.AccountDetailsBulkResponse),
+	})
+}
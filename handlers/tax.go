@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/taxsaving"
+)
+
+var taxSavingReportTool = server.ServerTool{
+	Tool: mcp.NewTool("tax_saving_report",
+		mcp.WithDescription("Report how much of the section 80C and 80CCD(1B) deduction limits the user has used in an Indian financial year and the remaining headroom. Counts ELSS purchases from the mutual fund transactions, the EPF employee share estimated from the current and past employers' credits, and life insurance, PPF, NPS, Sukanya Samriddhi, NSC, tax saver FD and tuition fee payments detected in the bank transactions. Every item links back to the row it came from. Health insurance and home loan EMIs are listed as excluded. Deductions only apply in the old tax regime and figures are estimates, not tax advice."),
+		mcp.WithString("financial_year",
+			mcp.Required(),
+			mcp.Description("Indian financial year, e.g. 2024-25"),
+		),
+		mcp.WithString("as_of_date",
+			mcp.Description("Optional date in YYYY-MM-DD format up to which the year is counted. Defaults to today."),
+		),
+	),
+	Handler: taxSavingReport,
+}
+
+func taxSavingReport(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phoneNumber, err := phoneNumber(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fyArg, err := req.RequireString("financial_year")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fy, err := models.ParseFinancialYear(fyArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	asOf, err := dateArg(req, "as_of_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if asOf.IsZero() {
+		asOf = time.Now().UTC()
+	}
+
+	// every source is optional, the report notes the ones that are missing
+	var d taxsaving.Data
+	if d.NetWorth, err = loadToolData[models.FetchNetWorthResponse](phoneNumber, "fetch_net_worth"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading net worth", err)
+	}
+	if d.MF, err = loadToolData[models.MFTransactionsResponse](phoneNumber, "fetch_mf_transactions"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading mutual fund transactions", err)
+	}
+	if d.EPF, err = loadToolData[models.EPFDetailsResponse](phoneNumber, "fetch_epf_details"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading EPF details", err)
+	}
+	if d.EPF != nil {
+		if d.EPFAsOf, err = epfAsOf(phoneNumber, d.EPF); err != nil {
+			return internalError("error dating EPF details", err)
+		}
+	}
+	if d.Bank, err = loadToolData[models.BankTransactionsResponse](phoneNumber, "fetch_bank_transactions"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return internalError("error reading bank transactions", err)
+	}
+	return jsonResult(taxsaving.Build(d, fy, asOf))
+}
@@ -1,29 +1,57 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/epifi/fi-mcp-lite/handlers"
 	"github.com/epifi/fi-mcp-lite/middlewares"
 	"github.com/epifi/fi-mcp-lite/pkg"
+	"github.com/epifi/fi-mcp-lite/pkg/categorize"
+	"github.com/epifi/fi-mcp-lite/pkg/export"
+	"github.com/epifi/fi-mcp-lite/pkg/schema"
 )
 
-var authMiddleware *middlewares.AuthMiddleware
+var (
+	authMiddleware *middlewares.AuthMiddleware
+	rulesEngine    *categorize.Engine
+	dataHealth     *schema.Monitor
+	// schemaErr is why the schemas could not be loaded, in which case dataHealth is nil
+	schemaErr error
+)
 
 func main() {
 	authMiddleware = middlewares.NewAuthMiddleware()
+	rulesEngine = categorize.NewEngine(pkg.GetRulesFile())
+	if _, err := rulesEngine.Reload(); err != nil {
+		log.Println("error loading categorisation rules", err)
+	}
+	authMiddleware.SetRules(rulesEngine)
+	dataHealth = loadSchemas()
+	authMiddleware.SetSchemas(dataHealth)
+	if dataHealth != nil && pkg.GetDataValidationMode() != pkg.DataValidationOff {
+		// keep the data health report in step with the files being served
+		pkg.Data().SetOnReload(func() {
+			dataHealth.Check(pkg.TestDataDir, pkg.Data())
+		})
+	}
+	if interval := pkg.GetReloadInterval(); interval > 0 {
+		pkg.Data().Watch(interval)
+	}
 	s := server.NewMCPServer(
 		"Hackathon MCP",
 		"0.1.0",
 		// Notifies clients when new tools gets added or any changes in tools
-		server.WithInstructions("A financial portfolio management MCP server that provides secure access to users' financial data through Fi Money, a financial hub for all things money. This MCP server enables users to:\n- Access comprehensive net worth analysis with asset/liability breakdowns\n- Retrieve detailed transaction histories for mutual funds and Employee Provident Fund accounts\n- View credit reports with scores, loan details, and account histories, this also contains user's date of birth that can be used for calculating their age\n\nIf the person asks, you can tell about Fi Money that it is money management platform that offers below services in partnership with regulated entities:\n\nAVAILABLE SERVICES:\n- Digital savings account with zero Forex cards\n- Invest in Indian Mutual funds, US Stocks (partnership with licensed brokers), Smart and Fixed Deposits.\n- Instant Personal Loans \n- Faster UPI and Bank Transfers payments\n- Credit score monitoring and reports\n\nIMPORTANT LIMITATIONS:\n- This MCP server retrieves only actual user data via Net worth tracker and based on consent provided by the user  and does not generate hypothetical or estimated financial information\n- In this version of the MCP server, user's historical bank transactions, historical stocks transaction data, salary (unless categorically declared) is not present. Don't assume these data points for any kind of analysis.\n\nCRITICAL INSTRUCTIONS FOR FINANCIAL DATA:\n\n1. DATA BOUNDARIES: Only provide information that exists in the user's Fi Money Net worth tracker. Never estimate, extrapolate, or generate hypothetical financial data.\n\n2. SPENDING ANALYSIS: If user asks about spending patterns, categories, or analysis tell the user we currently don't offer that data through the MCP:\n   - For detailed spending insights, direct them to: \"For comprehensive spending analysis and categorization, please use the Fi Money mobile app which provides detailed spending insights and budgeting tools.\"\n\n3. MISSING DATA HANDLING: If requested data is not available:\n   - Clearly state what data is missing\n   - Explain how user can connect additional accounts in Fi Money app\n   - Never fill gaps with estimated or generic information\n"),
+		server.WithInstructions("A financial portfolio management MCP server that provides secure access to users' financial data through Fi Money, a financial hub for all things money. This MCP server enables users to:\n- Access comprehensive net worth analysis with asset/liability breakdowns\n- Retrieve detailed transaction histories for mutual funds and Employee Provident Fund accounts\n- Summarize bank cash flow with salary credits, recurring payments and balance trends\n- Check emergency fund adequacy and months of runway from liquid assets and monthly outflow\n- Plan goals with the monthly SIP required, the assets that can be earmarked and a simulated probability of success\n- View credit reports with scores, loan details, and account histories, this also contains user's date of birth that can be used for calculating their age\n\nIf the person asks, you can tell about Fi Money that it is money management platform that offers below services in partnership with regulated entities:\n\nAVAILABLE SERVICES:\n- Digital savings account with zero Forex cards\n- Invest in Indian Mutual funds, US Stocks (partnership with licensed brokers), Smart and Fixed Deposits.\n- Instant Personal Loans \n- Faster UPI and Bank Transfers payments\n- Credit score monitoring and reports\n\nIMPORTANT LIMITATIONS:\n- This MCP server retrieves only actual user data via Net worth tracker and based on consent provided by the user  and does not generate hypothetical or estimated financial information\n- Bank transactions cover a limited recent period. Salary is only known from credits detected in them by summarize_cash_flow. Don't assume these data points beyond what the tools return.\n- tax_saving_report only covers the old tax regime and estimates the EPF employee share. Mention both when quoting it.\n- Goal plans from plan_goal are projections under the return, volatility and inflation assumptions it returns. Present them with those assumptions, never as guarantees.\n\nCRITICAL INSTRUCTIONS FOR FINANCIAL DATA:\n\n1. DATA BOUNDARIES: Only provide information that exists in the user's Fi Money Net worth tracker. Never estimate, extrapolate, or generate hypothetical financial data.\n\n2. SPENDING ANALYSIS: For cash flow, salary, recurring payments or balance questions use the summarize_cash_flow tool and only quote the figures it returns, citing the transaction rows they come from. Don't compute your own totals or categories from raw bank transactions.\n   - For detailed spending categorization and budgeting, direct them to: \"For comprehensive spending analysis and categorization, please use the Fi Money mobile app which provides detailed spending insights and budgeting tools.\"\n\n3. MISSING DATA HANDLING: If requested data is not available:\n   - Clearly state what data is missing\n   - Explain how user can connect additional accounts in Fi Money app\n   - Never fill gaps with estimated or generic information\n"),
 		server.WithToolCapabilities(true),
 		server.WithResourceCapabilities(true, true),
 		server.WithLogging(),
@@ -32,8 +60,11 @@ func main() {
 
 	// Register tools from pkg.ToolList
 	for _, tool := range pkg.ToolList {
-		s.AddTool(mcp.NewTool(tool.Name, mcp.WithDescription(tool.Description)), dummyHandler)
+		opts := append([]mcp.ToolOption{mcp.WithDescription(tool.Description)}, tool.Arguments...)
+		s.AddTool(mcp.NewTool(tool.Name, opts...), dummyHandler)
 	}
+	// Register analytics tools computed from the data tools
+	s.AddTools(handlers.ToolList...)
 
 	// Configure streamable HTTP server with proper endpoints
 	httpMux := http.NewServeMux()
@@ -47,6 +78,10 @@ func main() {
 	httpMux.HandleFunc("/login", loginHandler)
 	httpMux.HandleFunc("/check-session", checkSessionHandler)
 	httpMux.HandleFunc("/tool", toolCallHandler)
+	httpMux.HandleFunc("/export", exportHandler)
+	httpMux.Handle("/admin/rules", middlewares.AdminMiddleware(http.HandlerFunc(rulesHandler)))
+	httpMux.Handle("/admin/data-health", middlewares.AdminMiddleware(http.HandlerFunc(dataHealthHandler)))
+	httpMux.Handle("/admin/reload", middlewares.AdminMiddleware(http.HandlerFunc(reloadHandler)))
 	port := pkg.GetPort()
 	log.Println("starting server on port:", port)
 	if servErr := http.ListenAndServe(fmt.Sprintf(":%s", port), httpMux); servErr != nil {
@@ -54,6 +89,40 @@ func main() {
 	}
 }
 
+// loadSchemas loads the schema of every data tool and checks test_data_dir against
+// them as set by FI_MCP_DATA_VALIDATION. In fail mode the server exits when a
+// schema can't be loaded or a file is invalid, otherwise the problems are logged.
+func loadSchemas() *schema.Monitor {
+	mode := pkg.GetDataValidationMode()
+	var tools []string
+	for _, tool := range pkg.ToolList {
+		tools = append(tools, tool.Name)
+	}
+	set, err := schema.Load(pkg.GetSchemaDir(), tools)
+	if err != nil {
+		if mode == pkg.DataValidationFail {
+			log.Fatalln("error loading schemas", err)
+		}
+		log.Println("error loading schemas, the data will not be validated", err)
+		schemaErr = err
+		return nil
+	}
+	monitor := schema.NewMonitor(set, pkg.ValidateResponses())
+	if mode == pkg.DataValidationOff {
+		return monitor
+	}
+	// the cached files are the ones served, a file the cache could not load is reported missing
+	report := monitor.Check(pkg.TestDataDir, pkg.Data())
+	for _, f := range report.InvalidFiles {
+		log.Printf("invalid test data file %s/%s.json: %d errors, first: %s", f.PhoneNumber, f.Tool, f.ErrorCount, f.Errors[0])
+	}
+	if len(report.InvalidFiles) > 0 && mode == pkg.DataValidationFail {
+		log.Fatalf("%d of %d test data files are invalid", len(report.InvalidFiles), report.Files)
+	}
+	log.Printf("checked %d test data files against the schemas in %s, %d invalid", report.Files, set.Dir, len(report.InvalidFiles))
+	return monitor
+}
+
 func dummyHandler(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultText("dummy handler"), nil
 }
@@ -172,14 +241,156 @@ func toolCallHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Try to read the tool data from the test directory
-	filePath := fmt.Sprintf("test_data_dir/%s/%s.json", phoneNumber, toolName)
-	data, err := os.ReadFile(filePath)
+	data, err := pkg.ReadToolData(phoneNumber, toolName)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error reading tool data: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if dataHealth != nil && dataHealth.ValidatesResponses() {
+		if errs := dataHealth.CheckResponse(phoneNumber, toolName, data); len(errs) > 0 {
+			log.Printf("%s response for %s failed schema validation with %d errors, first: %s", toolName, phoneNumber, len(errs), errs[0])
+			http.Error(w, fmt.Sprintf("The %s data failed validation and was withheld", toolName), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Set content type and return the data
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data)
 }
+
+// Handler exporting the response of a data tool as a CSV or XLSX spreadsheet.
+// A CSV holds the sheet named by the sheet parameter, or the first one.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	// Allow CORS for local frontend
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	sessionId, toolName := query.Get("sessionId"), query.Get("tool")
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if sessionId == "" || toolName == "" {
+		http.Error(w, "sessionId and tool are required", http.StatusBadRequest)
+		return
+	}
+	if !export.Supported(toolName) {
+		http.Error(w, fmt.Sprintf("%s can't be exported, only %s", toolName, strings.Join(export.Tools(), ", ")), http.StatusBadRequest)
+		return
+	}
+	if export.ContentTypes[format] == "" {
+		http.Error(w, "format must be csv or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	phoneNumber, ok := authMiddleware.CheckSession(sessionId)
+	if !ok {
+		http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+		return
+	}
+	data, err := pkg.ReadToolData(phoneNumber, toolName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading tool data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if dataHealth != nil && dataHealth.ValidatesResponses() {
+		if errs := dataHealth.CheckResponse(phoneNumber, toolName, data); len(errs) > 0 {
+			log.Printf("%s export for %s failed schema validation with %d errors, first: %s", toolName, phoneNumber, len(errs), errs[0])
+			http.Error(w, fmt.Sprintf("The %s data failed validation and was withheld", toolName), http.StatusInternalServerError)
+			return
+		}
+	}
+	sheets, err := export.Sheets(toolName, data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error exporting tool data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var b bytes.Buffer
+	if err := export.Write(&b, format, sheets, query.Get("sheet")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", export.ContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.%s"`, phoneNumber, toolName, format))
+	w.Write(b.Bytes())
+}
+
+// Handler to inspect (GET) or reload (POST) the bank transaction categorisation rules.
+// When the rules file is invalid the previous rules stay in use.
+func rulesHandler(w http.ResponseWriter, r *http.Request) {
+	var status categorize.Status
+	switch r.Method {
+	case http.MethodGet:
+		status = rulesEngine.Status()
+	case http.MethodPost:
+		var err error
+		if status, err = rulesEngine.Reload(); err != nil {
+			log.Println("error reloading categorisation rules", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]any{"error": err.Error(), "active": status})
+			return
+		}
+		log.Println("reloaded", status.Rules, "categorisation rules from", status.Path)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Handler reporting the test data files that failed schema validation at startup
+// and the responses withheld since because they failed it
+func dataHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if dataHealth == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{"status": "unavailable", "error": schemaErr.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Mode string `json:"mode"`
+		schema.Health
+	}{Mode: pkg.GetDataValidationMode(), Health: dataHealth.Health()})
+}
+
+// Handler to inspect (GET) or force (POST) the reload of test_data_dir. The data is
+// also reloaded when a file changes, every FI_MCP_RELOAD_INTERVAL.
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	var status pkg.DataStatus
+	switch r.Method {
+	case http.MethodGet:
+		status = pkg.Data().Status()
+	case http.MethodPost:
+		var err error
+		if status, err = pkg.Data().Reload(); err != nil {
+			log.Println("error reloading test data", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]any{"error": err.Error(), "active": status})
+			return
+		}
+		log.Println("reloaded test data of", status.PhoneNumbers, "phone numbers from", status.Dir)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
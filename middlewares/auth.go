@@ -2,24 +2,34 @@ package middlewares
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/samber/lo"
 
 	"github.com/epifi/fi-mcp-lite/pkg"
+	"github.com/epifi/fi-mcp-lite/pkg/categorize"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/schema"
 )
 
 var (
 	loginRequiredJson = `{"status": "login_required","login_url": "%s","message": "Needs to login first by going to the login url.\nShow the login url as clickable link if client supports it. Otherwise display the URL for users to copy and paste into a browser. \nAsk users to come back and let you know once they are done with login in their browser"}`
 )
 
+type contextKey string
+
+// phoneNumberKey is the context key under which the logged-in phone number is stored for tool handlers
+const phoneNumberKey contextKey = "phone_number"
+
 type AuthMiddleware struct {
 	sessionStore map[string]string
+	rules        *categorize.Engine
+	schemas      *schema.Monitor
 }
 
 func NewAuthMiddleware() *AuthMiddleware {
@@ -41,17 +51,62 @@ func (m *AuthMiddleware) AuthMiddleware(next server.ToolHandlerFunc) server.Tool
 		if !lo.Contains(pkg.GetAllowedMobileNumbers(), phoneNumber) {
 			return mcp.NewToolResultError("phone number is not allowed"), nil
 		}
-		ctx = context.WithValue(ctx, "phone_number", phoneNumber)
+		ctx = context.WithValue(ctx, phoneNumberKey, phoneNumber)
 		toolName := req.Params.Name
-		data, readErr := os.ReadFile("test_data_dir/" + phoneNumber + "/" + toolName + ".json")
+		// analytics tools compute their response from the data files, so hand them over to their own handler
+		if !pkg.IsDataTool(toolName) {
+			return next(ctx, req)
+		}
+		data, readErr := pkg.ReadToolData(phoneNumber, toolName)
 		if readErr != nil {
 			log.Println("error reading test data file", readErr)
 			return mcp.NewToolResultError("error reading test data file"), nil
 		}
+		// the categories are derived from the file, so the file is what gets validated
+		if m.schemas != nil && m.schemas.ValidatesResponses() {
+			if errs := m.schemas.CheckResponse(phoneNumber, toolName, data); len(errs) > 0 {
+				log.Printf("%s response for %s failed schema validation with %d errors, first: %s", toolName, phoneNumber, len(errs), errs[0])
+				return mcp.NewToolResultError("the " + toolName + " data failed validation and was withheld"), nil
+			}
+		}
+		if toolName == "fetch_bank_transactions" && req.GetBool("include_categories", false) {
+			if data, readErr = m.categorizeBankTransactions(data); readErr != nil {
+				log.Println("error categorising bank transactions", readErr)
+				return mcp.NewToolResultError("error categorising bank transactions"), nil
+			}
+		}
 		return mcp.NewToolResultText(string(data)), nil
 	}
 }
 
+// SetRules sets the engine used to categorise bank transactions when a client asks for categories
+func (m *AuthMiddleware) SetRules(rules *categorize.Engine) {
+	m.rules = rules
+}
+
+// SetSchemas sets the monitor that validates data tool responses before they are sent
+func (m *AuthMiddleware) SetSchemas(schemas *schema.Monitor) {
+	m.schemas = schemas
+}
+
+// categorizeBankTransactions appends the category of every transaction to a fetch_bank_transactions payload
+func (m *AuthMiddleware) categorizeBankTransactions(data []byte) ([]byte, error) {
+	if m.rules == nil {
+		return nil, fmt.Errorf("no categorisation rules configured")
+	}
+	var resp models.BankTransactionsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m.rules.Annotate(&resp))
+}
+
+// PhoneNumberFromContext returns the phone number of the logged-in user set by AuthMiddleware
+func PhoneNumberFromContext(ctx context.Context) (string, bool) {
+	phoneNumber, ok := ctx.Value(phoneNumberKey).(string)
+	return phoneNumber, ok
+}
+
 // HTTPAuthMiddleware is a standard HTTP middleware that validates sessions
 func (m *AuthMiddleware) HTTPAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,123 @@
+package aa
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+	"github.com/epifi/fi-mcp-lite/pkg/validate"
+)
+
+func parse(t *testing.T, files ...string) []Account {
+	t.Helper()
+	var accounts []Account
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		accounts = append(accounts, a...)
+	}
+	return accounts
+}
+
+func TestConvert(t *testing.T) {
+	// the deposit file twice, as when two fetches overlap
+	r := Convert(parse(t, "deposit.xml", "term_deposit.xml", "mutual_funds.json", "equities.json", "deposit.xml"))
+	if want := []string{"deposit", "term_deposit", "mutual_funds", "equities"}; !reflect.DeepEqual(r.Types, want) {
+		t.Errorf("Types = %v, want %v", r.Types, want)
+	}
+	if got := r.AsOf.UTC().Format("2006-01-02 15:04"); got != "2025-06-30 12:30" {
+		t.Errorf("AsOf = %s", got)
+	}
+	if len(r.Warnings) != 1 {
+		t.Errorf("Warnings = %q, want the dividend payout", r.Warnings)
+	}
+
+	if len(r.Bank) != 1 || r.Bank[0].Bank != "HDFC Bank" || len(r.Bank[0].Txns) != 4 {
+		t.Fatalf("Bank = %+v", r.Bank)
+	}
+	if got, want := r.Bank[0].Txns[1], (models.BankTxn{Amount: "1500", Narration: "UPI-GROCER-512345678901", Date: "2025-06-05", Type: models.BankTxnTypeDebit, Mode: "UPI", CurrentBalance: "58500"}); got != want {
+		t.Errorf("txn = %+v, want %+v", got, want)
+	}
+
+	if len(r.Schemes) != 2 {
+		t.Fatalf("Schemes = %+v", r.Schemes)
+	}
+	if s := r.Schemes[1]; s.Registrar != "KFINTECH" || s.Units != 100 || s.OptionType != "IDCW" || len(s.Txns) != 0 {
+		t.Errorf("scheme = %+v", s)
+	}
+	if len(r.Stocks) != 1 || len(r.Stocks[0].Txns) != 2 || r.Stocks[0].Txns[0].Type != models.StockTxnTypeBuy || r.Stocks[0].Txns[1].NAV != nil {
+		t.Errorf("Stocks = %+v", r.Stocks)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	f, err := persona.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an NPS account that no FI type of the payloads covers is kept
+	nps := models.NewMoney(5000)
+	f.SetAccounts(persona.IsInstrument("ACC_INSTRUMENT_TYPE_NPS"), map[string]models.AccountDetailsEntry{
+		"nps": {AccountDetails: models.AccountDetails{AccInstrumentType: "ACC_INSTRUMENT_TYPE_NPS"}, NPSSummary: &models.AccountSummary{CurrentValue: &nps}},
+	})
+
+	r := Convert(parse(t, "deposit.xml", "term_deposit.xml", "mutual_funds.json", "equities.json"))
+	tools := r.Apply(f)
+	if err := f.WriteTools(dir, tools...); err != nil {
+		t.Fatal(err)
+	}
+	f, err = persona.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, v := range f.NetWorth.NetWorthResponse.AssetValues {
+		got[v.NetWorthAttribute] = v.Value.Float()
+	}
+	want := map[string]float64{
+		"ASSET_TYPE_MUTUAL_FUND":       20150,
+		"ASSET_TYPE_INDIAN_SECURITIES": 12500,
+		"ASSET_TYPE_DEPOSITS":          107250,
+		"ASSET_TYPE_SAVINGS_ACCOUNTS":  10150.5,
+		"ASSET_TYPE_NPS":               5000,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("assets = %v, want %v", got, want)
+	}
+	if total := f.NetWorth.NetWorthResponse.TotalNetWorthValue.Float(); total != 155050.5 {
+		t.Errorf("total = %v", total)
+	}
+	if n := len(f.NetWorth.AccountDetailsBulkResponse.AccountDetailsMap); n != 6 {
+		t.Errorf("%d accounts, want 6", n)
+	}
+	details := f.NetWorth.MFSchemeAnalytics.SchemeAnalytics[0].EnrichedAnalytics.Analytics.SchemeDetails
+	// 50 of the 200 units bought at 50 were sold at 58
+	if details.InvestedValue.Float() != 15750 || details.RealisedReturns.Float() != 400 || details.XIRR == 0 {
+		t.Errorf("analytics = %+v", details)
+	}
+
+	violations, err := validate.Persona(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		t.Errorf("violation: %s", v)
+	}
+}
+
+func TestParseRejectsEncryptedPayloads(t *testing.T) {
+	if _, err := Parse([]byte(`{"FI": [{"fipID": "HDFC-FIP", "data": [{"maskedAccNumber": "XX01", "encryptedFI": "b64"}]}]}`)); err == nil {
+		t.Error("expected an error, the payload has no decrypted account")
+	}
+}
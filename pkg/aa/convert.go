@@ -0,0 +1,506 @@
+package aa
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// Result holds the accounts of one or more payloads in the shapes of the data tools
+type Result struct {
+	// AsOf is the latest balance, NAV or transaction date found
+	AsOf     time.Time
+	Bank     []models.BankTransactions
+	Accounts map[string]models.AccountDetailsEntry
+	Schemes  []persona.Scheme
+	Stocks   []models.StockTransactions
+	// Types are the FI types that were converted, Apply replaces only what they cover
+	Types    []string
+	Warnings []string
+
+	// bankIndex and seen hold the position in Bank and the transactions of every
+	// bank account added so far, so that payloads with overlapping periods don't
+	// add an account or a transaction twice
+	bankIndex map[string]int
+	seen      map[string]map[string]bool
+}
+
+// bankTxnTypes are the transaction types of the deposit schema
+var bankTxnTypes = map[string]int{
+	"CREDIT":      models.BankTxnTypeCredit,
+	"DEBIT":       models.BankTxnTypeDebit,
+	"OPENING":     models.BankTxnTypeOpening,
+	"INTEREST":    models.BankTxnTypeInterest,
+	"TDS":         models.BankTxnTypeTDS,
+	"INSTALLMENT": models.BankTxnTypeInstallment,
+	"CLOSING":     models.BankTxnTypeClosing,
+	"OTHERS":      models.BankTxnTypeOthers,
+}
+
+// banks names the banks by the first four letters of their IFSC codes
+var banks = map[string]models.FipMeta{
+	"HDFC": {Name: "HDFC Bank", DisplayName: "HDFC"},
+	"ICIC": {Name: "ICICI Bank", DisplayName: "ICICI"},
+	"SBIN": {Name: "State Bank of India", DisplayName: "SBI"},
+	"UTIB": {Name: "Axis Bank", DisplayName: "AXIS"},
+	"KKBK": {Name: "Kotak Mahindra Bank", DisplayName: "KOTAK"},
+	"PUNB": {Name: "Punjab National Bank", DisplayName: "PNB"},
+	"BARB": {Name: "Bank of Baroda", DisplayName: "BOB"},
+	"CNRB": {Name: "Canara Bank", DisplayName: "CANARA"},
+	"UBIN": {Name: "Union Bank of India", DisplayName: "UNION"},
+	"YESB": {Name: "Yes Bank", DisplayName: "YES"},
+	"INDB": {Name: "IndusInd Bank", DisplayName: "INDUSIND"},
+	"IDFB": {Name: "IDFC FIRST Bank", DisplayName: "IDFC"},
+	"FDRL": {Name: "Federal Bank", DisplayName: "FEDERAL"},
+	"AUBL": {Name: "AU Small Finance Bank", DisplayName: "AU"},
+}
+
+// dateLayouts are the date formats found in FI payloads, most specific first
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02", "02-01-2006", "02/01/2006"}
+
+// Convert converts the accounts of the supported FI types: deposit,
+// term_deposit, recurring_deposit, mutual_funds and equities. Accounts of
+// other types are skipped with a warning.
+func Convert(accounts []Account) *Result {
+	r := &Result{Accounts: map[string]models.AccountDetailsEntry{}, bankIndex: map[string]int{}, seen: map[string]map[string]bool{}}
+	schemes := map[string]*persona.Scheme{}
+	var keys []string
+	for _, a := range accounts {
+		typ := a.Type()
+		switch typ {
+		case "deposit":
+			r.deposit(a)
+		case "term_deposit", "recurring_deposit":
+			r.termDeposit(a, typ)
+		case "mutual_funds":
+			r.mutualFunds(a, schemes, &keys)
+		case "equities":
+			r.equities(a)
+		default:
+			r.warn(a, "FI type %q is not supported, skipped", typ)
+			continue
+		}
+		if !slices.Contains(r.Types, typ) {
+			r.Types = append(r.Types, typ)
+		}
+	}
+	for _, k := range keys {
+		r.Schemes = append(r.Schemes, *schemes[k])
+	}
+	sort.Slice(r.Stocks, func(i, j int) bool { return r.Stocks[i].ISIN < r.Stocks[j].ISIN })
+	return r
+}
+
+// Apply replaces the parts of the fixtures covered by the converted FI types
+// and returns the tools whose responses changed
+func (r *Result) Apply(f *persona.Fixtures) []string {
+	var tools []string
+	has := func(types ...string) bool {
+		for _, t := range types {
+			if slices.Contains(r.Types, t) {
+				return true
+			}
+		}
+		return false
+	}
+	if has("deposit") {
+		f.SetBankTransactions(r.Bank)
+		tools = append(tools, "fetch_bank_transactions")
+	}
+	if has("deposit", "term_deposit", "recurring_deposit", "equities") {
+		f.SetAccounts(func(entry models.AccountDetailsEntry) bool { return slices.Contains(r.Types, typeOf(entry)) }, r.Accounts)
+		tools = append(tools, "fetch_net_worth")
+	}
+	if has("mutual_funds") {
+		f.SetMutualFunds(r.Schemes, r.AsOf)
+		tools = append(tools, "fetch_mf_transactions", "fetch_net_worth")
+	}
+	if has("equities") {
+		f.SetStockTransactions(r.Stocks)
+		tools = append(tools, "fetch_stock_transactions")
+	}
+	return tools
+}
+
+// typeOf returns the FI type a connected account is converted from
+func typeOf(entry models.AccountDetailsEntry) string {
+	switch entry.AccountDetails.AccInstrumentType {
+	case "ACC_INSTRUMENT_TYPE_DEPOSIT":
+		if entry.DepositSummary != nil && entry.DepositSummary.DepositAccountType == "DEPOSIT_ACCOUNT_TYPE_FIXED" {
+			return "term_deposit"
+		}
+		return "deposit"
+	case "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT":
+		return "recurring_deposit"
+	case "ACC_INSTRUMENT_TYPE_EQUITIES":
+		return "equities"
+	}
+	return ""
+}
+
+func (r *Result) warn(a Account, format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf("%s account %s: ", a.Type(), masked(a))+fmt.Sprintf(format, args...))
+}
+
+// date parses a payload date and moves AsOf forward to it
+func (r *Result) date(s string) (time.Time, bool) {
+	t, ok := parseDate(s)
+	if ok && t.After(r.AsOf) {
+		r.AsOf = t
+	}
+	return t, ok
+}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rfc3339 formats a payload date like the dates of the account summaries, or
+// returns "" when there is none
+func rfc3339(s string) string {
+	if t, ok := parseDate(s); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+func amount(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	return v
+}
+
+func masked(a Account) string {
+	return a.node.attr("maskedAccNumber", "maskedFolioNo", "maskedDematID")
+}
+
+// bankOf names the bank of an account from its IFSC code, or from the FIP id
+func bankOf(ifsc, fipID string) models.FipMeta {
+	if len(ifsc) >= 4 {
+		if meta, ok := banks[strings.ToUpper(ifsc[:4])]; ok {
+			meta.Bank = meta.DisplayName
+			return meta
+		}
+	}
+	code := strings.ToUpper(strings.Split(fipID, "-")[0])
+	for _, meta := range banks {
+		if code != "" && strings.HasPrefix(code, meta.DisplayName) {
+			meta.Bank = meta.DisplayName
+			return meta
+		}
+	}
+	return models.FipMeta{Name: fipID, DisplayName: code, Bank: code}
+}
+
+// bankDetails are the account details of a deposit held with a bank
+func bankDetails(a Account, ifsc string) models.AccountDetails {
+	meta := bankOf(ifsc, a.FipID)
+	fipID := a.FipID
+	if fipID == "" {
+		fipID = meta.DisplayName + "-FIP"
+	}
+	return models.AccountDetails{FipID: fipID, MaskedAccountNumber: masked(a), IFSCCode: ifsc, FipMeta: &meta}
+}
+
+func depositStatus(s string) string {
+	if s == "" {
+		s = "ACTIVE"
+	}
+	return "DEPOSIT_ACCOUNT_STATUS_" + strings.ToUpper(s)
+}
+
+// deposit converts a savings or current account and its transactions
+func (r *Result) deposit(a Account) {
+	summary := a.node.child("summary")
+	ifsc := summary.attr("ifscCode", "ifsc")
+	details := bankDetails(a, ifsc)
+	kind := "DEPOSIT_ACCOUNT_TYPE_SAVINGS"
+	if strings.EqualFold(summary.attr("type"), "CURRENT") {
+		kind = "DEPOSIT_ACCOUNT_TYPE_CURRENT"
+	}
+	details.AccInstrumentType = "ACC_INSTRUMENT_TYPE_DEPOSIT"
+	details.AccountType = map[string]string{"depositAccountType": kind}
+	r.date(summary.attr("balanceDateTime"))
+	id := persona.AccountID(details.FipID, "deposit", details.MaskedAccountNumber)
+	balance := models.NewMoney(amount(summary.attr("currentBalance")))
+	r.Accounts[id] = models.AccountDetailsEntry{AccountDetails: details, DepositSummary: &models.AccountSummary{
+		AccountID:            id,
+		CurrentBalance:       &balance,
+		BalanceDate:          rfc3339(summary.attr("balanceDateTime")),
+		OpeningDate:          rfc3339(summary.attr("openingDate")),
+		DepositAccountType:   kind,
+		DepositAccountStatus: depositStatus(summary.attr("status")),
+		Branch:               summary.attr("branch"),
+		IFSCCode:             ifsc,
+		MICRCode:             summary.attr("micrCode"),
+	}}
+
+	i, ok := r.bankIndex[id]
+	if !ok {
+		i = len(r.Bank)
+		r.bankIndex[id] = i
+		r.seen[id] = map[string]bool{}
+		r.Bank = append(r.Bank, models.BankTransactions{Bank: details.FipMeta.Name, Txns: []models.BankTxn{}})
+	}
+	account := &r.Bank[i]
+	for _, t := range a.node.child("transactions").find("transaction") {
+		date, ok := r.date(t.attr("transactionTimestamp", "valueDate"))
+		if !ok {
+			r.warn(a, "transaction %s has no date, skipped", t.attr("txnId"))
+			continue
+		}
+		typ, ok := bankTxnTypes[strings.ToUpper(t.attr("type"))]
+		if !ok {
+			typ = models.BankTxnTypeOthers
+		}
+		mode := strings.ToUpper(t.attr("mode"))
+		if mode == "" {
+			mode = "OTHERS"
+		}
+		txn := models.BankTxn{
+			Amount:    persona.FormatAmount(amount(t.attr("amount"))),
+			Narration: t.attr("narration"),
+			Date:      date.Format(models.DateLayout),
+			Type:      typ,
+			Mode:      mode,
+		}
+		if b := t.attr("currentBalance"); b != "" {
+			txn.CurrentBalance = persona.FormatAmount(amount(b))
+		}
+		key := t.attr("txnId")
+		if key == "" {
+			key = fmt.Sprint(txn)
+		}
+		if r.seen[id][key] {
+			continue
+		}
+		r.seen[id][key] = true
+		account.Txns = append(account.Txns, txn)
+	}
+	sort.SliceStable(account.Txns, func(i, j int) bool { return account.Txns[i].Date < account.Txns[j].Date })
+}
+
+// termDeposit converts a fixed or recurring deposit
+func (r *Result) termDeposit(a Account, typ string) {
+	summary := a.node.child("summary")
+	ifsc := summary.attr("ifsc", "ifscCode")
+	details := bankDetails(a, ifsc)
+	balance := models.NewMoney(amount(summary.attr("currentValue", "currentBalance", "principalAmount")))
+	s := &models.AccountSummary{
+		CurrentBalance:       &balance,
+		BalanceDate:          rfc3339(summary.attr("balanceDateTime")),
+		OpeningDate:          rfc3339(summary.attr("openingDate")),
+		MaturityDate:         rfc3339(summary.attr("maturityDate")),
+		DepositAccountStatus: depositStatus(summary.attr("status")),
+		Branch:               summary.attr("branch"),
+		IFSCCode:             ifsc,
+	}
+	entry := models.AccountDetailsEntry{AccountDetails: details}
+	if typ == "term_deposit" {
+		s.DepositAccountType = "DEPOSIT_ACCOUNT_TYPE_FIXED"
+		entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_DEPOSIT"
+		entry.AccountDetails.AccountType = map[string]string{"depositAccountType": s.DepositAccountType}
+		entry.DepositSummary = s
+	} else {
+		principal := models.NewMoney(amount(summary.attr("principalAmount")))
+		s.CurrentPrincipalAmount = &principal
+		entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT"
+		entry.AccountDetails.AccountType = map[string]string{"recurringDepositAccountType": "RECURRING_DEPOSIT_ACCOUNT_TYPE_RECURRING"}
+		entry.RecurringDepositSummary = s
+	}
+	s.AccountID = persona.AccountID(details.FipID, typ, details.MaskedAccountNumber)
+	r.Accounts[s.AccountID] = entry
+}
+
+// mutualFunds converts the holdings and transactions of a mutual fund account
+// into schemes keyed by ISIN and folio, which keep the order they are found in
+func (r *Result) mutualFunds(a Account, schemes map[string]*persona.Scheme, keys *[]string) {
+	summary := a.node.child("summary")
+	holdings := summary.find("holding")
+	folio := func(n *node) string {
+		if f := n.attr("folioNo", "folioNumber"); f != "" {
+			return f
+		}
+		return masked(a)
+	}
+	scheme := func(n *node) *persona.Scheme {
+		key := n.attr("isin") + "|" + folio(n)
+		s, ok := schemes[key]
+		if !ok {
+			s = &persona.Scheme{
+				ISIN:      n.attr("isin"),
+				Name:      n.attr("isinDescription", "schemeName"),
+				AMC:       strings.ToUpper(strings.ReplaceAll(n.attr("amc"), " ", "_")),
+				Registrar: persona.Registrar(n.attr("registrar")),
+				Folio:     folio(n),
+				Category:  n.attr("schemeCategory"),
+			}
+			if strings.EqualFold(n.attr("schemeOption"), "IDCW") {
+				s.OptionType = "IDCW"
+			}
+			schemes[key] = s
+			*keys = append(*keys, key)
+		}
+		return s
+	}
+	for _, h := range holdings {
+		if h.attr("isin") == "" {
+			r.warn(a, "holding without an ISIN, skipped")
+			continue
+		}
+		s := scheme(h)
+		s.Units += amount(h.attr("closingUnits", "units"))
+		s.NAV = amount(h.attr("nav"))
+		r.date(h.attr("navDate"))
+	}
+	// the cost of the account is only known per scheme when it holds one
+	if len(holdings) == 1 {
+		if cost := amount(summary.attr("costValue")); cost > 0 {
+			scheme(holdings[0]).Cost = cost
+		}
+	}
+
+	held := map[*persona.Scheme]bool{}
+	for _, s := range schemes {
+		held[s] = s.Units > 0
+	}
+	for _, t := range a.node.child("transactions").find("transaction") {
+		if t.attr("isin") == "" {
+			r.warn(a, "transaction %s has no ISIN, skipped", t.attr("txnId"))
+			continue
+		}
+		date, ok := r.date(t.attr("transactionDate", "navDate"))
+		if !ok {
+			r.warn(a, "transaction %s has no date, skipped", t.attr("txnId"))
+			continue
+		}
+		var orderType int
+		switch typ := strings.ToUpper(t.attr("type")); typ {
+		case "BUY", "PURCHASE", "SIP", "SWITCH_IN", "DIVIDEND_REINVESTMENT", "STP_IN":
+			orderType = models.MFOrderTypeBuy
+		case "SELL", "REDEMPTION", "SWITCH_OUT", "STP_OUT", "SWP":
+			orderType = models.MFOrderTypeSell
+		default:
+			r.warn(a, "transaction %s of type %q is not a purchase or a redemption, skipped", t.attr("txnId"), typ)
+			continue
+		}
+		s := scheme(t)
+		s.Txns = append(s.Txns, models.MFTxn{
+			OrderType: orderType,
+			Date:      date.Format(models.DateLayout),
+			Price:     amount(t.attr("nav")),
+			Units:     amount(t.attr("units")),
+			Amount:    amount(t.attr("amount")),
+		})
+	}
+	// schemes that only appear in the transactions hold what they leave
+	for _, s := range schemes {
+		if held[s] || s.Units > 0 {
+			continue
+		}
+		var units float64
+		for _, t := range s.Txns {
+			if t.OrderType == models.MFOrderTypeBuy {
+				units += t.Units
+			} else {
+				units -= t.Units
+			}
+		}
+		if units > 1e-6 {
+			s.Units = models.Round(units, 3)
+		}
+	}
+}
+
+// depositories are the FIP metadata of NSDL and CDSL demat accounts
+var depositories = map[string]models.FipMeta{
+	"fip@nsdl": {Name: "National Securities Depository Limited", DisplayName: "NSDL"},
+	"fip@cdsl": {Name: "Central Depository Services Limited", DisplayName: "CDSL"},
+}
+
+// equities converts a demat account and its trades
+func (r *Result) equities(a Account) {
+	fipID := strings.ToLower(a.FipID)
+	if !strings.Contains(fipID, "cdsl") {
+		fipID = "fip@nsdl"
+	} else {
+		fipID = "fip@cdsl"
+	}
+	meta := depositories[fipID]
+	id := persona.AccountID(fipID, "equities", masked(a))
+	summary := &models.AccountSummary{AccountID: id}
+	var total float64
+	for _, h := range a.node.child("summary").find("holding") {
+		price := models.NewMoney(amount(h.attr("lastTradedPrice")))
+		holding := models.Holding{
+			ISIN:            h.attr("isin"),
+			ISINDescription: h.attr("isinDescription"),
+			IssuerName:      h.attr("issuerName"),
+			Type:            "EQUITY_HOLDING_TYPE_DEMAT",
+			Units:           amount(h.attr("units")),
+			LastTradedPrice: &price,
+		}
+		summary.HoldingsInfo = append(summary.HoldingsInfo, holding)
+		total += models.Round(holding.Quantity()*holding.Price(), 2)
+	}
+	if v := amount(a.node.child("summary").attr("currentValue")); v > 0 {
+		total = v
+	}
+	value := models.NewMoney(models.Round(total, 2))
+	summary.CurrentValue = &value
+	r.Accounts[id] = models.AccountDetailsEntry{
+		AccountDetails: models.AccountDetails{
+			FipID:               fipID,
+			MaskedAccountNumber: masked(a),
+			AccInstrumentType:   "ACC_INSTRUMENT_TYPE_EQUITIES",
+			AccountType:         map[string]string{"equityAccountType": "EQUITY_ACCOUNT_TYPE_DEFAULT_TYPE"},
+			FipMeta:             &meta,
+		},
+		EquitySummary: summary,
+	}
+
+	for _, t := range a.node.child("transactions").find("transaction") {
+		date, ok := r.date(t.attr("transactionDateTime", "transactionDate"))
+		if !ok {
+			r.warn(a, "transaction %s has no date, skipped", t.attr("txnId"))
+			continue
+		}
+		txn := models.StockTxn{Date: date.Format(models.DateLayout), Quantity: amount(t.attr("units"))}
+		switch typ := strings.ToUpper(t.attr("type")); typ {
+		case "BUY":
+			txn.Type = models.StockTxnTypeBuy
+		case "SELL":
+			txn.Type = models.StockTxnTypeSell
+		case "BONUS":
+			txn.Type = models.StockTxnTypeBonus
+		case "SPLIT":
+			txn.Type = models.StockTxnTypeSplit
+		default:
+			r.warn(a, "transaction %s of type %q is not supported, skipped", t.attr("txnId"), typ)
+			continue
+		}
+		if rate := amount(t.attr("rate", "tradePrice")); rate > 0 {
+			txn.NAV = &rate
+		}
+		isin := t.attr("isin")
+		i := slices.IndexFunc(r.Stocks, func(s models.StockTransactions) bool { return s.ISIN == isin })
+		if i < 0 {
+			r.Stocks = append(r.Stocks, models.StockTransactions{ISIN: isin})
+			i = len(r.Stocks) - 1
+		}
+		r.Stocks[i].Txns = append(r.Stocks[i].Txns, txn)
+	}
+	for _, s := range r.Stocks {
+		sort.SliceStable(s.Txns, func(i, j int) bool { return s.Txns[i].Date < s.Txns[j].Date })
+	}
+}
@@ -0,0 +1,200 @@
+// Package aa reads Account Aggregator FI data in the ReBIT formats and converts
+// it into the responses of the data tools.
+//
+// The ReBIT schemas are the same in XML and JSON: every FI type has an Account
+// with Profile, Summary and Transactions elements whose values are attributes.
+// Both encodings are read into the same tree, so the converters don't care which
+// one a payload used.
+package aa
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// node is an element of a payload. Names and attribute keys are lowercased.
+// Scalar JSON values and XML attributes are attributes, JSON objects and XML
+// elements are children.
+type node struct {
+	name     string
+	attrs    map[string]string
+	children []*node
+}
+
+func newNode(name string) *node {
+	return &node{name: strings.ToLower(name), attrs: map[string]string{}}
+}
+
+// attr returns the first non-empty attribute of keys
+func (n *node) attr(keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(n.attrs[strings.ToLower(k)]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// child returns the first child called name, or an empty node
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return newNode(name)
+}
+
+// find returns the descendants called name, not looking inside the matches
+func (n *node) find(name string) []*node {
+	var out []*node
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, c.find(name)...)
+	}
+	return out
+}
+
+// Account is one account of a payload with the id of the FIP that sent it
+type Account struct {
+	FipID string
+	node  *node
+}
+
+// Type returns the ReBIT FI type of the account, e.g. deposit or mutual_funds
+func (a Account) Type() string {
+	return strings.ReplaceAll(strings.ToLower(a.node.attr("type")), "-", "_")
+}
+
+// Parse reads the accounts of a FI payload. It takes a single Account document,
+// a decrypted FI response with several of them or a list of either, in XML or
+// JSON.
+func Parse(data []byte) ([]Account, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var root *node
+	var err error
+	if len(data) > 0 && data[0] == '<' {
+		root, err = parseXML(data)
+	} else {
+		root, err = parseJSON(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var accounts []Account
+	collect(root, "", &accounts)
+	if len(accounts) == 0 {
+		return nil, errors.New("no Account found")
+	}
+	return accounts, nil
+}
+
+// collect adds the accounts under n, passing the FIP id down from the FI element
+func collect(n *node, fipID string, accounts *[]Account) {
+	if id := n.attr("fipID", "fip_id"); id != "" {
+		fipID = id
+	}
+	if n.name == "account" || n.attr("type") != "" && n.attr("maskedAccNumber", "maskedFolioNo", "maskedDematID") != "" {
+		*accounts = append(*accounts, Account{FipID: fipID, node: n})
+		return
+	}
+	for _, c := range n.children {
+		collect(c, fipID, accounts)
+	}
+}
+
+func parseXML(data []byte) (*node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := newNode("")
+	stack := []*node{root}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := newNode(t.Name.Local)
+			for _, a := range t.Attr {
+				n.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			// <amount>100</amount> is read like amount="100"
+			if s := strings.TrimSpace(text.String()); s != "" && len(n.children) == 0 {
+				parent := stack[len(stack)-1]
+				parent.attrs[n.name] = s
+			}
+			text.Reset()
+		}
+	}
+	return root, nil
+}
+
+func parseJSON(data []byte) (*node, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	root := newNode("")
+	addJSON(root, "", v)
+	return root, nil
+}
+
+// addJSON adds the value of key to n
+func addJSON(n *node, key string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		c := n
+		if key != "" {
+			c = newNode(key)
+			n.children = append(n.children, c)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// objects keep no order, sorting the keys at least makes it the same on every run
+		sort.Strings(keys)
+		for _, k := range keys {
+			addJSON(c, k, v[k])
+		}
+	case []any:
+		if key == "" {
+			// a list of documents, each gets a node of its own
+			key = "item"
+		}
+		for _, x := range v {
+			addJSON(n, key, x)
+		}
+	case string:
+		n.attrs[strings.ToLower(key)] = v
+	case json.Number:
+		n.attrs[strings.ToLower(key)] = v.String()
+	case bool:
+		n.attrs[strings.ToLower(key)] = strconv.FormatBool(v)
+	}
+}
@@ -0,0 +1,321 @@
+package allocation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Holding sources
+const (
+	SourceMFAnalytics = "mfSchemeAnalytics"
+	SourceAccounts    = "accountDetailsBulkResponse"
+	SourceNetWorth    = "netWorthResponse"
+)
+
+// targetTolerance is how far the target allocation may sum from 100%
+const targetTolerance = 0.5
+
+// Holding is one position of the portfolio and the bucket it is allocated to
+type Holding struct {
+	Name          string  `json:"name"`
+	ISIN          string  `json:"isin,omitempty"`
+	AMC           string  `json:"amc,omitempty"`
+	Attribute     string  `json:"netWorthAttribute"`
+	Bucket        Bucket  `json:"bucket"`
+	Source        string  `json:"source"`
+	Value         float64 `json:"value"`
+	WeightPercent float64 `json:"weightPercent"`
+}
+
+// BucketAllocation is the value held in one bucket
+type BucketAllocation struct {
+	Bucket        Bucket   `json:"bucket"`
+	Value         float64  `json:"value"`
+	Percent       float64  `json:"percent"`
+	TargetPercent *float64 `json:"targetPercent,omitempty"`
+	DriftPercent  *float64 `json:"driftPercent,omitempty"`
+	// RebalanceAmount is the amount to add (positive) or remove (negative) to reach the target
+	RebalanceAmount *float64 `json:"rebalanceAmount,omitempty"`
+}
+
+// AttributeReconciliation compares a netWorthAttribute with the holdings it was broken down into
+type AttributeReconciliation struct {
+	Attribute      string  `json:"netWorthAttribute"`
+	ReportedValue  float64 `json:"reportedValue"`
+	AllocatedValue float64 `json:"allocatedValue"`
+	Source         string  `json:"source"`
+}
+
+// AMCExposure is the mutual fund value managed by one AMC
+type AMCExposure struct {
+	AMC                  string  `json:"amc"`
+	Value                float64 `json:"value"`
+	PercentOfMutualFunds float64 `json:"percentOfMutualFunds"`
+	PercentOfPortfolio   float64 `json:"percentOfPortfolio"`
+}
+
+// Concentration measures how concentrated the portfolio is
+type Concentration struct {
+	LargestHolding *Holding `json:"largestHolding,omitempty"`
+	TopFivePercent float64  `json:"topFivePercent"`
+	// HHI is the Herfindahl-Hirschman index of the holding weights in percent, from 0 to 10000
+	HHI                       float64       `json:"hhi"`
+	EffectiveNumberOfHoldings float64       `json:"effectiveNumberOfHoldings"`
+	AMCs                      []AMCExposure `json:"amcs"`
+	AMCHHI                    float64       `json:"amcHhi"`
+}
+
+// Report is the asset allocation of a user
+type Report struct {
+	TotalValue      float64                   `json:"totalValue"`
+	Allocation      []BucketAllocation        `json:"allocation"`
+	MaxDriftPercent *float64                  `json:"maxDriftPercent,omitempty"`
+	Concentration   Concentration             `json:"concentration"`
+	Holdings        []Holding                 `json:"holdings"`
+	Reconciliation  []AttributeReconciliation `json:"reconciliation"`
+	Notes           []string                  `json:"notes"`
+}
+
+// ParseTarget validates a target allocation given as percentages per bucket name
+func ParseTarget(raw map[string]float64) (map[Bucket]float64, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	target := make(map[Bucket]float64, len(raw))
+	var total float64
+	for name, pct := range raw {
+		b, ok := ParseBucket(name)
+		if !ok {
+			names := make([]string, len(Buckets))
+			for i, b := range Buckets {
+				names[i] = strings.ToLower(string(b))
+			}
+			return nil, fmt.Errorf("unknown asset class %q in target allocation, expected one of %s", name, strings.Join(names, ", "))
+		}
+		if pct < 0 {
+			return nil, fmt.Errorf("target allocation for %s must not be negative", name)
+		}
+		target[b] += pct
+		total += pct
+	}
+	if math.Abs(total-100) > targetTolerance {
+		return nil, fmt.Errorf("target allocation must sum to 100, got %v", models.Round(total, 2))
+	}
+	return target, nil
+}
+
+// Analyze breaks the assets in the net worth response down into buckets. Mutual
+// funds are split by scheme using mfSchemeAnalytics, securities and deposits by
+// holding using accountDetailsBulkResponse, and the remaining netWorthAttributes
+// are allocated as a whole. Liabilities are not part of the allocation.
+func Analyze(netWorth *models.FetchNetWorthResponse, target map[Bucket]float64) *Report {
+	r := &Report{Holdings: []Holding{}, Reconciliation: []AttributeReconciliation{}, Notes: []string{}}
+	if netWorth == nil || netWorth.NetWorthResponse == nil {
+		r.Notes = append(r.Notes, "no net worth data available")
+		r.Allocation = allocate(nil, 0, target)
+		return r
+	}
+
+	detailed := map[string][]Holding{
+		"ASSET_TYPE_MUTUAL_FUND": mfHoldings(netWorth.MFSchemeAnalytics),
+	}
+	for attribute, holdings := range accountHoldings(netWorth.AccountDetailsBulkResponse, netWorth.MFSchemeAnalytics) {
+		detailed[attribute] = append(detailed[attribute], holdings...)
+	}
+
+	reported := make(map[string]float64)
+	var attributes []string
+	for _, a := range netWorth.NetWorthResponse.AssetValues {
+		if strings.HasPrefix(a.NetWorthAttribute, "LIABILITY_") {
+			continue
+		}
+		if _, ok := reported[a.NetWorthAttribute]; !ok {
+			attributes = append(attributes, a.NetWorthAttribute)
+		}
+		reported[a.NetWorthAttribute] += a.Value.Float()
+	}
+	// ETFs, REITs and InvITs are part of the Indian securities unless reported on their own
+	for _, attribute := range []string{"ASSET_TYPE_ETF", "ASSET_TYPE_REIT", "ASSET_TYPE_INVIT"} {
+		if _, ok := reported[attribute]; !ok && len(detailed[attribute]) > 0 {
+			for _, h := range detailed[attribute] {
+				h.Attribute = "ASSET_TYPE_INDIAN_SECURITIES"
+				detailed[h.Attribute] = append(detailed[h.Attribute], h)
+			}
+			delete(detailed, attribute)
+		}
+	}
+	// holdings found in the account details without a matching attribute are still assets
+	for attribute, holdings := range detailed {
+		if _, ok := reported[attribute]; !ok && len(holdings) > 0 {
+			attributes = append(attributes, attribute)
+		}
+	}
+	sort.Strings(attributes)
+
+	for _, attribute := range attributes {
+		rec := AttributeReconciliation{Attribute: attribute, ReportedValue: models.Round(reported[attribute], 2)}
+		holdings := detailed[attribute]
+		if len(holdings) == 0 {
+			if reported[attribute] > 0 {
+				holdings = []Holding{{
+					Name:      attributeName(attribute),
+					Attribute: attribute,
+					Bucket:    attributeBucket(attribute),
+					Source:    SourceNetWorth,
+					Value:     reported[attribute],
+				}}
+			}
+			rec.Source = SourceNetWorth
+		} else {
+			rec.Source = holdings[0].Source
+		}
+		var allocated float64
+		for _, h := range holdings {
+			allocated += h.Value
+		}
+		rec.AllocatedValue = models.Round(allocated, 2)
+		if math.Abs(allocated-reported[attribute]) >= 1 {
+			r.Notes = append(r.Notes, fmt.Sprintf("%s is reported as %.2f in assetValues but its holdings in %s add up to %.2f, the holdings are used",
+				attribute, rec.ReportedValue, rec.Source, rec.AllocatedValue))
+		}
+		r.Reconciliation = append(r.Reconciliation, rec)
+		r.Holdings = append(r.Holdings, mergeByISIN(holdings)...)
+	}
+
+	var total float64
+	for _, h := range r.Holdings {
+		total += h.Value
+	}
+	sort.SliceStable(r.Holdings, func(i, j int) bool { return r.Holdings[i].Value > r.Holdings[j].Value })
+	for i := range r.Holdings {
+		if total > 0 {
+			r.Holdings[i].WeightPercent = models.Round(r.Holdings[i].Value/total*100, 2)
+		}
+		r.Holdings[i].Value = models.Round(r.Holdings[i].Value, 2)
+	}
+	r.TotalValue = models.Round(total, 2)
+	r.Allocation = allocate(r.Holdings, total, target)
+	if target != nil && total > 0 {
+		var maxDrift float64
+		for _, a := range r.Allocation {
+			if a.DriftPercent != nil && math.Abs(*a.DriftPercent) > math.Abs(maxDrift) {
+				maxDrift = *a.DriftPercent
+			}
+		}
+		r.MaxDriftPercent = &maxDrift
+	}
+	r.Concentration = concentration(r.Holdings, total)
+	return r
+}
+
+// attributeNames are the display names of the netWorthAttributes allocated as a whole
+var attributeNames = map[string]string{
+	"ASSET_TYPE_MUTUAL_FUND":       "Mutual funds",
+	"ASSET_TYPE_INDIAN_SECURITIES": "Indian securities",
+	"ASSET_TYPE_ETF":               "ETFs",
+	"ASSET_TYPE_US_SECURITIES":     "US securities",
+	"ASSET_TYPE_EPF":               "EPF",
+	"ASSET_TYPE_DEPOSITS":          "Deposits",
+	"ASSET_TYPE_SAVINGS_ACCOUNTS":  "Savings accounts",
+	"ASSET_TYPE_SGB":               "Sovereign gold bonds",
+	"ASSET_TYPE_NPS":               "NPS",
+}
+
+func attributeName(attribute string) string {
+	if name, ok := attributeNames[attribute]; ok {
+		return name
+	}
+	return attribute
+}
+
+// mergeByISIN combines the holdings of a security held in several folios or accounts
+func mergeByISIN(holdings []Holding) []Holding {
+	var merged []Holding
+	index := make(map[string]int)
+	for _, h := range holdings {
+		if h.ISIN == "" {
+			merged = append(merged, h)
+			continue
+		}
+		if i, ok := index[h.ISIN]; ok {
+			merged[i].Value += h.Value
+			continue
+		}
+		index[h.ISIN] = len(merged)
+		merged = append(merged, h)
+	}
+	return merged
+}
+
+func allocate(holdings []Holding, total float64, target map[Bucket]float64) []BucketAllocation {
+	values := make(map[Bucket]float64)
+	for _, h := range holdings {
+		values[h.Bucket] += h.Value
+	}
+	allocation := make([]BucketAllocation, 0, len(Buckets))
+	for _, b := range Buckets {
+		a := BucketAllocation{Bucket: b, Value: models.Round(values[b], 2)}
+		if total > 0 {
+			a.Percent = models.Round(values[b]/total*100, 2)
+		}
+		if target != nil && total > 0 {
+			pct := target[b]
+			drift := models.Round(a.Percent-pct, 2)
+			rebalance := models.Round(total*pct/100-values[b], 2)
+			a.TargetPercent, a.DriftPercent, a.RebalanceAmount = &pct, &drift, &rebalance
+		}
+		allocation = append(allocation, a)
+	}
+	return allocation
+}
+
+func concentration(holdings []Holding, total float64) Concentration {
+	c := Concentration{AMCs: []AMCExposure{}}
+	if total <= 0 || len(holdings) == 0 {
+		return c
+	}
+	largest := holdings[0]
+	c.LargestHolding = &largest
+	for i, h := range holdings {
+		w := h.Value / total * 100
+		c.HHI += w * w
+		if i < 5 {
+			c.TopFivePercent += w
+		}
+	}
+	c.EffectiveNumberOfHoldings = models.Round(10000/c.HHI, 2)
+	c.HHI = models.Round(c.HHI, 2)
+	c.TopFivePercent = models.Round(c.TopFivePercent, 2)
+
+	amcValues := make(map[string]float64)
+	var mfTotal float64
+	for _, h := range holdings {
+		if h.AMC == "" {
+			continue
+		}
+		amcValues[h.AMC] += h.Value
+		mfTotal += h.Value
+	}
+	for amc, v := range amcValues {
+		share := v / mfTotal * 100
+		c.AMCHHI += share * share
+		c.AMCs = append(c.AMCs, AMCExposure{
+			AMC:                  amc,
+			Value:                models.Round(v, 2),
+			PercentOfMutualFunds: models.Round(share, 2),
+			PercentOfPortfolio:   models.Round(v/total*100, 2),
+		})
+	}
+	sort.Slice(c.AMCs, func(i, j int) bool {
+		if c.AMCs[i].Value != c.AMCs[j].Value {
+			return c.AMCs[i].Value > c.AMCs[j].Value
+		}
+		return c.AMCs[i].AMC < c.AMCs[j].AMC
+	})
+	c.AMCHHI = models.Round(c.AMCHHI, 2)
+	return c
+}
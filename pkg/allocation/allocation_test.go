@@ -0,0 +1,80 @@
+package allocation
+
+import (
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func money(v float64) *models.Money {
+	m := models.NewMoney(v)
+	return &m
+}
+
+func scheme(isin, amc, assetClass, category string, value float64) models.SchemeAnalytics {
+	var s models.SchemeAnalytics
+	s.SchemeDetail = models.SchemeDetail{AMC: amc, ISINNumber: isin, AssetClass: assetClass, CategoryName: category}
+	s.EnrichedAnalytics.Analytics.SchemeDetails.CurrentValue = money(value)
+	return s
+}
+
+func TestAnalyze(t *testing.T) {
+	netWorth := &models.FetchNetWorthResponse{
+		NetWorthResponse: &models.NetWorthResponse{
+			AssetValues: []models.NetWorthValue{
+				{NetWorthAttribute: "ASSET_TYPE_MUTUAL_FUND", Value: money(600)},
+				{NetWorthAttribute: "ASSET_TYPE_SGB", Value: money(200)},
+				{NetWorthAttribute: "ASSET_TYPE_SAVINGS_ACCOUNTS", Value: money(200)},
+				{NetWorthAttribute: "LIABILITY_TYPE_CREDIT_CARD", Value: money(-50)},
+			},
+		},
+		MFSchemeAnalytics: &models.MFSchemeAnalytics{SchemeAnalytics: []models.SchemeAnalytics{
+			scheme("A", "AMC_1", "EQUITY", "FLEXI_CAP_FUND", 300),
+			scheme("B", "AMC_1", "EQUITY", "INTERNATIONAL_FUNDS", 100),
+			scheme("C", "AMC_2", "DEBT", "GOVERNMENT_BOND", 200),
+		}},
+	}
+	target, err := ParseTarget(map[string]float64{"equity": 50, "Debt": 30, "gold": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Analyze(netWorth, target)
+	if r.TotalValue != 1000 {
+		t.Errorf("total = %v, want 1000", r.TotalValue)
+	}
+	want := map[Bucket]struct{ percent, drift float64 }{
+		BucketEquity:        {30, -20},
+		BucketDebt:          {20, -10},
+		BucketGold:          {20, 0},
+		BucketCash:          {20, 20},
+		BucketInternational: {10, 10},
+	}
+	for _, a := range r.Allocation {
+		w := want[a.Bucket]
+		if a.Percent != w.percent || a.DriftPercent == nil || *a.DriftPercent != w.drift {
+			t.Errorf("%s = %v%% drift %v, want %v%% drift %v", a.Bucket, a.Percent, a.DriftPercent, w.percent, w.drift)
+		}
+	}
+	if r.MaxDriftPercent == nil || *r.MaxDriftPercent != -20 {
+		t.Errorf("max drift = %v, want -20", r.MaxDriftPercent)
+	}
+	// weights of 30, 20, 20, 20, 10
+	if r.Concentration.HHI != 2200 {
+		t.Errorf("hhi = %v, want 2200", r.Concentration.HHI)
+	}
+	if amcs := r.Concentration.AMCs; len(amcs) != 2 || amcs[0].AMC != "AMC_1" || amcs[0].PercentOfMutualFunds != 66.67 {
+		t.Errorf("amcs = %+v, want AMC_1 first with 66.67%%", amcs)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, target := range []map[string]float64{
+		{"equity": 60, "debt": 30},
+		{"equity": 60, "crypto": 40},
+		{"equity": 110, "debt": -10},
+	} {
+		if _, err := ParseTarget(target); err == nil {
+			t.Errorf("ParseTarget(%v) succeeded, want an error", target)
+		}
+	}
+}
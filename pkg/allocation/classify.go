@@ -0,0 +1,116 @@
+package allocation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Bucket is a broad asset class of the allocation breakdown
+type Bucket string
+
+const (
+	BucketEquity        Bucket = "EQUITY"
+	BucketDebt          Bucket = "DEBT"
+	BucketGold          Bucket = "GOLD"
+	BucketCash          Bucket = "CASH"
+	BucketRealEstate    Bucket = "REAL_ESTATE"
+	BucketInternational Bucket = "INTERNATIONAL"
+	// BucketOther holds assets with a mixed or unknown underlying, e.g. NPS
+	BucketOther Bucket = "OTHER"
+)
+
+// Buckets lists the buckets in the order they are reported
+var Buckets = []Bucket{BucketEquity, BucketDebt, BucketGold, BucketCash, BucketRealEstate, BucketInternational, BucketOther}
+
+// ParseBucket accepts a bucket name in any case, with spaces or dashes for underscores
+func ParseBucket(s string) (Bucket, bool) {
+	name := strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(s)))
+	for _, b := range Buckets {
+		if string(b) == name {
+			return b, true
+		}
+	}
+	return "", false
+}
+
+// attributeBuckets is the bucket of each netWorthAttribute when no finer detail is available
+var attributeBuckets = map[string]Bucket{
+	"ASSET_TYPE_MUTUAL_FUND":       BucketEquity,
+	"ASSET_TYPE_INDIAN_SECURITIES": BucketEquity,
+	"ASSET_TYPE_ETF":               BucketEquity,
+	"ASSET_TYPE_US_SECURITIES":     BucketInternational,
+	"ASSET_TYPE_EPF":               BucketDebt,
+	"ASSET_TYPE_DEPOSITS":          BucketDebt,
+	"ASSET_TYPE_SAVINGS_ACCOUNTS":  BucketCash,
+	"ASSET_TYPE_SGB":               BucketGold,
+	"ASSET_TYPE_REIT":              BucketRealEstate,
+	"ASSET_TYPE_INVIT":             BucketRealEstate,
+}
+
+func attributeBucket(attribute string) Bucket {
+	if b, ok := attributeBuckets[attribute]; ok {
+		return b
+	}
+	return BucketOther
+}
+
+// equityHybridCategories are hybrid categories that hold mostly equity
+var equityHybridCategories = map[string]bool{
+	"AGGRESSIVE_HYBRID_FUND":   true,
+	"BALANCED_ADVANTAGE_FUND":  true,
+	"DYNAMIC_ASSET_ALLOCATION": true,
+	"MULTI_ASSET_ALLOCATION":   true,
+	"EQUITY_SAVINGS":           true,
+}
+
+var (
+	goldNamePattern          = regexp.MustCompile(`(?i)\b(gold|silver)\b`)
+	internationalNamePattern = regexp.MustCompile(`(?i)\b(international|global|overseas|nasdaq|s&p 500|hang seng|us equity|world)\b`)
+	cashNamePattern          = regexp.MustCompile(`(?i)\b(liquid|overnight|money market)\b`)
+	debtNamePattern          = regexp.MustCompile(`(?i)\b(debt|bond|gilt|g-sec|gsec|treasury|duration|income|credit risk|banking and psu|sdl)\b`)
+	realEstateNamePattern    = regexp.MustCompile(`(?i)\b(reit|invit|real estate|realty|infrastructure investment trust)\b`)
+)
+
+// classifyMF returns the bucket of a mutual fund from its asset class and
+// category in mfSchemeAnalytics, falling back to the scheme name
+func classifyMF(assetClass, categoryName, schemeName string) Bucket {
+	switch assetClass {
+	case "EQUITY":
+		if categoryName == "INTERNATIONAL_FUNDS" {
+			return BucketInternational
+		}
+		return BucketEquity
+	case "HYBRID":
+		if equityHybridCategories[categoryName] {
+			return BucketEquity
+		}
+		return BucketDebt
+	case "DEBT":
+		if categoryName == "LIQUID_FUND" || categoryName == "MONEY_MARKET_FUND" || categoryName == "OVERNIGHT_FUND" {
+			return BucketCash
+		}
+		return BucketDebt
+	case "CASH":
+		return BucketCash
+	case "COMMODITY":
+		return BucketGold
+	}
+	return classifyName(schemeName, BucketEquity)
+}
+
+// classifyName guesses the bucket of a fund or ETF from its name
+func classifyName(name string, fallback Bucket) Bucket {
+	switch {
+	case goldNamePattern.MatchString(name):
+		return BucketGold
+	case internationalNamePattern.MatchString(name):
+		return BucketInternational
+	case realEstateNamePattern.MatchString(name):
+		return BucketRealEstate
+	case cashNamePattern.MatchString(name):
+		return BucketCash
+	case debtNamePattern.MatchString(name):
+		return BucketDebt
+	}
+	return fallback
+}
@@ -0,0 +1,125 @@
+package allocation
+
+import (
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// mfHoldings returns a holding per scheme in mfSchemeAnalytics valued at its current value
+func mfHoldings(analytics *models.MFSchemeAnalytics) []Holding {
+	if analytics == nil {
+		return nil
+	}
+	var holdings []Holding
+	for _, s := range analytics.SchemeAnalytics {
+		d := s.SchemeDetail
+		value := s.EnrichedAnalytics.Analytics.SchemeDetails.CurrentValue.Float()
+		if value == 0 {
+			continue
+		}
+		holdings = append(holdings, Holding{
+			Name:      d.NameData.LongName,
+			ISIN:      d.ISINNumber,
+			AMC:       d.AMC,
+			Attribute: "ASSET_TYPE_MUTUAL_FUND",
+			Bucket:    classifyMF(d.AssetClass, d.CategoryName, d.NameData.LongName),
+			Source:    SourceMFAnalytics,
+			Value:     value,
+		})
+	}
+	return holdings
+}
+
+// accountHoldings returns the holdings of the demat and deposit accounts keyed by
+// the netWorthAttribute they are part of. Savings accounts, EPF and the other
+// account types are left to their netWorthAttribute.
+func accountHoldings(accounts *models.AccountDetailsBulkResponse, analytics *models.MFSchemeAnalytics) map[string][]Holding {
+	schemes := make(map[string]models.SchemeDetail)
+	if analytics != nil {
+		for _, s := range analytics.SchemeAnalytics {
+			schemes[s.SchemeDetail.ISINNumber] = s.SchemeDetail
+		}
+	}
+	out := make(map[string][]Holding)
+	for _, id := range accounts.AccountIDs() {
+		entry := accounts.AccountDetailsMap[id]
+		var (
+			attribute string
+			summary   *models.AccountSummary
+			classify  func(h models.Holding) Bucket
+		)
+		switch entry.AccountDetails.AccInstrumentType {
+		case "ACC_INSTRUMENT_TYPE_EQUITIES":
+			attribute, summary = "ASSET_TYPE_INDIAN_SECURITIES", entry.EquitySummary
+			classify = func(models.Holding) Bucket { return BucketEquity }
+		case "ACC_INSTRUMENT_TYPE_ETF":
+			attribute, summary = "ASSET_TYPE_ETF", entry.ETFSummary
+			classify = func(h models.Holding) Bucket {
+				if s, ok := schemes[h.ISIN]; ok {
+					return classifyMF(s.AssetClass, s.CategoryName, h.Name())
+				}
+				return classifyName(h.Name(), BucketEquity)
+			}
+		case "ACC_INSTRUMENT_TYPE_REIT":
+			attribute, summary = "ASSET_TYPE_REIT", entry.REITSummary
+			classify = func(models.Holding) Bucket { return BucketRealEstate }
+		case "ACC_INSTRUMENT_TYPE_INVIT":
+			attribute, summary = "ASSET_TYPE_INVIT", entry.InvITSummary
+			classify = func(models.Holding) Bucket { return BucketRealEstate }
+		case "ACC_INSTRUMENT_TYPE_DEPOSIT":
+			if entry.DepositSummary == nil || entry.DepositSummary.DepositAccountType != "DEPOSIT_ACCOUNT_TYPE_FIXED" {
+				continue
+			}
+			out["ASSET_TYPE_DEPOSITS"] = append(out["ASSET_TYPE_DEPOSITS"], depositHolding(entry, entry.DepositSummary, "fixed deposit"))
+			continue
+		case "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT":
+			if entry.RecurringDepositSummary == nil {
+				continue
+			}
+			out["ASSET_TYPE_DEPOSITS"] = append(out["ASSET_TYPE_DEPOSITS"], depositHolding(entry, entry.RecurringDepositSummary, "recurring deposit"))
+			continue
+		default:
+			continue
+		}
+		if summary == nil {
+			continue
+		}
+		var priced []Holding
+		var pricedValue float64
+		for _, h := range summary.HoldingsInfo {
+			value := h.Quantity() * h.Price()
+			if value <= 0 {
+				continue
+			}
+			priced = append(priced, Holding{
+				Name:      h.Name(),
+				ISIN:      h.ISIN,
+				Attribute: attribute,
+				Bucket:    classify(h),
+				Source:    SourceAccounts,
+				Value:     value,
+			})
+			pricedValue += value
+		}
+		// without prices the holdings can't be valued, allocate the account as a whole
+		if len(priced) == 0 && summary.Value() > 0 {
+			var bucket Bucket
+			name := entry.AccountDetails.MaskedAccountNumber
+			if len(summary.HoldingsInfo) > 0 {
+				bucket, name = classify(summary.HoldingsInfo[0]), summary.HoldingsInfo[0].Name()
+			} else {
+				bucket = classify(models.Holding{})
+			}
+			priced = append(priced, Holding{Name: name, Attribute: attribute, Bucket: bucket, Source: SourceAccounts, Value: summary.Value()})
+		}
+		out[attribute] = append(out[attribute], priced...)
+	}
+	return out
+}
+
+func depositHolding(entry models.AccountDetailsEntry, summary *models.AccountSummary, kind string) Holding {
+	name := kind + " " + entry.AccountDetails.MaskedAccountNumber
+	if entry.AccountDetails.FipMeta != nil && entry.AccountDetails.FipMeta.DisplayName != "" {
+		name = entry.AccountDetails.FipMeta.DisplayName + " " + name
+	}
+	return Holding{Name: name, Attribute: "ASSET_TYPE_DEPOSITS", Bucket: BucketDebt, Source: SourceAccounts, Value: summary.Value()}
+}
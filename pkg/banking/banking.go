@@ -0,0 +1,61 @@
+package banking
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var (
+	digitsPattern = regexp.MustCompile(`[0-9]+`)
+	monthPattern  = regexp.MustCompile(`(?i)\b(jan(uary)?|feb(ruary)?|mar(ch)?|apr(il)?|may|june?|july?|aug(ust)?|sept?(ember)?|oct(ober)?|nov(ember)?|dec(ember)?)\b`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// Transaction is a bank transaction referenced by an analysis
+type Transaction struct {
+	Bank      string  `json:"bank"`
+	Date      string  `json:"date"`
+	Amount    float64 `json:"amount"`
+	Narration string  `json:"narration"`
+	// Source is the JSON pointer of the row in the fetch_bank_transactions payload
+	Source string `json:"source"`
+}
+
+// NewTransaction references row txn of account bank in the fetch_bank_transactions payload
+func NewTransaction(resp *models.BankTransactionsResponse, bank, txn int) Transaction {
+	b := resp.BankTransactions[bank]
+	t := b.Txns[txn]
+	return Transaction{
+		Bank:      b.Bank,
+		Date:      t.Date,
+		Amount:    t.AmountValue(),
+		Narration: t.Narration,
+		Source:    SourcePointer(bank, txn),
+	}
+}
+
+// SourcePointer returns the JSON pointer of a row in the fetch_bank_transactions payload
+func SourcePointer(bank, txn int) string {
+	return fmt.Sprintf("/bankTransactions/%d/txns/%d", bank, txn)
+}
+
+// NarrationKey normalises a narration so that the same payee or payer in different
+// months maps to the same key: digits, month names and extra spaces are removed
+func NarrationKey(narration string) string {
+	key := strings.ToUpper(narration)
+	key = digitsPattern.ReplaceAllString(key, "#")
+	key = monthPattern.ReplaceAllString(key, "")
+	key = spacePattern.ReplaceAllString(key, " ")
+	return strings.Trim(key, " -/")
+}
+
+// monthOf returns the YYYY-MM part of a YYYY-MM-DD date
+func monthOf(date string) string {
+	if len(date) < 7 {
+		return date
+	}
+	return date[:7]
+}
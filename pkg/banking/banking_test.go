@@ -0,0 +1,61 @@
+package banking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func TestCounterparty(t *testing.T) {
+	tests := map[string]string{
+		"UPI-SWIGGY-SWIGGY8@YBL-YESB0YBLUPI-101057836776-PAYMENT FROM PHONE":        "SWIGGY",
+		"UPI-HARDIK  AGRAWAL-9971488189.NIYO@IDFCBANK-IDFB0040101-031108651605-UPI": "HARDIK AGRAWAL",
+		"UPI-XXXXXX4811-JOHN@OKSBI-IDIB0000001-101911439713-PAYMENT FROM PHONE":     "JOHN@OKSBI",
+		"IMPS-RAKESH KUMAR-JULY RENT":                                               "IMPS-RAKESH KUMAR- RENT",
+		"ACH D-HDFCMF-SIP/FLEXICAP/WG-45001":                                        "ACH D-HDFCMF-SIP/FLEXICAP/WG-#",
+	}
+	for narration, want := range tests {
+		if got := Counterparty(narration); got != want {
+			t.Errorf("Counterparty(%q) = %q, want %q", narration, got, want)
+		}
+	}
+}
+
+func TestSummarizeCashFlow(t *testing.T) {
+	resp := &models.BankTransactionsResponse{BankTransactions: []models.BankTransactions{{
+		Bank: "Test Bank",
+		Txns: []models.BankTxn{
+			{Amount: "50000", Narration: "SALARY CREDIT - ACME - JUNE 2024", Date: "2024-06-01", Type: models.BankTxnTypeCredit, CurrentBalance: "60000"},
+			{Amount: "5000", Narration: "ACH D-HDFCMF-SIP/20240605/X1", Date: "2024-06-05", Type: models.BankTxnTypeInstallment, CurrentBalance: "55000"},
+			{Amount: "700", Narration: "UPI-SWIGGY-SWIGGY@YBL-FOOD ORDER", Date: "2024-06-09", Type: models.BankTxnTypeDebit, CurrentBalance: "54300"},
+			{Amount: "50000", Narration: "SALARY CREDIT - ACME - JULY 2024", Date: "2024-07-01", Type: models.BankTxnTypeCredit, CurrentBalance: "104300"},
+			{Amount: "5000", Narration: "ACH D-HDFCMF-SIP/20240705/X1", Date: "2024-07-05", Type: models.BankTxnTypeInstallment, CurrentBalance: "90000"},
+			{Amount: "1500", Narration: "UPI-SWIGGY-SWIGGY@YBL-FOOD ORDER", Date: "2024-07-09", Type: models.BankTxnTypeDebit, CurrentBalance: "88500"},
+		},
+	}}}
+	cf := SummarizeCashFlow(resp, time.Time{}, time.Time{})
+
+	if len(cf.Months) != 2 || cf.Months[0].Inflow != 50000 || cf.Months[0].Outflow != 5700 || cf.Months[0].Salary != 50000 {
+		t.Fatalf("months = %+v", cf.Months)
+	}
+	if got := cf.Months[1].OutflowSources; len(got) != 2 || got[0] != "/bankTransactions/0/txns/4" {
+		t.Errorf("July outflow sources = %v", got)
+	}
+	// the SIP recurs, the food orders vary too much to count as recurring
+	if len(cf.Recurring) != 1 || cf.Recurring[0].Kind != KindSIP || cf.Recurring[0].MonthlyAverage != 5000 {
+		t.Errorf("recurring = %+v, want only the SIP", cf.Recurring)
+	}
+	a := cf.Accounts[0]
+	if *a.OpeningBalance != 10000 || *a.ClosingBalance != 88500 {
+		t.Errorf("opening and closing = %v, %v, want 10000, 88500", *a.OpeningBalance, *a.ClosingBalance)
+	}
+	if len(a.Breaks) != 1 || a.Breaks[0].Source != "/bankTransactions/0/txns/4" || a.Breaks[0].Expected != 99300 {
+		t.Errorf("breaks = %+v, want the July SIP row", a.Breaks)
+	}
+
+	july := SummarizeCashFlow(resp, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if len(july.Months) != 1 || july.Salary.Months != 1 || len(july.Recurring) != 0 {
+		t.Errorf("July only = %+v", july)
+	}
+}
@@ -0,0 +1,291 @@
+package banking
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Kinds of recurring debits
+const (
+	KindSIP              = "SIP"
+	KindRecurringDeposit = "RECURRING_DEPOSIT"
+	KindEMI              = "EMI"
+	KindInsurance        = "INSURANCE"
+	KindInvestment       = "INVESTMENT"
+	KindRent             = "RENT"
+	KindCardBill         = "CREDIT_CARD_BILL"
+	KindSubscription     = "SUBSCRIPTION"
+	KindUtility          = "UTILITY"
+	KindOther            = "OTHER"
+)
+
+// kindPatterns are tried in order, so that "SIP" wins over the "MF" of an AMC name
+var kindPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{KindSIP, regexp.MustCompile(`(?i)\bSIP\b`)},
+	{KindRecurringDeposit, regexp.MustCompile(`(?i)\bRD\b|RECURRING DEPOSIT|\bRD\d`)},
+	{KindEMI, regexp.MustCompile(`(?i)\bEMI\b|LOAN`)},
+	{KindInsurance, regexp.MustCompile(`(?i)INSURANCE|PREMIUM|\bLIC\b|POLICY`)},
+	{KindInvestment, regexp.MustCompile(`(?i)\bNPS\b|\bPPF\b|ZERODHA|GROWW|UPSTOX|LUMPSUM|MUTUAL|\bMF\b`)},
+	{KindRent, regexp.MustCompile(`(?i)\bRENT\b`)},
+	{KindCardBill, regexp.MustCompile(`(?i)CREDIT CARD|CARD PAYMENT|CARD BILL|\bCRED\b|CREDCLUB|DREAMPLUG`)},
+	{KindSubscription, regexp.MustCompile(`(?i)NETFLIX|SPOTIFY|PRIME|HOTSTAR|SONYLIV|YOUTUBE|SUBSCR|APPLE\.COM|GOOGLE ?PLAY`)},
+	{KindUtility, regexp.MustCompile(`(?i)ELECTRICITY|POWER|BROADBAND|AIRTEL|\bJIO\b|\bGAS\b|WATER|\bDTH\b|MOBILE BILL`)},
+}
+
+// recurringTolerance is how far the amounts of an OTHER recurring debit may stray from their average
+const recurringTolerance = 0.2
+
+// MonthFlow is the money in and out of all accounts in a calendar month
+type MonthFlow struct {
+	Month   string  `json:"month"`
+	Inflow  float64 `json:"inflow"`
+	Outflow float64 `json:"outflow"`
+	Net     float64 `json:"net"`
+	Salary  float64 `json:"salary"`
+	// InflowSources and OutflowSources are the rows adding up to Inflow and Outflow
+	InflowSources  []string `json:"inflowSources"`
+	OutflowSources []string `json:"outflowSources"`
+}
+
+// Recurring is a debit to the same counterparty in more than one month
+type Recurring struct {
+	Counterparty   string        `json:"counterparty"`
+	Kind           string        `json:"kind"`
+	Months         int           `json:"months"`
+	MonthlyAverage float64       `json:"monthlyAverage"`
+	LastAmount     float64       `json:"lastAmount"`
+	LastDate       string        `json:"lastDate"`
+	Transactions   []Transaction `json:"transactions"`
+}
+
+// BalancePoint is the balance of an account at the end of a day
+type BalancePoint struct {
+	Date    string  `json:"date"`
+	Balance float64 `json:"balance"`
+	Source  string  `json:"source"`
+}
+
+// BalanceBreak is a row whose reported balance doesn't follow from the previous row
+type BalanceBreak struct {
+	Source   string  `json:"source"`
+	Expected float64 `json:"expected"`
+	Reported float64 `json:"reported"`
+}
+
+// AccountFlow is the balance trajectory of one bank account
+type AccountFlow struct {
+	Bank           string         `json:"bank"`
+	Source         string         `json:"source"`
+	OpeningBalance *float64       `json:"openingBalance,omitempty"`
+	ClosingBalance *float64       `json:"closingBalance,omitempty"`
+	Inflow         float64        `json:"inflow"`
+	Outflow        float64        `json:"outflow"`
+	Trajectory     []BalancePoint `json:"trajectory"`
+	Breaks         []BalanceBreak `json:"breaks"`
+}
+
+// CashFlow summarises the bank transactions of a user
+type CashFlow struct {
+	From      string        `json:"from,omitempty"`
+	To        string        `json:"to,omitempty"`
+	Inflow    float64       `json:"inflow"`
+	Outflow   float64       `json:"outflow"`
+	Net       float64       `json:"net"`
+	Months    []MonthFlow   `json:"months"`
+	Salary    Income        `json:"salary"`
+	Recurring []Recurring   `json:"recurring"`
+	Accounts  []AccountFlow `json:"accounts"`
+	Notes     []string      `json:"notes"`
+}
+
+// row is a transaction with its position in the payload
+type row struct {
+	bank, txn int
+	t         models.BankTxn
+}
+
+// SummarizeCashFlow summarises the bank transactions dated between from and to,
+// either of which may be zero for an open range. Every figure lists the JSON
+// pointers of the rows it was computed from.
+func SummarizeCashFlow(resp *models.BankTransactionsResponse, from, to time.Time) *CashFlow {
+	include := func(t models.BankTxn) bool {
+		date, err := t.Time()
+		if err != nil {
+			return false
+		}
+		return (from.IsZero() || !date.Before(from)) && (to.IsZero() || !date.After(to))
+	}
+	cf := &CashFlow{
+		Months:    []MonthFlow{},
+		Recurring: []Recurring{},
+		Accounts:  []AccountFlow{},
+		Salary:    detectSalary(resp, include),
+		Notes: []string{
+			"credits and interest are inflows, all other rows except opening and closing balances are outflows",
+			"transfers between the user's own accounts count as both an outflow and an inflow",
+		},
+	}
+	if !from.IsZero() {
+		cf.From = from.Format(models.DateLayout)
+	}
+	if !to.IsZero() {
+		cf.To = to.Format(models.DateLayout)
+	}
+	if resp == nil {
+		return cf
+	}
+
+	months := make(map[string]*MonthFlow)
+	debits := make(map[string][]Transaction)
+	for bi, b := range resp.BankTransactions {
+		var rows []row
+		for ti, t := range b.Txns {
+			if include(t) {
+				rows = append(rows, row{bank: bi, txn: ti, t: t})
+			}
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].t.Date < rows[j].t.Date })
+		account := accountFlow(bi, b.Bank, rows)
+
+		for _, r := range rows {
+			m, ok := months[monthOf(r.t.Date)]
+			if !ok {
+				m = &MonthFlow{Month: monthOf(r.t.Date), InflowSources: []string{}, OutflowSources: []string{}}
+				months[m.Month] = m
+			}
+			source := SourcePointer(r.bank, r.txn)
+			switch amount := r.t.AmountValue(); r.t.Sign() {
+			case 1:
+				m.Inflow += amount
+				m.InflowSources = append(m.InflowSources, source)
+				account.Inflow += amount
+			case -1:
+				m.Outflow += amount
+				m.OutflowSources = append(m.OutflowSources, source)
+				account.Outflow += amount
+				key := Counterparty(r.t.Narration)
+				debits[key] = append(debits[key], NewTransaction(resp, r.bank, r.txn))
+			}
+		}
+		account.Inflow = models.Round(account.Inflow, 2)
+		account.Outflow = models.Round(account.Outflow, 2)
+		cf.Accounts = append(cf.Accounts, account)
+	}
+
+	for _, c := range cf.Salary.Credits {
+		if m, ok := months[monthOf(c.Date)]; ok {
+			m.Salary += c.Amount
+		}
+	}
+	for _, m := range months {
+		m.Inflow, m.Outflow = models.Round(m.Inflow, 2), models.Round(m.Outflow, 2)
+		m.Net = models.Round(m.Inflow-m.Outflow, 2)
+		m.Salary = models.Round(m.Salary, 2)
+		cf.Inflow += m.Inflow
+		cf.Outflow += m.Outflow
+		cf.Months = append(cf.Months, *m)
+	}
+	sort.Slice(cf.Months, func(i, j int) bool { return cf.Months[i].Month < cf.Months[j].Month })
+	cf.Inflow, cf.Outflow = models.Round(cf.Inflow, 2), models.Round(cf.Outflow, 2)
+	cf.Net = models.Round(cf.Inflow-cf.Outflow, 2)
+
+	for counterparty, txns := range debits {
+		if r, ok := recurring(counterparty, txns); ok {
+			cf.Recurring = append(cf.Recurring, r)
+		}
+	}
+	sort.Slice(cf.Recurring, func(i, j int) bool {
+		if cf.Recurring[i].MonthlyAverage != cf.Recurring[j].MonthlyAverage {
+			return cf.Recurring[i].MonthlyAverage > cf.Recurring[j].MonthlyAverage
+		}
+		return cf.Recurring[i].Counterparty < cf.Recurring[j].Counterparty
+	})
+	return cf
+}
+
+// accountFlow builds the end of day balances of an account from its rows sorted by
+// date, and the rows where the reported balance breaks from the running balance
+func accountFlow(bank int, name string, rows []row) AccountFlow {
+	a := AccountFlow{Bank: name, Source: fmt.Sprintf("/bankTransactions/%d", bank), Trajectory: []BalancePoint{}, Breaks: []BalanceBreak{}}
+	var previous *float64
+	for _, r := range rows {
+		balance, ok := r.t.BalanceValue()
+		if !ok {
+			continue
+		}
+		change := float64(r.t.Sign()) * r.t.AmountValue()
+		if previous == nil {
+			opening := models.Round(balance-change, 2)
+			a.OpeningBalance = &opening
+		} else if expected := *previous + change; math.Abs(expected-balance) > 0.005 {
+			a.Breaks = append(a.Breaks, BalanceBreak{Source: SourcePointer(r.bank, r.txn), Expected: models.Round(expected, 2), Reported: balance})
+		}
+		point := BalancePoint{Date: r.t.Date, Balance: balance, Source: SourcePointer(r.bank, r.txn)}
+		if n := len(a.Trajectory); n > 0 && a.Trajectory[n-1].Date == r.t.Date {
+			a.Trajectory[n-1] = point
+		} else {
+			a.Trajectory = append(a.Trajectory, point)
+		}
+		previous = &balance
+	}
+	if previous != nil {
+		closing := *previous
+		a.ClosingBalance = &closing
+	}
+	return a
+}
+
+// recurring reports whether the debits to a counterparty recur in more than one
+// month. Debits of a known kind recur whatever their amounts, others only when
+// the amounts stay within recurringTolerance of their average.
+func recurring(counterparty string, txns []Transaction) (Recurring, bool) {
+	months := make(map[string]bool)
+	for _, t := range txns {
+		months[monthOf(t.Date)] = true
+	}
+	if len(months) < 2 {
+		return Recurring{}, false
+	}
+	sort.Slice(txns, func(i, j int) bool { return txns[i].Date < txns[j].Date })
+	kind := KindOf(counterparty + " " + txns[len(txns)-1].Narration)
+	avg := average(txns)
+	if kind == KindOther {
+		for _, t := range txns {
+			if math.Abs(t.Amount-avg) > recurringTolerance*avg {
+				return Recurring{}, false
+			}
+		}
+	}
+	var total float64
+	for _, t := range txns {
+		total += t.Amount
+	}
+	last := txns[len(txns)-1]
+	return Recurring{
+		Counterparty:   counterparty,
+		Kind:           kind,
+		Months:         len(months),
+		MonthlyAverage: models.Round(total/float64(len(months)), 2),
+		LastAmount:     last.Amount,
+		LastDate:       last.Date,
+		Transactions:   txns,
+	}, true
+}
+
+// KindOf classifies a debit by its narration
+func KindOf(narration string) string {
+	for _, k := range kindPatterns {
+		if k.pattern.MatchString(narration) {
+			return k.kind
+		}
+	}
+	return KindOther
+}
@@ -0,0 +1,19 @@
+package banking
+
+// Counterparty normalises the payee or payer of a transaction so that payments to
+// the same merchant or person group together. UPI narrations map to the name of
+// the counterparty, or to the VPA when the name is masked. Other narrations map
+// to their NarrationKey.
+func Counterparty(narration string) string {
+	n := ParseNarration(narration)
+	if n.Channel != ChannelUPI {
+		return NarrationKey(narration)
+	}
+	switch {
+	case n.Counterparty != "":
+		return n.Counterparty
+	case n.VPA != "":
+		return n.VPA
+	}
+	return NarrationKey(narration)
+}
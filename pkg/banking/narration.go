@@ -0,0 +1,141 @@
+package banking
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Payment channels of a narration
+const (
+	ChannelUPI     = "UPI"
+	ChannelIMPS    = "IMPS"
+	ChannelNEFT    = "NEFT"
+	ChannelRTGS    = "RTGS"
+	ChannelACH     = "ACH"
+	ChannelBillPay = "BILLPAY"
+	ChannelATM     = "ATM"
+	ChannelCheque  = "CHEQUE"
+	ChannelOther   = "OTHER"
+)
+
+// Directions of a narration
+const (
+	DirectionDebit  = "DEBIT"
+	DirectionCredit = "CREDIT"
+)
+
+var (
+	ifscPattern         = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	bankCodePattern     = regexp.MustCompile(`^[A-Z]{4}$`)
+	referencePattern    = regexp.MustCompile(`^(?:[A-Z]{0,6}\d{8,}|\d{6,})$`)
+	letterPattern       = regexp.MustCompile(`[A-Z]`)
+	trailingPunctuation = regexp.MustCompile(`[\s.\-/]+$`)
+	digitsOnly          = regexp.MustCompile(`^\d+$`)
+	maskedAccount       = regexp.MustCompile(`^[X*]{2,}[0-9A-Z]*$`)
+	channelPrefix       = regexp.MustCompile(`^(UPI|IMPS|NEFT|RTGS|ACH|BILLPAY)(?:\s+(DR|CR|D|C|RET))?\s*[-/]`)
+	otherChannels       = []struct {
+		channel string
+		pattern *regexp.Regexp
+	}{
+		{ChannelATM, regexp.MustCompile(`^(CASH WDL|NWD|ATM)\b`)},
+		{ChannelCheque, regexp.MustCompile(`^(CHQ|CHEQUE)\b`)},
+		{ChannelIMPS, regexp.MustCompile(`^\.?IMPS\b`)},
+	}
+)
+
+// Narration is a bank narration split into its parts. Parts that can't be told
+// apart are left empty and the narration is kept as the remark.
+type Narration struct {
+	Channel string `json:"channel"`
+	// Direction is set when the narration itself says which way the money went
+	Direction    string `json:"direction,omitempty"`
+	Counterparty string `json:"counterparty,omitempty"`
+	VPA          string `json:"vpa,omitempty"`
+	IFSC         string `json:"ifsc,omitempty"`
+	// Bank is the four letter bank code of the counterparty when no full IFSC is given
+	Bank      string `json:"bank,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	Remark    string `json:"remark,omitempty"`
+}
+
+// ParseNarration splits the common UPI, IMPS, NEFT, RTGS, ACH and bill payment
+// narration formats, for example
+//
+//	UPI-<name>-<vpa>-<ifsc>-<reference>-<remark>
+//	IMPS-<reference>-<name>-<bank>-<account>-<remark>
+//	NEFT DR-<ifsc>-<name>-<remark>-<utr>
+//	ACH D-<name or ifsc>-<remark>-<reference>
+//
+// into counterparty, VPA, IFSC and reference. The order of the parts varies
+// between banks, so parts are recognised by their shape rather than position.
+func ParseNarration(narration string) Narration {
+	text := strings.ToUpper(strings.TrimSpace(narration))
+	m := channelPrefix.FindStringSubmatchIndex(text)
+	if m == nil {
+		n := Narration{Channel: ChannelOther, Remark: strings.TrimSpace(narration)}
+		for _, c := range otherChannels {
+			if c.pattern.MatchString(text) {
+				n.Channel = c.channel
+				break
+			}
+		}
+		return n
+	}
+	n := Narration{Channel: text[m[2]:m[3]]}
+	if m[4] >= 0 {
+		switch text[m[4]:m[5]] {
+		case "DR", "D":
+			n.Direction = DirectionDebit
+		case "CR", "C":
+			n.Direction = DirectionCredit
+		case "RET":
+			n.Direction, n.Remark = DirectionCredit, "RETURN"
+		}
+	}
+
+	var rest []string
+	ifscFirst, masked := false, false
+	for _, part := range strings.Split(text[m[1]:], "-") {
+		if part = spacePattern.ReplaceAllString(strings.TrimSpace(part), " "); part == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(part, "@") && !strings.Contains(part, " ") && n.VPA == "":
+			n.VPA = part
+		case ifscPattern.MatchString(part) && n.IFSC == "":
+			n.IFSC, ifscFirst = part, len(rest) == 0 && n.VPA == "" && n.Reference == ""
+		case maskedAccount.MatchString(part) && n.Account == "":
+			// a masked account in place of the name hides the counterparty
+			n.Account, masked = part, len(rest) == 0
+		case digitsOnly.MatchString(part) && len(part) >= 6 && (n.Reference == "" || !digitsOnly.MatchString(n.Reference)):
+			// a numeric reference wins over a mixed one, which then stays in the remark
+			if n.Reference != "" {
+				rest = append(rest, n.Reference)
+			}
+			n.Reference = part
+		case referencePattern.MatchString(part) && n.Reference == "":
+			n.Reference = part
+		default:
+			rest = append(rest, part)
+		}
+	}
+	// an IMPS narration names the counterparty's bank after the counterparty
+	if n.Channel == ChannelIMPS && len(rest) > 2 && bankCodePattern.MatchString(rest[1]) {
+		n.Bank, rest = rest[1], append(rest[:1:1], rest[2:]...)
+	}
+	// an ACH mandate starting with an IFSC is with that bank, the rest describes the payment
+	if n.Channel == ChannelACH && ifscFirst {
+		n.Bank = n.IFSC[:4]
+	} else if len(rest) > 0 && !masked && letterPattern.MatchString(rest[0]) {
+		n.Counterparty, rest = trailingPunctuation.ReplaceAllString(rest[0], ""), rest[1:]
+	}
+	if len(rest) > 0 {
+		remark := strings.Join(rest, "-")
+		if n.Remark != "" {
+			remark = n.Remark + " " + remark
+		}
+		n.Remark = remark
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package banking
+
+import (
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var salaryPattern = regexp.MustCompile(`(?i)\b(salary|sal|payroll|wages)\b`)
+
+// Income is the salary detected in the bank transactions
+type Income struct {
+	// Method is "narration" when credits mention salary, "recurring_credit" when a
+	// credit of a similar amount repeats every month and "none" otherwise
+	Method         string        `json:"method"`
+	MonthlyAverage float64       `json:"monthlyAverage"`
+	Months         int           `json:"months"`
+	Credits        []Transaction `json:"credits"`
+}
+
+// DetectSalary finds salary credits in the bank transactions. Credits mentioning
+// salary are used when present, otherwise the largest credit that recurs in at
+// least two months with amounts within 10% of each other.
+func DetectSalary(resp *models.BankTransactionsResponse) Income {
+	return detectSalary(resp, func(models.BankTxn) bool { return true })
+}
+
+// detectSalary is DetectSalary over the transactions accepted by include
+func detectSalary(resp *models.BankTransactionsResponse, include func(models.BankTxn) bool) Income {
+	income := Income{Method: "none", Credits: []Transaction{}}
+	if resp == nil {
+		return income
+	}
+	recurring := make(map[string][]Transaction)
+	for bi, b := range resp.BankTransactions {
+		for ti, t := range b.Txns {
+			if t.Type != models.BankTxnTypeCredit || !include(t) {
+				continue
+			}
+			c := NewTransaction(resp, bi, ti)
+			if salaryPattern.MatchString(t.Narration) {
+				income.Credits = append(income.Credits, c)
+				continue
+			}
+			key := b.Bank + "|" + NarrationKey(t.Narration)
+			recurring[key] = append(recurring[key], c)
+		}
+	}
+	if len(income.Credits) > 0 {
+		income.Method = "narration"
+	} else {
+		var best []Transaction
+		var bestAmount float64
+		for _, credits := range recurring {
+			if !isRecurring(credits) {
+				continue
+			}
+			if avg := average(credits); avg > bestAmount {
+				best, bestAmount = credits, avg
+			}
+		}
+		if best != nil {
+			income.Method, income.Credits = "recurring_credit", best
+		}
+	}
+	if len(income.Credits) == 0 {
+		return income
+	}
+	sort.Slice(income.Credits, func(i, j int) bool { return income.Credits[i].Date < income.Credits[j].Date })
+	months := make(map[string]bool)
+	var total float64
+	for _, c := range income.Credits {
+		months[monthOf(c.Date)] = true
+		total += c.Amount
+	}
+	income.Months = len(months)
+	income.MonthlyAverage = models.Round(total/float64(len(months)), 2)
+	return income
+}
+
+func isRecurring(credits []Transaction) bool {
+	months := make(map[string]bool)
+	for _, c := range credits {
+		months[monthOf(c.Date)] = true
+	}
+	if len(months) < 2 {
+		return false
+	}
+	avg := average(credits)
+	for _, c := range credits {
+		if math.Abs(c.Amount-avg) > 0.1*avg {
+			return false
+		}
+	}
+	return true
+}
+
+func average(credits []Transaction) float64 {
+	var total float64
+	for _, c := range credits {
+		total += c.Amount
+	}
+	return total / float64(len(credits))
+}
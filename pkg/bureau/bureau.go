@@ -0,0 +1,162 @@
+// Package bureau reads credit bureau reports into the creditReportData of the
+// fetch_credit_report tool. It takes the Experian XML or JSON response
+// (INProfileResponse, also when escaped inside a SOAP envelope) and reports
+// already in the fetch_credit_report shape. Fields are matched by name whatever
+// their case and underscores, so Date_of_Request fills DateOfRequest.
+package bureau
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Vendor is the vendor of reports that don't name one
+const Vendor = "EXPERIAN"
+
+// aliases are the Experian names of the fields whose key differs from the
+// key of their JSON name in the model
+var aliases = map[string][]string{
+	"creditaccount":               {"caisaccount"},
+	"creditaccountsummary":        {"caissummary"},
+	"account":                     {"creditaccount"},
+	"creditaccountdetails":        {"caisaccountdetails"},
+	"capsapplicationdetailsarray": {"capsapplicationdetails"},
+	"bureauscoreconfidencelevel":  {"bureauscoreconfidlevel"},
+}
+
+// Parse reads the reports found in data. A node is taken to be a report when it
+// has a CreditProfileHeader. Summaries the report leaves out are worked out from
+// its accounts and enquiries.
+func Parse(data []byte) ([]models.CreditReport, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	reports := find(root)
+	if len(reports) == 0 {
+		// SOAP responses carry the report as escaped XML in a text node
+		root.walk(nil, func(n, _ *node) bool {
+			if strings.HasPrefix(n.text, "<") {
+				if inner, err := parseXML([]byte(n.text)); err == nil {
+					reports = append(reports, find(inner)...)
+				}
+			}
+			return true
+		})
+	}
+	if len(reports) == 0 {
+		return nil, errors.New("no report with a CreditProfileHeader found")
+	}
+	return reports, nil
+}
+
+func parse(data []byte) (*node, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("empty report")
+	case data[0] == '<':
+		return parseXML(data)
+	case data[0] == '{' || data[0] == '[':
+		return parseJSON(data)
+	}
+	return nil, errors.New("not an XML or JSON report")
+}
+
+// find converts the report nodes below root. The vendor comes from a sibling of
+// the report, as in the fetch_credit_report shape.
+func find(root *node) []models.CreditReport {
+	var reports []models.CreditReport
+	root.walk(nil, func(n, parent *node) bool {
+		if len(n.find("creditprofileheader")) == 0 {
+			return true
+		}
+		r := models.CreditReport{Vendor: Vendor}
+		fill(reflect.ValueOf(&r.CreditReportData).Elem(), n)
+		if parent != nil {
+			if v := parent.find("vendor"); len(v) > 0 && v[0].text != "" {
+				r.Vendor = strings.ToUpper(v[0].text)
+			}
+		}
+		summarise(&r.CreditReportData)
+		reports = append(reports, r)
+		return false
+	})
+	return reports
+}
+
+// fill sets the fields of the struct v from the children of n. Slices without
+// omitempty are left empty rather than nil, as the bureau sends them.
+func fill(v reflect.Value, n *node) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || f.Type == reflect.TypeOf(models.CreditReportData{}.Segment) {
+			continue
+		}
+		k := key(name)
+		found := n.find(append([]string{k}, aliases[k]...)...)
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.String:
+			if len(found) > 0 {
+				fv.SetString(clean(k, found[0].text))
+			}
+		case reflect.Struct:
+			if len(found) > 0 {
+				fill(fv, found[0])
+			}
+		case reflect.Pointer:
+			if len(found) > 0 {
+				p := reflect.New(f.Type.Elem())
+				fill(p.Elem(), found[0])
+				fv.Set(p)
+			}
+		case reflect.Slice:
+			s := reflect.MakeSlice(f.Type, 0, len(found))
+			for _, c := range found {
+				e := reflect.New(f.Type.Elem()).Elem()
+				fill(e, c)
+				s = reflect.Append(s, e)
+			}
+			if len(found) > 0 || !strings.Contains(opts, "omitempty") {
+				fv.Set(s)
+			}
+		}
+	}
+}
+
+// dateLayouts are the date formats converted to YYYYMMDD
+var dateLayouts = []string{models.BureauDateLayout, models.DateLayout, "02-01-2006", "02/01/2006", "02-Jan-2006", time.RFC3339}
+
+// codes are the two digit code fields the credit tools look up, which some
+// exports send unpadded
+var codes = map[string]bool{"accounttype": true, "accountstatus": true}
+
+// clean normalises the value of the field with key k: dates to YYYYMMDD, codes
+// to two digits and amounts without thousands separators
+func clean(k, s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+	case codes[k]:
+		if len(s) == 1 && s[0] >= '0' && s[0] <= '9' {
+			return "0" + s
+		}
+	case strings.Contains(k, "date"):
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(models.BureauDateLayout)
+			}
+		}
+	case strings.Contains(k, "amount"), strings.Contains(k, "balance"):
+		return strings.NewReplacer(",", "", " ", "", "₹", "", "INR", "").Replace(s)
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package bureau
+
+import (
+	"encoding/json"
+	"html"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/schema"
+)
+
+func read(t *testing.T, file string) models.CreditReport {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := Parse(data)
+	if err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("%s: %d reports, want 1", file, len(reports))
+	}
+	errs, warnings := Check(reports[0])
+	for _, e := range append(errs, warnings...) {
+		t.Errorf("%s: %s", file, e)
+	}
+	return reports[0]
+}
+
+func TestParseXML(t *testing.T) {
+	r := read(t, "experian.xml")
+	d := r.CreditReportData
+	if r.Vendor != "EXPERIAN" || d.CreditProfileHeader.ReportDate != "20250612" || d.Score.BureauScore != "762" || d.Score.BureauScoreConfidenceLevel != "H" {
+		t.Errorf("report = %+v", r)
+	}
+	if got := d.CurrentApplication.CurrentApplicationDetails.CurrentApplicantDetails.DateOfBirthApplicant; got != "19900315" {
+		t.Errorf("dateOfBirthApplicant = %q", got)
+	}
+	if s := d.CreditAccount.CreditAccountSummary; s.Account.CreditAccountActive != "3" || s.TotalOutstandingBalance.OutstandingBalanceAll != "2548500" {
+		t.Errorf("summary = %+v", s)
+	}
+	accounts := d.CreditAccount.CreditAccountDetails
+	if len(accounts) != 4 {
+		t.Fatalf("%d accounts, want 4", len(accounts))
+	}
+	want := models.CreditAccountDetail{
+		SubscriberName: "HDFC Bank", PortfolioType: "R", AccountType: "10", OpenDate: "20190205",
+		CreditLimitAmount: "300000", HighestCreditOrOriginalLoanAmount: "300000", AccountStatus: "11", PaymentRating: "0",
+		PaymentHistoryProfile: "000000100000000000000000000000000000", CurrentBalance: "62500", AmountPastDue: "0",
+		DateReported: "20250604", OccupationCode: "S", RateOfInterest: "42.0", RepaymentTenure: "0",
+		DateOfAddition: "20190205", CurrencyCode: "INR", AccountHolderTypeCode: "1",
+	}
+	if accounts[1] != want {
+		t.Errorf("account = %+v, want %+v", accounts[1], want)
+	}
+	if accounts[2].AccountType != "06" {
+		t.Errorf("accountType = %q, want the code padded", accounts[2].AccountType)
+	}
+	if caps := d.Caps.CapsApplicationDetailsArray; len(caps) != 2 || caps[0].DateOfRequest != "20250528" || caps[0].FinancePurpose != "5" {
+		t.Errorf("caps = %+v", caps)
+	}
+	if d.NonCreditCaps == nil || d.NonCreditCaps.CapsApplicationDetailsArray == nil {
+		t.Errorf("nonCreditCaps = %+v, want an empty enquiry list", d.NonCreditCaps)
+	}
+	if d.MatchResult.ExactMatch != "Y" || d.TotalCapsSummary.TotalCapsLast180Days != "2" {
+		t.Errorf("matchResult = %+v, totalCapsSummary = %+v", d.MatchResult, d.TotalCapsSummary)
+	}
+}
+
+func TestParseJSONFillsSummaries(t *testing.T) {
+	d := read(t, "experian.json").CreditReportData
+	a := d.CreditAccount.CreditAccountDetails[0]
+	if a.OpenDate != "20220714" || a.CreditLimitAmount != "150000" || a.CurrentBalance != "148200" || a.AccountStatus != "71" {
+		t.Errorf("account = %+v", a)
+	}
+	want := models.CreditAccountSummary{
+		Account: models.CreditAccountCounts{CreditAccountTotal: "2", CreditAccountActive: "2", CreditAccountDefault: "0", CreditAccountClosed: "0", CADSuitFiledCurrentBalance: "0"},
+		TotalOutstandingBalance: models.TotalOutstandingBalance{
+			OutstandingBalanceSecured: "512000", OutstandingBalanceSecuredPercentage: "78",
+			OutstandingBalanceUnSecured: "148200", OutstandingBalanceUnSecuredPercentage: "22",
+			OutstandingBalanceAll: "660200",
+		},
+	}
+	if s := d.CreditAccount.CreditAccountSummary; s != want {
+		t.Errorf("summary = %+v, want %+v", s, want)
+	}
+	if s := d.Caps.CapsSummary; s != (models.CapsSummary{CapsLast7Days: "0", CapsLast30Days: "1", CapsLast90Days: "1", CapsLast180Days: "1"}) {
+		t.Errorf("capsSummary = %+v", s)
+	}
+	if d.TotalCapsSummary == nil || d.TotalCapsSummary.TotalCapsLast30Days != "1" {
+		t.Errorf("totalCapsSummary = %+v", d.TotalCapsSummary)
+	}
+}
+
+func TestParseSOAP(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "experian.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><ns2:processResponse><ns2:out>` +
+		html.EscapeString(string(data)) + `</ns2:out></ns2:processResponse></soapenv:Body></soapenv:Envelope>`
+	reports, err := Parse([]byte(envelope))
+	if err != nil || len(reports) != 1 || len(reports[0].CreditReportData.CreditAccount.CreditAccountDetails) != 4 {
+		t.Errorf("Parse = %+v, %v", reports, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	reports, err := Parse([]byte(`{"CreditProfileHeader": {"ReportDate": "31-31-2025"},
+		"CAIS_Account": {"CAIS_Account_DETAILS": {"Subscriber_Name": "X", "Portfolio_Type": "Z", "Account_Type": "99", "Account_Status": "11",
+		"Open_Date": "20240101", "Date_Reported": "20250101", "Current_Balance": "12k"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, warnings := Check(reports[0])
+	wantErrs := []string{
+		`creditProfileHeader.reportDate: "31-31-2025" is not a YYYYMMDD date`,
+		`creditAccountDetails[0].portfolioType: "Z" is not one of I, M, R, O, C`,
+		`creditAccountDetails[0].currentBalance: "12k" is not a number`,
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("errs = %q, want %q", errs, wantErrs)
+	}
+	if len(warnings) != 1 || warnings[0] != `creditAccountDetails[0].accountType: unknown code "99"` {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+// TestTestDataDir reads the credit reports of the personas back, as the
+// fetch_credit_report shape is one of the inputs, and checks the result against
+// the schema
+func TestTestDataDir(t *testing.T) {
+	set, err := schema.Load(filepath.Join("..", "..", "schemas"), []string{"fetch_credit_report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := filepath.Glob(filepath.Join("..", "..", "test_data_dir", "*", "fetch_credit_report.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no credit reports found: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want models.CreditReportResponse
+		if err := json.Unmarshal(data, &want); err != nil {
+			t.Fatal(err)
+		}
+		if len(want.CreditReports) == 0 {
+			continue
+		}
+		reports, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		for i := range want.CreditReports {
+			want.CreditReports[i].CreditReportData.Segment = nil
+		}
+		if !reflect.DeepEqual(reports, want.CreditReports) {
+			t.Errorf("%s: read back differently", path)
+		}
+		out, _ := json.Marshal(models.CreditReportResponse{CreditReports: reports})
+		if errs, _ := set.Validate("fetch_credit_report", out); len(errs) != 0 {
+			t.Errorf("%s: %v", path, errs)
+		}
+	}
+}
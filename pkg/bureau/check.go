@@ -0,0 +1,253 @@
+package bureau
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// summarise fills in the account summary and the enquiry counts when the report
+// leaves them out
+func summarise(d *models.CreditReportData) {
+	if d.CreditAccount.CreditAccountSummary == (models.CreditAccountSummary{}) {
+		d.CreditAccount.CreditAccountSummary = accountSummary(d.CreditAccount.CreditAccountDetails)
+	}
+	reported, err := models.ParseBureauDate(d.CreditProfileHeader.ReportDate)
+	if err != nil {
+		return
+	}
+	var all []models.CapsApplicationDetail
+	if c := d.Caps; c != nil {
+		all = append(all, c.CapsApplicationDetailsArray...)
+		if c.CapsSummary == (models.CapsSummary{}) {
+			n := enquiries(c.CapsApplicationDetailsArray, reported)
+			c.CapsSummary = models.CapsSummary{CapsLast7Days: n[0], CapsLast30Days: n[1], CapsLast90Days: n[2], CapsLast180Days: n[3]}
+		}
+	}
+	if c := d.NonCreditCaps; c != nil {
+		all = append(all, c.CapsApplicationDetailsArray...)
+		if c.NonCreditCapsSummary == (models.NonCreditCapsSummary{}) {
+			n := enquiries(c.CapsApplicationDetailsArray, reported)
+			c.NonCreditCapsSummary = models.NonCreditCapsSummary{NonCreditCapsLast7Days: n[0], NonCreditCapsLast30Days: n[1], NonCreditCapsLast90Days: n[2], NonCreditCapsLast180Days: n[3]}
+		}
+	}
+	if d.TotalCapsSummary == nil && (d.Caps != nil || d.NonCreditCaps != nil) {
+		n := enquiries(all, reported)
+		d.TotalCapsSummary = &models.TotalCapsSummary{TotalCapsLast7Days: n[0], TotalCapsLast30Days: n[1], TotalCapsLast90Days: n[2], TotalCapsLast180Days: n[3]}
+	}
+}
+
+// accountSummary counts the accounts and adds up the balances of the open ones
+func accountSummary(details []models.CreditAccountDetail) models.CreditAccountSummary {
+	var active, closed, defaults int
+	var secured, unsecured, suitFiled float64
+	for _, a := range details {
+		acc := credit.DecodeAccount(a)
+		switch acc.State {
+		case credit.StateWrittenOff:
+			defaults++
+		case credit.StateSuitFiled, credit.StateWilfulDefault:
+			defaults++
+			suitFiled += acc.CurrentBalance
+		}
+		if acc.State == credit.StateClosed || a.DateClosed != "" {
+			closed++
+			continue
+		}
+		active++
+		if acc.Secured {
+			secured += acc.CurrentBalance
+		} else {
+			unsecured += acc.CurrentBalance
+		}
+	}
+	var securedPercent, unsecuredPercent float64
+	if all := secured + unsecured; all > 0 {
+		securedPercent = math.Round(secured / all * 100)
+		unsecuredPercent = 100 - securedPercent
+	}
+	return models.CreditAccountSummary{
+		Account: models.CreditAccountCounts{
+			CreditAccountTotal:         strconv.Itoa(len(details)),
+			CreditAccountActive:        strconv.Itoa(active),
+			CreditAccountDefault:       strconv.Itoa(defaults),
+			CreditAccountClosed:        strconv.Itoa(closed),
+			CADSuitFiledCurrentBalance: amount(suitFiled),
+		},
+		TotalOutstandingBalance: models.TotalOutstandingBalance{
+			OutstandingBalanceSecured:             amount(secured),
+			OutstandingBalanceSecuredPercentage:   amount(securedPercent),
+			OutstandingBalanceUnSecured:           amount(unsecured),
+			OutstandingBalanceUnSecuredPercentage: amount(unsecuredPercent),
+			OutstandingBalanceAll:                 amount(secured + unsecured),
+		},
+	}
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// enquiries counts the enquiries made in the 7, 30, 90 and 180 days up to the report date
+func enquiries(details []models.CapsApplicationDetail, reported time.Time) [4]string {
+	var n [4]int
+	for _, e := range details {
+		t, err := models.ParseBureauDate(e.DateOfRequest)
+		if err != nil || t.After(reported) {
+			continue
+		}
+		for i, days := range []int{7, 30, 90, 180} {
+			if reported.Sub(t) < time.Duration(days)*24*time.Hour {
+				n[i]++
+			}
+		}
+	}
+	var s [4]string
+	for i := range n {
+		s[i] = strconv.Itoa(n[i])
+	}
+	return s
+}
+
+var (
+	numberRe        = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	accountTypeRe   = regexp.MustCompile(`^[0-9]{1,2}$`)
+	accountStatusRe = regexp.MustCompile(`^[0-9]{2}$`)
+	currencyRe      = regexp.MustCompile(`^[A-Z]{3}$`)
+	scoreRe         = regexp.MustCompile(`^[0-9]{1,3}$`)
+)
+
+// portfolioTypes are the portfolio type codes of the credit report schema
+var portfolioTypes = map[string]bool{"I": true, "M": true, "R": true, "O": true, "C": true}
+
+// checker collects the problems of a report, errs being those the credit
+// report schema rejects
+type checker struct {
+	errs, warnings []string
+}
+
+func (c *checker) errorf(format string, args ...any) {
+	c.errs = append(c.errs, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) warnf(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) date(path, v string, required bool) {
+	if v == "" && !required {
+		return
+	}
+	if _, err := models.ParseBureauDate(v); err != nil {
+		c.errorf("%s: %q is not a YYYYMMDD date", path, v)
+	}
+}
+
+func (c *checker) number(path, v string) {
+	if v != "" && !numberRe.MatchString(v) {
+		c.errorf("%s: %q is not a number", path, v)
+	}
+}
+
+// Check validates a report against the credit report model. errs are the
+// problems that would fail the fetch_credit_report schema; warnings are codes
+// the credit tools don't know and summaries that disagree with the accounts.
+func Check(r models.CreditReport) (errs, warnings []string) {
+	c := &checker{}
+	d := r.CreditReportData
+	c.date("creditProfileHeader.reportDate", d.CreditProfileHeader.ReportDate, true)
+	if a := d.CurrentApplication; a != nil {
+		c.number("currentApplication.amountFinanced", a.CurrentApplicationDetails.AmountFinanced)
+		c.number("currentApplication.durationOfAgreement", a.CurrentApplicationDetails.DurationOfAgreement)
+		c.date("currentApplication.dateOfBirthApplicant", a.CurrentApplicationDetails.CurrentApplicantDetails.DateOfBirthApplicant, false)
+	}
+	if s := d.Score; s != nil {
+		if !scoreRe.MatchString(s.BureauScore) {
+			c.errorf("score.bureauScore: %q is not a score", s.BureauScore)
+		} else if score, _ := strconv.Atoi(s.BureauScore); score < 300 || score > 900 {
+			c.warnf("score.bureauScore: %d is outside 300-900", score)
+		}
+	}
+
+	var open float64
+	for i, a := range d.CreditAccount.CreditAccountDetails {
+		path := fmt.Sprintf("creditAccountDetails[%d]", i)
+		if a.SubscriberName == "" {
+			c.errorf("%s.subscriberName is empty", path)
+		}
+		if !portfolioTypes[a.PortfolioType] {
+			c.errorf("%s.portfolioType: %q is not one of I, M, R, O, C", path, a.PortfolioType)
+		}
+		if !accountTypeRe.MatchString(a.AccountType) {
+			c.errorf("%s.accountType: %q is not an account type code", path, a.AccountType)
+		}
+		if !accountStatusRe.MatchString(a.AccountStatus) {
+			c.errorf("%s.accountStatus: %q is not an account status code", path, a.AccountStatus)
+		}
+		c.date(path+".openDate", a.OpenDate, true)
+		c.date(path+".dateReported", a.DateReported, true)
+		c.date(path+".dateClosed", a.DateClosed, false)
+		c.date(path+".dateOfAddition", a.DateOfAddition, false)
+		if a.CurrentBalance == "" {
+			c.errorf("%s.currentBalance is empty", path)
+		}
+		c.number(path+".currentBalance", a.CurrentBalance)
+		c.number(path+".amountPastDue", a.AmountPastDue)
+		c.number(path+".creditLimitAmount", a.CreditLimitAmount)
+		c.number(path+".highestCreditOrOriginalLoanAmount", a.HighestCreditOrOriginalLoanAmount)
+		c.number(path+".rateOfInterest", a.RateOfInterest)
+		c.number(path+".repaymentTenure", a.RepaymentTenure)
+		if len(a.PaymentHistoryProfile) > credit.PaymentHistoryMonths {
+			c.errorf("%s.paymentHistoryProfile is longer than %d months", path, credit.PaymentHistoryMonths)
+		}
+		if a.CurrencyCode != "" && !currencyRe.MatchString(a.CurrencyCode) {
+			c.errorf("%s.currencyCode: %q is not an ISO currency code", path, a.CurrencyCode)
+		}
+
+		acc := credit.DecodeAccount(a)
+		if strings.HasPrefix(acc.AccountType.Label, "Unknown") {
+			c.warnf("%s.accountType: unknown code %q", path, a.AccountType)
+		}
+		if acc.State == credit.StateUnknown {
+			c.warnf("%s.accountStatus: unknown code %q", path, a.AccountStatus)
+		}
+		for _, m := range acc.PaymentHistory {
+			if m.Status == "Unknown" {
+				c.warnf("%s.paymentHistoryProfile: unknown status %q", path, m.Code)
+				break
+			}
+		}
+		if acc.State != credit.StateClosed && a.DateClosed == "" {
+			open += acc.CurrentBalance
+		}
+	}
+
+	s := d.CreditAccount.CreditAccountSummary
+	if total, n := models.ParseBureauAmount(s.Account.CreditAccountTotal), len(d.CreditAccount.CreditAccountDetails); int(total) != n {
+		c.warnf("creditAccountSummary: %s accounts but %d in creditAccountDetails", s.Account.CreditAccountTotal, n)
+	}
+	if all := models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceAll); math.Abs(all-open) > 1 {
+		c.warnf("creditAccountSummary: outstanding balance is %v but the open accounts add up to %v", all, open)
+	}
+
+	var caps, nonCredit []models.CapsApplicationDetail
+	if d.Caps != nil {
+		caps = d.Caps.CapsApplicationDetailsArray
+	}
+	if d.NonCreditCaps != nil {
+		nonCredit = d.NonCreditCaps.CapsApplicationDetailsArray
+	}
+	for i, e := range caps {
+		c.date(fmt.Sprintf("caps[%d].DateOfRequest", i), e.DateOfRequest, false)
+	}
+	for i, e := range nonCredit {
+		c.date(fmt.Sprintf("nonCreditCaps[%d].DateOfRequest", i), e.DateOfRequest, false)
+	}
+	return c.errs, c.warnings
+}
@@ -0,0 +1,126 @@
+package bureau
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// node is an XML element or a JSON value. Repeated elements and the items of
+// a JSON array are children with the same name.
+type node struct {
+	name     string
+	text     string
+	children []*node
+}
+
+// key lowercases a field name and drops everything but letters and digits, so
+// that Date_of_Request, DateOfRequest and dateOfRequest are the same field
+func key(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, name)
+}
+
+// find returns the children named one of names, compared by key
+func (n *node) find(names ...string) []*node {
+	var found []*node
+	for _, c := range n.children {
+		k := key(c.name)
+		for _, name := range names {
+			if k == name {
+				found = append(found, c)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// walk calls fn for every node below n, parents first, skipping the children
+// of the nodes for which fn returns false
+func (n *node) walk(parent *node, fn func(n, parent *node) bool) {
+	if !fn(n, parent) {
+		return
+	}
+	for _, c := range n.children {
+		c.walk(n, fn)
+	}
+}
+
+func parseXML(data []byte) (*node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	// bureaus declare ISO-8859-1 or UTF-8, the values that matter are ASCII
+	d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	root := &node{}
+	stack := []*node{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			top.text = strings.TrimSpace(top.text)
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.text += string(t)
+		}
+	}
+	return root, nil
+}
+
+func parseJSON(data []byte) (*node, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	root := &node{}
+	addJSON(root, "", v)
+	return root, nil
+}
+
+// addJSON adds v to parent as children named name, one per item of an array
+func addJSON(parent *node, name string, v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			addJSON(parent, name, item)
+		}
+	case map[string]any:
+		n := &node{name: name}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			addJSON(n, k, v[k])
+		}
+		parent.children = append(parent.children, n)
+	case nil:
+		parent.children = append(parent.children, &node{name: name})
+	default:
+		parent.children = append(parent.children, &node{name: name, text: strings.TrimSpace(fmt.Sprint(v))})
+	}
+}
@@ -0,0 +1,341 @@
+package capgains
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// EventKind is the kind of a transaction affecting the lots of a security
+type EventKind int
+
+const (
+	EventBuy EventKind = iota + 1
+	EventSell
+	// EventBonus adds units at zero cost, acquired on the allotment date
+	EventBonus
+	// EventSplit adds units to the existing lots in proportion, keeping their cost and acquisition date
+	EventSplit
+)
+
+// Event is a transaction on a security. Units is the number of units bought,
+// sold or added by a bonus or split, and Amount the total cost or sale value.
+type Event struct {
+	Kind   EventKind
+	Date   time.Time
+	Units  float64
+	Amount float64
+}
+
+// Security is a mutual fund scheme or stock with its transactions
+type Security struct {
+	ISIN       string
+	Name       string
+	Source     string
+	AssetClass AssetClass
+	Events     []Event
+	// CurrentPrice values the open lots for unrealised gains, zero when unknown
+	CurrentPrice float64
+	// Warnings are the transactions that could not be read, reported with the gains
+	Warnings []string
+}
+
+// Options controls the computation of capital gains
+type Options struct {
+	FinancialYear models.FinancialYear
+	// AsOf is the date at which unrealised gains are computed. Defaults to the
+	// latest transaction date, or the end of the financial year without any;
+	// callers with the net worth response pass its ValuationDate.
+	AsOf time.Time
+	// GrandfatheredFMV is the fair market value per unit on 31 January 2018 keyed by ISIN
+	GrandfatheredFMV map[string]float64
+}
+
+// Gain is the gain on units of one lot, either sold or still held
+type Gain struct {
+	ISIN            string     `json:"isin"`
+	Name            string     `json:"name"`
+	Source          string     `json:"source"`
+	AssetClass      AssetClass `json:"assetClass"`
+	AcquiredOn      string     `json:"acquiredOn"`
+	SoldOn          string     `json:"soldOn,omitempty"`
+	HoldingDays     int        `json:"holdingDays"`
+	Units           float64    `json:"units"`
+	CostBasis       float64    `json:"costBasis"`
+	Grandfathered   bool       `json:"grandfathered,omitempty"`
+	Value           float64    `json:"value"`
+	Gain            float64    `json:"gain"`
+	Term            Term       `json:"term"`
+	TaxRatePercent  *float64   `json:"taxRatePercent,omitempty"`
+	TaxRule         string     `json:"taxRule,omitempty"`
+	ValueIsEstimate bool       `json:"valueIsEstimate,omitempty"`
+}
+
+// Summary aggregates the realised gains of an asset class and term
+type Summary struct {
+	AssetClass   AssetClass `json:"assetClass"`
+	Term         Term       `json:"term"`
+	SaleValue    float64    `json:"saleValue"`
+	CostBasis    float64    `json:"costBasis"`
+	Gain         float64    `json:"gain"`
+	Exemption    float64    `json:"exemption,omitempty"`
+	TaxableGain  float64    `json:"taxableGain"`
+	EstimatedTax *float64   `json:"estimatedTax,omitempty"`
+}
+
+// Report holds the realised gains of the financial year and the unrealised gains as of the as-of date
+type Report struct {
+	FinancialYear string    `json:"financialYear"`
+	AsOf          string    `json:"asOf"`
+	Summary       []Summary `json:"summary"`
+	Realised      []Gain    `json:"realised"`
+	Unrealised    []Gain    `json:"unrealised"`
+	Warnings      []string  `json:"warnings,omitempty"`
+	Disclaimer    string    `json:"disclaimer"`
+}
+
+const disclaimer = "Computed with FIFO lot matching from the transactions available on Fi Money. Indexation, loss set-off and carry forward, surcharge and cess are not applied. Verify with a tax professional before filing."
+
+type lot struct {
+	acquired    time.Time
+	units       float64
+	costPerUnit float64
+}
+
+// Compute matches sells against lots FIFO and classifies the realised and unrealised gains
+func Compute(securities []Security, opts Options) *Report {
+	asOf := opts.AsOf
+	if asOf.IsZero() {
+		asOf = latestDate(securities, opts.FinancialYear.End())
+	}
+	asOf = time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	report := &Report{
+		FinancialYear: opts.FinancialYear.String(),
+		AsOf:          asOf.Format(models.DateLayout),
+		Realised:      []Gain{},
+		Unrealised:    []Gain{},
+		Disclaimer:    disclaimer,
+	}
+	for _, sec := range securities {
+		computeSecurity(report, sec, opts, asOf)
+	}
+	report.Summary = summarize(report.Realised, opts.FinancialYear)
+	return report
+}
+
+// latestDate returns the date of the latest event of the securities, or
+// otherwise when they have none
+func latestDate(securities []Security, otherwise time.Time) time.Time {
+	var latest time.Time
+	for _, sec := range securities {
+		for _, e := range sec.Events {
+			if e.Date.After(latest) {
+				latest = e.Date
+			}
+		}
+	}
+	if latest.IsZero() {
+		return otherwise
+	}
+	return latest
+}
+
+func computeSecurity(report *Report, sec Security, opts Options, asOf time.Time) {
+	report.Warnings = append(report.Warnings, sec.Warnings...)
+	events := make([]Event, 0, len(sec.Events))
+	for _, e := range sec.Events {
+		if !e.Date.After(asOf) {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+
+	var lots []lot
+	var noFMV bool
+	for _, e := range events {
+		switch e.Kind {
+		case EventBuy:
+			if e.Units <= 0 {
+				continue
+			}
+			if e.Amount == 0 {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s: buy of %v units on %s has no price, cost basis taken as zero", sec.ISIN, e.Units, e.Date.Format(models.DateLayout)))
+			}
+			lots = append(lots, lot{acquired: e.Date, units: e.Units, costPerUnit: e.Amount / e.Units})
+		case EventBonus:
+			lots = append(lots, lot{acquired: e.Date, units: e.Units})
+		case EventSplit:
+			var held float64
+			for _, l := range lots {
+				held += l.units
+			}
+			if held <= 0 {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s: split on %s with no units held, ignored", sec.ISIN, e.Date.Format(models.DateLayout)))
+				continue
+			}
+			ratio := (held + e.Units) / held
+			for i := range lots {
+				lots[i].units *= ratio
+				lots[i].costPerUnit /= ratio
+			}
+		case EventSell:
+			var missing bool
+			lots, missing = sell(report, sec, lots, e, opts)
+			noFMV = noFMV || missing
+		}
+	}
+	if noFMV {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s: units acquired up to %s are grandfathered but no FMV on that day was given, actual cost used", sec.ISIN, GrandfatheringDate.Format(models.DateLayout)))
+	}
+
+	for _, l := range lots {
+		if l.units <= 1e-9 {
+			continue
+		}
+		term := classifyTerm(sec.AssetClass, l.acquired, asOf)
+		cost := l.units * l.costPerUnit
+		g := Gain{
+			ISIN:        sec.ISIN,
+			Name:        sec.Name,
+			Source:      sec.Source,
+			AssetClass:  sec.AssetClass,
+			AcquiredOn:  l.acquired.Format(models.DateLayout),
+			HoldingDays: days(l.acquired, asOf),
+			Units:       models.Round(l.units, 4),
+			CostBasis:   models.Round(cost, 2),
+			Term:        term,
+		}
+		if sec.CurrentPrice > 0 {
+			value := l.units * sec.CurrentPrice
+			g.Value = models.Round(value, 2)
+			g.Gain = models.Round(value-cost, 2)
+		} else {
+			g.ValueIsEstimate = true
+			g.Value = g.CostBasis
+		}
+		report.Unrealised = append(report.Unrealised, g)
+	}
+}
+
+// sell consumes the sold units from the oldest lots and records the gains falling
+// in the financial year. It reports whether grandfathered units were sold without
+// an FMV to value them at.
+func sell(report *Report, sec Security, lots []lot, e Event, opts Options) ([]lot, bool) {
+	var noFMV bool
+	remaining := e.Units
+	salePrice := 0.0
+	if e.Units > 0 {
+		salePrice = e.Amount / e.Units
+	}
+	for remaining > 1e-9 && len(lots) > 0 {
+		l := &lots[0]
+		units := min(l.units, remaining)
+		remaining -= units
+		l.units -= units
+		acquired, costPerUnit := l.acquired, l.costPerUnit
+		if l.units <= 1e-9 {
+			lots = lots[1:]
+		}
+		if !opts.FinancialYear.Contains(e.Date) {
+			continue
+		}
+
+		term := classifyTerm(sec.AssetClass, acquired, e.Date)
+		g := Gain{
+			ISIN:        sec.ISIN,
+			Name:        sec.Name,
+			Source:      sec.Source,
+			AssetClass:  sec.AssetClass,
+			AcquiredOn:  acquired.Format(models.DateLayout),
+			SoldOn:      e.Date.Format(models.DateLayout),
+			HoldingDays: days(acquired, e.Date),
+			Units:       models.Round(units, 4),
+			Term:        term,
+		}
+		if sec.AssetClass == AssetClassEquity && term == TermLong && !acquired.After(GrandfatheringDate) {
+			if fmv, ok := opts.GrandfatheredFMV[sec.ISIN]; ok {
+				// cost is the higher of the actual cost and the lower of the FMV on 31 January 2018 and the sale price
+				if grandfathered := min(fmv, salePrice); grandfathered > costPerUnit {
+					costPerUnit = grandfathered
+					g.Grandfathered = true
+				}
+			} else {
+				noFMV = true
+			}
+		}
+		cost := units * costPerUnit
+		value := units * salePrice
+		g.CostBasis = models.Round(cost, 2)
+		g.Value = models.Round(value, 2)
+		g.Gain = models.Round(value-cost, 2)
+		g.TaxRatePercent, g.TaxRule = taxRule(sec.AssetClass, term, acquired, e.Date)
+		report.Realised = append(report.Realised, g)
+	}
+	if remaining > 1e-9 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s: sell of %v units on %s exceeds the units held by %v, the excess is ignored", sec.ISIN, e.Units, e.Date.Format(models.DateLayout), models.Round(remaining, 4)))
+	}
+	return lots, noFMV
+}
+
+func summarize(gains []Gain, fy models.FinancialYear) []Summary {
+	type key struct {
+		class AssetClass
+		term  Term
+	}
+	var order []key
+	totals := make(map[key]*Summary)
+	weightedTax := make(map[key]float64)
+	flatRate := make(map[key]bool)
+	for _, g := range gains {
+		k := key{g.AssetClass, g.Term}
+		s, ok := totals[k]
+		if !ok {
+			s = &Summary{AssetClass: g.AssetClass, Term: g.Term}
+			totals[k] = s
+			order = append(order, k)
+			flatRate[k] = true
+		}
+		s.SaleValue += g.Value
+		s.CostBasis += g.CostBasis
+		s.Gain += g.Gain
+		if g.TaxRatePercent == nil {
+			flatRate[k] = false
+		} else {
+			weightedTax[k] += g.Gain * *g.TaxRatePercent / 100
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].class != order[j].class {
+			return order[i].class < order[j].class
+		}
+		return order[i].term > order[j].term
+	})
+	summaries := make([]Summary, 0, len(order))
+	for _, k := range order {
+		s := totals[k]
+		s.TaxableGain = max(s.Gain, 0)
+		if k.class == AssetClassEquity && k.term == TermLong {
+			s.Exemption = min(equityLTCGExemption(fy), s.TaxableGain)
+			s.TaxableGain -= s.Exemption
+		}
+		// tax is only estimated for flat rate gains, scaling the per lot tax by the taxable share of the gain
+		if flatRate[k] && s.Gain > 0 {
+			tax := models.Round(max(weightedTax[k], 0)*s.TaxableGain/s.Gain, 2)
+			s.EstimatedTax = &tax
+		}
+		s.SaleValue = models.Round(s.SaleValue, 2)
+		s.CostBasis = models.Round(s.CostBasis, 2)
+		s.Gain = models.Round(s.Gain, 2)
+		s.Exemption = models.Round(s.Exemption, 2)
+		s.TaxableGain = models.Round(s.TaxableGain, 2)
+		summaries = append(summaries, *s)
+	}
+	return summaries
+}
+
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
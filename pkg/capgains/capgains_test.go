@@ -0,0 +1,136 @@
+package capgains
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(models.DateLayout, s)
+	return t
+}
+
+func TestComputeFIFOWithBonusAndSplit(t *testing.T) {
+	sec := Security{
+		ISIN:       "INE000000001",
+		AssetClass: AssetClassEquity,
+		Events: []Event{
+			// out of order on purpose, events are sorted by date
+			{Kind: EventSell, Date: day("2024-08-01"), Units: 30, Amount: 30 * 60},
+			{Kind: EventBuy, Date: day("2023-01-01"), Units: 10, Amount: 1000},
+			{Kind: EventBuy, Date: day("2024-03-01"), Units: 10, Amount: 1200},
+			// 1:1 split doubles the units and halves the cost per unit
+			{Kind: EventSplit, Date: day("2024-04-01"), Units: 20},
+			{Kind: EventBonus, Date: day("2024-05-01"), Units: 5},
+		},
+		CurrentPrice: 70,
+	}
+	fy, _ := models.ParseFinancialYear("2024-25")
+	report := Compute([]Security{sec}, Options{FinancialYear: fy, AsOf: day("2025-01-01")})
+
+	if len(report.Realised) != 2 {
+		t.Fatalf("expected 2 realised lots, got %+v", report.Realised)
+	}
+	first, second := report.Realised[0], report.Realised[1]
+	if first.Units != 20 || first.CostBasis != 1000 || first.Term != TermLong || *first.TaxRatePercent != 12.5 {
+		t.Errorf("first lot: got %+v", first)
+	}
+	if second.Units != 10 || second.CostBasis != 600 || second.Term != TermShort || *second.TaxRatePercent != 20 {
+		t.Errorf("second lot: got %+v", second)
+	}
+	// 10 split units of the second buy and the 5 bonus units remain
+	if len(report.Unrealised) != 2 || report.Unrealised[1].CostBasis != 0 || report.Unrealised[1].Units != 5 {
+		t.Errorf("unrealised: got %+v", report.Unrealised)
+	}
+	for _, s := range report.Summary {
+		if s.Term == TermLong && (s.Exemption != 200 || s.TaxableGain != 0) {
+			t.Errorf("long term summary: got %+v", s)
+		}
+	}
+}
+
+func TestComputeGrandfathering(t *testing.T) {
+	sec := Security{
+		ISIN:       "INE000000002",
+		AssetClass: AssetClassEquity,
+		Events: []Event{
+			{Kind: EventBuy, Date: day("2017-01-01"), Units: 10, Amount: 1000},
+			{Kind: EventSell, Date: day("2024-06-01"), Units: 10, Amount: 2000},
+		},
+	}
+	fy, _ := models.ParseFinancialYear("FY2024-25")
+	report := Compute([]Security{sec}, Options{
+		FinancialYear:    fy,
+		GrandfatheredFMV: map[string]float64{"INE000000002": 150},
+	})
+	if g := report.Realised[0]; !g.Grandfathered || g.CostBasis != 1500 || g.Gain != 500 {
+		t.Errorf("grandfathered lot: got %+v", g)
+	}
+	// without an as-of date the gains are computed at the latest transaction
+	if report.AsOf != "2024-06-01" {
+		t.Errorf("as of %s, want 2024-06-01", report.AsOf)
+	}
+}
+
+func TestComputeGrandfatheringWithoutFMVWarnsOnce(t *testing.T) {
+	sec := Security{
+		ISIN:       "INE000000003",
+		AssetClass: AssetClassEquity,
+		Events: []Event{
+			{Kind: EventBuy, Date: day("2016-01-01"), Units: 10, Amount: 1000},
+			{Kind: EventBuy, Date: day("2017-01-01"), Units: 10, Amount: 1200},
+			{Kind: EventSell, Date: day("2024-06-01"), Units: 15, Amount: 3000},
+			{Kind: EventSell, Date: day("2024-07-01"), Units: 5, Amount: 1000},
+		},
+	}
+	fy, _ := models.ParseFinancialYear("2024-25")
+	report := Compute([]Security{sec}, Options{FinancialYear: fy, AsOf: day("2025-01-01")})
+	if len(report.Realised) != 3 || len(report.Warnings) != 1 {
+		t.Errorf("realised %+v, warnings %q", report.Realised, report.Warnings)
+	}
+}
+
+func TestFromMFSkipsUnknownOrderTypes(t *testing.T) {
+	txns := &models.MFTransactionsResponse{MFTransactions: []models.MFSchemeTransactions{{
+		ISIN: "INF000000001",
+		Txns: []models.MFTxn{
+			{OrderType: models.MFOrderTypeBuy, Date: "2024-01-01", Units: 10, Amount: 1000},
+			{OrderType: 7, Date: "2024-02-01", Units: 5, Amount: 600},
+			{OrderType: models.MFOrderTypeSell, Date: "2024-03-01", Units: 4, Amount: 480},
+		},
+	}}}
+	securities, err := FromMF(txns, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec := securities[0]; len(sec.Events) != 2 || sec.Events[1].Kind != EventSell || len(sec.Warnings) != 1 {
+		t.Errorf("security: got %+v", sec)
+	}
+	report := Compute(securities, Options{AsOf: day("2024-06-01")})
+	if len(report.Warnings) != 1 || report.Unrealised[0].Units != 6 {
+		t.Errorf("unrealised %+v, warnings %q", report.Unrealised, report.Warnings)
+	}
+}
+
+func TestClassifyTerm(t *testing.T) {
+	tests := []struct {
+		class    AssetClass
+		acquired string
+		sold     string
+		want     Term
+	}{
+		{AssetClassEquity, "2023-01-01", "2024-01-01", TermShort},
+		{AssetClassEquity, "2023-01-01", "2024-01-02", TermLong},
+		{AssetClassDebt, "2021-01-01", "2024-01-01", TermShort},
+		{AssetClassDebt, "2021-01-01", "2024-01-02", TermLong},
+		{AssetClassDebt, "2023-04-01", "2030-01-01", TermShort},
+		{AssetClassOther, "2022-08-01", "2024-08-02", TermLong},
+	}
+	for _, tt := range tests {
+		if got := classifyTerm(tt.class, day(tt.acquired), day(tt.sold)); got != tt.want {
+			t.Errorf("%s bought %s sold %s: got %s, want %s", tt.class, tt.acquired, tt.sold, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package capgains
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// AssetClass decides the holding period and tax rates applied to a security
+type AssetClass string
+
+const (
+	// AssetClassEquity covers listed shares, equity ETFs and equity oriented mutual funds
+	AssetClassEquity AssetClass = "EQUITY"
+	// AssetClassDebt covers debt, liquid and overnight mutual funds
+	AssetClassDebt AssetClass = "DEBT"
+	// AssetClassOther covers gold, international and other non-equity funds
+	AssetClassOther AssetClass = "OTHER"
+)
+
+// Term is the classification of a gain by holding period
+type Term string
+
+const (
+	TermShort Term = "SHORT_TERM"
+	TermLong  Term = "LONG_TERM"
+)
+
+var (
+	// GrandfatheringDate is the date up to which equity acquisitions are grandfathered u/s 112A
+	GrandfatheringDate = date(2018, time.January, 31)
+	// SpecifiedFundDate is the date from which debt fund units are always short term u/s 50AA
+	SpecifiedFundDate = date(2023, time.April, 1)
+	// Budget2024Date is the date from which the Finance (No. 2) Act, 2024 rates and holding periods apply
+	Budget2024Date = date(2024, time.July, 23)
+)
+
+// equityHybridCategories are hybrid categories that hold at least 65% in domestic equity
+var equityHybridCategories = map[string]bool{
+	"AGGRESSIVE_HYBRID_FUND":   true,
+	"BALANCED_ADVANTAGE_FUND":  true,
+	"DYNAMIC_ASSET_ALLOCATION": true,
+	"ARBITRAGE_FUND":           true,
+	"EQUITY_SAVINGS":           true,
+}
+
+var (
+	debtNamePattern = regexp.MustCompile(`(?i)\b(liquid|overnight|debt|bond|gilt|treasury|money market|duration|income|credit risk|banking and psu)\b`)
+	goldNamePattern = regexp.MustCompile(`(?i)\b(gold|silver|international|global|overseas|nasdaq|us equity)\b`)
+)
+
+// ClassifyMF returns the tax asset class of a mutual fund from its asset class and
+// category in mfSchemeAnalytics, falling back to the scheme name when they are not known
+func ClassifyMF(assetClass, categoryName, schemeName string) AssetClass {
+	switch assetClass {
+	case "EQUITY":
+		if categoryName == "INTERNATIONAL_FUNDS" || categoryName == "FUND_OF_FUNDS" {
+			return AssetClassOther
+		}
+		return AssetClassEquity
+	case "HYBRID":
+		if equityHybridCategories[categoryName] {
+			return AssetClassEquity
+		}
+		return AssetClassOther
+	case "DEBT", "CASH":
+		return AssetClassDebt
+	case "COMMODITY":
+		return AssetClassOther
+	}
+	switch {
+	case goldNamePattern.MatchString(schemeName):
+		return AssetClassOther
+	case debtNamePattern.MatchString(schemeName):
+		return AssetClassDebt
+	}
+	return AssetClassEquity
+}
+
+// classifyTerm returns whether units acquired and sold on the given dates are held long term
+func classifyTerm(class AssetClass, acquired, sold time.Time) Term {
+	months := 12
+	switch class {
+	case AssetClassDebt:
+		if !acquired.Before(SpecifiedFundDate) {
+			return TermShort
+		}
+		fallthrough
+	case AssetClassOther:
+		months = 36
+		if !sold.Before(Budget2024Date) {
+			months = 24
+		}
+	}
+	if sold.After(acquired.AddDate(0, months, 0)) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// taxRule describes how a realised gain is taxed. ratePercent is nil when the
+// gain is added to income and taxed at the slab rate.
+func taxRule(class AssetClass, term Term, acquired, sold time.Time) (ratePercent *float64, rule string) {
+	afterBudget := !sold.Before(Budget2024Date)
+	rate := func(v float64) *float64 { return &v }
+	switch {
+	case class == AssetClassEquity && term == TermShort:
+		if afterBudget {
+			return rate(20), "STCG u/s 111A at 20%"
+		}
+		return rate(15), "STCG u/s 111A at 15%"
+	case class == AssetClassEquity:
+		if afterBudget {
+			return rate(12.5), "LTCG u/s 112A at 12.5% above the annual exemption"
+		}
+		return rate(10), "LTCG u/s 112A at 10% above the annual exemption"
+	case class == AssetClassDebt && !acquired.Before(SpecifiedFundDate):
+		return nil, "Deemed STCG u/s 50AA taxed at slab rate"
+	case term == TermShort:
+		return nil, "STCG taxed at slab rate"
+	case afterBudget:
+		return rate(12.5), "LTCG u/s 112 at 12.5% without indexation"
+	}
+	return rate(20), "LTCG u/s 112 at 20% with indexation (indexation not computed)"
+}
+
+// equityLTCGExemption returns the annual exemption on equity LTCG u/s 112A for the financial year
+func equityLTCGExemption(fy models.FinancialYear) float64 {
+	if fy.StartYear >= 2024 {
+		return 125000
+	}
+	return 100000
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,119 @@
+package capgains
+
+import (
+	"fmt"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Sources of the securities, reported on every gain
+const (
+	SourceMF    = "fetch_mf_transactions"
+	SourceStock = "fetch_stock_transactions"
+)
+
+// FromMF builds one security per scheme from the MF transactions, merging folios
+// of the same scheme. The asset class and current NAV come from the scheme analytics.
+// Transactions of an unknown order type are skipped with a warning.
+func FromMF(txns *models.MFTransactionsResponse, analytics *models.MFSchemeAnalytics) ([]Security, error) {
+	details := make(map[string]models.SchemeDetail)
+	if analytics != nil {
+		for _, a := range analytics.SchemeAnalytics {
+			details[a.SchemeDetail.ISINNumber] = a.SchemeDetail
+		}
+	}
+
+	var securities []Security
+	index := make(map[string]int)
+	for _, scheme := range txns.MFTransactions {
+		i, ok := index[scheme.ISIN]
+		if !ok {
+			detail := details[scheme.ISIN]
+			i = len(securities)
+			index[scheme.ISIN] = i
+			securities = append(securities, Security{
+				ISIN:         scheme.ISIN,
+				Name:         scheme.SchemeName,
+				Source:       SourceMF,
+				AssetClass:   ClassifyMF(detail.AssetClass, detail.CategoryName, scheme.SchemeName),
+				CurrentPrice: detail.NAV.Float(),
+			})
+		}
+		for _, txn := range scheme.Txns {
+			date, err := txn.Time()
+			if err != nil {
+				return nil, err
+			}
+			var kind EventKind
+			switch txn.OrderType {
+			case models.MFOrderTypeBuy:
+				kind = EventBuy
+			case models.MFOrderTypeSell:
+				kind = EventSell
+			default:
+				securities[i].Warnings = append(securities[i].Warnings, fmt.Sprintf("%s: unknown order type %d on %s ignored", scheme.ISIN, txn.OrderType, txn.Date))
+				continue
+			}
+			securities[i].Events = append(securities[i].Events, Event{Kind: kind, Date: date, Units: txn.Units, Amount: txn.Amount})
+		}
+	}
+	return securities, nil
+}
+
+// FromStocks builds one security per ISIN from the stock transactions. Names and
+// current prices come from the equity, ETF, REIT and InvIT holdings in the account
+// details, falling back to the last transaction price. All securities are treated
+// as listed equity.
+func FromStocks(txns *models.StockTransactionsResponse, accounts *models.AccountDetailsBulkResponse) ([]Security, error) {
+	holdings := holdingsByISIN(accounts)
+	var securities []Security
+	for _, stock := range txns.StockTransactions {
+		sec := Security{
+			ISIN:       stock.ISIN,
+			Name:       holdings[stock.ISIN].Name(),
+			Source:     SourceStock,
+			AssetClass: AssetClassEquity,
+		}
+		var lastPrice float64
+		for _, txn := range stock.Txns {
+			date, err := txn.Time()
+			if err != nil {
+				return nil, err
+			}
+			e := Event{Date: date, Units: txn.Quantity, Amount: txn.Quantity * txn.Price()}
+			switch txn.Type {
+			case models.StockTxnTypeBuy:
+				e.Kind = EventBuy
+			case models.StockTxnTypeSell:
+				e.Kind = EventSell
+			case models.StockTxnTypeBonus:
+				e.Kind, e.Amount = EventBonus, 0
+			case models.StockTxnTypeSplit:
+				e.Kind, e.Amount = EventSplit, 0
+			default:
+				continue
+			}
+			if txn.Price() > 0 {
+				lastPrice = txn.Price()
+			}
+			sec.Events = append(sec.Events, e)
+		}
+		sec.CurrentPrice = holdings[stock.ISIN].Price()
+		if sec.CurrentPrice == 0 {
+			sec.CurrentPrice = lastPrice
+		}
+		securities = append(securities, sec)
+	}
+	return securities, nil
+}
+
+// holdingsByISIN returns the first demat holding of each ISIN
+func holdingsByISIN(accounts *models.AccountDetailsBulkResponse) map[string]models.Holding {
+	holdings := make(map[string]models.Holding)
+	for _, h := range accounts.DematHoldings() {
+		if _, ok := holdings[h.ISIN]; !ok {
+			holdings[h.ISIN] = h.Holding
+		}
+	}
+	return holdings
+}
@@ -0,0 +1,302 @@
+// Package cas reads the consolidated account statements (CAS) of mutual fund
+// folios sent by CAMS and KFintech. It takes the statement as text (pdftotext
+// -layout of the PDF), as the JSON and CSV exports of casparser, or as any CSV
+// with a header naming the folio, ISIN, date and units columns.
+package cas
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// Statement holds the schemes of one or more statements, one per ISIN and folio
+type Statement struct {
+	// AsOf is the latest valuation or transaction date
+	AsOf     time.Time
+	Schemes  []persona.Scheme
+	Warnings []string
+	// reported holds the ISIN|folio keys of the schemes whose closing units the
+	// statement gave rather than worked out from its own transactions
+	reported map[string]bool
+}
+
+// Parse reads a statement, telling JSON, CSV and text apart by their content
+func Parse(data []byte) (*Statement, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var st *Statement
+	var err error
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		st, err = parseJSON(data)
+	case isCSV(data):
+		st, err = parseCSV(data)
+	default:
+		st, err = parseText(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(st.Schemes) == 0 {
+		return nil, fmt.Errorf("no scheme found")
+	}
+	return st, nil
+}
+
+// builder collects the schemes of a statement in the order they are found
+type builder struct {
+	st      *Statement
+	schemes map[string]*persona.Scheme
+	keys    []string
+	rta     string
+}
+
+func newBuilder() *builder {
+	return &builder{st: &Statement{reported: map[string]bool{}}, schemes: map[string]*persona.Scheme{}}
+}
+
+// folioKey drops the spaces statements put around the slash of a folio number
+func folioKey(folio string) string {
+	return strings.Join(strings.Fields(folio), "")
+}
+
+// amcName turns an AMC such as "HDFC Mutual Fund" into the HDFC_MUTUAL_FUND of the scheme analytics
+func amcName(amc string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(amc), "_"))
+}
+
+// scheme returns the scheme of an ISIN in a folio, adding it when it is new
+func (b *builder) scheme(isin, folio string) *persona.Scheme {
+	key := isin + "|" + folioKey(folio)
+	s, ok := b.schemes[key]
+	if !ok {
+		s = &persona.Scheme{ISIN: isin, Folio: folioKey(folio), Registrar: b.rta}
+		b.schemes[key] = s
+		b.keys = append(b.keys, key)
+	}
+	return s
+}
+
+func (b *builder) warn(format string, args ...any) {
+	b.st.Warnings = append(b.st.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (b *builder) asOf(t time.Time) {
+	if t.After(b.st.AsOf) {
+		b.st.AsOf = t
+	}
+}
+
+// sellWords and reversalWords classify a transaction by its type or description
+var (
+	sellWords     = []string{"REDEMPTION", "REDEEM", "SWITCH_OUT", "SWITCH OUT", "SWITCH-OUT", "SWITCHOUT", "STP OUT", "SWP", "TRANSFER OUT", "SELL"}
+	reversalWords = []string{"REVERSAL", "REJECTION", "REJECTED"}
+)
+
+func hasWord(s string, words []string) bool {
+	s = strings.ToUpper(s)
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// txn adds a transaction with units to a scheme. Rows without units such as
+// stamp duty, STT and dividend payouts are left out. A reversal takes back the
+// last purchase of the same units.
+func (b *builder) txn(s *persona.Scheme, date time.Time, kind string, amount, units, nav float64) {
+	if units == 0 {
+		return
+	}
+	b.asOf(date)
+	if hasWord(kind, reversalWords) {
+		for i := len(s.Txns) - 1; i >= 0; i-- {
+			t := s.Txns[i]
+			if t.OrderType == models.MFOrderTypeBuy && math.Abs(t.Units-math.Abs(units)) < 1e-6 {
+				s.Txns = append(s.Txns[:i], s.Txns[i+1:]...)
+				return
+			}
+		}
+		b.warn("%s folio %s: reversal of %v units on %s matches no purchase, skipped", s.ISIN, s.Folio, units, date.Format(models.DateLayout))
+		return
+	}
+	orderType := models.MFOrderTypeBuy
+	if units < 0 || hasWord(kind, sellWords) {
+		orderType = models.MFOrderTypeSell
+	}
+	amount, units = math.Abs(amount), math.Abs(units)
+	if nav == 0 {
+		nav = models.Round(amount/units, 4)
+	}
+	s.Txns = append(s.Txns, models.MFTxn{OrderType: orderType, Date: date.Format(models.DateLayout), Price: nav, Units: units, Amount: amount})
+}
+
+// done works out the closing units of the schemes the statement gave no
+// balance for from their transactions and returns the statement
+func (b *builder) done(withBalance map[*persona.Scheme]bool) *Statement {
+	for _, k := range b.keys {
+		s := b.schemes[k]
+		if withBalance[s] {
+			b.st.reported[k] = true
+		} else {
+			s.Units = math.Max(models.Round(netUnits(s.Txns, ""), 3), 0)
+		}
+		if s.NAV == 0 && len(s.Txns) > 0 {
+			s.NAV = s.Txns[len(s.Txns)-1].Price
+		}
+		b.st.Schemes = append(b.st.Schemes, *s)
+	}
+	return b.st
+}
+
+// netUnits adds up the units bought less those sold in the transactions dated
+// after after, all of them when it is empty
+func netUnits(txns []models.MFTxn, after string) float64 {
+	var units float64
+	for _, t := range txns {
+		switch {
+		case t.Date <= after:
+		case t.OrderType == models.MFOrderTypeBuy:
+			units += t.Units
+		default:
+			units -= t.Units
+		}
+	}
+	return units
+}
+
+// Merge combines statements that may cover the same folios, e.g. a CAMS and a
+// KFintech statement or two overlapping periods. A scheme is identified by its
+// ISIN and folio whatever AMC name the statements give it. A transaction found
+// in several statements is kept once. The closing balance is the one of the
+// latest statement that reports it, moved by the transactions after it, or
+// else is worked out from the merged transactions. Every folio of a scheme gets
+// the NAV of the latest statement holding it.
+func Merge(statements ...*Statement) *Statement {
+	out := &Statement{reported: map[string]bool{}}
+	index := map[string]int{}
+	asOf := map[string]time.Time{}
+	// units and unitsAsOf are the closing balance of the latest statement reporting it
+	units := map[string]float64{}
+	unitsAsOf := map[string]time.Time{}
+	nav := map[string]float64{}
+	navAsOf := map[string]time.Time{}
+	for _, st := range statements {
+		out.Warnings = append(out.Warnings, st.Warnings...)
+		if st.AsOf.After(out.AsOf) {
+			out.AsOf = st.AsOf
+		}
+		for _, s := range st.Schemes {
+			if s.NAV > 0 && !st.AsOf.Before(navAsOf[s.ISIN]) {
+				nav[s.ISIN], navAsOf[s.ISIN] = s.NAV, st.AsOf
+			}
+			key := s.ISIN + "|" + s.Folio
+			if st.reported[key] && (!out.reported[key] || !st.AsOf.Before(unitsAsOf[key])) {
+				out.reported[key] = true
+				units[key], unitsAsOf[key] = s.Units, st.AsOf
+			}
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(out.Schemes)
+				asOf[key] = st.AsOf
+				s.Txns = append([]models.MFTxn(nil), s.Txns...)
+				out.Schemes = append(out.Schemes, s)
+				continue
+			}
+			m := &out.Schemes[i]
+			m.Txns = mergeTxns(m.Txns, s.Txns)
+			if !st.AsOf.Before(asOf[key]) {
+				asOf[key] = st.AsOf
+				prev := *m
+				*m = s
+				m.Txns = prev.Txns
+				// exports that leave out the names keep those of the other statements
+				for _, f := range []struct{ to, from *string }{
+					{&m.Name, &prev.Name}, {&m.AMC, &prev.AMC}, {&m.Registrar, &prev.Registrar}, {&m.Category, &prev.Category},
+				} {
+					if *f.to == "" {
+						*f.to = *f.from
+					}
+				}
+			}
+		}
+	}
+	for i := range out.Schemes {
+		s := &out.Schemes[i]
+		if v, ok := nav[s.ISIN]; ok {
+			s.NAV = v
+		}
+		key := s.ISIN + "|" + s.Folio
+		if out.reported[key] {
+			s.Units = math.Max(models.Round(units[key]+netUnits(s.Txns, unitsAsOf[key].Format(models.DateLayout)), 3), 0)
+		} else {
+			s.Units = math.Max(models.Round(netUnits(s.Txns, ""), 3), 0)
+		}
+	}
+	return out
+}
+
+// mergeTxns adds the transactions of b that a doesn't have. Transactions are
+// compared by type, date, units and amount, since not every statement gives the
+// NAV, and matched one to one, so two SIPs of the same amount on the same day
+// in one statement both stay.
+func mergeTxns(a, b []models.MFTxn) []models.MFTxn {
+	type key struct {
+		orderType     int
+		date          string
+		units, amount float64
+	}
+	keyOf := func(t models.MFTxn) key {
+		return key{t.OrderType, t.Date, models.Round(t.Units, 3), models.Round(t.Amount, 2)}
+	}
+	count := map[key]int{}
+	for _, t := range a {
+		count[keyOf(t)]++
+	}
+	for _, t := range b {
+		if k := keyOf(t); count[k] > 0 {
+			count[k]--
+			continue
+		}
+		a = append(a, t)
+	}
+	return a
+}
+
+// dateLayouts are the date formats found in statements
+var dateLayouts = []string{"02-Jan-2006", "2006-01-02", "02-01-2006", "02/01/2006", "02-Jan-06", "02 Jan 2006", "2-Jan-2006"}
+
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+var numberRe = regexp.MustCompile(`^\(?-?[\d,]*\.?\d+\)?$`)
+
+// number parses an amount such as 2,900.00, -2900 or (2,900.00), the last two negative
+func number(s string) float64 {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "INR"))
+	if !numberRe.MatchString(s) {
+		return 0
+	}
+	negative := strings.HasPrefix(s, "(")
+	v, _ := strconv.ParseFloat(strings.NewReplacer(",", "", "(", "", ")", "").Replace(s), 64)
+	if negative {
+		return -v
+	}
+	return v
+}
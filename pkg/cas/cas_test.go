@@ -0,0 +1,140 @@
+package cas
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+	"github.com/epifi/fi-mcp-lite/pkg/validate"
+)
+
+func parse(t *testing.T, files ...string) []*Statement {
+	t.Helper()
+	var statements []*Statement
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		statements = append(statements, st)
+	}
+	return statements
+}
+
+func TestParse(t *testing.T) {
+	st := parse(t, "cams.txt", "kfintech.csv", "cas.json")
+
+	text := st[0]
+	if len(text.Schemes) != 2 || text.AsOf.Format(models.DateLayout) != "2025-03-31" {
+		t.Fatalf("text = %+v", text)
+	}
+	// the reversal takes back the third SIP
+	if s := text.Schemes[0]; s.Folio != "15463921/78" || s.AMC != "HDFC_MUTUAL_FUND" || s.Registrar != "CAMS" || s.Units != 10.75 || s.NAV != 1750 || len(s.Txns) != 3 || s.Txns[2].OrderType != models.MFOrderTypeSell {
+		t.Errorf("scheme = %+v", s)
+	}
+	if s := text.Schemes[1]; s.Registrar != "KFINTECH" || s.Name != "Axis Bluechip Fund - Direct Growth" || s.Units != 1000 {
+		t.Errorf("scheme = %+v", s)
+	}
+
+	csv := st[1]
+	if len(csv.Schemes) != 2 || csv.Schemes[0].Units != 1100 || csv.Schemes[1].Units != 5 || len(csv.Schemes[1].Txns) != 1 {
+		t.Errorf("csv = %+v", csv.Schemes)
+	}
+
+	json := st[2]
+	if s := json.Schemes[0]; s.Units != 15.75 || s.NAV != 2050 || s.Cost != 26923.08 || len(s.Txns) != 4 || s.Registrar != "CAMS" {
+		t.Errorf("json = %+v", s)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	st := Merge(parse(t, "cams.txt", "kfintech.csv", "cas.json")...)
+	if st.AsOf.Format(models.DateLayout) != "2025-06-30" || len(st.Schemes) != 3 {
+		t.Fatalf("merged = %+v", st)
+	}
+	// the purchases found in both the text and the JSON statement are kept once
+	if s := st.Schemes[0]; len(s.Txns) != 4 || s.Units != 15.75 || s.NAV != 2050 || s.Name != "HDFC Flexi Cap Fund - Direct Plan - Growth Option" {
+		t.Errorf("HDFC = %+v", s)
+	}
+	// the CSV names no registrar, the one of the text statement stays
+	if s := st.Schemes[1]; len(s.Txns) != 2 || s.Units != 1100 || s.NAV != 60 || s.Registrar != "KFINTECH" {
+		t.Errorf("Axis = %+v", s)
+	}
+}
+
+// TestMergeWorksOutUnreportedUnits merges two CSVs without a balance column, whose
+// closing units are worked out from all the merged transactions
+func TestMergeWorksOutUnreportedUnits(t *testing.T) {
+	var statements []*Statement
+	for _, data := range []string{
+		"folio,isin,date,description,amount,units\n1/1,INF846K01DP8,2025-01-10,Purchase,5000,100\n1/1,INF846K01DP8,2025-02-10,Purchase,2600,50\n",
+		"folio,isin,date,description,amount,units\n1/1,INF846K01DP8,2025-03-10,Purchase,1100,20\n",
+	} {
+		st, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements = append(statements, st)
+	}
+	if s := Merge(statements...).Schemes[0]; len(s.Txns) != 3 || s.Units != 170 {
+		t.Errorf("merged = %+v", s)
+	}
+	// a reported balance is moved by the transactions of later statements
+	reported := parse(t, "kfintech.csv")[0]
+	later, err := Parse([]byte("folio,isin,date,description,amount,units\n91012345678,INF846K01DP8,2025-06-10,Redemption,3100,-50\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range Merge(reported, later).Schemes {
+		if s.ISIN == "INF846K01DP8" && s.Units != 1050 {
+			t.Errorf("Axis units = %v, want 1050", s.Units)
+		}
+	}
+}
+
+func TestSetMutualFunds(t *testing.T) {
+	dir := t.TempDir()
+	f, err := persona.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := Merge(parse(t, "cams.txt", "kfintech.csv", "cas.json")...)
+	f.SetMutualFunds(st.Schemes, st.AsOf)
+	if err := f.WriteTools(dir, "fetch_mf_transactions", "fetch_net_worth"); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = persona.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	analytics := f.NetWorth.MFSchemeAnalytics.SchemeAnalytics
+	if len(analytics) != 2 {
+		t.Fatalf("%d schemes in the analytics, want one per ISIN", len(analytics))
+	}
+	// both HDFC folios add up
+	for _, a := range analytics {
+		if a.SchemeDetail.ISINNumber != "INF179K01UT0" {
+			continue
+		}
+		if units := a.EnrichedAnalytics.Analytics.SchemeDetails.Units; units != 20.75 {
+			t.Errorf("HDFC units = %v, want 20.75", units)
+		}
+	}
+	if n := len(f.MFTransactions.MFTransactions); n != 3 {
+		t.Errorf("%d folios in the transactions, want 3", n)
+	}
+
+	violations, err := validate.Persona(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		t.Errorf("violation: %s", v)
+	}
+}
@@ -0,0 +1,114 @@
+package cas
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// csvColumns maps the headers used by casparser and the registrar exports,
+// lowercased with everything but letters dropped, to the fields they hold
+var csvColumns = map[string]string{
+	"folio": "folio", "foliono": "folio", "folionumber": "folio",
+	"isin":   "isin",
+	"scheme": "scheme", "schemename": "scheme",
+	"amc": "amc", "amcname": "amc",
+	"rta": "rta", "registrar": "rta",
+	"date": "date", "transactiondate": "date", "txndate": "date",
+	"type": "type", "transactiontype": "type",
+	"description": "description", "transactiondescription": "description", "narration": "description",
+	"amount": "amount", "amountinr": "amount", "amountrs": "amount",
+	"units": "units",
+	"nav":   "nav", "price": "nav", "navprice": "nav", "priceinr": "nav",
+	"balance": "balance", "unitbalance": "balance", "balanceunits": "balance", "closingunits": "balance",
+}
+
+// csvRequired are the columns a CSV statement can't do without
+var csvRequired = []string{"folio", "isin", "date", "units"}
+
+func headerKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(s))
+}
+
+// isCSV reports whether the first line is a CSV header naming the required columns
+func isCSV(data []byte) bool {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	found := map[string]bool{}
+	for _, h := range strings.Split(string(line), ",") {
+		found[csvColumns[headerKey(h)]] = true
+	}
+	for _, c := range csvRequired {
+		if !found[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCSV reads one transaction per row. The balance of the latest row of a
+// scheme, when there is a balance column, is its closing balance.
+func parseCSV(data []byte) (*Statement, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, h := range rows[0] {
+		if c, ok := csvColumns[headerKey(h)]; ok {
+			if _, dup := columns[c]; !dup {
+				columns[c] = i
+			}
+		}
+	}
+	b := newBuilder()
+	withBalance := map[*persona.Scheme]bool{}
+	balanceDate := map[*persona.Scheme]time.Time{}
+	for n, row := range rows[1:] {
+		get := func(c string) string {
+			if i, ok := columns[c]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if get("isin") == "" {
+			if strings.Join(row, "") != "" {
+				b.warn("row %d has no ISIN, skipped", n+2)
+			}
+			continue
+		}
+		date, ok := parseDate(get("date"))
+		if !ok {
+			b.warn("row %d has no date, skipped", n+2)
+			continue
+		}
+		s := b.scheme(get("isin"), get("folio"))
+		if name := get("scheme"); name != "" {
+			s.Name = name
+		}
+		if amc := get("amc"); amc != "" {
+			s.AMC = amcName(amc)
+		}
+		if rta := get("rta"); rta != "" {
+			s.Registrar = persona.Registrar(rta)
+		}
+		b.txn(s, date, get("type")+" "+get("description"), number(get("amount")), number(get("units")), number(get("nav")))
+		if balance := get("balance"); balance != "" && number(get("units")) != 0 && !date.Before(balanceDate[s]) {
+			s.Units = number(balance)
+			withBalance[s] = true
+			balanceDate[s] = date
+		}
+	}
+	return b.done(withBalance), nil
+}
@@ -0,0 +1,100 @@
+package cas
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// value is a JSON number, a string holding one or null
+type value float64
+
+func (v *value) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*v = 0
+		return nil
+	}
+	*v = value(number(s))
+	return nil
+}
+
+// casJSON is the statement as exported by casparser
+type casJSON struct {
+	StatementPeriod struct {
+		To string `json:"to"`
+	} `json:"statement_period"`
+	FileType string `json:"file_type"`
+	Folios   []struct {
+		Folio   string `json:"folio"`
+		AMC     string `json:"amc"`
+		Schemes []struct {
+			Scheme    string `json:"scheme"`
+			ISIN      string `json:"isin"`
+			RTA       string `json:"rta"`
+			Type      string `json:"type"`
+			Close     *value `json:"close"`
+			Valuation struct {
+				Date string `json:"date"`
+				NAV  value  `json:"nav"`
+				Cost value  `json:"cost"`
+			} `json:"valuation"`
+			Transactions []struct {
+				Date        string `json:"date"`
+				Description string `json:"description"`
+				Amount      value  `json:"amount"`
+				Units       value  `json:"units"`
+				NAV         value  `json:"nav"`
+				Type        string `json:"type"`
+			} `json:"transactions"`
+		} `json:"schemes"`
+	} `json:"folios"`
+}
+
+func parseJSON(data []byte) (*Statement, error) {
+	var doc casJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	b := newBuilder()
+	b.rta = persona.Registrar(doc.FileType)
+	if t, ok := parseDate(doc.StatementPeriod.To); ok {
+		b.asOf(t)
+	}
+	withBalance := map[*persona.Scheme]bool{}
+	for _, f := range doc.Folios {
+		for _, sc := range f.Schemes {
+			if sc.ISIN == "" {
+				b.warn("folio %s: %s has no ISIN, skipped", f.Folio, sc.Scheme)
+				continue
+			}
+			s := b.scheme(sc.ISIN, f.Folio)
+			s.Name, s.AMC = sc.Scheme, amcName(f.AMC)
+			if sc.RTA != "" {
+				s.Registrar = persona.Registrar(sc.RTA)
+			}
+			if sc.Type == "DEBT" {
+				s.AssetClass = "DEBT"
+			}
+			for _, t := range sc.Transactions {
+				date, ok := parseDate(t.Date)
+				if !ok {
+					b.warn("folio %s: %s has a transaction without a date, skipped", f.Folio, sc.ISIN)
+					continue
+				}
+				b.txn(s, date, t.Type+" "+t.Description, float64(t.Amount), float64(t.Units), float64(t.NAV))
+			}
+			if sc.Close != nil {
+				s.Units = float64(*sc.Close)
+				withBalance[s] = true
+			}
+			s.NAV, s.Cost = float64(sc.Valuation.NAV), float64(sc.Valuation.Cost)
+			if t, ok := parseDate(sc.Valuation.Date); ok {
+				b.asOf(t)
+			}
+		}
+	}
+	return b.done(withBalance), nil
+}
@@ -0,0 +1,90 @@
+package cas
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// The lines of a statement converted with pdftotext -layout that matter
+var (
+	amcRe     = regexp.MustCompile(`^\s*([A-Za-z0-9&.' ]+ Mutual Fund)\s*$`)
+	folioRe   = regexp.MustCompile(`Folio No\s*:\s*([0-9A-Za-z]+(?:\s*/\s*[0-9A-Za-z]+)?)`)
+	schemeRe  = regexp.MustCompile(`^\s*(?:[A-Z0-9]+-)?(.+?)\s*-\s*ISIN\s*:\s*([A-Z]{2}[A-Z0-9]{9}[0-9])`)
+	rtaRe     = regexp.MustCompile(`Registrar\s*:\s*([A-Za-z]+)`)
+	txnRe     = regexp.MustCompile(`^\s*(\d{2}-[A-Za-z]{3}-\d{4})\s+(.+?)\s+(\(?-?[\d,]+\.\d+\)?)\s+(\(?-?[\d,]+\.\d+\)?)\s+(\(?-?[\d,]+\.\d+\)?)\s+(\(?-?[\d,]+\.\d+\)?)\s*$`)
+	closingRe = regexp.MustCompile(`Closing Unit Balance\s*:\s*([\d,]+\.\d+)`)
+	navRe     = regexp.MustCompile(`NAV on (\d{2}-[A-Za-z]{3}-\d{4})\s*:\s*INR\s*([\d,]+\.\d+)`)
+	costRe    = regexp.MustCompile(`(?:Total )?Cost Value\s*:\s*(?:INR\s*)?([\d,]+\.\d+)`)
+	periodRe  = regexp.MustCompile(`\d{2}-[A-Za-z]{3}-\d{4}\s+[Tt][Oo]\s+(\d{2}-[A-Za-z]{3}-\d{4})`)
+)
+
+// parseText reads a CAMS or KFintech CAS as text. The AMC, folio and scheme
+// lines open the blocks the transaction rows of a scheme follow, and the
+// closing balance line gives the units, NAV and cost at the end of the period.
+func parseText(data []byte) (*Statement, error) {
+	b := newBuilder()
+	if bytes.Contains(bytes.ToUpper(data), []byte("KFIN")) && !bytes.Contains(bytes.ToUpper(data), []byte("CAMS")) {
+		b.rta = "KFINTECH"
+	}
+	withBalance := map[*persona.Scheme]bool{}
+	var amc, folio string
+	var s *persona.Scheme
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := periodRe.FindStringSubmatch(line); m != nil && b.st.AsOf.IsZero() {
+			if t, ok := parseDate(m[1]); ok {
+				b.asOf(t)
+			}
+		}
+		if m := amcRe.FindStringSubmatch(line); m != nil {
+			amc = m[1]
+			continue
+		}
+		if m := folioRe.FindStringSubmatch(line); m != nil {
+			folio = m[1]
+			s = nil
+			continue
+		}
+		if m := schemeRe.FindStringSubmatch(line); m != nil {
+			if folio == "" {
+				b.warn("%s is not under a folio, skipped", m[2])
+				continue
+			}
+			s = b.scheme(m[2], folio)
+			s.Name, s.AMC = strings.TrimSpace(m[1]), amcName(amc)
+			if r := rtaRe.FindStringSubmatch(line); r != nil {
+				s.Registrar = persona.Registrar(r[1])
+			}
+			continue
+		}
+		if s == nil {
+			continue
+		}
+		if m := txnRe.FindStringSubmatch(line); m != nil {
+			if date, ok := parseDate(m[1]); ok {
+				b.txn(s, date, m[2], number(m[3]), number(m[4]), number(m[5]))
+			}
+			continue
+		}
+		if m := closingRe.FindStringSubmatch(line); m != nil {
+			s.Units = number(m[1])
+			withBalance[s] = true
+			if n := navRe.FindStringSubmatch(line); n != nil {
+				s.NAV = number(n[2])
+				if t, ok := parseDate(n[1]); ok {
+					b.asOf(t)
+				}
+			}
+			if c := costRe.FindStringSubmatch(line); c != nil {
+				s.Cost = number(c[1])
+			}
+		}
+	}
+	return b.done(withBalance), scanner.Err()
+}
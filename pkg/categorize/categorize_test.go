@@ -0,0 +1,124 @@
+package categorize
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// TestCorpus checks the shipped rules against narrations taken from the test data
+func TestCorpus(t *testing.T) {
+	e := NewEngine("../../rules/categories.yaml")
+	if _, err := e.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open("testdata/corpus.tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.HasPrefix(scanner.Text(), "#") {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 8 {
+			t.Fatalf("line %d: want 8 fields, got %d", line, len(fields))
+		}
+		txnType, _ := strconv.Atoi(fields[0])
+		got := e.Categorize(models.BankTxn{Type: txnType, Amount: fields[1], Narration: fields[2]})
+		want := Result{Category: fields[3]}
+		want.Counterparty, want.VPA, want.IFSC, want.Reference = fields[4], fields[5], fields[6], fields[7]
+		if got.Category != want.Category || got.Counterparty != want.Counterparty || got.VPA != want.VPA || got.IFSC != want.IFSC || got.Reference != want.Reference {
+			t.Errorf("line %d %q: got %s/%q/%q/%q/%q (rule %s), want %s/%q/%q/%q/%q", line, fields[2],
+				got.Category, got.Counterparty, got.VPA, got.IFSC, got.Reference, got.Rule,
+				want.Category, want.Counterparty, want.VPA, want.IFSC, want.Reference)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := map[string]string{
+		"rules:\n  - name: a\n    narration: x\n":                                      "rule a: category is required",
+		"rules:\n  - name: a\n    category: X\n":                                       "rule a: at least one condition is required",
+		"rules:\n  - category: X\n    narration: '('\n":                                "rule #1: invalid narration pattern",
+		"rules:\n  - name: a\n    category: X\n    channel: SWIFT\n":                   `rule a: unknown channel "SWIFT"`,
+		"rules:\n  - name: a\n    category: X\n    direction: out\n":                   "rule a: direction must be credit or debit",
+		"rules:\n  - name: a\n    category: X\n    min_amount: 5\n    max_amount: 1\n": "rule a: min_amount is above max_amount",
+		"rules:\n  - name: a\n    category: X\n    amount: 5\n":                        "field amount not found",
+	}
+	for data, want := range tests {
+		_, err := ParseRules([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseRules(%q) error = %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestReloadKeepsRulesOnError(t *testing.T) {
+	path := t.TempDir() + "/rules.yaml"
+	os.WriteFile(path, []byte("rules:\n  - name: rent\n    category: RENT\n    narration: RENT\n"), 0o644)
+	e := NewEngine(path)
+	if _, err := e.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("rules:\n  - name: broken\n"), 0o644)
+	if status, err := e.Reload(); err == nil || status.Rules != 1 {
+		t.Fatalf("Reload() = %+v, %v, want an error with the old rule kept", status, err)
+	}
+	if got := e.Categorize(models.BankTxn{Type: models.BankTxnTypeDebit, Amount: "100", Narration: "IMPS-X-JULY RENT"}); got.Category != "RENT" {
+		t.Errorf("category = %s, want RENT", got.Category)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	rules, err := ParseRules([]byte("rules:\n  - name: food\n    category: FOOD\n    vpa: SWIGGY\n    max_amount: 1000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{rules: rules}
+	resp := &models.BankTransactionsResponse{SchemaDescription: "Txns are arrays.", BankTransactions: []models.BankTransactions{{
+		Bank: "Test Bank",
+		Txns: []models.BankTxn{
+			{Amount: "280", Narration: "UPI-SWIGGY-SWIGGY@YBL-FOOD ORDER", Date: "2024-06-09", Type: models.BankTxnTypeDebit, Mode: "UPI", CurrentBalance: "1000"},
+			{Amount: "2800", Narration: "UPI-SWIGGY-SWIGGY@YBL-PARTY ORDER", Date: "2024-06-10", Type: models.BankTxnTypeDebit, Mode: "UPI", CurrentBalance: "500"},
+		},
+	}}}
+	data, err := json.Marshal(e.Annotate(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		SchemaDescription string `json:"schemaDescription"`
+		BankTransactions  []struct {
+			Txns [][]json.RawMessage `json:"txns"`
+		} `json:"bankTransactions"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	txns := out.BankTransactions[0].Txns
+	if len(txns) != 2 || len(txns[0]) != 7 {
+		t.Fatalf("txns = %s", data)
+	}
+	var first, second Result
+	json.Unmarshal(txns[0][6], &first)
+	json.Unmarshal(txns[1][6], &second)
+	if first.Category != "FOOD" || first.Rule != "food" || first.VPA != "SWIGGY@YBL" || second.Category != Uncategorised {
+		t.Errorf("results = %+v, %+v", first, second)
+	}
+	if !strings.HasPrefix(out.SchemaDescription, "Txns are arrays. Each txn has a seventh element") {
+		t.Errorf("schemaDescription = %q", out.SchemaDescription)
+	}
+}
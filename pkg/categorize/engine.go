@@ -0,0 +1,133 @@
+package categorize
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Uncategorised is the category of transactions no rule matches
+const Uncategorised = "UNCATEGORISED"
+
+// Result is the category of a transaction with the parts parsed from its narration
+type Result struct {
+	Category string `json:"category"`
+	// Rule is the name of the rule that set the category
+	Rule string `json:"rule,omitempty"`
+	banking.Narration
+}
+
+// Status describes the rules loaded in an engine
+type Status struct {
+	Path     string    `json:"path"`
+	Rules    int       `json:"rules"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// Engine categorises bank transactions with the rules of a YAML file. The rules
+// can be reloaded while the engine is in use.
+type Engine struct {
+	path string
+
+	mu       sync.RWMutex
+	rules    []compiledRule
+	loadedAt time.Time
+}
+
+// NewEngine returns an engine for the rules file at path. No rules are loaded
+// until Reload is called.
+func NewEngine(path string) *Engine {
+	return &Engine{path: path}
+}
+
+// Reload reads the rules file again. When the file can't be read or has an
+// invalid rule the error is returned and the rules already loaded stay in use.
+func (e *Engine) Reload() (Status, error) {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return e.Status(), fmt.Errorf("error reading rules file: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return e.Status(), err
+	}
+	e.mu.Lock()
+	e.rules, e.loadedAt = rules, time.Now().UTC()
+	e.mu.Unlock()
+	return e.Status(), nil
+}
+
+// Status returns the path, number and load time of the rules in use
+func (e *Engine) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return Status{Path: e.path, Rules: len(e.rules), LoadedAt: e.loadedAt}
+}
+
+// Categorize returns the category of a transaction and the parts of its narration
+func (e *Engine) Categorize(t models.BankTxn) Result {
+	parsed := banking.ParseNarration(t.Narration)
+	// opening and closing balance rows have no direction and only match rules without one
+	var direction string
+	switch t.Sign() {
+	case 1:
+		direction = DirectionCredit
+	case -1:
+		direction = DirectionDebit
+	}
+	amount := t.AmountValue()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if r.matches(t.Narration, parsed, direction, amount) {
+			return Result{Category: r.Category, Rule: r.Name, Narration: parsed}
+		}
+	}
+	return Result{Category: Uncategorised, Narration: parsed}
+}
+
+// annotationSchema is appended to the schema description of annotated bank transactions
+const annotationSchema = " Each txn has a seventh element: an object with the category and the name of the rule that set it, and the channel, direction, counterparty, vpa, ifsc, bank, account, reference and remark parsed from the narration."
+
+// Annotated is a fetch_bank_transactions payload with a category appended to every transaction
+type Annotated struct {
+	SchemaDescription string          `json:"schemaDescription,omitempty"`
+	BankTransactions  []AnnotatedBank `json:"bankTransactions"`
+}
+
+type AnnotatedBank struct {
+	Bank string         `json:"bank"`
+	Txns []AnnotatedTxn `json:"txns"`
+}
+
+// AnnotatedTxn is serialised as the positional bank transaction followed by its Result
+type AnnotatedTxn struct {
+	models.BankTxn
+	Result Result
+}
+
+func (t AnnotatedTxn) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{t.Amount, t.Narration, t.Date, t.Type, t.Mode, t.CurrentBalance, t.Result})
+}
+
+// Annotate categorises every transaction of a fetch_bank_transactions payload
+func (e *Engine) Annotate(resp *models.BankTransactionsResponse) *Annotated {
+	a := &Annotated{BankTransactions: []AnnotatedBank{}}
+	if resp.SchemaDescription != "" {
+		a.SchemaDescription = resp.SchemaDescription + annotationSchema
+	}
+	for _, b := range resp.BankTransactions {
+		bank := AnnotatedBank{Bank: b.Bank, Txns: make([]AnnotatedTxn, 0, len(b.Txns))}
+		for _, t := range b.Txns {
+			bank.Txns = append(bank.Txns, AnnotatedTxn{BankTxn: t, Result: e.Categorize(t)})
+		}
+		a.BankTransactions = append(a.BankTransactions, bank)
+	}
+	return a
+}
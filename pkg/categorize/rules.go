@@ -0,0 +1,125 @@
+package categorize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+)
+
+// Directions a rule can match
+const (
+	DirectionCredit = "credit"
+	DirectionDebit  = "debit"
+)
+
+var channels = map[string]bool{
+	banking.ChannelUPI: true, banking.ChannelIMPS: true, banking.ChannelNEFT: true, banking.ChannelRTGS: true,
+	banking.ChannelACH: true, banking.ChannelBillPay: true, banking.ChannelATM: true, banking.ChannelCheque: true,
+	banking.ChannelOther: true,
+}
+
+// Rule assigns a category to the transactions matching all of its conditions
+type Rule struct {
+	Name         string   `yaml:"name" json:"name"`
+	Category     string   `yaml:"category" json:"category"`
+	Narration    string   `yaml:"narration,omitempty" json:"narration,omitempty"`
+	Counterparty string   `yaml:"counterparty,omitempty" json:"counterparty,omitempty"`
+	VPA          string   `yaml:"vpa,omitempty" json:"vpa,omitempty"`
+	Channel      string   `yaml:"channel,omitempty" json:"channel,omitempty"`
+	Direction    string   `yaml:"direction,omitempty" json:"direction,omitempty"`
+	MinAmount    *float64 `yaml:"min_amount,omitempty" json:"minAmount,omitempty"`
+	MaxAmount    *float64 `yaml:"max_amount,omitempty" json:"maxAmount,omitempty"`
+}
+
+// ruleFile is the layout of a rules file
+type ruleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// compiledRule is a validated rule with its regular expressions compiled
+type compiledRule struct {
+	Rule
+	narration, counterparty, vpa *regexp.Regexp
+}
+
+// ParseRules parses and validates a YAML rules file
+func ParseRules(data []byte) ([]compiledRule, error) {
+	var file ruleFile
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("error decoding rules: %w", err)
+	}
+	rules := make([]compiledRule, 0, len(file.Rules))
+	for i, r := range file.Rules {
+		c, err := compile(r)
+		if err != nil {
+			name := r.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		rules = append(rules, c)
+	}
+	return rules, nil
+}
+
+func compile(r Rule) (compiledRule, error) {
+	c := compiledRule{Rule: r}
+	c.Category = strings.ToUpper(strings.TrimSpace(r.Category))
+	c.Channel = strings.ToUpper(strings.TrimSpace(r.Channel))
+	c.Direction = strings.ToLower(strings.TrimSpace(r.Direction))
+	if c.Category == "" {
+		return c, fmt.Errorf("category is required")
+	}
+	if r.Narration == "" && r.Counterparty == "" && r.VPA == "" && r.Channel == "" && r.Direction == "" && r.MinAmount == nil && r.MaxAmount == nil {
+		return c, fmt.Errorf("at least one condition is required")
+	}
+	if c.Channel != "" && !channels[c.Channel] {
+		return c, fmt.Errorf("unknown channel %q", r.Channel)
+	}
+	if c.Direction != "" && c.Direction != DirectionCredit && c.Direction != DirectionDebit {
+		return c, fmt.Errorf("direction must be %s or %s", DirectionCredit, DirectionDebit)
+	}
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return c, fmt.Errorf("min_amount is above max_amount")
+	}
+	var err error
+	for _, p := range []struct {
+		field   string
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{"narration", r.Narration, &c.narration},
+		{"counterparty", r.Counterparty, &c.counterparty},
+		{"vpa", r.VPA, &c.vpa},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		if *p.re, err = regexp.Compile("(?i)" + p.pattern); err != nil {
+			return c, fmt.Errorf("invalid %s pattern: %w", p.field, err)
+		}
+	}
+	return c, nil
+}
+
+// matches reports whether the transaction meets all conditions of the rule
+func (r compiledRule) matches(narration string, parsed banking.Narration, direction string, amount float64) bool {
+	switch {
+	case r.Direction != "" && r.Direction != direction,
+		r.Channel != "" && r.Channel != parsed.Channel,
+		r.MinAmount != nil && amount < *r.MinAmount,
+		r.MaxAmount != nil && amount > *r.MaxAmount,
+		r.narration != nil && !r.narration.MatchString(narration),
+		r.counterparty != nil && !r.counterparty.MatchString(parsed.Counterparty),
+		r.vpa != nil && !r.vpa.MatchString(parsed.VPA):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetRulesFile returns the path of the YAML file with the bank transaction categorisation rules
+func GetRulesFile() string {
+	if path := os.Getenv("FI_MCP_RULES_FILE"); path != "" {
+		return path
+	}
+	return "rules/categories.yaml"
+}
+
+// GetAdminToken returns the bearer token required by the /admin endpoints. When it
+// is empty the endpoints only accept requests from localhost.
+func GetAdminToken() string {
+	return os.Getenv("FI_MCP_ADMIN_TOKEN")
+}
+
+// Data validation modes for the check of test_data_dir against the schemas at startup
+const (
+	DataValidationWarn = "warn"
+	DataValidationFail = "fail"
+	DataValidationOff  = "off"
+)
+
+// GetSchemaDir returns the directory holding the JSON Schema of every data tool
+func GetSchemaDir() string {
+	if dir := os.Getenv("FI_MCP_SCHEMA_DIR"); dir != "" {
+		return dir
+	}
+	return "schemas"
+}
+
+// GetDataValidationMode returns what the server does with invalid files at startup:
+// log them and serve anyway (warn, the default), refuse to start (fail) or skip the check (off)
+func GetDataValidationMode() string {
+	switch mode := os.Getenv("FI_MCP_DATA_VALIDATION"); mode {
+	case DataValidationFail, DataValidationOff:
+		return mode
+	}
+	return DataValidationWarn
+}
+
+// ValidateResponses reports whether data tool responses are validated against their
+// schema before they are sent, set with FI_MCP_VALIDATE_RESPONSES=true
+func ValidateResponses() bool {
+	v, _ := strconv.ParseBool(os.Getenv("FI_MCP_VALIDATE_RESPONSES"))
+	return v
+}
+
+// GetReloadInterval returns how often test_data_dir is polled for changed files,
+// set with FI_MCP_RELOAD_INTERVAL as a duration such as 5s. Zero turns polling off.
+func GetReloadInterval() time.Duration {
+	if v := os.Getenv("FI_MCP_RELOAD_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return 2 * time.Second
+}
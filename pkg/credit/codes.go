@@ -0,0 +1,232 @@
+package credit
+
+import "strings"
+
+// accountTypes are the bureau account type codes, also used as enquiry finance purposes
+var accountTypes = map[string]string{
+	"00": "Other",
+	"01": "Auto Loan",
+	"02": "Housing Loan",
+	"03": "Property Loan",
+	"04": "Loan Against Shares/Securities",
+	"05": "Personal Loan",
+	"06": "Consumer Loan",
+	"07": "Gold Loan",
+	"08": "Education Loan",
+	"09": "Loan to Professional",
+	"10": "Credit Card",
+	"11": "Leasing",
+	"12": "Overdraft",
+	"13": "Two-Wheeler Loan",
+	"14": "Non-Funded Credit Facility",
+	"15": "Loan Against Bank Deposits",
+	"16": "Fleet Card",
+	"17": "Commercial Vehicle Loan",
+	"18": "Telco - Wireless",
+	"19": "Telco - Broadband",
+	"20": "Telco - Landline",
+	"31": "Secured Credit Card",
+	"32": "Used Car Loan",
+	"33": "Construction Equipment Loan",
+	"34": "Tractor Loan",
+	"35": "Corporate Credit Card",
+	"36": "Kisan Credit Card",
+	"37": "Loan on Credit Card",
+	"38": "Prime Minister Jan Dhan Yojana - Overdraft",
+	"39": "Mudra Loan",
+	"40": "Microfinance - Business Loan",
+	"41": "Microfinance - Personal Loan",
+	"42": "Microfinance - Housing Loan",
+	"43": "Microfinance - Other",
+	"44": "Pradhan Mantri Awas Yojana - CLSS",
+	"45": "P2P Personal Loan",
+	"46": "P2P Auto Loan",
+	"47": "P2P Education Loan",
+	"50": "Business Loan - Secured",
+	"51": "Business Loan - General",
+	"52": "Business Loan - Priority Sector - Small Business",
+	"53": "Business Loan - Priority Sector - Agriculture",
+	"54": "Business Loan - Priority Sector - Others",
+	"55": "Business Non-Funded Credit Facility - General",
+	"56": "Business Non-Funded Credit Facility - Priority Sector - Small Business",
+	"57": "Business Non-Funded Credit Facility - Priority Sector - Agriculture",
+	"58": "Business Non-Funded Credit Facility - Priority Sector - Others",
+	"59": "Business Loan Against Bank Deposits",
+	"61": "Business Loan - Unsecured",
+}
+
+// securedAccountTypes are the account types backed by collateral
+var securedAccountTypes = map[string]bool{
+	"01": true, "02": true, "03": true, "04": true, "07": true, "11": true, "13": true,
+	"15": true, "17": true, "31": true, "32": true, "33": true, "34": true, "42": true,
+	"44": true, "46": true, "50": true, "59": true,
+}
+
+// AccountState is the broad state of an account derived from its status code
+type AccountState string
+
+const (
+	StateActive        AccountState = "ACTIVE"
+	StateClosed        AccountState = "CLOSED"
+	StateDelinquent    AccountState = "DELINQUENT"
+	StateRestructured  AccountState = "RESTRUCTURED"
+	StateSettled       AccountState = "SETTLED"
+	StateWrittenOff    AccountState = "WRITTEN_OFF"
+	StateSuitFiled     AccountState = "SUIT_FILED"
+	StateWilfulDefault AccountState = "WILFUL_DEFAULT"
+	StateUnknown       AccountState = "UNKNOWN"
+)
+
+type accountStatus struct {
+	label string
+	state AccountState
+}
+
+// accountStatuses are the bureau account status codes
+var accountStatuses = map[string]accountStatus{
+	"00": {"No Suit Filed", StateActive},
+	"11": {"Active", StateActive},
+	"12": {"Closed", StateClosed},
+	"13": {"Closed", StateClosed},
+	"14": {"Closed", StateClosed},
+	"15": {"Closed", StateClosed},
+	"16": {"Closed", StateClosed},
+	"17": {"Closed", StateClosed},
+	"21": {"Active - Overdue", StateDelinquent},
+	"22": {"Active - Overdue", StateDelinquent},
+	"23": {"Active - Overdue", StateDelinquent},
+	"24": {"Active - Overdue", StateDelinquent},
+	"25": {"Active - Overdue", StateDelinquent},
+	"30": {"Restructured", StateRestructured},
+	"31": {"Restructured Loan (Govt. Mandated)", StateRestructured},
+	"32": {"Settled", StateSettled},
+	"33": {"Post Write-Off Settled", StateSettled},
+	"34": {"Account Sold", StateClosed},
+	"35": {"Written Off and Account Sold", StateWrittenOff},
+	"36": {"Account Purchased", StateActive},
+	"37": {"Account Purchased and Written Off", StateWrittenOff},
+	"38": {"Account Purchased and Settled", StateSettled},
+	"39": {"Account Purchased and Restructured", StateRestructured},
+	"40": {"Status Cleared", StateActive},
+	"41": {"Restructured Loan", StateRestructured},
+	"42": {"Restructured Loan (Govt. Mandated)", StateRestructured},
+	"43": {"Written Off", StateWrittenOff},
+	"44": {"Settled", StateSettled},
+	"45": {"Post Write-Off Settled", StateSettled},
+	"46": {"Account Sold", StateClosed},
+	"47": {"Written Off and Account Sold", StateWrittenOff},
+	"48": {"Account Purchased", StateActive},
+	"49": {"Account Purchased and Written Off", StateWrittenOff},
+	"50": {"Account Purchased and Settled", StateSettled},
+	"51": {"Account Purchased and Restructured", StateRestructured},
+	"52": {"Status Cleared", StateActive},
+	"53": {"Suit Filed", StateSuitFiled},
+	"54": {"Suit Filed and Written Off", StateSuitFiled},
+	"55": {"Suit Filed and Settled", StateSuitFiled},
+	"56": {"Suit Filed and Post Write-Off Settled", StateSuitFiled},
+	"57": {"Suit Filed and Account Sold", StateSuitFiled},
+	"58": {"Suit Filed and Written Off and Account Sold", StateSuitFiled},
+	"59": {"Suit Filed and Account Purchased", StateSuitFiled},
+	"60": {"Suit Filed and Account Purchased and Written Off", StateSuitFiled},
+	"61": {"Suit Filed and Account Purchased and Settled", StateSuitFiled},
+	"62": {"Suit Filed and Account Purchased and Restructured", StateSuitFiled},
+	"63": {"Suit Filed and Status Cleared", StateSuitFiled},
+	"64": {"Wilful Default and Restructured Loan", StateWilfulDefault},
+	"65": {"Wilful Default and Restructured Loan (Govt. Mandated)", StateWilfulDefault},
+	"66": {"Wilful Default and Settled", StateWilfulDefault},
+	"67": {"Wilful Default and Post Write-Off Settled", StateWilfulDefault},
+	"68": {"Wilful Default and Account Sold", StateWilfulDefault},
+	"69": {"Wilful Default and Written Off and Account Sold", StateWilfulDefault},
+	"70": {"Wilful Default and Account Purchased", StateWilfulDefault},
+	"71": {"Active - 30+ Days Past Due", StateDelinquent},
+	"72": {"Wilful Default and Account Purchased and Written Off", StateWilfulDefault},
+	"73": {"Wilful Default and Account Purchased and Settled", StateWilfulDefault},
+	"74": {"Wilful Default and Account Purchased and Restructured", StateWilfulDefault},
+	"75": {"Wilful Default and Status Cleared", StateWilfulDefault},
+	"76": {"Suit Filed (Wilful Default) and Restructured", StateWilfulDefault},
+	"77": {"Suit Filed (Wilful Default) and Restructured Loan (Govt. Mandated)", StateWilfulDefault},
+	"78": {"Active - 60+ Days Past Due", StateDelinquent},
+	"79": {"Suit Filed (Wilful Default) and Settled", StateWilfulDefault},
+	"80": {"Active - 90+ Days Past Due", StateDelinquent},
+	"81": {"Suit Filed (Wilful Default) and Post Write-Off Settled", StateWilfulDefault},
+	"82": {"Active - 120+ Days Past Due", StateDelinquent},
+	"83": {"Active - 150+ Days Past Due", StateDelinquent},
+	"84": {"Active - 180+ Days Past Due", StateDelinquent},
+	"85": {"Suit Filed (Wilful Default) and Account Sold", StateWilfulDefault},
+	"86": {"Suit Filed (Wilful Default) and Written Off and Account Sold", StateWilfulDefault},
+	"87": {"Suit Filed (Wilful Default) and Account Purchased", StateWilfulDefault},
+	"88": {"Suit Filed (Wilful Default) and Account Purchased and Written Off", StateWilfulDefault},
+	"89": {"Wilful Default", StateWilfulDefault},
+	"90": {"Suit Filed (Wilful Default) and Account Purchased and Restructured", StateWilfulDefault},
+	"91": {"Suit Filed (Wilful Default) and Status Cleared", StateWilfulDefault},
+	"93": {"Suit Filed (Wilful Default)", StateWilfulDefault},
+	"94": {"Suit Filed (Wilful Default) and Account Purchased and Settled", StateWilfulDefault},
+	"97": {"Suit Filed (Wilful Default) and Written Off", StateWilfulDefault},
+}
+
+// portfolioTypes are the bureau portfolio type codes
+var portfolioTypes = map[string]string{
+	"I": "Installment",
+	"R": "Revolving",
+	"M": "Mortgage",
+	"O": "Open",
+	"C": "Line of Credit",
+}
+
+// accountHolderTypes are the bureau ownership codes
+var accountHolderTypes = map[string]string{
+	"1": "Individual",
+	"2": "Joint",
+	"3": "Authorised User",
+	"7": "Guarantor",
+}
+
+// occupations are the bureau occupation codes
+var occupations = map[string]string{
+	"S": "Salaried",
+	"N": "Self Employed",
+	"P": "Self Employed Professional",
+	"O": "Others",
+}
+
+// DPD bands of the payment history profile and payment rating
+type dpdBand struct {
+	label  string
+	minDPD int
+}
+
+var dpdBands = map[byte]dpdBand{
+	'0': {"0-29 days past due", 0},
+	'1': {"30-59 days past due", 30},
+	'2': {"60-89 days past due", 60},
+	'3': {"90-119 days past due", 90},
+	'4': {"120-149 days past due", 120},
+	'5': {"150-179 days past due", 150},
+	'6': {"180+ days past due", 180},
+	'S': {"Standard", 0},
+	'M': {"Special Mention Account", 1},
+	'B': {"Sub-Standard", 90},
+	'D': {"Doubtful", 90},
+	'L': {"Loss", 90},
+}
+
+func accountTypeLabel(code string) string {
+	return lookup(accountTypes, code)
+}
+
+func lookup(labels map[string]string, code string) string {
+	if label, ok := labels[strings.TrimSpace(code)]; ok {
+		return label
+	}
+	if code == "" {
+		return ""
+	}
+	return "Unknown (" + code + ")"
+}
+
+func statusOf(code string) accountStatus {
+	if s, ok := accountStatuses[strings.TrimSpace(code)]; ok {
+		return s
+	}
+	return accountStatus{"Unknown (" + code + ")", StateUnknown}
+}
@@ -0,0 +1,315 @@
+package credit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// PaymentHistoryMonths is the number of months in a payment history profile
+const PaymentHistoryMonths = 36
+
+// Code is a bureau code with its label
+type Code struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// MonthStatus is the repayment status of an account in one month
+type MonthStatus struct {
+	Month    string `json:"month"`
+	Code     string `json:"code"`
+	Status   string `json:"status"`
+	Reported bool   `json:"reported"`
+	// MinDPD is the lower bound of the days past due band, nil when the month was not reported
+	MinDPD *int `json:"minDpd,omitempty"`
+}
+
+// PaymentHistorySummary aggregates the payment history of an account
+type PaymentHistorySummary struct {
+	MonthsReported   int  `json:"monthsReported"`
+	MonthsDelinquent int  `json:"monthsDelinquent"`
+	MaxDPD           int  `json:"maxDpd"`
+	LatestDPD        *int `json:"latestDpd,omitempty"`
+}
+
+// Account is a decoded tradeline
+type Account struct {
+	Index                 int                   `json:"index"`
+	Lender                string                `json:"lender"`
+	AccountType           Code                  `json:"accountType"`
+	PortfolioType         Code                  `json:"portfolioType"`
+	Secured               bool                  `json:"secured"`
+	Status                Code                  `json:"status"`
+	State                 AccountState          `json:"state"`
+	PaymentRating         Code                  `json:"paymentRating"`
+	OpenDate              string                `json:"openDate,omitempty"`
+	DateReported          string                `json:"dateReported,omitempty"`
+	DateClosed            string                `json:"dateClosed,omitempty"`
+	DateOfAddition        string                `json:"dateOfAddition,omitempty"`
+	SanctionedAmount      float64               `json:"sanctionedAmount"`
+	CreditLimit           *float64              `json:"creditLimit,omitempty"`
+	CurrentBalance        float64               `json:"currentBalance"`
+	AmountPastDue         float64               `json:"amountPastDue"`
+	UtilisationPercent    *float64              `json:"utilisationPercent,omitempty"`
+	InterestRatePercent   *float64              `json:"interestRatePercent,omitempty"`
+	RepaymentTenureMonths *int                  `json:"repaymentTenureMonths,omitempty"`
+	HolderType            string                `json:"holderType,omitempty"`
+	Occupation            string                `json:"occupation,omitempty"`
+	Currency              string                `json:"currency,omitempty"`
+	PaymentHistory        []MonthStatus         `json:"paymentHistory"`
+	PaymentHistorySummary PaymentHistorySummary `json:"paymentHistorySummary"`
+}
+
+// Revolving reports the utilisation of the revolving accounts with a credit limit
+type Revolving struct {
+	TotalBalance       float64  `json:"totalBalance"`
+	TotalLimit         float64  `json:"totalLimit"`
+	UtilisationPercent *float64 `json:"utilisationPercent,omitempty"`
+}
+
+// Enquiry is a decoded credit or non-credit enquiry
+type Enquiry struct {
+	Lender         string `json:"lender"`
+	Date           string `json:"date,omitempty"`
+	FinancePurpose Code   `json:"financePurpose"`
+	EnquiryReason  string `json:"enquiryReason,omitempty"`
+}
+
+// Enquiries summarises the enquiries in the report
+type Enquiries struct {
+	Last7Days        int       `json:"last7Days"`
+	Last30Days       int       `json:"last30Days"`
+	Last90Days       int       `json:"last90Days"`
+	Last180Days      int       `json:"last180Days"`
+	Credit           []Enquiry `json:"credit"`
+	NonCreditLast180 int       `json:"nonCreditLast180Days"`
+	NonCredit        []Enquiry `json:"nonCredit"`
+}
+
+// Summary is the decoded account summary of the report
+type Summary struct {
+	TotalAccounts               int     `json:"totalAccounts"`
+	ActiveAccounts              int     `json:"activeAccounts"`
+	ClosedAccounts              int     `json:"closedAccounts"`
+	DefaultAccounts             int     `json:"defaultAccounts"`
+	SuitFiledCurrentBalance     float64 `json:"suitFiledCurrentBalance"`
+	OutstandingSecured          float64 `json:"outstandingSecured"`
+	OutstandingUnsecured        float64 `json:"outstandingUnsecured"`
+	OutstandingTotal            float64 `json:"outstandingTotal"`
+	OutstandingSecuredPercent   float64 `json:"outstandingSecuredPercent"`
+	OutstandingUnsecuredPercent float64 `json:"outstandingUnsecuredPercent"`
+	TotalAmountPastDue          float64 `json:"totalAmountPastDue"`
+}
+
+// Report is a credit report with its bureau codes decoded
+type Report struct {
+	Vendor          string    `json:"vendor,omitempty"`
+	ReportDate      string    `json:"reportDate,omitempty"`
+	ReportTime      string    `json:"reportTime,omitempty"`
+	DateOfBirth     string    `json:"dateOfBirth,omitempty"`
+	BureauScore     *int      `json:"bureauScore,omitempty"`
+	ScoreConfidence string    `json:"scoreConfidence,omitempty"`
+	Summary         Summary   `json:"summary"`
+	Accounts        []Account `json:"accounts"`
+	Revolving       Revolving `json:"revolving"`
+	Enquiries       Enquiries `json:"enquiries"`
+}
+
+var scoreConfidence = map[string]string{"H": "High", "M": "Medium", "L": "Low"}
+
+// Decode maps the bureau codes of a credit report to labels, normalises its dates
+// to YYYY-MM-DD and expands the payment history of each account month by month
+func Decode(r models.CreditReport) Report {
+	d := r.CreditReportData
+	out := Report{
+		Vendor:     r.Vendor,
+		ReportDate: isoDate(d.CreditProfileHeader.ReportDate),
+		ReportTime: isoTime(d.CreditProfileHeader.ReportTime),
+		Accounts:   []Account{},
+		Enquiries:  Enquiries{Credit: []Enquiry{}, NonCredit: []Enquiry{}},
+	}
+	if d.CurrentApplication != nil {
+		out.DateOfBirth = isoDate(d.CurrentApplication.CurrentApplicationDetails.CurrentApplicantDetails.DateOfBirthApplicant)
+	}
+	if d.Score != nil {
+		if score, err := strconv.Atoi(strings.TrimSpace(d.Score.BureauScore)); err == nil {
+			out.BureauScore = &score
+		}
+		out.ScoreConfidence = lookup(scoreConfidence, d.Score.BureauScoreConfidenceLevel)
+	}
+
+	s := d.CreditAccount.CreditAccountSummary
+	out.Summary = Summary{
+		TotalAccounts:               atoi(s.Account.CreditAccountTotal),
+		ActiveAccounts:              atoi(s.Account.CreditAccountActive),
+		ClosedAccounts:              atoi(s.Account.CreditAccountClosed),
+		DefaultAccounts:             atoi(s.Account.CreditAccountDefault),
+		SuitFiledCurrentBalance:     models.ParseBureauAmount(s.Account.CADSuitFiledCurrentBalance),
+		OutstandingSecured:          models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceSecured),
+		OutstandingUnsecured:        models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceUnSecured),
+		OutstandingTotal:            models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceAll),
+		OutstandingSecuredPercent:   models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceSecuredPercentage),
+		OutstandingUnsecuredPercent: models.ParseBureauAmount(s.TotalOutstandingBalance.OutstandingBalanceUnSecuredPercentage),
+	}
+
+	for i, a := range d.CreditAccount.CreditAccountDetails {
+		acc := DecodeAccount(a)
+		acc.Index = i
+		out.Summary.TotalAmountPastDue += acc.AmountPastDue
+		if acc.PortfolioType.Code == "R" && acc.CreditLimit != nil && acc.State != StateClosed {
+			out.Revolving.TotalBalance += acc.CurrentBalance
+			out.Revolving.TotalLimit += *acc.CreditLimit
+		}
+		out.Accounts = append(out.Accounts, acc)
+	}
+	if out.Revolving.TotalLimit > 0 {
+		u := models.Round(out.Revolving.TotalBalance/out.Revolving.TotalLimit*100, 2)
+		out.Revolving.UtilisationPercent = &u
+	}
+
+	if c := d.TotalCapsSummary; c != nil {
+		out.Enquiries.Last7Days = atoi(c.TotalCapsLast7Days)
+		out.Enquiries.Last30Days = atoi(c.TotalCapsLast30Days)
+		out.Enquiries.Last90Days = atoi(c.TotalCapsLast90Days)
+		out.Enquiries.Last180Days = atoi(c.TotalCapsLast180Days)
+	}
+	if d.Caps != nil {
+		for _, e := range d.Caps.CapsApplicationDetailsArray {
+			out.Enquiries.Credit = append(out.Enquiries.Credit, decodeEnquiry(e))
+		}
+	}
+	if d.NonCreditCaps != nil {
+		out.Enquiries.NonCreditLast180 = atoi(d.NonCreditCaps.NonCreditCapsSummary.NonCreditCapsLast180Days)
+		for _, e := range d.NonCreditCaps.CapsApplicationDetailsArray {
+			out.Enquiries.NonCredit = append(out.Enquiries.NonCredit, decodeEnquiry(e))
+		}
+	}
+	return out
+}
+
+// DecodeAccount decodes a single tradeline
+func DecodeAccount(a models.CreditAccountDetail) Account {
+	status := statusOf(a.AccountStatus)
+	acc := Account{
+		Lender:           a.SubscriberName,
+		AccountType:      Code{a.AccountType, accountTypeLabel(a.AccountType)},
+		PortfolioType:    Code{a.PortfolioType, lookup(portfolioTypes, a.PortfolioType)},
+		Secured:          securedAccountTypes[a.AccountType],
+		Status:           Code{a.AccountStatus, status.label},
+		State:            status.state,
+		PaymentRating:    Code{a.PaymentRating, ratingLabel(a.PaymentRating)},
+		OpenDate:         isoDate(a.OpenDate),
+		DateReported:     isoDate(a.DateReported),
+		DateClosed:       isoDate(a.DateClosed),
+		DateOfAddition:   isoDate(a.DateOfAddition),
+		SanctionedAmount: models.ParseBureauAmount(a.HighestCreditOrOriginalLoanAmount),
+		CurrentBalance:   models.ParseBureauAmount(a.CurrentBalance),
+		AmountPastDue:    models.ParseBureauAmount(a.AmountPastDue),
+		HolderType:       lookup(accountHolderTypes, a.AccountHolderTypeCode),
+		Occupation:       lookup(occupations, a.OccupationCode),
+		Currency:         a.CurrencyCode,
+	}
+	if limit := models.ParseBureauAmount(a.CreditLimitAmount); limit > 0 {
+		acc.CreditLimit = &limit
+		if a.PortfolioType == "R" {
+			u := models.Round(acc.CurrentBalance/limit*100, 2)
+			acc.UtilisationPercent = &u
+		}
+	}
+	if rate, err := strconv.ParseFloat(strings.TrimSpace(a.RateOfInterest), 64); err == nil {
+		acc.InterestRatePercent = &rate
+	}
+	if tenure, err := strconv.Atoi(strings.TrimSpace(a.RepaymentTenure)); err == nil && tenure > 0 {
+		acc.RepaymentTenureMonths = &tenure
+	}
+	acc.PaymentHistory, acc.PaymentHistorySummary = expandPaymentHistory(a.PaymentHistoryProfile, a.DateReported)
+	return acc
+}
+
+// expandPaymentHistory turns the payment history profile into a month by month
+// timeline. The first character is the month of dateReported and each following
+// character is one month earlier. '?' marks a month that was not reported.
+func expandPaymentHistory(profile, dateReported string) ([]MonthStatus, PaymentHistorySummary) {
+	history := []MonthStatus{}
+	var summary PaymentHistorySummary
+	reported, err := models.ParseBureauDate(dateReported)
+	hasDate := err == nil
+	for i := 0; i < len(profile) && i < PaymentHistoryMonths; i++ {
+		c := profile[i]
+		m := MonthStatus{Code: string(c)}
+		if hasDate {
+			m.Month = time.Date(reported.Year(), reported.Month()-time.Month(i), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		} else {
+			m.Month = "month -" + strconv.Itoa(i)
+		}
+		if band, ok := dpdBands[c]; ok {
+			dpd := band.minDPD
+			m.Status, m.Reported, m.MinDPD = band.label, true, &dpd
+			summary.MonthsReported++
+			if dpd > 0 {
+				summary.MonthsDelinquent++
+			}
+			if dpd > summary.MaxDPD {
+				summary.MaxDPD = dpd
+			}
+			if summary.LatestDPD == nil {
+				latest := dpd
+				summary.LatestDPD = &latest
+			}
+		} else if c == '?' || c == ' ' || c == 'X' {
+			m.Status = "Not reported"
+		} else {
+			m.Status = "Unknown"
+		}
+		history = append(history, m)
+	}
+	return history, summary
+}
+
+func ratingLabel(code string) string {
+	code = strings.TrimSpace(code)
+	if len(code) == 1 {
+		if band, ok := dpdBands[code[0]]; ok {
+			return band.label
+		}
+	}
+	if code == "" {
+		return ""
+	}
+	return "Unknown (" + code + ")"
+}
+
+func decodeEnquiry(e models.CapsApplicationDetail) Enquiry {
+	return Enquiry{
+		Lender:         e.SubscriberName,
+		Date:           isoDate(e.DateOfRequest),
+		FinancePurpose: Code{e.FinancePurpose, accountTypeLabel(e.FinancePurpose)},
+		EnquiryReason:  e.EnquiryReason,
+	}
+}
+
+// isoDate converts a YYYYMMDD bureau date to YYYY-MM-DD, leaving unparsable values as they are
+func isoDate(s string) string {
+	t, err := models.ParseBureauDate(s)
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return t.Format(models.DateLayout)
+}
+
+// isoTime converts a HHMMSS bureau time to HH:MM:SS
+func isoTime(s string) string {
+	t, err := time.Parse("150405", strings.TrimSpace(s))
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return t.Format("15:04:05")
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(strings.TrimSpace(s))
+	return v
+}
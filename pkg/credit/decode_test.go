@@ -0,0 +1,55 @@
+package credit
+
+import (
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func TestDecodeAccount(t *testing.T) {
+	acc := DecodeAccount(models.CreditAccountDetail{
+		SubscriberName:        "HDFC Bank",
+		PortfolioType:         "R",
+		AccountType:           "10",
+		OpenDate:              "20060110",
+		CreditLimitAmount:     "200000",
+		AccountStatus:         "71",
+		PaymentRating:         "1",
+		PaymentHistoryProfile: "102" + "??????????????????????????????????",
+		CurrentBalance:        "50000",
+		AmountPastDue:         "2500",
+		DateReported:          "20240115",
+		RateOfInterest:        "36.0",
+		RepaymentTenure:       "0",
+	})
+	if acc.AccountType.Label != "Credit Card" || acc.PortfolioType.Label != "Revolving" || acc.State != StateDelinquent {
+		t.Errorf("decoded codes = %+v %+v %s", acc.AccountType, acc.PortfolioType, acc.State)
+	}
+	if acc.OpenDate != "2006-01-10" || acc.DateReported != "2024-01-15" {
+		t.Errorf("dates = %s %s, want 2006-01-10 2024-01-15", acc.OpenDate, acc.DateReported)
+	}
+	if acc.UtilisationPercent == nil || *acc.UtilisationPercent != 25 {
+		t.Errorf("utilisation = %v, want 25", acc.UtilisationPercent)
+	}
+	if acc.RepaymentTenureMonths != nil {
+		t.Errorf("tenure = %v, want nil for revolving account", *acc.RepaymentTenureMonths)
+	}
+	if len(acc.PaymentHistory) != PaymentHistoryMonths {
+		t.Fatalf("got %d months of history, want %d", len(acc.PaymentHistory), PaymentHistoryMonths)
+	}
+	want := []struct {
+		month    string
+		reported bool
+		minDPD   int
+	}{{"2024-01", true, 30}, {"2023-12", true, 0}, {"2023-11", true, 60}, {"2023-10", false, 0}}
+	for i, w := range want {
+		m := acc.PaymentHistory[i]
+		if m.Month != w.month || m.Reported != w.reported || (m.MinDPD != nil && *m.MinDPD != w.minDPD) {
+			t.Errorf("month %d = %+v, want %+v", i, m, w)
+		}
+	}
+	s := acc.PaymentHistorySummary
+	if s.MonthsReported != 3 || s.MonthsDelinquent != 2 || s.MaxDPD != 60 || s.LatestDPD == nil || *s.LatestDPD != 30 {
+		t.Errorf("summary = %+v", s)
+	}
+}
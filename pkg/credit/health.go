@@ -0,0 +1,287 @@
+package credit
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// HealthDisclaimer is attached to every credit health result
+const HealthDisclaimer = "This credit health index is a simple heuristic computed from the fields of the credit report. It is not a bureau score, it cannot be converted into one and real score changes may differ in size and direction."
+
+// Factor names of the credit health model
+const (
+	FactorPaymentHistory  = "PAYMENT_HISTORY"
+	FactorUtilisation     = "CREDIT_UTILISATION"
+	FactorCreditAge       = "CREDIT_AGE"
+	FactorCreditMix       = "CREDIT_MIX"
+	FactorRecentEnquiries = "RECENT_ENQUIRIES"
+)
+
+// factorWeights are the maximum points of each factor, adding up to 100
+var factorWeights = []struct {
+	name   string
+	weight float64
+}{
+	{FactorPaymentHistory, 35},
+	{FactorUtilisation, 30},
+	{FactorCreditAge, 15},
+	{FactorCreditMix, 10},
+	{FactorRecentEnquiries, 10},
+}
+
+// derogatoryStates are account states that weigh on the payment history for as long as they are reported
+var derogatoryStates = map[AccountState]bool{
+	StateWrittenOff:    true,
+	StateSettled:       true,
+	StateSuitFiled:     true,
+	StateWilfulDefault: true,
+}
+
+// Factor is the contribution of one factor to the credit health index
+type Factor struct {
+	Name      string             `json:"name"`
+	Weight    float64            `json:"weight"`
+	Points    float64            `json:"points"`
+	Inputs    map[string]float64 `json:"inputs"`
+	Rationale string             `json:"rationale"`
+}
+
+// seriousDelinquencyCap is the highest index of a profile with a serious delinquency
+const seriousDelinquencyCap = 49
+
+// Health is the credit health index of a report
+type Health struct {
+	Index   float64  `json:"index"`
+	Band    string   `json:"band"`
+	Factors []Factor `json:"factors"`
+	// CappedBy explains why the index was capped below the sum of the factor points
+	CappedBy string `json:"cappedBy,omitempty"`
+}
+
+// Profile is the part of a decoded report the health model looks at. It is
+// what simulated actions modify.
+type Profile struct {
+	AsOf      time.Time
+	Accounts  []Account
+	Enquiries Enquiries
+}
+
+// NewProfile builds the profile of a decoded report, as of its report date
+func NewProfile(r Report) Profile {
+	asOf, err := time.Parse(models.DateLayout, r.ReportDate)
+	if err != nil {
+		asOf = time.Now().UTC()
+	}
+	accounts := make([]Account, len(r.Accounts))
+	copy(accounts, r.Accounts)
+	return Profile{AsOf: asOf, Accounts: accounts, Enquiries: r.Enquiries}
+}
+
+func (p Profile) open() []Account {
+	var open []Account
+	for _, a := range p.Accounts {
+		if a.State != StateClosed {
+			open = append(open, a)
+		}
+	}
+	return open
+}
+
+// Evaluate scores the profile
+func Evaluate(p Profile) Health {
+	scores := map[string]func(Profile) (float64, map[string]float64, string){
+		FactorPaymentHistory:  paymentHistory,
+		FactorUtilisation:     utilisation,
+		FactorCreditAge:       creditAge,
+		FactorCreditMix:       creditMix,
+		FactorRecentEnquiries: recentEnquiries,
+	}
+	var h Health
+	for _, f := range factorWeights {
+		score, inputs, rationale := scores[f.name](p)
+		points := models.Round(f.weight*clamp(score), 2)
+		h.Index += points
+		h.Factors = append(h.Factors, Factor{Name: f.name, Weight: f.weight, Points: points, Inputs: inputs, Rationale: rationale})
+	}
+	h.Index = models.Round(h.Index, 2)
+	if reason := seriousDelinquency(p); reason != "" && h.Index > seriousDelinquencyCap {
+		h.Index, h.CappedBy = seriousDelinquencyCap, reason
+	}
+	switch {
+	case h.Index >= 80:
+		h.Band = "EXCELLENT"
+	case h.Index >= 65:
+		h.Band = "GOOD"
+	case h.Index >= 50:
+		h.Band = "FAIR"
+	default:
+		h.Band = "POOR"
+	}
+	return h
+}
+
+func paymentHistory(p Profile) (float64, map[string]float64, string) {
+	var overdue, delinquentMonths, maxDPD, derogatory float64
+	for _, a := range p.Accounts {
+		if a.AmountPastDue > 0 && a.State != StateClosed {
+			overdue++
+		}
+		delinquentMonths += float64(a.PaymentHistorySummary.MonthsDelinquent)
+		maxDPD = math.Max(maxDPD, float64(a.PaymentHistorySummary.MaxDPD))
+		if derogatoryStates[a.State] {
+			derogatory++
+		}
+	}
+	score := 1 - math.Min(0.45, 0.15*overdue) - math.Min(0.3, 0.03*delinquentMonths) - math.Min(0.5, 0.25*derogatory)
+	if maxDPD >= 90 {
+		score -= 0.2
+	}
+	inputs := map[string]float64{
+		"accountsWithAmountPastDue": overdue,
+		"delinquentMonths":          delinquentMonths,
+		"maxDpd":                    maxDPD,
+		"derogatoryAccounts":        derogatory,
+	}
+	rationale := fmt.Sprintf("%d open accounts with an amount past due (-15%% each), %d delinquent months in the last 36 (-3%% each), %d written off, settled, suit filed or wilful default accounts (-25%% each), and -20%% when any account reached 90 days past due",
+		int(overdue), int(delinquentMonths), int(derogatory))
+	return score, inputs, rationale
+}
+
+// seriousDelinquency returns why the profile has a serious delinquency, if it has one
+func seriousDelinquency(p Profile) string {
+	for i, a := range p.Accounts {
+		if derogatoryStates[a.State] {
+			return fmt.Sprintf("account %d (%s) is reported as %s", i, a.Lender, a.Status.Label)
+		}
+		if a.State == StateClosed {
+			continue
+		}
+		if latest := a.PaymentHistorySummary.LatestDPD; latest != nil && *latest >= 90 {
+			return fmt.Sprintf("account %d (%s) is %d or more days past due", i, a.Lender, *latest)
+		}
+		if a.State == StateDelinquent && a.AmountPastDue > 0 {
+			return fmt.Sprintf("account %d (%s) is reported as %s with %.2f past due", i, a.Lender, a.Status.Label, a.AmountPastDue)
+		}
+	}
+	return ""
+}
+
+// utilisationCurve maps revolving utilisation in percent to a score
+var utilisationCurve = [][2]float64{{0, 1}, {10, 1}, {30, 0.85}, {50, 0.6}, {75, 0.3}, {100, 0.1}, {150, 0}}
+
+func utilisation(p Profile) (float64, map[string]float64, string) {
+	var balance, limit, maxCard float64
+	for _, a := range p.open() {
+		if a.PortfolioType.Code != "R" || a.CreditLimit == nil {
+			continue
+		}
+		balance += a.CurrentBalance
+		limit += *a.CreditLimit
+		if a.UtilisationPercent != nil {
+			maxCard = math.Max(maxCard, *a.UtilisationPercent)
+		}
+	}
+	if limit == 0 {
+		return 0.7, map[string]float64{"revolvingLimit": 0}, "no open revolving account with a credit limit, scored as neutral"
+	}
+	u := balance / limit * 100
+	inputs := map[string]float64{
+		"revolvingBalance":              models.Round(balance, 2),
+		"revolvingLimit":                models.Round(limit, 2),
+		"utilisationPercent":            models.Round(u, 2),
+		"highestCardUtilisationPercent": models.Round(maxCard, 2),
+	}
+	return interpolate(utilisationCurve, u), inputs, fmt.Sprintf("revolving utilisation of %.1f%%; up to 10%% scores full points, falling to 85%% of the points at 30%%, 60%% at 50%%, 30%% at 75%% and 10%% at 100%%", u)
+}
+
+// ageCurve maps the average age of open accounts in years to a score
+var ageCurve = [][2]float64{{0, 0.2}, {1, 0.4}, {3, 0.7}, {5, 0.85}, {8, 1}}
+
+func creditAge(p Profile) (float64, map[string]float64, string) {
+	accounts := p.open()
+	if len(accounts) == 0 {
+		accounts = p.Accounts
+	}
+	var total, oldest float64
+	var counted int
+	for _, a := range accounts {
+		opened, err := time.Parse(models.DateLayout, a.OpenDate)
+		if err != nil {
+			continue
+		}
+		years := p.AsOf.Sub(opened).Hours() / 24 / 365.25
+		if years < 0 {
+			years = 0
+		}
+		total += years
+		oldest = math.Max(oldest, years)
+		counted++
+	}
+	if counted == 0 {
+		return 0, map[string]float64{"accounts": 0}, "no account with an open date, no credit history to score"
+	}
+	avg := total / float64(counted)
+	inputs := map[string]float64{"averageAgeYears": models.Round(avg, 2), "oldestAccountYears": models.Round(oldest, 2), "accounts": float64(counted)}
+	return interpolate(ageCurve, avg), inputs, fmt.Sprintf("average age of open accounts of %.1f years; 8 years or more scores full points", avg)
+}
+
+func creditMix(p Profile) (float64, map[string]float64, string) {
+	var secured, unsecured, securedBalance, unsecuredBalance float64
+	for _, a := range p.open() {
+		if a.Secured {
+			secured++
+			securedBalance += a.CurrentBalance
+		} else {
+			unsecured++
+			unsecuredBalance += a.CurrentBalance
+		}
+	}
+	inputs := map[string]float64{"securedAccounts": secured, "unsecuredAccounts": unsecured}
+	var score float64
+	var rationale string
+	switch {
+	case secured > 0 && unsecured > 0:
+		score, rationale = 1, "both secured and unsecured credit is open"
+	case secured > 0 || unsecured > 0:
+		score, rationale = 0.6, "only one kind of credit, secured or unsecured, is open"
+	default:
+		return 0.3, inputs, "no open credit account"
+	}
+	if total := securedBalance + unsecuredBalance; total > 0 {
+		share := unsecuredBalance / total * 100
+		inputs["unsecuredOutstandingPercent"] = models.Round(share, 2)
+		if share > 70 {
+			score -= 0.2
+			rationale += ", and more than 70% of the outstanding balance is unsecured (-20%)"
+		}
+	}
+	return score, inputs, rationale
+}
+
+func recentEnquiries(p Profile) (float64, map[string]float64, string) {
+	last30 := float64(p.Enquiries.Last30Days)
+	older := math.Max(0, float64(p.Enquiries.Last180Days)-last30)
+	inputs := map[string]float64{"last30Days": last30, "last180Days": float64(p.Enquiries.Last180Days)}
+	return 1 - 0.15*last30 - 0.05*older, inputs, fmt.Sprintf("%d credit enquiries in the last 30 days (-15%% each) and %d more in the last 180 days (-5%% each)", int(last30), int(older))
+}
+
+// interpolate evaluates a piecewise linear curve of (x, y) points sorted by x
+func interpolate(curve [][2]float64, x float64) float64 {
+	if x <= curve[0][0] {
+		return curve[0][1]
+	}
+	for i := 1; i < len(curve); i++ {
+		if x <= curve[i][0] {
+			a, b := curve[i-1], curve[i]
+			return a[1] + (b[1]-a[1])*(x-a[0])/(b[0]-a[0])
+		}
+	}
+	return curve[len(curve)-1][1]
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
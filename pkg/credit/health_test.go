@@ -0,0 +1,70 @@
+package credit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	limit, util := 100000.0, 80.0
+	dpd := 0
+	p := Profile{
+		AsOf: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Accounts: []Account{
+			{
+				Lender: "Card", PortfolioType: Code{"R", "Revolving"}, State: StateActive, OpenDate: "2016-05-01",
+				CreditLimit: &limit, CurrentBalance: 80000, UtilisationPercent: &util,
+				PaymentHistorySummary: PaymentHistorySummary{MonthsReported: 36, LatestDPD: &dpd},
+			},
+			{
+				Lender: "Home", PortfolioType: Code{"I", "Installment"}, Secured: true, State: StateActive, OpenDate: "2020-05-01",
+				CurrentBalance: 2000000,
+			},
+		},
+	}
+	index := func(i int) *int { return &i }
+	s, err := Simulate(p, []Action{
+		{Type: ActionPayDown, AccountIndex: index(0), Amount: 70000},
+		{Type: ActionNewEnquiry, Count: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := map[string]float64{}
+	for _, c := range s.FactorChanges {
+		changes[c.Name] = c.Change
+	}
+	if changes[FactorUtilisation] <= 0 {
+		t.Errorf("utilisation change = %v, want an improvement", changes[FactorUtilisation])
+	}
+	if changes[FactorRecentEnquiries] != -3 {
+		t.Errorf("enquiries change = %v, want -3", changes[FactorRecentEnquiries])
+	}
+	if changes[FactorPaymentHistory] != 0 || changes[FactorCreditAge] != 0 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	if p.Accounts[0].CurrentBalance != 80000 {
+		t.Error("Simulate modified the input profile")
+	}
+
+	if _, err := Simulate(p, []Action{{Type: ActionCloseAccount, AccountIndex: index(5)}}); err == nil {
+		t.Error("expected an error for an unknown account index")
+	}
+}
+
+func TestSimulateCloseDelinquentAccount(t *testing.T) {
+	p := Profile{
+		AsOf:     time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Accounts: []Account{{Lender: "Card", State: StateDelinquent, CurrentBalance: 5000, AmountPastDue: 2000}},
+	}
+	zero := 0
+	s, err := Simulate(p, []Action{{Type: ActionCloseAccount, AccountIndex: &zero}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both the balance and the past due amount are assumed to be settled
+	if len(s.Warnings) != 2 || !strings.Contains(s.Warnings[1], "2000.00 past due") {
+		t.Errorf("warnings = %q", s.Warnings)
+	}
+}
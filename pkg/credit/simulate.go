@@ -0,0 +1,133 @@
+package credit
+
+import (
+	"fmt"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Action types understood by Simulate
+const (
+	ActionCloseAccount = "close_account"
+	ActionPayDown      = "pay_down"
+	ActionNewEnquiry   = "new_enquiry"
+)
+
+// Action is a hypothetical change to the credit profile. AccountIndex refers to
+// the index of the account in the decoded report.
+type Action struct {
+	Type         string  `json:"type"`
+	AccountIndex *int    `json:"account_index,omitempty"`
+	Amount       float64 `json:"amount,omitempty"`
+	Count        int     `json:"count,omitempty"`
+}
+
+// FactorChange is the change of one factor between the current and simulated profile
+type FactorChange struct {
+	Name         string  `json:"name"`
+	PointsBefore float64 `json:"pointsBefore"`
+	PointsAfter  float64 `json:"pointsAfter"`
+	Change       float64 `json:"change"`
+	Rationale    string  `json:"rationale"`
+}
+
+// Simulation compares the credit health before and after the actions
+type Simulation struct {
+	Disclaimer    string         `json:"disclaimer"`
+	Current       Health         `json:"current"`
+	Simulated     Health         `json:"simulated"`
+	IndexChange   float64        `json:"indexChange"`
+	FactorChanges []FactorChange `json:"factorChanges"`
+	Applied       []string       `json:"applied"`
+	Warnings      []string       `json:"warnings"`
+}
+
+// Simulate applies the actions to the profile and reports the factor level impact
+func Simulate(p Profile, actions []Action) (*Simulation, error) {
+	after := Profile{AsOf: p.AsOf, Accounts: make([]Account, len(p.Accounts)), Enquiries: p.Enquiries}
+	copy(after.Accounts, p.Accounts)
+	s := &Simulation{Disclaimer: HealthDisclaimer, Applied: []string{}, Warnings: []string{}, FactorChanges: []FactorChange{}}
+	for i, action := range actions {
+		if err := apply(&after, action, s); err != nil {
+			return nil, fmt.Errorf("action %d: %w", i, err)
+		}
+	}
+	s.Current, s.Simulated = Evaluate(p), Evaluate(after)
+	s.IndexChange = models.Round(s.Simulated.Index-s.Current.Index, 2)
+	for i, before := range s.Current.Factors {
+		f := s.Simulated.Factors[i]
+		s.FactorChanges = append(s.FactorChanges, FactorChange{
+			Name:         f.Name,
+			PointsBefore: before.Points,
+			PointsAfter:  f.Points,
+			Change:       models.Round(f.Points-before.Points, 2),
+			Rationale:    f.Rationale,
+		})
+	}
+	return s, nil
+}
+
+func apply(p *Profile, action Action, s *Simulation) error {
+	switch action.Type {
+	case ActionNewEnquiry:
+		count := action.Count
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 {
+			return fmt.Errorf("count must be positive")
+		}
+		p.Enquiries.Last7Days += count
+		p.Enquiries.Last30Days += count
+		p.Enquiries.Last90Days += count
+		p.Enquiries.Last180Days += count
+		s.Applied = append(s.Applied, fmt.Sprintf("%d new credit enquiries", count))
+		return nil
+	case ActionCloseAccount, ActionPayDown:
+	default:
+		return fmt.Errorf("unknown action type %q, expected one of %s, %s or %s", action.Type, ActionCloseAccount, ActionPayDown, ActionNewEnquiry)
+	}
+
+	if action.AccountIndex == nil {
+		return fmt.Errorf("%s needs an account_index", action.Type)
+	}
+	i := *action.AccountIndex
+	if i < 0 || i >= len(p.Accounts) {
+		return fmt.Errorf("account_index %d out of range, the report has %d accounts", i, len(p.Accounts))
+	}
+	a := &p.Accounts[i]
+	name := fmt.Sprintf("%s %s (account %d)", a.Lender, a.AccountType.Label, i)
+	if a.State == StateClosed {
+		return fmt.Errorf("%s is already closed", name)
+	}
+
+	if action.Type == ActionCloseAccount {
+		if a.CurrentBalance > 0 {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("%s has a balance of %.2f which is assumed to be repaid before closing", name, a.CurrentBalance))
+		}
+		if a.AmountPastDue > 0 {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("%s has %.2f past due which is assumed to be settled before closing, its payment history still counts", name, a.AmountPastDue))
+		}
+		a.CurrentBalance, a.AmountPastDue = 0, 0
+		a.State, a.Status = StateClosed, Code{Code: a.Status.Code, Label: "Closed (simulated)"}
+		s.Applied = append(s.Applied, "closed "+name)
+		return nil
+	}
+
+	if action.Amount <= 0 {
+		return fmt.Errorf("pay_down needs a positive amount")
+	}
+	amount := action.Amount
+	if amount > a.CurrentBalance {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("payment of %.2f on %s is more than its balance of %.2f, only the balance is paid", amount, name, a.CurrentBalance))
+		amount = a.CurrentBalance
+	}
+	a.CurrentBalance -= amount
+	a.AmountPastDue = max(0, a.AmountPastDue-amount)
+	if a.CreditLimit != nil && a.UtilisationPercent != nil {
+		u := models.Round(a.CurrentBalance / *a.CreditLimit * 100, 2)
+		a.UtilisationPercent = &u
+	}
+	s.Applied = append(s.Applied, fmt.Sprintf("paid %.2f on %s", amount, name))
+	return nil
+}
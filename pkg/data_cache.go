@@ -0,0 +1,346 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DataStatus describes the data held by a DataCache and the outcome of its reloads
+type DataStatus struct {
+	Dir          string    `json:"dir"`
+	PhoneNumbers int       `json:"phoneNumbers"`
+	Files        int       `json:"files"`
+	LoadedAt     time.Time `json:"loadedAt"`
+	PollInterval string    `json:"pollInterval"`
+	// Reloads and ReloadFailures count the reloads that succeeded and those that
+	// kept the previous data of at least one persona
+	Reloads          int64      `json:"reloads"`
+	ReloadFailures   int64      `json:"reloadFailures"`
+	LastReloadAt     time.Time  `json:"lastReloadAt"`
+	LastReloadMillis float64    `json:"lastReloadMillis"`
+	LastError        string     `json:"lastError,omitempty"`
+	LastErrorAt      *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// DataCache holds the JSON files of a test data dir in memory. A reload reads
+// the personas whose files changed, checks that every file is valid JSON and
+// swaps the new data in at once, so a tool call sees either the old or the new
+// files of a persona and never a half-written one.
+type DataCache struct {
+	dir  string
+	data atomic.Pointer[dataSet]
+
+	// reloadMu serialises reloads, readers never wait for it
+	reloadMu     sync.Mutex
+	onReload     func()
+	pollInterval time.Duration
+
+	statsMu sync.Mutex
+	stats   DataStatus
+}
+
+// dataSet is an immutable view of the test data dir
+type dataSet struct {
+	loadedAt     time.Time
+	phoneNumbers []string
+	personas     map[string]*personaFiles
+	// seen holds the fingerprint of every persona directory found by the reload,
+	// including those whose files could not be loaded, so that polling doesn't
+	// retry a broken persona until it changes again
+	seen map[string]string
+}
+
+// personaFiles holds the files of one phone number keyed by their path relative
+// to its directory without the .json extension, e.g. fetch_net_worth or
+// snapshots/2025-06-01/fetch_net_worth
+type personaFiles struct {
+	fingerprint   string
+	files         map[string][]byte
+	snapshotDates []string
+}
+
+// NewDataCache returns an empty cache of dir. Nothing is loaded until Reload is called.
+func NewDataCache(dir string) *DataCache {
+	c := &DataCache{dir: dir}
+	c.data.Store(&dataSet{personas: map[string]*personaFiles{}, seen: map[string]string{}})
+	return c
+}
+
+var (
+	dataCache     *DataCache
+	dataCacheOnce sync.Once
+)
+
+// Data returns the cache of TestDataDir, loading it on first use
+func Data() *DataCache {
+	dataCacheOnce.Do(func() {
+		dataCache = NewDataCache(TestDataDir)
+		if _, err := dataCache.Reload(); err != nil {
+			log.Println("error loading test data", err)
+		}
+	})
+	return dataCache
+}
+
+// SetOnReload sets a function called after every reload that changed the data
+func (c *DataCache) SetOnReload(f func()) {
+	c.reloadMu.Lock()
+	c.onReload = f
+	c.reloadMu.Unlock()
+}
+
+// Reload reads the directories of the phone numbers whose files changed since
+// the last reload. When a file can't be read or isn't valid JSON the persona
+// keeps its previous files, the other personas are still updated and the
+// error is returned.
+func (c *DataCache) Reload() (DataStatus, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	start := time.Now()
+	next, changed, err := c.load(c.data.Load())
+	if next != nil {
+		c.data.Store(next)
+	}
+
+	c.statsMu.Lock()
+	c.stats.LastReloadAt = start.UTC()
+	c.stats.LastReloadMillis = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		c.stats.ReloadFailures++
+		c.stats.LastError = err.Error()
+		at := start.UTC()
+		c.stats.LastErrorAt = &at
+	} else {
+		c.stats.Reloads++
+	}
+	c.statsMu.Unlock()
+
+	if changed && c.onReload != nil {
+		c.onReload()
+	}
+	return c.Status(), err
+}
+
+// load builds the data set following prev, reusing the personas whose fingerprint didn't change
+func (c *DataCache) load(prev *dataSet) (*dataSet, bool, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading test data dir: %w", err)
+	}
+	next := &dataSet{loadedAt: time.Now().UTC(), personas: map[string]*personaFiles{}, seen: map[string]string{}}
+	changed := false
+	var errs []error
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		phone := e.Name()
+		old := prev.personas[phone]
+		fingerprint, err := fingerprintDir(filepath.Join(c.dir, phone))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", phone, err))
+			// polling waits for the directory to change before trying again
+			next.seen[phone] = failedFingerprint(err)
+			next.keep(phone, old)
+			continue
+		}
+		next.seen[phone] = fingerprint
+		if old != nil && old.fingerprint == fingerprint {
+			next.keep(phone, old)
+			continue
+		}
+		persona, err := readPersona(filepath.Join(c.dir, phone), fingerprint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", phone, err))
+			next.keep(phone, old)
+			continue
+		}
+		next.keep(phone, persona)
+		changed = true
+	}
+	if len(next.personas) != len(prev.personas) {
+		changed = true
+	}
+	sort.Strings(next.phoneNumbers)
+	if !changed {
+		next.loadedAt = prev.loadedAt
+	}
+	return next, changed, errors.Join(errs...)
+}
+
+// keep adds the files of a persona, when there are any
+func (d *dataSet) keep(phone string, p *personaFiles) {
+	if p == nil {
+		return
+	}
+	d.personas[phone] = p
+	d.phoneNumbers = append(d.phoneNumbers, phone)
+}
+
+// fingerprintDir sums up the path, size and modification time of every file under dir
+func fingerprintDir(dir string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// failedFingerprint stands for a directory that could not be fingerprinted, so
+// that it only counts as changed once its error does
+func failedFingerprint(err error) string {
+	return "error: " + err.Error()
+}
+
+// readPersona reads the tool responses and snapshots of a phone number directory
+func readPersona(dir, fingerprint string) (*personaFiles, error) {
+	p := &personaFiles{fingerprint: fingerprint, files: map[string][]byte{}}
+	dates := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("%s is not valid JSON", rel)
+		}
+		key := strings.TrimSuffix(filepath.ToSlash(rel), ".json")
+		p.files[key] = data
+		if parts := strings.Split(key, "/"); len(parts) == 3 && parts[0] == SnapshotsDir {
+			if _, err := time.Parse("2006-01-02", parts[1]); err == nil {
+				dates[parts[1]] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for date := range dates {
+		p.snapshotDates = append(p.snapshotDates, date)
+	}
+	sort.Strings(p.snapshotDates)
+	return p, nil
+}
+
+// Changed reports whether a phone number directory was added, removed or had a
+// file changed since the last reload
+func (c *DataCache) Changed() bool {
+	seen := c.data.Load().seen
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return false
+	}
+	dirs := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dirs++
+		fingerprint, err := fingerprintDir(filepath.Join(c.dir, e.Name()))
+		if err != nil {
+			fingerprint = failedFingerprint(err)
+		}
+		if fingerprint != seen[e.Name()] {
+			return true
+		}
+	}
+	return dirs != len(seen)
+}
+
+// Watch polls the test data dir every interval and reloads it when it changed.
+// It returns at once and keeps polling for the life of the process.
+func (c *DataCache) Watch(interval time.Duration) {
+	c.statsMu.Lock()
+	c.pollInterval = interval
+	c.statsMu.Unlock()
+	go func() {
+		for range time.Tick(interval) {
+			if !c.Changed() {
+				continue
+			}
+			status, err := c.Reload()
+			if err != nil {
+				log.Println("error reloading test data, keeping the previous files of the personas that failed", err)
+				continue
+			}
+			log.Println("reloaded test data of", status.PhoneNumbers, "phone numbers from", status.Dir)
+		}
+	}()
+}
+
+// Status returns the size of the data in use and the reload counters
+func (c *DataCache) Status() DataStatus {
+	d := c.data.Load()
+	c.statsMu.Lock()
+	status := c.stats
+	if c.pollInterval > 0 {
+		status.PollInterval = c.pollInterval.String()
+	}
+	c.statsMu.Unlock()
+	status.Dir = c.dir
+	status.PhoneNumbers = len(d.phoneNumbers)
+	status.LoadedAt = d.loadedAt
+	for _, p := range d.personas {
+		status.Files += len(p.files)
+	}
+	return status
+}
+
+// PhoneNumbers returns the phone numbers with data in sorted order
+func (c *DataCache) PhoneNumbers() []string {
+	return c.data.Load().phoneNumbers
+}
+
+// ToolData returns the raw JSON response of a data tool for a phone number. The
+// returned slice is shared and must not be modified.
+func (c *DataCache) ToolData(phoneNumber, toolName string) ([]byte, error) {
+	return c.file(phoneNumber, toolName)
+}
+
+// SnapshotDates returns the dates of the snapshots of a phone number in ascending order
+func (c *DataCache) SnapshotDates(phoneNumber string) []string {
+	if p := c.data.Load().personas[phoneNumber]; p != nil {
+		return p.snapshotDates
+	}
+	return nil
+}
+
+// SnapshotToolData returns the raw JSON response of a data tool in the snapshot of the given date
+func (c *DataCache) SnapshotToolData(phoneNumber, date, toolName string) ([]byte, error) {
+	return c.file(phoneNumber, SnapshotsDir+"/"+date+"/"+toolName)
+}
+
+func (c *DataCache) file(phoneNumber, key string) ([]byte, error) {
+	if p := c.data.Load().personas[phoneNumber]; p != nil {
+		if data, ok := p.files[key]; ok {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", filepath.Join(c.dir, phoneNumber, key+".json"), fs.ErrNotExist)
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDataCacheReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "9000000001", "fetch_net_worth.json"), `{"a": 1}`)
+	writeFile(t, filepath.Join(dir, "9000000001", "snapshots", "2025-06-01", "fetch_net_worth.json"), `{"a": 0}`)
+	c := NewDataCache(dir)
+	if _, err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := c.ToolData("9000000001", "fetch_net_worth"); err != nil || string(data) != `{"a": 1}` {
+		t.Errorf("ToolData = %s, %v", data, err)
+	}
+	if got := c.SnapshotDates("9000000001"); !reflect.DeepEqual(got, []string{"2025-06-01"}) {
+		t.Errorf("SnapshotDates = %v", got)
+	}
+	if _, err := c.ToolData("9000000001", "fetch_epf_details"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: %v", err)
+	}
+	if c.Changed() {
+		t.Error("Changed right after a reload")
+	}
+
+	// a new persona is picked up
+	writeFile(t, filepath.Join(dir, "9000000002", "fetch_net_worth.json"), `{"b": 2}`)
+	if !c.Changed() {
+		t.Error("new persona not detected")
+	}
+	if _, err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.PhoneNumbers(); !reflect.DeepEqual(got, []string{"9000000001", "9000000002"}) {
+		t.Errorf("PhoneNumbers = %v", got)
+	}
+
+	// a half-written file keeps the previous data of its persona only
+	writeFile(t, filepath.Join(dir, "9000000001", "fetch_net_worth.json"), `{"a": `)
+	writeFile(t, filepath.Join(dir, "9000000002", "fetch_net_worth.json"), `{"b": 3}`)
+	if _, err := c.Reload(); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if data, _ := c.ToolData("9000000001", "fetch_net_worth"); string(data) != `{"a": 1}` {
+		t.Errorf("broken persona = %s, want the previous data", data)
+	}
+	if data, _ := c.ToolData("9000000002", "fetch_net_worth"); string(data) != `{"b": 3}` {
+		t.Errorf("other persona = %s, want the new data", data)
+	}
+	if c.Changed() {
+		t.Error("a persona that failed to load is retried before it changes again")
+	}
+
+	// a removed persona goes away
+	writeFile(t, filepath.Join(dir, "9000000001", "fetch_net_worth.json"), `{"a": 4}`)
+	if err := os.RemoveAll(filepath.Join(dir, "9000000002")); err != nil {
+		t.Fatal(err)
+	}
+	status, err := c.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PhoneNumbers != 1 || status.Reloads != 3 || status.ReloadFailures != 1 || status.LastError == "" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestDataCacheUnreadableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads every directory")
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "9000000001", "snapshots", "2025-06-01", "fetch_net_worth.json"), `{"a": 0}`)
+	snapshots := filepath.Join(dir, "9000000001", "snapshots")
+	if err := os.Chmod(snapshots, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(snapshots, 0o755)
+	c := NewDataCache(dir)
+	if _, err := c.Reload(); err == nil {
+		t.Error("expected an error for an unreadable directory")
+	}
+	if c.Changed() {
+		t.Error("a directory that can't be fingerprinted is retried before it changes")
+	}
+	if err := os.Chmod(snapshots, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Changed() {
+		t.Error("a directory readable again is not detected")
+	}
+}
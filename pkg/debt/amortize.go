@@ -0,0 +1,42 @@
+package debt
+
+import "math"
+
+// monthlyRate converts an annual interest rate in percent to a monthly rate
+func monthlyRate(annualPercent float64) float64 {
+	return annualPercent / 12 / 100
+}
+
+// payment returns the EMI that repays balance over months at the monthly rate
+func payment(balance, rate float64, months int) float64 {
+	if months <= 0 {
+		return balance
+	}
+	if rate == 0 {
+		return balance / float64(months)
+	}
+	f := math.Pow(1+rate, float64(months))
+	return balance * rate * f / (f - 1)
+}
+
+// remaining returns the number of EMIs left and the interest paid on them. ok is
+// false when the EMI doesn't cover the monthly interest and the loan never closes.
+func remaining(balance, rate, emi float64) (months int, interest float64, ok bool) {
+	if balance <= 0 {
+		return 0, 0, true
+	}
+	if emi <= balance*rate || emi <= 0 {
+		return 0, 0, false
+	}
+	for balance > 0.005 {
+		i := balance * rate
+		interest += i
+		balance += i - emi
+		months++
+		if balance < 0 {
+			interest += balance
+			balance = 0
+		}
+	}
+	return months, interest, true
+}
@@ -0,0 +1,200 @@
+package debt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Sources of the EMI of a debt
+const (
+	EMISourceBank        = "bank_transactions"
+	EMISourceTenure      = "estimated_from_tenure"
+	EMISourceMinimumDue  = "assumed_minimum_due"
+	EMISourceUnavailable = "unavailable"
+)
+
+const (
+	// cardMinimumDuePercent is the share of the balance assumed as the minimum due of a card
+	cardMinimumDuePercent = 5
+	// cardMinimumDueFloor is the smallest minimum due assumed for a card
+	cardMinimumDueFloor = 500
+	// defaultCardRate is the annual rate assumed for a card reported without one
+	defaultCardRate = 42
+)
+
+// Debt is an open loan or card of the credit report with its repayment
+type Debt struct {
+	// AccountIndex is the index of the account in fetch_credit_report_decoded
+	AccountIndex      int                   `json:"accountIndex"`
+	Lender            string                `json:"lender"`
+	AccountType       string                `json:"accountType"`
+	AccountTypeLabel  string                `json:"accountTypeLabel"`
+	Revolving         bool                  `json:"revolving"`
+	Balance           float64               `json:"balance"`
+	AmountPastDue     float64               `json:"amountPastDue"`
+	RatePercent       float64               `json:"ratePercent"`
+	RateAssumed       bool                  `json:"rateAssumed,omitempty"`
+	EMI               float64               `json:"emi"`
+	EMISource         string                `json:"emiSource"`
+	EMITransactions   []banking.Transaction `json:"emiTransactions,omitempty"`
+	RemainingMonths   *int                  `json:"remainingMonths,omitempty"`
+	RemainingInterest *float64              `json:"remainingInterest,omitempty"`
+	PayoffMonth       string                `json:"payoffMonth,omitempty"`
+	// TenureRemainingMonths is the original tenure less the months since opening
+	TenureRemainingMonths *int   `json:"tenureRemainingMonths,omitempty"`
+	Note                  string `json:"note,omitempty"`
+}
+
+// Totals aggregates the debts against income
+type Totals struct {
+	Outstanding         float64  `json:"outstanding"`
+	MonthlyEMI          float64  `json:"monthlyEmi"`
+	MonthlyCardMinimums float64  `json:"monthlyCardMinimums"`
+	MonthlyIncome       float64  `json:"monthlyIncome"`
+	DTIPercent          *float64 `json:"dtiPercent,omitempty"`
+	DTIWithCardsPercent *float64 `json:"dtiWithCardsPercent,omitempty"`
+}
+
+// Report is the debt analysis of a user
+type Report struct {
+	AsOf                  string         `json:"asOf"`
+	Debts                 []Debt         `json:"debts"`
+	Totals                Totals         `json:"totals"`
+	Income                banking.Income `json:"income"`
+	UnmatchedInstallments []Installment  `json:"unmatchedInstallments"`
+	Strategies            []Plan         `json:"strategies"`
+	Assumptions           []string       `json:"assumptions"`
+}
+
+// Options of Analyze
+type Options struct {
+	// ExtraMonthlyPayment is paid on top of the EMIs and minimum dues in the payoff plans
+	ExtraMonthlyPayment float64
+}
+
+// Analyze matches the open loans and cards of the credit report with their EMI
+// debits in the bank transactions, estimates their remaining tenure and interest,
+// computes the debt-to-income ratio and builds avalanche and snowball payoff plans
+func Analyze(report credit.Report, txns *models.BankTransactionsResponse, opts Options) *Report {
+	asOf, err := time.Parse(models.DateLayout, report.ReportDate)
+	if err != nil {
+		asOf = time.Now().UTC()
+	}
+	r := &Report{
+		AsOf:                  asOf.Format(models.DateLayout),
+		Debts:                 []Debt{},
+		UnmatchedInstallments: []Installment{},
+		Income:                banking.DetectSalary(txns),
+		Assumptions: []string{
+			"balances are as reported in the credit report on " + asOf.Format(models.DateLayout),
+			fmt.Sprintf("card minimum due is %d%% of the balance, at least %d", cardMinimumDuePercent, cardMinimumDueFloor),
+			fmt.Sprintf("cards reported without a rate are charged %d%% a year", defaultCardRate),
+			"interest is charged monthly on the reducing balance",
+		},
+	}
+
+	for i, a := range report.Accounts {
+		if a.State == credit.StateClosed || a.CurrentBalance <= 0 {
+			continue
+		}
+		d := Debt{
+			AccountIndex:     i,
+			Lender:           a.Lender,
+			AccountType:      a.AccountType.Code,
+			AccountTypeLabel: a.AccountType.Label,
+			Revolving:        a.PortfolioType.Code == "R",
+			Balance:          a.CurrentBalance,
+			AmountPastDue:    a.AmountPastDue,
+			EMISource:        EMISourceUnavailable,
+		}
+		if a.InterestRatePercent != nil {
+			d.RatePercent = *a.InterestRatePercent
+		} else if d.Revolving {
+			d.RatePercent, d.RateAssumed = defaultCardRate, true
+		}
+		if !d.Revolving && a.RepaymentTenureMonths != nil {
+			if opened, err := time.Parse(models.DateLayout, a.OpenDate); err == nil {
+				left := *a.RepaymentTenureMonths - models.MonthsBetween(opened, asOf)
+				if left < 1 {
+					left = 1
+				}
+				d.TenureRemainingMonths = &left
+			}
+		}
+		r.Debts = append(r.Debts, d)
+	}
+
+	var loanSeries []Installment
+	for _, s := range installments(txns) {
+		if isLoanInstallment(s.Narration) {
+			loanSeries = append(loanSeries, s)
+		}
+	}
+	matched, unmatched := matchInstallments(r.Debts, loanSeries)
+	if unmatched != nil {
+		r.UnmatchedInstallments = unmatched
+	}
+
+	for i := range r.Debts {
+		d := &r.Debts[i]
+		rate := monthlyRate(d.RatePercent)
+		switch s, ok := matched[i]; {
+		case ok:
+			d.EMI, d.EMISource, d.EMITransactions = s.Amount, EMISourceBank, s.Transactions
+		case d.Revolving:
+			d.EMI, d.EMISource = cardMinimumDue(d.Balance), EMISourceMinimumDue
+		case d.TenureRemainingMonths != nil:
+			d.EMI, d.EMISource = models.Round(payment(d.Balance, rate, *d.TenureRemainingMonths), 2), EMISourceTenure
+		default:
+			d.Note = "no EMI found in the bank transactions and no tenure in the credit report"
+			continue
+		}
+		if d.Revolving {
+			r.Totals.MonthlyCardMinimums += d.EMI
+		} else {
+			r.Totals.MonthlyEMI += d.EMI
+		}
+		months, interest, ok := remaining(d.Balance, rate, d.EMI)
+		if !ok {
+			d.Note = "the EMI does not cover the monthly interest, the balance will not reduce"
+			continue
+		}
+		interest = models.Round(interest, 2)
+		d.RemainingMonths, d.RemainingInterest = &months, &interest
+		d.PayoffMonth = asOf.AddDate(0, months, 0).Format("2006-01")
+	}
+
+	for _, d := range r.Debts {
+		r.Totals.Outstanding += d.Balance
+	}
+	r.Totals.Outstanding = models.Round(r.Totals.Outstanding, 2)
+	r.Totals.MonthlyEMI = models.Round(r.Totals.MonthlyEMI, 2)
+	r.Totals.MonthlyCardMinimums = models.Round(r.Totals.MonthlyCardMinimums, 2)
+	r.Totals.MonthlyIncome = r.Income.MonthlyAverage
+	if income := r.Income.MonthlyAverage; income > 0 {
+		dti := models.Round(r.Totals.MonthlyEMI/income*100, 2)
+		withCards := models.Round((r.Totals.MonthlyEMI+r.Totals.MonthlyCardMinimums)/income*100, 2)
+		r.Totals.DTIPercent, r.Totals.DTIWithCardsPercent = &dti, &withCards
+	}
+
+	r.Strategies = []Plan{
+		plan(StrategyAvalanche, r.Debts, opts.ExtraMonthlyPayment, asOf),
+		plan(StrategySnowball, r.Debts, opts.ExtraMonthlyPayment, asOf),
+	}
+	return r
+}
+
+func cardMinimumDue(balance float64) float64 {
+	due := balance * cardMinimumDuePercent / 100
+	if due < cardMinimumDueFloor {
+		due = cardMinimumDueFloor
+	}
+	if due > balance {
+		due = balance
+	}
+	return models.Round(due, 2)
+}
@@ -0,0 +1,44 @@
+package debt
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPaymentAndRemaining(t *testing.T) {
+	rate := monthlyRate(12)
+	emi := payment(100000, rate, 12)
+	if math.Abs(emi-8884.88) > 0.01 {
+		t.Fatalf("payment = %.2f, want 8884.88", emi)
+	}
+	months, interest, ok := remaining(100000, rate, emi)
+	if !ok || months != 12 || math.Abs(interest-6618.55) > 0.05 {
+		t.Errorf("remaining = %d, %.2f, %v, want 12, 6618.55, true", months, interest, ok)
+	}
+	if _, _, ok := remaining(100000, rate, 1000); ok {
+		t.Error("an EMI below the monthly interest should never close the loan")
+	}
+}
+
+func TestPlanOrder(t *testing.T) {
+	debts := []Debt{
+		{AccountIndex: 0, Balance: 50000, RatePercent: 36, EMI: 2500},
+		{AccountIndex: 1, Balance: 10000, RatePercent: 10, EMI: 1000},
+	}
+	asOf := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	avalanche := plan(StrategyAvalanche, debts, 5000, asOf)
+	snowball := plan(StrategySnowball, debts, 5000, asOf)
+	if avalanche.Order[0].AccountIndex != 0 {
+		t.Errorf("avalanche cleared account %d first, want the highest rate", avalanche.Order[0].AccountIndex)
+	}
+	if snowball.Order[0].AccountIndex != 1 {
+		t.Errorf("snowball cleared account %d first, want the smallest balance", snowball.Order[0].AccountIndex)
+	}
+	if avalanche.TotalInterest > snowball.TotalInterest {
+		t.Errorf("avalanche interest %.2f exceeds snowball %.2f", avalanche.TotalInterest, snowball.TotalInterest)
+	}
+	if avalanche.Months == nil || avalanche.MonthlyBudget != 8500 {
+		t.Errorf("avalanche = %+v, want a finite plan with a budget of 8500", avalanche)
+	}
+}
@@ -0,0 +1,161 @@
+package debt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+var (
+	loanPattern       = regexp.MustCompile(`(?i)(\bemi\b|loan|\bhl\d|\bal\d)`)
+	investmentPattern = regexp.MustCompile(`(?i)(\bsip\b|sip/|\bmf\b|mutual|\brd\b|recurring deposit|nps)`)
+)
+
+// loanKeywords map narration keywords to the bureau account types they indicate
+var loanKeywords = []struct {
+	pattern *regexp.Regexp
+	types   []string
+}{
+	{regexp.MustCompile(`(?i)home\s*loan|housing|\bhl\b|homefin`), []string{"02", "44"}},
+	{regexp.MustCompile(`(?i)auto\s*loan|car\s*loan|vehicle`), []string{"01", "32", "17"}},
+	{regexp.MustCompile(`(?i)two\s*wheeler|bike`), []string{"13"}},
+	{regexp.MustCompile(`(?i)consumer|durable`), []string{"06"}},
+	{regexp.MustCompile(`(?i)personal\s*loan|\bpl\b`), []string{"05", "45"}},
+	{regexp.MustCompile(`(?i)education|student`), []string{"08", "47"}},
+	{regexp.MustCompile(`(?i)gold\s*loan`), []string{"07"}},
+	{regexp.MustCompile(`(?i)property|\blap\b`), []string{"03"}},
+	{regexp.MustCompile(`(?i)business`), []string{"50", "51", "61"}},
+}
+
+// lenderAliases map lender names in bureau reports to the codes used for them in
+// narrations, e.g. the IFSC prefix
+var lenderAliases = map[string][]string{
+	"state bank of india":  {"SBI", "SBIN"},
+	"punjab national bank": {"PNB", "PUNB"},
+	"hdfc":                 {"HDFC"},
+	"icici":                {"ICIC", "ICICI"},
+	"axis":                 {"UTIB", "AXIS"},
+	"kotak":                {"KKBK", "KOTAK"},
+	"tata capital":         {"TATACAP", "TATA CAP"},
+	"bajaj":                {"BAJAJ", "BAJAJFIN"},
+	"idfc":                 {"IDFB", "IDFC"},
+	"yes bank":             {"YESB", "YES BANK"},
+	"bank of baroda":       {"BARB", "BOB"},
+	"canara":               {"CNRB", "CANARA"},
+	"union bank":           {"UBIN", "UNION"},
+}
+
+// Installment is a series of recurring installment debits with the same narration
+type Installment struct {
+	Key          string                `json:"key"`
+	Bank         string                `json:"bank"`
+	Narration    string                `json:"narration"`
+	Amount       float64               `json:"amount"`
+	Transactions []banking.Transaction `json:"transactions"`
+}
+
+// installments groups the INSTALLMENT debits by bank and normalised narration
+func installments(resp *models.BankTransactionsResponse) []Installment {
+	if resp == nil {
+		return nil
+	}
+	index := make(map[string]int)
+	var out []Installment
+	for bi, b := range resp.BankTransactions {
+		for ti, t := range b.Txns {
+			if t.Type != models.BankTxnTypeInstallment {
+				continue
+			}
+			key := b.Bank + "|" + banking.NarrationKey(t.Narration)
+			i, ok := index[key]
+			if !ok {
+				i = len(out)
+				index[key] = i
+				out = append(out, Installment{Key: key, Bank: b.Bank, Narration: t.Narration})
+			}
+			out[i].Transactions = append(out[i].Transactions, banking.NewTransaction(resp, bi, ti))
+		}
+	}
+	for i := range out {
+		txns := out[i].Transactions
+		sort.Slice(txns, func(a, b int) bool { return txns[a].Date < txns[b].Date })
+		// the latest debit is the current EMI
+		out[i].Amount = txns[len(txns)-1].Amount
+		out[i].Narration = txns[len(txns)-1].Narration
+	}
+	return out
+}
+
+// isLoanInstallment tells loan EMIs apart from SIPs and recurring deposit installments
+func isLoanInstallment(narration string) bool {
+	return loanPattern.MatchString(narration) && !investmentPattern.MatchString(narration)
+}
+
+// matchScore scores how well an installment narration matches a loan, 0 meaning no match
+func matchScore(narration string, loan Debt) int {
+	upper := strings.ToUpper(narration)
+	score := 0
+	for _, k := range loanKeywords {
+		if !k.pattern.MatchString(narration) {
+			continue
+		}
+		for _, t := range k.types {
+			if t == loan.AccountType {
+				score += 2
+			}
+		}
+	}
+	lender := strings.ToLower(loan.Lender)
+	for name, aliases := range lenderAliases {
+		if !strings.Contains(lender, name) {
+			continue
+		}
+		for _, alias := range aliases {
+			if strings.Contains(upper, alias) {
+				score += 2
+				break
+			}
+		}
+	}
+	if first := strings.ToUpper(strings.Fields(loan.Lender + " ")[0]); len(first) > 3 && strings.Contains(upper, first) {
+		score++
+	}
+	return score
+}
+
+// matchInstallments assigns each loan EMI series to the loan it matches best.
+// Every series and loan is used at most once, best scores first.
+func matchInstallments(debts []Debt, series []Installment) (map[int]Installment, []Installment) {
+	type candidate struct{ debt, series, score int }
+	var candidates []candidate
+	for si, s := range series {
+		for di, d := range debts {
+			if d.Revolving {
+				continue
+			}
+			if score := matchScore(s.Narration, d); score >= 2 {
+				candidates = append(candidates, candidate{di, si, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	matched := make(map[int]Installment)
+	usedSeries := make(map[int]bool)
+	for _, c := range candidates {
+		if _, ok := matched[c.debt]; ok || usedSeries[c.series] {
+			continue
+		}
+		matched[c.debt] = series[c.series]
+		usedSeries[c.series] = true
+	}
+	var unmatched []Installment
+	for si, s := range series {
+		if !usedSeries[si] {
+			unmatched = append(unmatched, s)
+		}
+	}
+	return matched, unmatched
+}
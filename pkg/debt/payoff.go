@@ -0,0 +1,135 @@
+package debt
+
+import (
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Payoff strategies
+const (
+	// StrategyAvalanche pays extra money towards the debt with the highest rate first
+	StrategyAvalanche = "avalanche"
+	// StrategySnowball pays extra money towards the debt with the smallest balance first
+	StrategySnowball = "snowball"
+)
+
+// maxPlanMonths bounds the payoff simulation
+const maxPlanMonths = 600
+
+// Payoff is when a debt is cleared in a plan
+type Payoff struct {
+	AccountIndex int     `json:"accountIndex"`
+	Lender       string  `json:"lender"`
+	AccountType  string  `json:"accountType"`
+	Month        int     `json:"month"`
+	Date         string  `json:"date"`
+	InterestPaid float64 `json:"interestPaid"`
+}
+
+// YearEnd is the state of a plan at the end of a year of payments
+type YearEnd struct {
+	Year         int     `json:"year"`
+	Balance      float64 `json:"balance"`
+	InterestPaid float64 `json:"interestPaid"`
+}
+
+// Plan is a payoff schedule where the monthly budget stays the same and the
+// payments of cleared debts roll over to the next target
+type Plan struct {
+	Strategy      string    `json:"strategy"`
+	MonthlyBudget float64   `json:"monthlyBudget"`
+	Months        *int      `json:"months,omitempty"`
+	DebtFreeDate  string    `json:"debtFreeDate,omitempty"`
+	TotalInterest float64   `json:"totalInterest"`
+	Order         []Payoff  `json:"order"`
+	Yearly        []YearEnd `json:"yearly"`
+	Note          string    `json:"note,omitempty"`
+}
+
+func plan(strategy string, debts []Debt, extra float64, asOf time.Time) Plan {
+	type state struct {
+		debt     Debt
+		balance  float64
+		interest float64
+		paid     bool
+	}
+	var states []*state
+	budget := extra
+	for _, d := range debts {
+		if d.EMI <= 0 {
+			continue
+		}
+		states = append(states, &state{debt: d, balance: d.Balance})
+		budget += d.EMI
+	}
+	p := Plan{Strategy: strategy, MonthlyBudget: models.Round(budget, 2), Order: []Payoff{}, Yearly: []YearEnd{}}
+	if len(states) == 0 {
+		return p
+	}
+	targets := make([]*state, len(states))
+	copy(targets, states)
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := targets[i].debt, targets[j].debt
+		if strategy == StrategyAvalanche && a.RatePercent != b.RatePercent {
+			return a.RatePercent > b.RatePercent
+		}
+		return a.Balance < b.Balance
+	})
+
+	var total float64
+	for month := 1; month <= maxPlanMonths; month++ {
+		left := budget
+		for _, s := range states {
+			if s.paid {
+				continue
+			}
+			i := s.balance * monthlyRate(s.debt.RatePercent)
+			s.balance += i
+			s.interest += i
+			total += i
+			pay := min(s.debt.EMI, s.balance, left)
+			s.balance -= pay
+			left -= pay
+		}
+		for _, s := range targets {
+			if s.paid || left <= 0 {
+				continue
+			}
+			pay := min(s.balance, left)
+			s.balance -= pay
+			left -= pay
+		}
+		remaining := 0.0
+		for _, s := range states {
+			if !s.paid && s.balance <= 0.005 {
+				s.paid = true
+				p.Order = append(p.Order, Payoff{
+					AccountIndex: s.debt.AccountIndex,
+					Lender:       s.debt.Lender,
+					AccountType:  s.debt.AccountTypeLabel,
+					Month:        month,
+					Date:         asOf.AddDate(0, month, 0).Format("2006-01"),
+					InterestPaid: models.Round(s.interest, 2),
+				})
+			}
+			if !s.paid {
+				remaining += s.balance
+			}
+		}
+		if month%12 == 0 || remaining == 0 {
+			p.Yearly = append(p.Yearly, YearEnd{Year: (month + 11) / 12, Balance: models.Round(remaining, 2), InterestPaid: models.Round(total, 2)})
+		}
+		if remaining == 0 {
+			months := month
+			p.Months, p.DebtFreeDate = &months, asOf.AddDate(0, month, 0).Format("2006-01")
+			break
+		}
+	}
+	p.TotalInterest = models.Round(total, 2)
+	if p.Months == nil {
+		p.Note = "the debts are not cleared within 50 years at this budget"
+	}
+	return p
+}
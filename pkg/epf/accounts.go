@@ -0,0 +1,275 @@
+package epf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Flag types
+const (
+	// FlagDormant marks a member id without contributions for dormantAfterMonths or more
+	FlagDormant = "DORMANT"
+	// FlagUntransferred marks a balance left with a previous employer
+	FlagUntransferred = "UNTRANSFERRED_BALANCE"
+	// FlagOverlappingService marks two employers whose service periods overlap
+	FlagOverlappingService = "OVERLAPPING_SERVICE"
+	// FlagDuplicateUAN marks a UAN reported more than once
+	FlagDuplicateUAN = "DUPLICATE_UAN"
+	// FlagBalanceMismatch marks a UAN whose member balances don't add up to its overall balance
+	FlagBalanceMismatch = "BALANCE_MISMATCH"
+)
+
+// dormantAfterMonths is how long an account goes without contributions before it is flagged dormant
+const dormantAfterMonths = 36
+
+// Account is the member id of a UAN at one employer
+type Account struct {
+	UANIndex int `json:"uanIndex"`
+	// EstIndex is the position of the member id in est_details
+	EstIndex      int     `json:"estIndex"`
+	Employer      string  `json:"employer"`
+	MemberID      string  `json:"memberId"`
+	Office        string  `json:"office"`
+	JoinDate      string  `json:"joinDate,omitempty"`
+	ExitDate      string  `json:"exitDate,omitempty"`
+	Active        bool    `json:"active"`
+	ServiceMonths int     `json:"serviceMonths"`
+	Balance       float64 `json:"balance"`
+	EmployeeShare float64 `json:"employeeShare"`
+	EmployerShare float64 `json:"employerShare"`
+	// MonthsSinceExit is set for employers the member has left
+	MonthsSinceExit *int `json:"monthsSinceExit,omitempty"`
+
+	join, end        time.Time
+	employeeCredited float64
+}
+
+// Flag is an issue found in the EPF accounts
+type Flag struct {
+	Type     string `json:"type"`
+	UANIndex int    `json:"uanIndex"`
+	// Employers lists the employers the flag is about
+	Employers []string `json:"employers,omitempty"`
+	Message   string   `json:"message"`
+}
+
+// Summary is the state of all EPF accounts of a user
+type Summary struct {
+	AsOf           string    `json:"asOf"`
+	Accounts       []Account `json:"accounts"`
+	Balance        float64   `json:"balance"`
+	PensionBalance float64   `json:"pensionBalance"`
+	Flags          []Flag    `json:"flags"`
+}
+
+// Summarize lists the member ids of every UAN with their service periods and
+// flags dormant accounts, untransferred balances, overlapping service and
+// inconsistencies in the EPF details. A UAN reported twice is counted once.
+func Summarize(resp *models.EPFDetailsResponse, asOf time.Time) *Summary {
+	s := &Summary{AsOf: asOf.Format(models.DateLayout), Accounts: []Account{}, Flags: []Flag{}}
+	if resp == nil {
+		return s
+	}
+	seen := make(map[string]int)
+	for u, uan := range resp.UANAccounts {
+		details := uan.RawDetails
+		key := uanKey(details)
+		if first, ok := seen[key]; ok {
+			s.Flags = append(s.Flags, Flag{
+				Type:     FlagDuplicateUAN,
+				UANIndex: u,
+				Message:  fmt.Sprintf("UAN %d repeats the member ids of UAN %d and is counted once", u, first),
+			})
+			continue
+		}
+		seen[key] = u
+
+		var accounts []Account
+		var sum float64
+		for e, est := range details.EstDetails {
+			a := newAccount(u, e, est, asOf)
+			sum += a.Balance
+			accounts = append(accounts, a)
+		}
+		s.Flags = append(s.Flags, accountFlags(u, accounts)...)
+		s.Accounts = append(s.Accounts, accounts...)
+
+		overall := models.ParseEPFAmount(details.OverallPFBalance.CurrentPFBalance)
+		if details.OverallPFBalance.CurrentPFBalance == "" {
+			overall = sum
+		}
+		if diff := overall - sum; diff > 0.5 || diff < -0.5 {
+			s.Flags = append(s.Flags, Flag{
+				Type:     FlagBalanceMismatch,
+				UANIndex: u,
+				Message:  fmt.Sprintf("member balances add up to %.2f but the overall PF balance is %.2f, the overall balance is used", sum, overall),
+			})
+		}
+		s.Balance += overall
+		s.PensionBalance += models.ParseEPFAmount(details.OverallPFBalance.PensionBalance)
+	}
+	s.Balance = models.Round(s.Balance, 2)
+	s.PensionBalance = models.Round(s.PensionBalance, 2)
+	return s
+}
+
+// AsOf dates the balances of the EPF details, which carry no date of their own:
+// the latest passbook entry, else valued, the day the rest of the user's data
+// was valued on, else the latest date of joining or leaving an employer. It is
+// zero when there is no date at all.
+func AsOf(resp *models.EPFDetailsResponse, valued time.Time) time.Time {
+	var passbook, service time.Time
+	if resp != nil {
+		for _, uan := range resp.UANAccounts {
+			for _, est := range uan.RawDetails.EstDetails {
+				for _, e := range est.Passbook {
+					if t, ok := e.Time(); ok {
+						passbook = later(passbook, t)
+					}
+				}
+				if t, ok := est.JoinDate(); ok {
+					service = later(service, t)
+				}
+				if t, ok := est.ExitDate(); ok {
+					service = later(service, t)
+				}
+			}
+		}
+	}
+	switch {
+	case !passbook.IsZero():
+		return passbook
+	case !valued.IsZero():
+		return valued
+	}
+	return service
+}
+
+func newAccount(uan, estIndex int, est models.EPFEstablishment, asOf time.Time) Account {
+	a := Account{
+		UANIndex:         uan,
+		EstIndex:         estIndex,
+		Employer:         est.EstName,
+		MemberID:         est.MemberID,
+		Office:           est.Office,
+		Balance:          models.ParseEPFAmount(est.PFBalance.NetBalance),
+		EmployeeShare:    models.ParseEPFAmount(est.PFBalance.EmployeeShare.Balance),
+		EmployerShare:    models.ParseEPFAmount(est.PFBalance.EmployerShare.Balance),
+		employeeCredited: models.ParseEPFAmount(est.PFBalance.EmployeeShare.Credit),
+		end:              asOf,
+	}
+	if join, ok := est.JoinDate(); ok {
+		a.join, a.JoinDate = join, join.Format(models.DateLayout)
+	}
+	if exit, ok := est.ExitDate(); ok {
+		a.end, a.ExitDate = exit, exit.Format(models.DateLayout)
+		since := models.MonthsBetween(exit, asOf)
+		a.MonthsSinceExit = &since
+	} else {
+		a.Active = true
+	}
+	if !a.join.IsZero() {
+		a.ServiceMonths = max(models.MonthsBetween(a.join, a.end), 0)
+	}
+	return a
+}
+
+// MonthlyEmployeeCredit is the employee share credited per month of service on average
+func (a Account) MonthlyEmployeeCredit() float64 {
+	if a.ServiceMonths == 0 {
+		return 0
+	}
+	return a.employeeCredited / float64(a.ServiceMonths)
+}
+
+// ServiceMonthsIn counts the whole months of service from from up to the exclusive
+// end to. Service at the current employer is assumed to continue past the as-of date.
+func (a Account) ServiceMonthsIn(from, to time.Time) int {
+	if a.join.IsZero() {
+		return 0
+	}
+	start := later(a.join, from)
+	if !a.Active {
+		to = earlier(a.end, to)
+	}
+	return max(models.MonthsBetween(start, to), 0)
+}
+
+func accountFlags(uan int, accounts []Account) []Flag {
+	var flags []Flag
+	var active []string
+	for _, a := range accounts {
+		if a.Active {
+			active = append(active, a.Employer)
+		}
+	}
+	for _, a := range accounts {
+		if a.Active || a.Balance <= 0 || a.MonthsSinceExit == nil {
+			continue
+		}
+		switch {
+		case *a.MonthsSinceExit >= dormantAfterMonths:
+			message := fmt.Sprintf("no contributions since leaving on %s (%d months) with %.2f still in the account, ", a.ExitDate, *a.MonthsSinceExit, a.Balance)
+			if len(active) > 0 {
+				message += "transfer it to the member id of " + strings.Join(active, ", ")
+			} else {
+				message += "interest may no longer be credited, transfer or withdraw the balance"
+			}
+			flags = append(flags, Flag{Type: FlagDormant, UANIndex: uan, Employers: []string{a.Employer}, Message: message})
+		case len(active) > 0:
+			flags = append(flags, Flag{
+				Type:      FlagUntransferred,
+				UANIndex:  uan,
+				Employers: []string{a.Employer},
+				Message:   fmt.Sprintf("%.2f is still with a previous employer, transfer it to the member id of %s", a.Balance, strings.Join(active, ", ")),
+			})
+		}
+	}
+	for i := range accounts {
+		for j := i + 1; j < len(accounts); j++ {
+			a, b := accounts[i], accounts[j]
+			if a.join.IsZero() || b.join.IsZero() {
+				continue
+			}
+			from, to := later(a.join, b.join), earlier(a.end, b.end)
+			if !to.After(from) {
+				continue
+			}
+			flags = append(flags, Flag{
+				Type:      FlagOverlappingService,
+				UANIndex:  uan,
+				Employers: []string{a.Employer, b.Employer},
+				Message:   fmt.Sprintf("service periods overlap from %s to %s (%d days), check the dates with EPFO", from.Format(models.DateLayout), to.Format(models.DateLayout), int(to.Sub(from).Hours()/24)),
+			})
+		}
+	}
+	return flags
+}
+
+// uanKey identifies a UAN by its member ids
+func uanKey(details models.EPFRawDetails) string {
+	ids := make([]string, 0, len(details.EstDetails))
+	for _, est := range details.EstDetails {
+		ids = append(ids, est.EstName+"|"+est.MemberID+"|"+est.DOJEPF)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ";")
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
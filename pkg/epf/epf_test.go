@@ -0,0 +1,136 @@
+package epf
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func establishment(name, join, exit, balance, employeeCredit string) models.EPFEstablishment {
+	return models.EPFEstablishment{
+		EstName: name, MemberID: name, DOJEPF: join, DOEEPF: exit,
+		PFBalance: models.EPFPFBalance{NetBalance: balance, EmployeeShare: models.EPFShare{Credit: employeeCredit}},
+	}
+}
+
+func TestSummarizeFlags(t *testing.T) {
+	uan := models.UANAccount{RawDetails: models.EPFRawDetails{
+		EstDetails: []models.EPFEstablishment{
+			establishment("OLD", "01-01-2015", "31-03-2020", "100000", "40000"),
+			establishment("NEW", "01-03-2020", models.EPFDateNotAvailable, "60000", "24000"),
+		},
+		OverallPFBalance: models.EPFOverallBalance{CurrentPFBalance: "160000"},
+	}}
+	asOf := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := Summarize(&models.EPFDetailsResponse{UANAccounts: []models.UANAccount{uan, uan}}, asOf)
+
+	types := map[string]int{}
+	for _, f := range s.Flags {
+		types[f.Type]++
+	}
+	for _, want := range []string{FlagDormant, FlagOverlappingService, FlagDuplicateUAN} {
+		if types[want] != 1 {
+			t.Errorf("flags = %+v, want one %s", s.Flags, want)
+		}
+	}
+	if types[FlagBalanceMismatch] != 0 {
+		t.Errorf("unexpected balance mismatch: %+v", s.Flags)
+	}
+	if s.Balance != 160000 || len(s.Accounts) != 2 {
+		t.Errorf("balance = %v over %d accounts, want 160000 over 2", s.Balance, len(s.Accounts))
+	}
+}
+
+func TestProject(t *testing.T) {
+	asOf := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	s := &Summary{Balance: 100000}
+	p, err := Project(s, Assumptions{CurrentAge: 30, RetirementAge: 31}, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without contributions a full financial year earns simple interest on the opening balance
+	if len(p.Years) != 1 || math.Abs(p.CorpusAtRetirement-108250) > 0.01 {
+		t.Errorf("corpus = %v over %d years, want 108250 over 1", p.CorpusAtRetirement, len(p.Years))
+	}
+	if p.Contribution.Source != ContributionNone {
+		t.Errorf("contribution source = %s, want %s", p.Contribution.Source, ContributionNone)
+	}
+
+	basic := 20000.0
+	p, err = Project(s, Assumptions{CurrentAge: 30, RetirementAge: 31, MonthlyBasicSalary: basic, InterestRates: map[int]float64{2024: 0}}, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 12% employee plus 12% employer less 8.33% of 15000 to EPS
+	want := 100000 + 12*(2400+2400-1249.5)
+	if math.Abs(p.CorpusAtRetirement-want) > 0.01 || p.TotalInterest != 0 {
+		t.Errorf("corpus = %v with interest %v, want %v without interest", p.CorpusAtRetirement, p.TotalInterest, want)
+	}
+
+	if _, err := Project(s, Assumptions{CurrentAge: 60, RetirementAge: 58}, asOf); err == nil {
+		t.Error("expected an error for a retirement age before the current age")
+	}
+}
+
+func TestAsOfInfersContribution(t *testing.T) {
+	resp := &models.EPFDetailsResponse{UANAccounts: []models.UANAccount{{RawDetails: models.EPFRawDetails{
+		EstDetails: []models.EPFEstablishment{establishment("NEW", "15-02-2022", models.EPFDateNotAvailable, "128000", "60000")},
+	}}}}
+	valued := time.Date(2024, 5, 21, 0, 0, 0, 0, time.UTC)
+	if got := AsOf(resp, time.Time{}); !got.Equal(time.Date(2022, 2, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AsOf without a valuation date = %v, want the date of joining", got)
+	}
+	asOf := AsOf(resp, valued)
+	if !asOf.Equal(valued) {
+		t.Fatalf("AsOf = %v, want %v", asOf, valued)
+	}
+	// 60000 credited over the 27 months to the date of the data at 12% of basic
+	p, err := Project(Summarize(resp, asOf), Assumptions{CurrentAge: 30, RetirementAge: 31}, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := p.Contribution; c.Source != ContributionInferred || c.MonthlyBasicSalary != 18518.52 {
+		t.Errorf("contribution = %+v, want 18518.52 inferred", c)
+	}
+
+	resp.UANAccounts[0].RawDetails.EstDetails[0].Passbook = []models.EPFPassbookEntry{{Date: "30-04-2024"}}
+	if got := AsOf(resp, valued); !got.Equal(time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AsOf with a passbook = %v, want its latest entry", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	est := establishment("NEW", "01-03-2023", models.EPFDateNotAvailable, "23000", "20000")
+	est.Passbook = []models.EPFPassbookEntry{
+		{Month: "2023-03", Date: "31-03-2023", Type: models.EPFEntryOpening, EmployeeShare: "10000", EmployerShare: "8000"},
+		{Month: "2023-04", Date: "15-05-2023", Type: models.EPFEntryContribution, EmployeeShare: "2000", EmployerShare: "1500", PensionShare: "500"},
+		{Month: "2024-03", Date: "31-03-2024", Type: models.EPFEntryInterest, EmployeeShare: "800", EmployerShare: "700"},
+		{Month: "2024-04", Date: "15-05-2024", Type: models.EPFEntryWithdrawal, EmployeeShare: "-1000", EmployerShare: "0"},
+	}
+	resp := &models.EPFDetailsResponse{UANAccounts: []models.UANAccount{{RawDetails: models.EPFRawDetails{
+		EstDetails: []models.EPFEstablishment{establishment("OLD", "01-01-2015", "31-03-2020", "0", "0"), est},
+	}}}}
+
+	p := History(resp, "", time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if len(p.Accounts) != 2 || len(p.Accounts[0].Entries) != 0 {
+		t.Fatalf("accounts = %+v", p.Accounts)
+	}
+	a := p.Accounts[1]
+	// the opening balance is before from but still counts towards the balances
+	if len(a.Entries) != 3 || a.Entries[0].EmployeeBalance != 12000 || a.Entries[2].EmployeeBalance != 11800 {
+		t.Errorf("entries = %+v", a.Entries)
+	}
+	want := []YearTotals{
+		{FinancialYear: "2023-24", EmployeeContribution: 2000, EmployerContribution: 1500, PensionContribution: 500, Interest: 1500, ClosingBalance: 23000},
+		{FinancialYear: "2024-25", Withdrawals: -1000, ClosingBalance: 22000},
+	}
+	if !reflect.DeepEqual(a.Years, want) {
+		t.Errorf("years = %+v, want %+v", a.Years, want)
+	}
+	if p := History(resp, "OLD", time.Time{}, time.Time{}); len(p.Accounts) != 1 || p.Accounts[0].ExitDate != "2020-03-31" {
+		t.Errorf("OLD = %+v", p.Accounts)
+	}
+}
@@ -0,0 +1,138 @@
+package epf
+
+import (
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// PassbookEntry is an entry of a member passbook with the employee and
+// employer balances after it
+type PassbookEntry struct {
+	Month           string  `json:"month"`
+	Date            string  `json:"date"`
+	Type            string  `json:"type"`
+	Particulars     string  `json:"particulars,omitempty"`
+	EmployeeShare   float64 `json:"employeeShare"`
+	EmployerShare   float64 `json:"employerShare"`
+	PensionShare    float64 `json:"pensionShare"`
+	EmployeeBalance float64 `json:"employeeBalance"`
+	EmployerBalance float64 `json:"employerBalance"`
+}
+
+// YearTotals adds up the passbook entries of a financial year. Transfers and
+// withdrawals are the employee and employer shares together, negative when
+// they leave the account.
+type YearTotals struct {
+	FinancialYear        string  `json:"financialYear"`
+	EmployeeContribution float64 `json:"employeeContribution"`
+	EmployerContribution float64 `json:"employerContribution"`
+	PensionContribution  float64 `json:"pensionContribution"`
+	Interest             float64 `json:"interest"`
+	TransfersIn          float64 `json:"transfersIn"`
+	TransfersOut         float64 `json:"transfersOut"`
+	Withdrawals          float64 `json:"withdrawals"`
+	ClosingBalance       float64 `json:"closingBalance"`
+}
+
+// PassbookAccount is the passbook of the member id of a UAN at one employer
+type PassbookAccount struct {
+	UANIndex int    `json:"uanIndex"`
+	EstIndex int    `json:"estIndex"`
+	Employer string `json:"employer"`
+	MemberID string `json:"memberId"`
+	JoinDate string `json:"joinDate,omitempty"`
+	ExitDate string `json:"exitDate,omitempty"`
+	// Entries is empty when the EPF details carry no passbook for the member id
+	Entries []PassbookEntry `json:"entries"`
+	Years   []YearTotals    `json:"years"`
+}
+
+// Passbook is the month by month history of the EPF accounts
+type Passbook struct {
+	From     string            `json:"from,omitempty"`
+	To       string            `json:"to,omitempty"`
+	Accounts []PassbookAccount `json:"accounts"`
+}
+
+// History lists the passbook entries dated from from to to inclusive, either of
+// them zero for no bound, with yearly totals. Balances count the entries before
+// from too. An empty memberID selects every member id.
+func History(resp *models.EPFDetailsResponse, memberID string, from, to time.Time) *Passbook {
+	p := &Passbook{Accounts: []PassbookAccount{}}
+	if !from.IsZero() {
+		p.From = from.Format(models.DateLayout)
+	}
+	if !to.IsZero() {
+		p.To = to.Format(models.DateLayout)
+	}
+	if resp == nil {
+		return p
+	}
+	for u, uan := range resp.UANAccounts {
+		for e, est := range uan.RawDetails.EstDetails {
+			if memberID != "" && est.MemberID != memberID {
+				continue
+			}
+			a := PassbookAccount{UANIndex: u, EstIndex: e, Employer: est.EstName, MemberID: est.MemberID, Entries: []PassbookEntry{}, Years: []YearTotals{}}
+			if join, ok := est.JoinDate(); ok {
+				a.JoinDate = join.Format(models.DateLayout)
+			}
+			if exit, ok := est.ExitDate(); ok {
+				a.ExitDate = exit.Format(models.DateLayout)
+			}
+			var employee, employer float64
+			for _, entry := range est.Passbook {
+				date, ok := entry.Time()
+				if !ok {
+					continue
+				}
+				pe := PassbookEntry{
+					Month:         entry.Month,
+					Date:          date.Format(models.DateLayout),
+					Type:          entry.Type,
+					Particulars:   entry.Particulars,
+					EmployeeShare: models.ParseEPFAmount(entry.EmployeeShare),
+					EmployerShare: models.ParseEPFAmount(entry.EmployerShare),
+					PensionShare:  models.ParseEPFAmount(entry.PensionShare),
+				}
+				employee += pe.EmployeeShare
+				employer += pe.EmployerShare
+				pe.EmployeeBalance, pe.EmployerBalance = models.Round(employee, 2), models.Round(employer, 2)
+				if (!from.IsZero() && date.Before(from)) || (!to.IsZero() && date.After(to)) {
+					continue
+				}
+				a.Entries = append(a.Entries, pe)
+				a.Years = addToYear(a.Years, models.FinancialYearOf(date).String(), pe)
+			}
+			p.Accounts = append(p.Accounts, a)
+		}
+	}
+	return p
+}
+
+// addToYear adds an entry to the totals of its financial year, the entries
+// coming in date order
+func addToYear(years []YearTotals, fy string, e PassbookEntry) []YearTotals {
+	if len(years) == 0 || years[len(years)-1].FinancialYear != fy {
+		years = append(years, YearTotals{FinancialYear: fy})
+	}
+	y := &years[len(years)-1]
+	both := e.EmployeeShare + e.EmployerShare
+	switch e.Type {
+	case models.EPFEntryContribution:
+		y.EmployeeContribution += e.EmployeeShare
+		y.EmployerContribution += e.EmployerShare
+		y.PensionContribution += e.PensionShare
+	case models.EPFEntryInterest:
+		y.Interest += both
+	case models.EPFEntryTransferIn:
+		y.TransfersIn += both
+	case models.EPFEntryTransferOut:
+		y.TransfersOut += both
+	case models.EPFEntryWithdrawal:
+		y.Withdrawals += both
+	}
+	y.ClosingBalance = e.EmployeeBalance + e.EmployerBalance
+	return years
+}
@@ -0,0 +1,232 @@
+package epf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const (
+	// DefaultInterestRate is the EPF rate declared for FY 2024-25, used for years without a configured rate
+	DefaultInterestRate = 8.25
+	// DefaultRetirementAge is the age at which EPF contributions stop
+	DefaultRetirementAge = 58
+	// DefaultEmployeeRate is the statutory employee contribution as a percent of basic salary
+	DefaultEmployeeRate = 12
+	// DefaultSalaryGrowth is the assumed yearly growth of the basic salary in percent
+	DefaultSalaryGrowth = 5
+
+	// employerRate is the employer contribution as a percent of basic salary
+	employerRate = 12
+	// pensionRate is the part of the employer contribution diverted to EPS, on at most pensionWageCeiling
+	pensionRate        = 8.33
+	pensionWageCeiling = 15000
+)
+
+// Sources of the contribution used in a projection
+const (
+	ContributionProvided = "provided"
+	ContributionInferred = "inferred"
+	ContributionNone     = "none"
+)
+
+// Assumptions configure a projection
+type Assumptions struct {
+	CurrentAge    int
+	RetirementAge int
+	// MonthlyBasicSalary is inferred from the contributions of the current employer when zero
+	MonthlyBasicSalary  float64
+	EmployeeRatePercent float64
+	SalaryGrowthPercent float64
+	// InterestRates maps the start year of a financial year to the rate applying from
+	// that year until the next configured one
+	InterestRates map[int]float64
+}
+
+// Contribution is the monthly contribution at the start of a projection
+type Contribution struct {
+	Source             string  `json:"source"`
+	MonthlyBasicSalary float64 `json:"monthlyBasicSalary"`
+	Employee           float64 `json:"employee"`
+	// Employer is the part of the employer contribution credited to EPF, the rest goes to EPS
+	Employer float64 `json:"employer"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// Year is one year of a projection, from one birthday to the next
+type Year struct {
+	Age                  int     `json:"age"`
+	From                 string  `json:"from"`
+	To                   string  `json:"to"`
+	RatePercent          float64 `json:"ratePercent"`
+	MonthlyBasicSalary   float64 `json:"monthlyBasicSalary"`
+	EmployeeContribution float64 `json:"employeeContribution"`
+	EmployerContribution float64 `json:"employerContribution"`
+	Interest             float64 `json:"interest"`
+	ClosingBalance       float64 `json:"closingBalance"`
+}
+
+// Projection is the EPF balance projected to retirement
+type Projection struct {
+	*Summary
+	CurrentAge          int          `json:"currentAge"`
+	RetirementAge       int          `json:"retirementAge"`
+	SalaryGrowthPercent float64      `json:"salaryGrowthPercent"`
+	Contribution        Contribution `json:"contribution"`
+	Years               []Year       `json:"years"`
+	CorpusAtRetirement  float64      `json:"corpusAtRetirement"`
+	TotalContributions  float64      `json:"totalContributions"`
+	TotalInterest       float64      `json:"totalInterest"`
+	Assumptions         []string     `json:"assumptions"`
+}
+
+// Validate checks the assumptions and fills in the defaults
+func (a *Assumptions) Validate() error {
+	if a.RetirementAge == 0 {
+		a.RetirementAge = DefaultRetirementAge
+	}
+	if a.EmployeeRatePercent == 0 {
+		a.EmployeeRatePercent = DefaultEmployeeRate
+	}
+	switch {
+	case a.CurrentAge < 15 || a.CurrentAge > 80:
+		return fmt.Errorf("current age %d must be between 15 and 80", a.CurrentAge)
+	case a.RetirementAge <= a.CurrentAge || a.RetirementAge > 80:
+		return fmt.Errorf("retirement age %d must be after the current age %d and at most 80", a.RetirementAge, a.CurrentAge)
+	case a.MonthlyBasicSalary < 0:
+		return fmt.Errorf("monthly basic salary must not be negative")
+	case a.EmployeeRatePercent < 0 || a.EmployeeRatePercent > 100:
+		return fmt.Errorf("employee contribution %.2f%% must be between 0 and 100", a.EmployeeRatePercent)
+	case a.SalaryGrowthPercent < -50 || a.SalaryGrowthPercent > 50:
+		return fmt.Errorf("salary growth %.2f%% must be between -50 and 50", a.SalaryGrowthPercent)
+	}
+	for year, rate := range a.InterestRates {
+		if rate < 0 || rate > 20 {
+			return fmt.Errorf("interest rate %.2f%% for FY %s must be between 0 and 20", rate, models.FinancialYear{StartYear: year})
+		}
+	}
+	return nil
+}
+
+// rate returns the interest rate of the financial year starting in year
+func (a *Assumptions) rate(year int) float64 {
+	best, rate := -1, DefaultInterestRate
+	for y, r := range a.InterestRates {
+		if y <= year && y > best {
+			best, rate = y, r
+		}
+	}
+	return rate
+}
+
+// Project projects the EPF balance year by year to retirement. Contributions are
+// made monthly while the member works for an employer, interest accrues monthly on
+// the running balance and is credited at the end of each financial year, as EPFO does.
+// The pension (EPS) balance is reported but not projected.
+func Project(summary *Summary, a Assumptions, asOf time.Time) (*Projection, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Projection{
+		Summary:             summary,
+		CurrentAge:          a.CurrentAge,
+		RetirementAge:       a.RetirementAge,
+		SalaryGrowthPercent: a.SalaryGrowthPercent,
+		Contribution:        contribution(summary, a),
+		Years:               []Year{},
+		Assumptions: []string{
+			fmt.Sprintf("employee contributes %.2f%% and employer %d%% of the basic salary, of which %.2f%% of at most %d goes to EPS", a.EmployeeRatePercent, employerRate, pensionRate, pensionWageCeiling),
+			fmt.Sprintf("basic salary grows %.2f%% a year on every birthday", a.SalaryGrowthPercent),
+			fmt.Sprintf("interest is %.2f%% a year unless configured for a financial year, credited every 31 March", DefaultInterestRate),
+			"balances of dormant accounts keep earning interest",
+		},
+	}
+	if len(a.InterestRates) > 0 {
+		years := make([]int, 0, len(a.InterestRates))
+		for y := range a.InterestRates {
+			years = append(years, y)
+		}
+		sort.Ints(years)
+		for _, y := range years {
+			p.Assumptions = append(p.Assumptions, fmt.Sprintf("interest is %.2f%% from FY %s", a.InterestRates[y], models.FinancialYear{StartYear: y}))
+		}
+	}
+
+	balance, accrued := summary.Balance, 0.0
+	basic := p.Contribution.MonthlyBasicSalary
+	month := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for age := a.CurrentAge; age < a.RetirementAge; age++ {
+		y := Year{
+			Age:                age,
+			From:               month.Format(models.DateLayout),
+			RatePercent:        a.rate(models.FinancialYearOf(month).StartYear),
+			MonthlyBasicSalary: models.Round(basic, 2),
+		}
+		employee, employer := contributions(basic, a.EmployeeRatePercent)
+		var interest float64
+		for m := 0; m < 12; m++ {
+			rate := a.rate(models.FinancialYearOf(month).StartYear)
+			balance += employee + employer
+			y.EmployeeContribution += employee
+			y.EmployerContribution += employer
+			i := balance * rate / 100 / 12
+			accrued += i
+			interest += i
+			month = month.AddDate(0, 1, 0)
+			if month.Month() == time.April {
+				balance += accrued
+				accrued = 0
+			}
+		}
+		y.To = month.AddDate(0, 0, -1).Format(models.DateLayout)
+		y.Interest = models.Round(interest, 2)
+		y.EmployeeContribution = models.Round(y.EmployeeContribution, 2)
+		y.EmployerContribution = models.Round(y.EmployerContribution, 2)
+		y.ClosingBalance = models.Round(balance+accrued, 2)
+		p.Years = append(p.Years, y)
+		p.TotalContributions += y.EmployeeContribution + y.EmployerContribution
+		p.TotalInterest += interest
+		basic *= 1 + a.SalaryGrowthPercent/100
+	}
+	// interest accrued in the last financial year is credited on settlement
+	p.CorpusAtRetirement = models.Round(balance+accrued, 2)
+	p.TotalContributions = models.Round(p.TotalContributions, 2)
+	p.TotalInterest = models.Round(p.TotalInterest, 2)
+	return p, nil
+}
+
+// contribution works out the monthly contribution, inferring the basic salary from
+// the employee share credited by the current employer when it isn't provided
+func contribution(s *Summary, a Assumptions) Contribution {
+	c := Contribution{Source: ContributionProvided, MonthlyBasicSalary: a.MonthlyBasicSalary}
+	if c.MonthlyBasicSalary == 0 {
+		c.Source = ContributionNone
+		for _, acc := range s.Accounts {
+			if !acc.Active || acc.ServiceMonths == 0 || acc.employeeCredited == 0 {
+				continue
+			}
+			// the employee share is a fixed percent of basic, so the average credit gives the average basic
+			basic := acc.employeeCredited / float64(acc.ServiceMonths) / (DefaultEmployeeRate / 100.0)
+			if basic > c.MonthlyBasicSalary {
+				c.Source, c.MonthlyBasicSalary = ContributionInferred, basic
+				c.Note = fmt.Sprintf("inferred from the employee share credited by %s over %d months at %d%% of basic", acc.Employer, acc.ServiceMonths, DefaultEmployeeRate)
+			}
+		}
+		if c.Source == ContributionNone {
+			c.Note = "no current employer found, the balance only earns interest"
+		}
+	}
+	c.MonthlyBasicSalary = models.Round(c.MonthlyBasicSalary, 2)
+	employee, employer := contributions(c.MonthlyBasicSalary, a.EmployeeRatePercent)
+	c.Employee, c.Employer = models.Round(employee, 2), models.Round(employer, 2)
+	return c
+}
+
+// contributions returns the monthly employee and employer EPF contributions for a basic salary
+func contributions(basic, employeePercent float64) (employee, employer float64) {
+	employee = basic * employeePercent / 100
+	employer = basic*employerRate/100 - min(basic, pensionWageCeiling)*pensionRate/100
+	return employee, employer
+}
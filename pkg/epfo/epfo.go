@@ -0,0 +1,297 @@
+// Package epfo reads EPFO member passbooks into the EPF details of the
+// fetch_epf_details tool, with the month by month history of every member id as
+// its passbook. It takes the passbook PDF converted with pdftotext -layout, or a
+// CSV with a header naming the wage month, date and share columns, either of
+// them preceded by the establishment, member id and UAN lines of the passbook.
+package epfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+)
+
+// Member is the passbook of one member id
+type Member struct {
+	UAN      string
+	EstID    string
+	EstName  string
+	MemberID string
+	Office   string
+	// Joined and Exited are set when the passbook gives the dates, otherwise
+	// they are worked out from the contributions
+	Joined, Exited time.Time
+
+	entries []entry
+}
+
+// entry is a passbook row with its shares signed
+type entry struct {
+	month, kind, particulars    string
+	date                        time.Time
+	employee, employer, pension float64
+}
+
+// Statement holds the members of one or more passbooks
+type Statement struct {
+	// AsOf is the latest entry date
+	AsOf     time.Time
+	Members  []*Member
+	Warnings []string
+}
+
+func (st *Statement) warn(format string, args ...any) {
+	st.Warnings = append(st.Warnings, fmt.Sprintf(format, args...))
+}
+
+// member returns the member with the id, adding it when it is new
+func (st *Statement) member(id string) *Member {
+	for _, m := range st.Members {
+		if m.MemberID == id {
+			return m
+		}
+	}
+	m := &Member{MemberID: id}
+	st.Members = append(st.Members, m)
+	return m
+}
+
+// add appends an entry to a member. The opening balance of a later year in the
+// same passbook only confirms the entries before it and is not added again.
+func (st *Statement) add(m *Member, e entry) {
+	if e.date.After(st.AsOf) {
+		st.AsOf = e.date
+	}
+	if e.kind == models.EPFEntryOpening && len(m.entries) > 0 {
+		st.check(m, "opening balance", e.date, e.employee, e.employer)
+		return
+	}
+	m.entries = append(m.entries, e)
+}
+
+// check warns when the entries of a member don't add up to a balance the passbook states
+func (st *Statement) check(m *Member, what string, date time.Time, employee, employer float64) {
+	var ee, er float64
+	for _, e := range m.entries {
+		ee += e.employee
+		er += e.employer
+	}
+	if math.Abs(ee-employee) > 1 || math.Abs(er-employer) > 1 {
+		st.warn("%s: %s on %s is %s/%s but the entries add up to %s/%s", m.MemberID, what,
+			date.Format(models.EPFDateLayout), persona.Rupees(employee), persona.Rupees(employer), persona.Rupees(ee), persona.Rupees(er))
+	}
+}
+
+// Merge combines passbooks that may cover the same member ids, e.g. the
+// passbooks of two financial years. An entry found in several passbooks is kept
+// once, and so is the opening balance of the earliest of them.
+func Merge(statements ...*Statement) *Statement {
+	out := &Statement{}
+	for _, st := range statements {
+		out.Warnings = append(out.Warnings, st.Warnings...)
+		if st.AsOf.After(out.AsOf) {
+			out.AsOf = st.AsOf
+		}
+		for _, m := range st.Members {
+			into := out.member(m.MemberID)
+			into.entries = mergeEntries(into.entries, m.entries)
+			for _, f := range []struct{ to, from *string }{
+				{&into.UAN, &m.UAN}, {&into.EstID, &m.EstID}, {&into.EstName, &m.EstName}, {&into.Office, &m.Office},
+			} {
+				if *f.from != "" {
+					*f.to = *f.from
+				}
+			}
+			if !m.Joined.IsZero() {
+				into.Joined = m.Joined
+			}
+			if !m.Exited.IsZero() {
+				into.Exited = m.Exited
+			}
+		}
+	}
+	for _, m := range out.Members {
+		m.sort()
+	}
+	return out
+}
+
+// mergeEntries adds the entries of b that a doesn't have, matched one to one
+func mergeEntries(a, b []entry) []entry {
+	type key struct {
+		kind, month                 string
+		date                        time.Time
+		employee, employer, pension float64
+	}
+	count := map[key]int{}
+	for _, e := range a {
+		count[key{e.kind, e.month, e.date, e.employee, e.employer, e.pension}]++
+	}
+	for _, e := range b {
+		if k := (key{e.kind, e.month, e.date, e.employee, e.employer, e.pension}); count[k] > 0 {
+			count[k]--
+			continue
+		}
+		a = append(a, e)
+	}
+	return a
+}
+
+// sort orders the entries by date and drops the opening balances that the
+// entries before them already account for
+func (m *Member) sort() {
+	sort.SliceStable(m.entries, func(i, j int) bool { return m.entries[i].date.Before(m.entries[j].date) })
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.kind == models.EPFEntryOpening && len(kept) > 0 {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.entries = kept
+}
+
+// Details builds the EPF details, one UAN account per UAN with its member ids in
+// the order they were joined. Without the dates in the passbook, a member id is
+// taken to be joined in the month of its first contribution and left at the end
+// of its last one when the balance was transferred out or another member id has
+// contributions at least two months later.
+func (st *Statement) Details() *models.EPFDetailsResponse {
+	var latest string
+	for _, m := range st.Members {
+		if last := m.lastContribution(); last > latest {
+			latest = last
+		}
+	}
+	resp := &models.EPFDetailsResponse{}
+	index := map[string]int{}
+	for _, m := range st.Members {
+		est, employee, employer := m.establishment(latest)
+		i, ok := index[m.UAN]
+		if !ok {
+			i = len(resp.UANAccounts)
+			index[m.UAN] = i
+			resp.UANAccounts = append(resp.UANAccounts, models.UANAccount{PhoneNumber: json.RawMessage(`{}`)})
+		}
+		raw := &resp.UANAccounts[i].RawDetails
+		raw.EstDetails = append(raw.EstDetails, est)
+		total := &raw.OverallPFBalance
+		total.PensionBalance = add(total.PensionBalance, m.sum(func(e entry) float64 { return e.pension }))
+		total.CurrentPFBalance = add(total.CurrentPFBalance, employee.balance+employer.balance)
+		total.EmployeeShareTotal = models.EPFShare{Credit: add(total.EmployeeShareTotal.Credit, employee.credit), Balance: add(total.EmployeeShareTotal.Balance, employee.balance)}
+		total.EmployerShareTotal = models.EPFShare{Credit: add(total.EmployerShareTotal.Credit, employer.credit), Balance: add(total.EmployerShareTotal.Balance, employer.balance)}
+	}
+	for i := range resp.UANAccounts {
+		est := resp.UANAccounts[i].RawDetails.EstDetails
+		sort.SliceStable(est, func(a, b int) bool {
+			ja, _ := est[a].JoinDate()
+			jb, _ := est[b].JoinDate()
+			return ja.Before(jb)
+		})
+	}
+	return resp
+}
+
+// share is what was credited to the employee or employer share and what is left
+type share struct{ credit, balance float64 }
+
+func (m *Member) establishment(latest string) (models.EPFEstablishment, share, share) {
+	var employee, employer share
+	est := models.EPFEstablishment{
+		EstName:  m.EstName,
+		MemberID: m.MemberID,
+		Office:   m.Office,
+		DOJEPF:   models.EPFDateNotAvailable,
+		DOEEPF:   models.EPFDateNotAvailable,
+		Passbook: []models.EPFPassbookEntry{},
+	}
+	var transferred bool
+	for _, e := range m.entries {
+		switch e.kind {
+		case models.EPFEntryOpening, models.EPFEntryContribution, models.EPFEntryTransferIn:
+			employee.credit += e.employee
+			employer.credit += e.employer
+		case models.EPFEntryTransferOut:
+			transferred = true
+		}
+		employee.balance += e.employee
+		employer.balance += e.employer
+		est.Passbook = append(est.Passbook, models.EPFPassbookEntry{
+			Month: e.month, Date: e.date.Format(models.EPFDateLayout), Type: e.kind, Particulars: e.particulars,
+			EmployeeShare: persona.Rupees(e.employee), EmployerShare: persona.Rupees(e.employer), PensionShare: persona.Rupees(e.pension),
+		})
+	}
+	est.PFBalance = models.EPFPFBalance{
+		NetBalance:    persona.Rupees(employee.balance + employer.balance),
+		EmployeeShare: models.EPFShare{Credit: persona.Rupees(employee.credit), Balance: persona.Rupees(employee.balance)},
+		EmployerShare: models.EPFShare{Credit: persona.Rupees(employer.credit), Balance: persona.Rupees(employer.balance)},
+	}
+
+	first, last := m.firstContribution(), m.lastContribution()
+	switch {
+	case !m.Joined.IsZero():
+		est.DOJEPF = m.Joined.Format(models.EPFDateLayout)
+	case first != "":
+		t, _ := time.Parse(monthLayout, first)
+		est.DOJEPF = t.Format(models.EPFDateLayout)
+	}
+	switch {
+	case !m.Exited.IsZero():
+		est.DOEEPF = m.Exited.Format(models.EPFDateLayout)
+	case last != "" && (transferred || monthsBefore(last, latest) >= 2):
+		t, _ := time.Parse(monthLayout, last)
+		est.DOEEPF = t.AddDate(0, 1, -1).Format(models.EPFDateLayout)
+	}
+	est.DOEEPS = est.DOEEPF
+	return est, employee, employer
+}
+
+func (m *Member) firstContribution() string {
+	for _, e := range m.entries {
+		if e.kind == models.EPFEntryContribution {
+			return e.month
+		}
+	}
+	return ""
+}
+
+func (m *Member) lastContribution() string {
+	var last string
+	for _, e := range m.entries {
+		if e.kind == models.EPFEntryContribution && e.month > last {
+			last = e.month
+		}
+	}
+	return last
+}
+
+func (m *Member) sum(f func(entry) float64) float64 {
+	var total float64
+	for _, e := range m.entries {
+		total += f(e)
+	}
+	return total
+}
+
+// monthLayout is the layout of the wage month of passbook entries
+const monthLayout = "2006-01"
+
+// monthsBefore counts the months from the wage month a to the wage month b
+func monthsBefore(a, b string) int {
+	ta, errA := time.Parse(monthLayout, a)
+	tb, errB := time.Parse(monthLayout, b)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return (tb.Year()-ta.Year())*12 + int(tb.Month()-ta.Month())
+}
+
+// add adds v to an amount of the overall balance
+func add(amount string, v float64) string {
+	return persona.Rupees(models.ParseEPFAmount(amount) + v)
+}
@@ -0,0 +1,139 @@
+package epfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/persona"
+	"github.com/epifi/fi-mcp-lite/pkg/validate"
+)
+
+func read(t *testing.T, files ...string) *Statement {
+	t.Helper()
+	var statements []*Statement
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		statements = append(statements, st)
+	}
+	return Merge(statements...)
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		file     string
+		entries  int
+		employee float64
+	}{
+		// the opening balance, 12 contributions, the transfer in and the interest
+		{"passbook_2023.txt", 15, 236925},
+		// the opening balance, 14 contributions, the interest and the transfer out
+		{"passbook_prev.csv", 17, 0},
+	} {
+		st := read(t, tc.file)
+		if len(st.Warnings) != 0 {
+			t.Errorf("%s: warnings %q", tc.file, st.Warnings)
+		}
+		if len(st.Members) != 1 {
+			t.Fatalf("%s: %d members", tc.file, len(st.Members))
+		}
+		m := st.Members[0]
+		if m.UAN != "100123456789" || m.Joined.IsZero() {
+			t.Errorf("%s: member %+v", tc.file, m)
+		}
+		if len(m.entries) != tc.entries {
+			t.Errorf("%s: %d entries, want %d", tc.file, len(m.entries), tc.entries)
+		}
+		if got := m.sum(func(e entry) float64 { return e.employee }); got != tc.employee {
+			t.Errorf("%s: employee share %v, want %v", tc.file, got, tc.employee)
+		}
+	}
+}
+
+func TestParseWarnsOnMismatchedBalances(t *testing.T) {
+	data := []byte(`Member ID/Name : MHBAN00123450000012345 / TEST USER
+OB Int. Updated upto 31/03/2023   1,000   500   0
+Apr-2023  15-05-2023  CR  Cont. For Due-Month 042023  6,000  4,750  1,250
+Closing Balance as on 31/03/2024   8,000   5,250   1,250
+`)
+	st, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Warnings) != 1 {
+		t.Errorf("Warnings = %q, want the closing balance", st.Warnings)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	// the current year twice, as when a passbook is downloaded again
+	st := read(t, "passbook_prev.csv", "passbook_2024.txt", "passbook_2023.txt", "passbook_2024.txt")
+	resp := st.Details()
+	if len(resp.UANAccounts) != 1 {
+		t.Fatalf("%d UAN accounts", len(resp.UANAccounts))
+	}
+	raw := resp.UANAccounts[0].RawDetails
+	if len(raw.EstDetails) != 2 {
+		t.Fatalf("%d establishments", len(raw.EstDetails))
+	}
+	prev, cur := raw.EstDetails[0], raw.EstDetails[1]
+	if prev.EstName != "NEXGEN SOFTWARE SOLUTIONS" || prev.DOJEPF != "15-06-2019" || prev.DOEEPF != "31-05-2022" || prev.PFBalance.NetBalance != "0" {
+		t.Errorf("previous employer %+v", prev)
+	}
+	if cur.Office != "BANDRA" || cur.DOJEPF != "10-06-2022" || cur.DOEEPF != models.EPFDateNotAvailable {
+		t.Errorf("current employer %+v", cur)
+	}
+	// the opening balance of 2024-25 is dropped, the 2023-24 entries account for it
+	if n := len(cur.Passbook); n != 18 {
+		t.Errorf("%d passbook entries, want 18", n)
+	}
+	if b := cur.PFBalance; b.EmployeeShare.Balance != "256725" || b.EmployerShare.Balance != "185390" || b.NetBalance != "442115" {
+		t.Errorf("pf_balance %+v", b)
+	}
+	if o := raw.OverallPFBalance; o.CurrentPFBalance != "442115" || o.PensionBalance != "54750" {
+		t.Errorf("overall_pf_balance %+v", o)
+	}
+	if got := st.AsOf.Format(models.DateLayout); got != "2024-07-15" {
+		t.Errorf("AsOf = %s", got)
+	}
+}
+
+func TestSetEPF(t *testing.T) {
+	dir := t.TempDir()
+	f, err := persona.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := read(t, "passbook_prev.csv", "passbook_2023.txt", "passbook_2024.txt")
+	f.SetEPF(st.Details(), st.AsOf)
+	if err := f.WriteTools(dir, "fetch_epf_details", "fetch_net_worth"); err != nil {
+		t.Fatal(err)
+	}
+	violations, err := validate.Persona(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		t.Errorf("violation: %s", v)
+	}
+	f, err = persona.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range f.NetWorth.NetWorthResponse.AssetValues {
+		if v.NetWorthAttribute == "ASSET_TYPE_EPF" && v.Value.Float() != 442115 {
+			t.Errorf("EPF asset = %v", v.Value.Float())
+		}
+	}
+	if _, ok := f.EPF.UANAccounts[0].RawDetails.EstDetails[1].Passbook[1].Time(); !ok {
+		t.Error("passbook entry without a date")
+	}
+}
@@ -0,0 +1,366 @@
+package epfo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// The header lines of a passbook. The separators allow for the cells of a CSV.
+var (
+	estRe    = regexp.MustCompile(`(?i)establishment\s*id\s*/\s*name[\s:,]*([A-Z0-9]{10,})\s*/\s*([^,]+?)[\s,]*$`)
+	memberRe = regexp.MustCompile(`(?i)member\s*id\s*/\s*name[\s:,]*([A-Z0-9]{10,})`)
+	uanRe    = regexp.MustCompile(`(?i)\bUAN[\s:,]*(\d{12})\b`)
+	joinRe   = regexp.MustCompile(`(?i)date\s*of\s*joining[^0-9]*(\d{2}[-/]\d{2}[-/]\d{4})`)
+	exitRe   = regexp.MustCompile(`(?i)date\s*of\s*exit[^0-9]*(\d{2}[-/]\d{2}[-/]\d{4})`)
+	officeRe = regexp.MustCompile(`(?i)^[\s,]*(?:regional\s*)?office[\s:,]+([A-Z][^,]*?)[\s,]*$`)
+)
+
+// The rows of a passbook converted with pdftotext -layout: the wage month,
+// the date, CR or DR, the particulars and the amounts, of which the last three
+// are the employee, employer and pension shares after the wages.
+var (
+	rowRe    = regexp.MustCompile(`^\s*([A-Za-z]{3}[- ]\d{4}|\d{6}|\d{2}/\d{4}|\d{4}-\d{2})\s+(\d{2}[-/]\d{2}[-/]\d{4})\s+(CR|DR)\s+(.*?)((?:\s+-?[\d,]+(?:\.\d+)?){3,5})\s*$`)
+	amountRe = regexp.MustCompile(`-?[\d,]+(?:\.\d+)?`)
+	// the yearly lines that carry their date in the particulars
+	openingRe  = regexp.MustCompile(`(?i)^\s*OB\s*Int\.?\s*Updated\s*up\s*to\s*(\d{2}[-/]\d{2}[-/]\d{4})`)
+	interestRe = regexp.MustCompile(`(?i)^\s*Int\.?\s*Updated\s*up\s*to\s*(\d{2}[-/]\d{2}[-/]\d{4})`)
+	closingRe  = regexp.MustCompile(`(?i)^\s*Closing\s*Balance\s*(?:as\s*on)?\s*(\d{2}[-/]\d{2}[-/]\d{4})`)
+)
+
+var dateLayouts = []string{models.EPFDateLayout, "02/01/2006", models.DateLayout}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", s)
+}
+
+var monthLayouts = []string{"Jan-2006", "Jan 2006", "012006", "01/2006", monthLayout}
+
+// parseMonth converts a wage month to YYYY-MM
+func parseMonth(s string) (string, error) {
+	for _, layout := range monthLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(monthLayout), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a wage month", s)
+}
+
+// parseAmount reads a passbook amount, blank being zero
+func parseAmount(s string) (float64, error) {
+	s = strings.NewReplacer(",", "", "₹", "", " ", "").Replace(s)
+	if s == "" || s == "-" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// kind tells the entry type of a row from its particulars and CR or DR, ""
+// being the totals and balances that are not entries
+func kind(particulars, crdr string) string {
+	p := strings.ToUpper(particulars)
+	switch {
+	case openingRe.MatchString(particulars), strings.Contains(p, "OPENING BALANCE"):
+		return models.EPFEntryOpening
+	case interestRe.MatchString(particulars), strings.HasPrefix(p, "INTEREST"):
+		return models.EPFEntryInterest
+	case strings.HasPrefix(p, "TOTAL"), closingRe.MatchString(particulars):
+		return ""
+	}
+	debit := strings.EqualFold(crdr, "DR")
+	switch {
+	case strings.Contains(p, "TRANSFER") || strings.Contains(p, "TRF"):
+		if debit {
+			return models.EPFEntryTransferOut
+		}
+		return models.EPFEntryTransferIn
+	case debit:
+		return models.EPFEntryWithdrawal
+	}
+	return models.EPFEntryContribution
+}
+
+// parser reads a passbook line by line. The header lines name the member id the
+// rows after them belong to; a passbook of several years repeats them.
+type parser struct {
+	st     *Statement
+	header Member
+	member *Member
+	// columns maps the fields of a CSV row to their column, nil until the header row
+	columns map[string]int
+	orphans int
+}
+
+// Parse reads a passbook
+func Parse(data []byte) (*Statement, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	p := &parser{st: &Statement{}}
+	for i, line := range strings.Split(string(data), "\n") {
+		if err := p.line(strings.TrimRight(line, "\r")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	if p.orphans > 0 {
+		p.st.warn("%d rows before the Member ID line skipped", p.orphans)
+	}
+	if len(p.st.Members) == 0 {
+		return nil, errors.New("no Member ID/Name line found")
+	}
+	for _, m := range p.st.Members {
+		m.sort()
+	}
+	return p.st, nil
+}
+
+func (p *parser) line(line string) error {
+	if strings.TrimSpace(strings.Trim(line, ",")) == "" {
+		return nil
+	}
+	if p.headerLine(line) {
+		return nil
+	}
+	if p.columns == nil && strings.Contains(line, ",") {
+		if columns := csvColumns(line); columns != nil {
+			p.columns = columns
+			return nil
+		}
+	}
+	if p.columns != nil {
+		return p.csvRow(line)
+	}
+	return p.textRow(line)
+}
+
+// headerLine applies a header line to the header and the current member
+func (p *parser) headerLine(line string) bool {
+	set := func(apply func(m *Member)) bool {
+		apply(&p.header)
+		if p.member != nil {
+			apply(p.member)
+		}
+		return true
+	}
+	date := func(re *regexp.Regexp) time.Time {
+		t, _ := parseDate(re.FindStringSubmatch(line)[1])
+		return t
+	}
+	switch {
+	case estRe.MatchString(line):
+		m := estRe.FindStringSubmatch(line)
+		p.header = Member{EstID: m[1], EstName: strings.TrimSpace(m[2]), UAN: p.header.UAN}
+		p.member = nil
+		return true
+	case memberRe.MatchString(line):
+		p.member = p.st.member(memberRe.FindStringSubmatch(line)[1])
+		h := p.header
+		return set(func(m *Member) {
+			for _, f := range []struct{ to, from *string }{{&m.UAN, &h.UAN}, {&m.EstID, &h.EstID}, {&m.EstName, &h.EstName}, {&m.Office, &h.Office}} {
+				if *f.from != "" {
+					*f.to = *f.from
+				}
+			}
+			if !h.Joined.IsZero() {
+				m.Joined = h.Joined
+			}
+			if !h.Exited.IsZero() {
+				m.Exited = h.Exited
+			}
+		})
+	case uanRe.MatchString(line) && !rowRe.MatchString(line):
+		uan := uanRe.FindStringSubmatch(line)[1]
+		return set(func(m *Member) { m.UAN = uan })
+	case joinRe.MatchString(line):
+		t := date(joinRe)
+		set(func(m *Member) { m.Joined = t })
+		if exitRe.MatchString(line) {
+			t := date(exitRe)
+			set(func(m *Member) { m.Exited = t })
+		}
+		return true
+	case exitRe.MatchString(line):
+		t := date(exitRe)
+		return set(func(m *Member) { m.Exited = t })
+	case officeRe.MatchString(line):
+		office := strings.TrimSpace(officeRe.FindStringSubmatch(line)[1])
+		return set(func(m *Member) { m.Office = office })
+	}
+	return false
+}
+
+func (p *parser) textRow(line string) error {
+	if m := rowRe.FindStringSubmatch(line); m != nil {
+		month, err := parseMonth(strings.ReplaceAll(m[1], " ", "-"))
+		if err != nil {
+			return err
+		}
+		date, err := parseDate(m[2])
+		if err != nil {
+			return err
+		}
+		shares := amountRe.FindAllString(m[5], -1)
+		return p.entry(month, date, m[3], strings.TrimSpace(m[4]), shares[len(shares)-3:])
+	}
+	for _, re := range []*regexp.Regexp{openingRe, interestRe, closingRe} {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		date, err := parseDate(m[1])
+		if err != nil {
+			return err
+		}
+		rest := strings.TrimSpace(line[len(m[0]):])
+		shares := amountRe.FindAllString(rest, -1)
+		if len(shares) < 2 {
+			return fmt.Errorf("%s: want the employee and employer shares", strings.TrimSpace(m[0]))
+		}
+		if len(shares) == 2 {
+			shares = append(shares, "0")
+		}
+		return p.entry("", date, "CR", strings.TrimSpace(m[0]), shares[len(shares)-3:])
+	}
+	// page headers, totals and everything else that is not a row
+	return nil
+}
+
+// csvFields are the column names of a CSV passbook by key
+var csvFields = map[string]string{
+	"wagemonth": "month", "month": "month",
+	"transactiondate": "date", "date": "date", "txndate": "date",
+	"transactiontype": "type", "type": "type", "crdr": "type",
+	"particulars": "particulars", "description": "particulars", "narration": "particulars",
+	"employeeshare": "employee", "employee": "employee", "eeshare": "employee", "employeecontribution": "employee",
+	"employershare": "employer", "employer": "employer", "ershare": "employer", "employercontribution": "employer",
+	"pension": "pension", "pensionshare": "pension", "pensioncontribution": "pension", "epsshare": "pension",
+	"memberid": "member", "uan": "uan",
+}
+
+// csvColumns returns the columns of a CSV header row, nil when the line has no
+// particulars or shares
+func csvColumns(line string) map[string]int {
+	cells, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil
+	}
+	columns := map[string]int{}
+	for i, c := range cells {
+		k := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(c))
+		if f, ok := csvFields[k]; ok {
+			if _, dup := columns[f]; !dup {
+				columns[f] = i
+			}
+		}
+	}
+	for _, f := range []string{"particulars", "employee", "employer"} {
+		if _, ok := columns[f]; !ok {
+			return nil
+		}
+	}
+	return columns
+}
+
+func (p *parser) csvRow(line string) error {
+	r := csv.NewReader(strings.NewReader(line))
+	r.FieldsPerRecord = -1
+	cells, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("invalid CSV: %w", err)
+	}
+	cell := func(f string) string {
+		if i, ok := p.columns[f]; ok && i < len(cells) {
+			return strings.TrimSpace(cells[i])
+		}
+		return ""
+	}
+	if id := cell("member"); id != "" {
+		p.member = p.st.member(id)
+		if p.member.UAN == "" {
+			p.member.UAN = p.header.UAN
+		}
+	}
+	if uan := cell("uan"); uan != "" && p.member != nil {
+		p.member.UAN = uan
+	}
+
+	particulars := cell("particulars")
+	var month string
+	var date time.Time
+	if m := cell("month"); m != "" {
+		if month, err = parseMonth(m); err != nil {
+			return err
+		}
+	}
+	if d := cell("date"); d != "" {
+		if date, err = parseDate(d); err != nil {
+			return err
+		}
+	} else {
+		for _, re := range []*regexp.Regexp{openingRe, interestRe, closingRe} {
+			if m := re.FindStringSubmatch(particulars); m != nil {
+				if date, err = parseDate(m[1]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if date.IsZero() {
+		if kind(particulars, cell("type")) == "" {
+			return nil
+		}
+		return fmt.Errorf("%q has no date", particulars)
+	}
+	return p.entry(month, date, cell("type"), particulars, []string{cell("employee"), cell("employer"), cell("pension")})
+}
+
+// entry adds a row to the current member, the shares being the employee,
+// employer and pension amounts as printed
+func (p *parser) entry(month string, date time.Time, crdr, particulars string, shares []string) error {
+	var amounts [3]float64
+	for i, s := range shares {
+		v, err := parseAmount(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an amount", s)
+		}
+		amounts[i] = v
+	}
+	if p.member == nil {
+		p.orphans++
+		return nil
+	}
+	if closingRe.MatchString(particulars) {
+		p.st.check(p.member, "closing balance", date, amounts[0], amounts[1])
+		return nil
+	}
+	k := kind(particulars, crdr)
+	if k == "" {
+		return nil
+	}
+	if k == models.EPFEntryTransferOut || k == models.EPFEntryWithdrawal {
+		for i := range amounts {
+			amounts[i] = -math.Abs(amounts[i])
+		}
+	}
+	if month == "" {
+		month = date.Format(monthLayout)
+	}
+	p.st.add(p.member, entry{month: month, kind: k, particulars: particulars, date: date, employee: amounts[0], employer: amounts[1], pension: amounts[2]})
+	return nil
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// WriteCSV writes a sheet as CSV with its header row. Dates are YYYY-MM-DD and
+// numbers have no thousands separators.
+func WriteCSV(w io.Writer, s Sheet) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(s.Columns))
+	for _, row := range s.Rows {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = text(row[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// text formats a cell for CSV
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(models.DateLayout)
+	}
+	return ""
+}
@@ -0,0 +1,376 @@
+// Package export flattens the responses of the data tools into sheets with a
+// header and typed columns, for CSV or XLSX files: the positional txns arrays
+// become one row per transaction, the accounts and holdings of
+// accountDetailsBulkResponse one row per account and holding, and the credit
+// report one row per tradeline.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/credit"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Column types
+const (
+	Text    = "text"
+	Number  = "number"
+	Integer = "integer"
+	Date    = "date"
+	Bool    = "bool"
+)
+
+// Column is the header and type of a sheet column
+type Column struct {
+	Name string
+	Type string
+}
+
+// Sheet is a table of rows. A cell holds a string, float64, int, time.Time or
+// bool as its column type says, or nil when the value is missing.
+type Sheet struct {
+	Name    string
+	Columns []Column
+	Rows    [][]any
+}
+
+// sheets flatten the response of a data tool
+var sheets = map[string]func(data []byte) ([]Sheet, error){
+	"fetch_bank_transactions":  bankSheets,
+	"fetch_mf_transactions":    mfSheets,
+	"fetch_stock_transactions": stockSheets,
+	"fetch_net_worth":          netWorthSheets,
+	"fetch_credit_report":      creditSheets,
+}
+
+// Tools returns the data tools that can be exported
+func Tools() []string {
+	tools := make([]string, 0, len(sheets))
+	for tool := range sheets {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// Supported tells whether the response of a tool can be exported
+func Supported(tool string) bool {
+	return sheets[tool] != nil
+}
+
+// Sheets flattens the response of a data tool
+func Sheets(tool string, data []byte) ([]Sheet, error) {
+	fn := sheets[tool]
+	if fn == nil {
+		return nil, fmt.Errorf("%s can't be exported, only %s", tool, strings.Join(Tools(), ", "))
+	}
+	return fn(data)
+}
+
+// Find returns the sheet with the name, or the first sheet when name is empty
+func Find(sheets []Sheet, name string) (Sheet, error) {
+	var names []string
+	for _, s := range sheets {
+		if name == "" || s.Name == name {
+			return s, nil
+		}
+		names = append(names, s.Name)
+	}
+	return Sheet{}, fmt.Errorf("no sheet %q, the sheets are %s", name, strings.Join(names, ", "))
+}
+
+// Labels of the type codes in the txns arrays
+var (
+	bankTxnTypes = map[int]string{
+		models.BankTxnTypeCredit:      "CREDIT",
+		models.BankTxnTypeDebit:       "DEBIT",
+		models.BankTxnTypeOpening:     "OPENING",
+		models.BankTxnTypeInterest:    "INTEREST",
+		models.BankTxnTypeTDS:         "TDS",
+		models.BankTxnTypeInstallment: "INSTALLMENT",
+		models.BankTxnTypeClosing:     "CLOSING",
+		models.BankTxnTypeOthers:      "OTHERS",
+	}
+	mfOrderTypes = map[int]string{
+		models.MFOrderTypeBuy:  "BUY",
+		models.MFOrderTypeSell: "SELL",
+	}
+	stockTxnTypes = map[int]string{
+		models.StockTxnTypeBuy:   "BUY",
+		models.StockTxnTypeSell:  "SELL",
+		models.StockTxnTypeBonus: "BONUS",
+		models.StockTxnTypeSplit: "SPLIT",
+	}
+)
+
+func label(labels map[int]string, code int) string {
+	if l, ok := labels[code]; ok {
+		return l
+	}
+	return fmt.Sprintf("UNKNOWN_%d", code)
+}
+
+func bankSheets(data []byte) ([]Sheet, error) {
+	var resp models.BankTransactionsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	s := Sheet{Name: "bank_transactions", Columns: []Column{
+		{"Bank", Text}, {"Date", Date}, {"Narration", Text}, {"Type", Text}, {"Mode", Text}, {"Amount", Number}, {"Balance", Number},
+	}}
+	for _, a := range resp.BankTransactions {
+		for _, t := range a.Txns {
+			var balance any
+			if v, ok := t.BalanceValue(); ok {
+				balance = v
+			}
+			s.Rows = append(s.Rows, []any{a.Bank, date(t.Date), t.Narration, label(bankTxnTypes, t.Type), t.Mode, t.AmountValue(), balance})
+		}
+	}
+	return []Sheet{s}, nil
+}
+
+func mfSheets(data []byte) ([]Sheet, error) {
+	var resp models.MFTransactionsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	s := Sheet{Name: "mf_transactions", Columns: []Column{
+		{"ISIN", Text}, {"Scheme", Text}, {"Folio", Text}, {"Date", Date}, {"Type", Text}, {"NAV", Number}, {"Units", Number}, {"Amount", Number},
+	}}
+	for _, m := range resp.MFTransactions {
+		for _, t := range m.Txns {
+			s.Rows = append(s.Rows, []any{m.ISIN, m.SchemeName, m.FolioID, date(t.Date), label(mfOrderTypes, t.OrderType), t.Price, t.Units, t.Amount})
+		}
+	}
+	return []Sheet{s}, nil
+}
+
+func stockSheets(data []byte) ([]Sheet, error) {
+	var resp models.StockTransactionsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	s := Sheet{Name: "stock_transactions", Columns: []Column{
+		{"ISIN", Text}, {"Date", Date}, {"Type", Text}, {"Quantity", Number}, {"Price", Number},
+	}}
+	for _, st := range resp.StockTransactions {
+		for _, t := range st.Txns {
+			s.Rows = append(s.Rows, []any{st.ISIN, date(t.Date), label(stockTxnTypes, t.Type), t.Quantity, deref(t.NAV)})
+		}
+	}
+	return []Sheet{s}, nil
+}
+
+func netWorthSheets(data []byte) ([]Sheet, error) {
+	var resp models.FetchNetWorthResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	values := Sheet{Name: "net_worth", Columns: []Column{{"Kind", Text}, {"Attribute", Text}, {"Value", Number}}}
+	if r := resp.NetWorthResponse; r != nil {
+		for _, v := range r.AssetValues {
+			values.Rows = append(values.Rows, []any{"ASSET", v.NetWorthAttribute, v.Value.Float()})
+		}
+		for _, v := range r.LiabilityValues {
+			values.Rows = append(values.Rows, []any{"LIABILITY", v.NetWorthAttribute, v.Value.Float()})
+		}
+		if r.TotalNetWorthValue != nil {
+			values.Rows = append(values.Rows, []any{"TOTAL", "", r.TotalNetWorthValue.Float()})
+		}
+	}
+
+	accounts := Sheet{Name: "accounts", Columns: []Column{
+		{"Account ID", Text}, {"Instrument", Text}, {"FIP", Text}, {"Institution", Text}, {"Masked Number", Text},
+		{"Account Type", Text}, {"Status", Text}, {"Value", Number}, {"Balance Date", Date}, {"Opening Date", Date}, {"Maturity Date", Date},
+	}}
+	holdings := Sheet{Name: "holdings", Columns: []Column{
+		{"Account ID", Text}, {"Instrument", Text}, {"ISIN", Text}, {"Name", Text}, {"Ticker", Text}, {"Folio", Text},
+		{"Units", Number}, {"Price", Number}, {"Value", Number}, {"NAV Date", Date},
+	}}
+	bulk := resp.AccountDetailsBulkResponse
+	for _, id := range bulk.AccountIDs() {
+		entry := bulk.AccountDetailsMap[id]
+		d := entry.AccountDetails
+		s := summary(entry)
+		var institution string
+		if d.FipMeta != nil {
+			institution = first(d.FipMeta.DisplayName, d.FipMeta.Name, d.FipMeta.Bank)
+		}
+		var value any
+		var status, balanceDate, opened, matures string
+		if s != nil {
+			value = s.Value()
+			status = first(s.AccountStatus, s.DepositAccountStatus, s.LoanStatus)
+			balanceDate, opened, matures = s.BalanceDate, s.OpeningDate, s.MaturityDate
+		}
+		accounts.Rows = append(accounts.Rows, []any{
+			id, d.AccInstrumentType, d.FipID, institution, d.MaskedAccountNumber,
+			accountType(d.AccountType), status, value, date(balanceDate), date(opened), date(matures),
+		})
+		if s == nil {
+			continue
+		}
+		for _, h := range s.HoldingsInfo {
+			var price, value any
+			if p := h.Price(); p != 0 {
+				price, value = p, models.Round(p*h.Quantity(), 2)
+			}
+			holdings.Rows = append(holdings.Rows, []any{
+				id, d.AccInstrumentType, h.ISIN, h.Name(), h.Ticker, h.FolioNumber, h.Quantity(), price, value, date(h.LastNAVDate),
+			})
+		}
+	}
+
+	schemes := Sheet{Name: "mf_schemes", Columns: []Column{
+		{"ISIN", Text}, {"Scheme", Text}, {"AMC", Text}, {"Category", Text}, {"Plan", Text}, {"Option", Text}, {"Asset Class", Text},
+		{"Units", Number}, {"NAV", Number}, {"Invested Value", Number}, {"Current Value", Number}, {"XIRR", Number},
+	}}
+	if a := resp.MFSchemeAnalytics; a != nil {
+		for _, sa := range a.SchemeAnalytics {
+			d, v := sa.SchemeDetail, sa.EnrichedAnalytics.Analytics.SchemeDetails
+			nav := v.NAVValue
+			if nav == nil {
+				nav = d.NAV
+			}
+			schemes.Rows = append(schemes.Rows, []any{
+				d.ISINNumber, d.NameData.LongName, d.AMC, d.CategoryName, d.PlanType, d.OptionType, d.AssetClass,
+				v.Units, money(nav), money(v.InvestedValue), money(v.CurrentValue), v.XIRR,
+			})
+		}
+	}
+	return []Sheet{values, accounts, holdings, schemes}, nil
+}
+
+func creditSheets(data []byte) ([]Sheet, error) {
+	var resp models.CreditReportResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	s := Sheet{Name: "credit_accounts", Columns: []Column{
+		{"Report Date", Date}, {"Lender", Text}, {"Account Type", Text}, {"Account Type Code", Text}, {"Portfolio Type", Text},
+		{"Status", Text}, {"State", Text}, {"Secured", Bool}, {"Open Date", Date}, {"Date Reported", Date}, {"Date Closed", Date},
+		{"Sanctioned Amount", Number}, {"Credit Limit", Number}, {"Current Balance", Number}, {"Amount Past Due", Number},
+		{"Utilisation %", Number}, {"Interest Rate %", Number}, {"Tenure Months", Integer},
+		{"Months Reported", Integer}, {"Months Delinquent", Integer}, {"Max DPD", Integer},
+	}}
+	for _, r := range resp.CreditReports {
+		d := r.CreditReportData
+		reported := date(d.CreditProfileHeader.ReportDate)
+		for _, detail := range d.CreditAccount.CreditAccountDetails {
+			a := credit.DecodeAccount(detail)
+			h := a.PaymentHistorySummary
+			s.Rows = append(s.Rows, []any{
+				reported, a.Lender, a.AccountType.Label, a.AccountType.Code, a.PortfolioType.Label,
+				a.Status.Label, string(a.State), a.Secured, date(a.OpenDate), date(a.DateReported), date(a.DateClosed),
+				a.SanctionedAmount, deref(a.CreditLimit), a.CurrentBalance, a.AmountPastDue,
+				deref(a.UtilisationPercent), deref(a.InterestRatePercent), deref(a.RepaymentTenureMonths),
+				h.MonthsReported, h.MonthsDelinquent, h.MaxDPD,
+			})
+		}
+	}
+	return []Sheet{s}, nil
+}
+
+// summary returns the summary of an account, whichever one its type uses
+func summary(e models.AccountDetailsEntry) *models.AccountSummary {
+	for _, s := range []*models.AccountSummary{
+		e.DepositSummary, e.RecurringDepositSummary, e.EquitySummary, e.ETFSummary, e.REITSummary, e.InvITSummary,
+		e.MutualFundSummary, e.SGBSummary, e.NPSSummary, e.EPFSummary, e.CreditCardSummary, e.LoanSummary,
+	} {
+		if s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// accountType joins the values of an accountType map in key order
+func accountType(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+	return strings.Join(values, ", ")
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// money returns an amount, or nil for an empty cell
+func money(m *models.Money) any {
+	if m == nil {
+		return nil
+	}
+	return m.Float()
+}
+
+// deref returns what p points to, or nil for an empty cell
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// dateLayouts are the date formats found across the tool responses
+var dateLayouts = []string{models.DateLayout, time.RFC3339, models.BureauDateLayout, models.EPFDateLayout}
+
+// date parses a date of any of the responses. An empty date is an empty cell
+// and one that doesn't parse is kept as text.
+func date(s string) any {
+	if s == "" {
+		return nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return s
+}
+
+// Formats of Write
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ContentTypes are the media types of the formats
+var ContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Write writes the sheets in a format. A CSV holds one sheet, the one named
+// sheet or else the first; an XLSX workbook holds them all.
+func Write(w io.Writer, format string, sheets []Sheet, sheet string) error {
+	switch format {
+	case FormatCSV:
+		s, err := Find(sheets, sheet)
+		if err != nil {
+			return err
+		}
+		return WriteCSV(w, s)
+	case FormatXLSX:
+		return WriteXLSX(w, sheets)
+	}
+	return fmt.Errorf("unknown format %q, want %s or %s", format, FormatCSV, FormatXLSX)
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSheets(t *testing.T) {
+	bank := `{"bankTransactions": [{"bank": "Test Bank", "txns": [
+		["1000", "SALARY", "2024-01-01", 1, "NEFT", "1000"],
+		["200", "UPI-GROCER", "2024-01-02", 2, "UPI", ""]
+	]}]}`
+	sheets, err := Sheets("fetch_bank_transactions", []byte(bank))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]any{
+		{"Test Bank", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "SALARY", "CREDIT", "NEFT", 1000.0, 1000.0},
+		{"Test Bank", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "UPI-GROCER", "DEBIT", "UPI", 200.0, nil},
+	}
+	if !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("rows = %v, want %v", sheets[0].Rows, want)
+	}
+
+	netWorth := `{"accountDetailsBulkResponse": {"accountDetailsMap": {"demat1": {
+		"accountDetails": {"accInstrumentType": "ACC_INSTRUMENT_TYPE_EQUITIES", "fipMeta": {"displayName": "CDSL"}},
+		"equitySummary": {"currentValue": {"units": "2500"}, "holdingsInfo": [
+			{"isin": "INE000000001", "issuerName": "Alpha Ltd", "units": 10, "lastTradedPrice": {"units": "250"}}
+		]}
+	}}}}`
+	sheets, err = Sheets("fetch_net_worth", []byte(netWorth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, _ := Find(sheets, "accounts")
+	holdings, _ := Find(sheets, "holdings")
+	if len(accounts.Rows) != 1 || accounts.Rows[0][3] != "CDSL" || accounts.Rows[0][7] != 2500.0 {
+		t.Errorf("accounts = %v", accounts.Rows)
+	}
+	if len(holdings.Rows) != 1 || holdings.Rows[0][3] != "Alpha Ltd" || holdings.Rows[0][8] != 2500.0 {
+		t.Errorf("holdings = %v", holdings.Rows)
+	}
+	if _, err := Find(sheets, "nope"); err == nil {
+		t.Error("Find: expected an error for a missing sheet")
+	}
+	if _, err := Sheets("fetch_epf_details", []byte(`{}`)); err == nil {
+		t.Error("Sheets: expected an error for a tool without sheets")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	s := Sheet{Columns: []Column{{"Date", Date}, {"Narration", Text}, {"Amount", Number}, {"Secured", Bool}}, Rows: [][]any{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "RENT, JAN", 1500.5, true},
+		{nil, "", nil, nil},
+	}}
+	if err := WriteCSV(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Date,Narration,Amount,Secured\n2024-01-01,\"RENT, JAN\",1500.5,true\n,,,\n"; b.String() != want {
+		t.Errorf("CSV = %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var b bytes.Buffer
+	sheets := []Sheet{
+		{Name: "first", Columns: []Column{{"Date", Date}, {"Name", Text}, {"Amount", Number}, {"Months", Integer}}, Rows: [][]any{
+			{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "A & B <co>", 12.5, 3},
+		}},
+		{Name: "second", Columns: []Column{{"Empty", Text}}},
+	}
+	if err := WriteXLSX(&b, sheets); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+		// every part must be well formed
+		d := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A2" s="2"><v>45292</v></c>`,
+		`A &amp; B &lt;co&gt;`,
+		`<c r="C2"><v>12.5</v></c>`,
+		`<c r="D2"><v>3</v></c>`,
+		`<autoFilter ref="A1:D2"/>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1.xml lacks %s", want)
+		}
+	}
+	if got := column(27); got != "AB" {
+		t.Errorf("column(27) = %s", got)
+	}
+}
+
+// TestTestDataDir exports every persona in test_data_dir
+func TestTestDataDir(t *testing.T) {
+	dirs, err := os.ReadDir("../../test_data_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		for _, tool := range Tools() {
+			data, err := os.ReadFile(filepath.Join("../../test_data_dir", dir.Name(), tool+".json"))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			sheets, err := Sheets(tool, data)
+			if err != nil {
+				t.Errorf("%s/%s: %v", dir.Name(), tool, err)
+				continue
+			}
+			for _, s := range sheets {
+				for i, row := range s.Rows {
+					if len(row) != len(s.Columns) {
+						t.Errorf("%s/%s: %s row %d has %d cells for %d columns", dir.Name(), tool, s.Name, i, len(row), len(s.Columns))
+					}
+				}
+				if err := WriteCSV(io.Discard, s); err != nil {
+					t.Errorf("%s/%s: %v", dir.Name(), tool, err)
+				}
+			}
+			if err := WriteXLSX(io.Discard, sheets); err != nil {
+				t.Errorf("%s/%s: %v", dir.Name(), tool, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package goal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/allocation"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const (
+	// DefaultInflation is the yearly inflation applied to the goal amount in percent
+	DefaultInflation = 6
+	// DefaultSimulations is the number of Monte Carlo runs
+	DefaultSimulations = 10000
+	// DefaultSeed makes the simulation repeatable unless another seed is given
+	DefaultSeed = 42
+
+	maxHorizonYears = 50
+	maxSimulations  = 100000
+)
+
+// RiskProfile sets the return and volatility assumed for the goal portfolio
+type RiskProfile string
+
+const (
+	RiskConservative RiskProfile = "conservative"
+	RiskModerate     RiskProfile = "moderate"
+	RiskAggressive   RiskProfile = "aggressive"
+)
+
+// RiskProfiles lists the profiles from least to most risky
+var RiskProfiles = []RiskProfile{RiskConservative, RiskModerate, RiskAggressive}
+
+// Assumption is the yearly expected return and volatility of a risk profile in percent
+type Assumption struct {
+	ExpectedReturnPercent float64 `json:"expectedReturnPercent"`
+	VolatilityPercent     float64 `json:"volatilityPercent"`
+	Portfolio             string  `json:"portfolio"`
+}
+
+var assumptions = map[RiskProfile]Assumption{
+	RiskConservative: {ExpectedReturnPercent: 7, VolatilityPercent: 4, Portfolio: "mostly debt funds and deposits, up to 20% equity"},
+	RiskModerate:     {ExpectedReturnPercent: 10, VolatilityPercent: 11, Portfolio: "about 60% equity and 40% debt"},
+	RiskAggressive:   {ExpectedReturnPercent: 12, VolatilityPercent: 16, Portfolio: "mostly equity"},
+}
+
+// ParseRiskProfile accepts a risk profile name in any case, defaulting to moderate when empty
+func ParseRiskProfile(s string) (RiskProfile, error) {
+	if s == "" {
+		return RiskModerate, nil
+	}
+	for _, p := range RiskProfiles {
+		if strings.EqualFold(s, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown risk profile %q, expected conservative, moderate or aggressive", s)
+}
+
+// Goal describes what the user saves for
+type Goal struct {
+	// Amount is the cost of the goal in today's money
+	Amount           float64
+	HorizonYears     float64
+	InflationPercent float64
+	Risk             RiskProfile
+	Simulations      int
+	Seed             int64
+}
+
+// Validate checks the goal is within the supported ranges
+func (g Goal) Validate() error {
+	switch {
+	case g.Amount <= 0:
+		return fmt.Errorf("goal amount must be positive")
+	case g.HorizonYears < 1.0/12 || g.HorizonYears > maxHorizonYears:
+		return fmt.Errorf("horizon must be between one month and %d years", maxHorizonYears)
+	case g.InflationPercent < 0 || g.InflationPercent > 20:
+		return fmt.Errorf("inflation must be between 0 and 20 percent")
+	case g.Simulations < 100 || g.Simulations > maxSimulations:
+		return fmt.Errorf("simulations must be between 100 and %d", maxSimulations)
+	}
+	if _, ok := assumptions[g.Risk]; !ok {
+		return fmt.Errorf("unknown risk profile %q", g.Risk)
+	}
+	return nil
+}
+
+// Asset is a holding considered for the goal
+type Asset struct {
+	Name      string            `json:"name"`
+	Attribute string            `json:"netWorthAttribute"`
+	Bucket    allocation.Bucket `json:"bucket"`
+	Value     float64           `json:"value"`
+	// Earmarked is the part of the value set aside for the goal
+	Earmarked float64 `json:"earmarked"`
+	Reason    string  `json:"reason"`
+}
+
+// Confidence is the monthly SIP reaching the goal in a share of the simulated runs
+type Confidence struct {
+	SuccessPercent float64 `json:"successPercent"`
+	MonthlySIP     float64 `json:"monthlySip"`
+}
+
+// Simulation is the outcome of the Monte Carlo runs
+type Simulation struct {
+	Runs int   `json:"runs"`
+	Seed int64 `json:"seed"`
+	// SuccessPercent is the share of runs reaching the goal with the required monthly SIP
+	SuccessPercent float64 `json:"successPercent"`
+	// SuccessPercentWithExistingSIPs is the share of runs reaching the goal if the active SIPs were redirected to it
+	SuccessPercentWithExistingSIPs float64      `json:"successPercentWithExistingSips"`
+	SIPForConfidence               []Confidence `json:"sipForConfidence"`
+	// CorpusP10, CorpusP50 and CorpusP90 are percentiles of the corpus at the horizon with the required monthly SIP
+	CorpusP10 float64 `json:"corpusP10"`
+	CorpusP50 float64 `json:"corpusP50"`
+	CorpusP90 float64 `json:"corpusP90"`
+}
+
+// Plan is how a goal can be reached
+type Plan struct {
+	GoalAmount       float64     `json:"goalAmount"`
+	HorizonYears     float64     `json:"horizonYears"`
+	InflationPercent float64     `json:"inflationPercent"`
+	FutureGoalAmount float64     `json:"futureGoalAmount"`
+	RiskProfile      RiskProfile `json:"riskProfile"`
+	Assumption
+	EarmarkedAssets    []Asset `json:"earmarkedAssets"`
+	OtherAssets        []Asset `json:"otherAssets"`
+	EarmarkedValue     float64 `json:"earmarkedValue"`
+	ProjectedEarmarked float64 `json:"projectedEarmarkedValue"`
+	// RequiredMonthlySIP reaches the goal if the expected return is earned every month
+	RequiredMonthlySIP float64 `json:"requiredMonthlySip"`
+	ExistingSIPs       []SIP   `json:"existingSips"`
+	ExistingMonthlySIP float64 `json:"existingMonthlySip"`
+	// AdditionalMonthlySIP is the required SIP left after redirecting the active SIPs to the goal
+	AdditionalMonthlySIP float64    `json:"additionalMonthlySip"`
+	Simulation           Simulation `json:"simulation"`
+	Notes                []string   `json:"notes"`
+}
+
+// lockedAttributes can't be withdrawn before retirement
+var lockedAttributes = map[string]bool{
+	"ASSET_TYPE_EPF": true,
+	"ASSET_TYPE_NPS": true,
+}
+
+// Build plans the goal with the investable assets in the net worth response and
+// the SIPs in the mutual fund transactions. Assets suited to the horizon are
+// earmarked until their projected value covers the goal, and the remainder is met
+// with a monthly SIP. Earmarked assets and the SIP are assumed to earn the return
+// of the risk profile.
+func Build(g Goal, netWorth *models.FetchNetWorthResponse, mf *models.MFTransactionsResponse) (*Plan, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	risk := effectiveRisk(g.HorizonYears, g.Risk)
+	a := assumptions[risk]
+	months := int(math.Round(g.HorizonYears * 12))
+	p := &Plan{
+		GoalAmount:       models.Round(g.Amount, 2),
+		HorizonYears:     g.HorizonYears,
+		InflationPercent: g.InflationPercent,
+		RiskProfile:      risk,
+		Assumption:       a,
+		EarmarkedAssets:  []Asset{},
+		OtherAssets:      []Asset{},
+		Notes:            []string{},
+	}
+	target := g.Amount * math.Pow(1+g.InflationPercent/100, g.HorizonYears)
+	p.FutureGoalAmount = models.Round(target, 2)
+
+	growth := math.Pow(1+a.ExpectedReturnPercent/100, float64(months)/12)
+	if risk != g.Risk {
+		p.Notes = append(p.Notes, fmt.Sprintf("a goal %v years away is planned with the %s risk profile instead of %s, short horizons leave no time to recover from a fall in equity", g.HorizonYears, risk, g.Risk))
+	}
+	p.EarmarkedAssets, p.OtherAssets = earmark(allocation.Analyze(netWorth, nil).Holdings, g.HorizonYears, risk, target/growth)
+	var earmarked float64
+	for _, asset := range p.EarmarkedAssets {
+		earmarked += asset.Earmarked
+	}
+	p.EarmarkedValue = models.Round(earmarked, 2)
+	p.ProjectedEarmarked = models.Round(earmarked*growth, 2)
+
+	p.RequiredMonthlySIP = models.Round(requiredSIP(target, earmarked, months, a.ExpectedReturnPercent/100), 2)
+	p.ExistingSIPs = DetectSIPs(mf)
+	var existing float64
+	for _, s := range p.ExistingSIPs {
+		if s.Active {
+			existing += s.MonthlyAmount
+		}
+	}
+	p.ExistingMonthlySIP = models.Round(existing, 2)
+	p.AdditionalMonthlySIP = models.Round(math.Max(0, p.RequiredMonthlySIP-existing), 2)
+
+	sim := simulate(earmarked, months, a.ExpectedReturnPercent/100, a.VolatilityPercent/100, g.Simulations, g.Seed)
+	p.Simulation = Simulation{
+		Runs:                           g.Simulations,
+		Seed:                           g.Seed,
+		SuccessPercent:                 models.Round(sim.successRate(target, p.RequiredMonthlySIP)*100, 2),
+		SuccessPercentWithExistingSIPs: models.Round(sim.successRate(target, existing)*100, 2),
+		CorpusP10:                      models.Round(sim.percentile(p.RequiredMonthlySIP, 0.1), 2),
+		CorpusP50:                      models.Round(sim.percentile(p.RequiredMonthlySIP, 0.5), 2),
+		CorpusP90:                      models.Round(sim.percentile(p.RequiredMonthlySIP, 0.9), 2),
+	}
+	for _, share := range []float64{0.5, 0.75, 0.9} {
+		p.Simulation.SIPForConfidence = append(p.Simulation.SIPForConfidence, Confidence{
+			SuccessPercent: share * 100,
+			MonthlySIP:     models.Round(math.Ceil(sim.sipForSuccess(target, share)), 2),
+		})
+	}
+
+	if p.RequiredMonthlySIP == 0 {
+		p.Notes = append(p.Notes, "the earmarked assets cover the goal at the expected return without a SIP, sipForConfidence has the SIP that makes success more likely")
+	}
+	if existing > 0 {
+		p.Notes = append(p.Notes, "existing SIPs may already fund other goals, additionalMonthlySip assumes all active SIPs are redirected to this one")
+	}
+	for _, asset := range p.EarmarkedAssets {
+		if asset.Bucket == allocation.BucketCash {
+			p.Notes = append(p.Notes, "savings account balances are earmarked, keep an emergency fund outside this goal")
+			break
+		}
+	}
+	return p, nil
+}
+
+// requiredSIP is the monthly SIP, invested at the start of each month, that grows
+// with the present value to the target at the annual rate
+func requiredSIP(target, presentValue float64, months int, annualRate float64) float64 {
+	i := math.Pow(1+annualRate, 1.0/12) - 1
+	growth := math.Pow(1+i, float64(months))
+	shortfall := target - presentValue*growth
+	if shortfall <= 0 {
+		return 0
+	}
+	if i == 0 {
+		return shortfall / float64(months)
+	}
+	return shortfall / ((growth - 1) / i * (1 + i))
+}
+
+// effectiveRisk caps the risk profile of short goals, which can't wait out a fall in equity
+func effectiveRisk(horizonYears float64, risk RiskProfile) RiskProfile {
+	switch {
+	case horizonYears < 3:
+		return RiskConservative
+	case horizonYears < 5 && risk == RiskAggressive:
+		return RiskModerate
+	}
+	return risk
+}
+
+// suitableBuckets lists the buckets that fit a horizon and risk profile, most suitable first
+func suitableBuckets(horizonYears float64, risk RiskProfile) []allocation.Bucket {
+	switch {
+	case horizonYears < 3:
+		return []allocation.Bucket{allocation.BucketDebt, allocation.BucketCash}
+	case horizonYears < 7 && risk == RiskConservative, horizonYears < 5:
+		return []allocation.Bucket{allocation.BucketDebt, allocation.BucketGold, allocation.BucketCash}
+	case horizonYears < 7:
+		return []allocation.Bucket{allocation.BucketDebt, allocation.BucketEquity, allocation.BucketGold, allocation.BucketInternational, allocation.BucketCash}
+	}
+	return []allocation.Bucket{allocation.BucketEquity, allocation.BucketInternational, allocation.BucketRealEstate, allocation.BucketGold, allocation.BucketDebt, allocation.BucketCash}
+}
+
+// earmark sets aside the holdings suited to the goal, most suitable first, until
+// they reach needed, the present value of the goal at the expected return
+func earmark(holdings []allocation.Holding, horizonYears float64, risk RiskProfile, needed float64) ([]Asset, []Asset) {
+	rank := make(map[allocation.Bucket]int)
+	for i, b := range suitableBuckets(horizonYears, risk) {
+		rank[b] = i + 1
+	}
+	var candidates []Asset
+	others := []Asset{}
+	for _, h := range holdings {
+		asset := Asset{Name: h.Name, Attribute: h.Attribute, Bucket: h.Bucket, Value: h.Value}
+		switch {
+		case h.Value <= 0:
+			continue
+		case lockedAttributes[h.Attribute]:
+			asset.Reason = "locked until retirement"
+		case rank[h.Bucket] == 0:
+			asset.Reason = fmt.Sprintf("%s assets don't suit a goal %v years away with the %s risk profile", strings.ToLower(string(h.Bucket)), horizonYears, risk)
+		default:
+			candidates = append(candidates, asset)
+			continue
+		}
+		others = append(others, asset)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return rank[candidates[i].Bucket] < rank[candidates[j].Bucket] })
+	earmarked := []Asset{}
+	for _, c := range candidates {
+		if needed <= 0 {
+			c.Reason = "not needed, the goal is covered by the assets above"
+			others = append(others, c)
+			continue
+		}
+		c.Earmarked = models.Round(math.Min(c.Value, needed), 2)
+		needed -= c.Earmarked
+		c.Reason = fmt.Sprintf("%s assets suit a goal %v years away with the %s risk profile", strings.ToLower(string(c.Bucket)), horizonYears, risk)
+		earmarked = append(earmarked, c)
+	}
+	return earmarked, others
+}
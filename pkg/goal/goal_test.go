@@ -0,0 +1,80 @@
+package goal
+
+import (
+	"math"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func TestRequiredSIP(t *testing.T) {
+	// with no volatility every run earns the expected return, so the required SIP reaches the target exactly
+	sip := requiredSIP(1000000, 100000, 60, 0.10)
+	p := simulate(100000, 60, 0.10, 0, 10, 1)
+	if got := p.lumpSum[0] + sip*p.perRupee[0]; math.Abs(got-1000000) > 0.01 {
+		t.Errorf("corpus with the required SIP = %v, want 1000000", got)
+	}
+	if got := requiredSIP(100000, 100000, 12, 0.10); got != 0 {
+		t.Errorf("requiredSIP with the target already covered = %v, want 0", got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	value := func(amount float64) *models.Money {
+		m := models.NewMoney(amount)
+		return &m
+	}
+	netWorth := &models.FetchNetWorthResponse{NetWorthResponse: &models.NetWorthResponse{AssetValues: []models.NetWorthValue{
+		{NetWorthAttribute: "ASSET_TYPE_SAVINGS_ACCOUNTS", Value: value(200000)},
+		{NetWorthAttribute: "ASSET_TYPE_EPF", Value: value(900000)},
+		{NetWorthAttribute: "ASSET_TYPE_INDIAN_SECURITIES", Value: value(300000)},
+	}}}
+	mf := &models.MFTransactionsResponse{MFTransactions: []models.MFSchemeTransactions{{
+		SchemeName: "Flexi Cap Fund", ISIN: "INF000000001", FolioID: "1",
+		Txns: []models.MFTxn{
+			{OrderType: models.MFOrderTypeBuy, Date: "2024-01-05", Amount: 5000},
+			{OrderType: models.MFOrderTypeBuy, Date: "2024-02-05", Amount: 5000},
+			{OrderType: models.MFOrderTypeBuy, Date: "2024-03-05", Amount: 5000},
+			{OrderType: models.MFOrderTypeBuy, Date: "2024-03-20", Amount: 50000},
+		},
+	}}}
+	g := Goal{Amount: 2000000, HorizonYears: 2, InflationPercent: 6, Risk: RiskAggressive, Simulations: 2000, Seed: 7}
+	p, err := Build(g, netWorth, mf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.RiskProfile != RiskConservative {
+		t.Errorf("risk profile = %s, want conservative for a 2 year goal", p.RiskProfile)
+	}
+	if len(p.EarmarkedAssets) != 1 || p.EarmarkedAssets[0].Attribute != "ASSET_TYPE_SAVINGS_ACCOUNTS" || p.EarmarkedValue != 200000 {
+		t.Errorf("earmarked = %+v, want only the savings accounts", p.EarmarkedAssets)
+	}
+	if len(p.ExistingSIPs) != 1 || p.ExistingSIPs[0].MonthlyAmount != 5000 || !p.ExistingSIPs[0].Active || p.ExistingMonthlySIP != 5000 {
+		t.Errorf("existing SIPs = %+v", p.ExistingSIPs)
+	}
+	if p.AdditionalMonthlySIP != models.Round(p.RequiredMonthlySIP-5000, 2) {
+		t.Errorf("additional SIP = %v, required %v", p.AdditionalMonthlySIP, p.RequiredMonthlySIP)
+	}
+	confidence := p.Simulation.SIPForConfidence
+	if len(confidence) != 3 || confidence[0].MonthlySIP > confidence[1].MonthlySIP || confidence[1].MonthlySIP > confidence[2].MonthlySIP {
+		t.Errorf("SIP for confidence = %+v", confidence)
+	}
+
+	again, _ := Build(g, netWorth, mf)
+	if again.Simulation.SuccessPercent != p.Simulation.SuccessPercent || again.Simulation.CorpusP50 != p.Simulation.CorpusP50 {
+		t.Errorf("simulation with the same seed differs: %+v vs %+v", again.Simulation, p.Simulation)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, g := range []Goal{
+		{Amount: 0, HorizonYears: 5, Risk: RiskModerate, Simulations: 1000},
+		{Amount: 100, HorizonYears: 60, Risk: RiskModerate, Simulations: 1000},
+		{Amount: 100, HorizonYears: 5, Risk: "yolo", Simulations: 1000},
+		{Amount: 100, HorizonYears: 5, Risk: RiskModerate, Simulations: 10},
+	} {
+		if err := g.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", g)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package goal
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// paths holds the outcome of every simulated run. With the same market returns the
+// corpus at the horizon is linear in the monthly SIP, so each run is kept as
+// lumpSum + sip*perRupee and any SIP can be evaluated without simulating again.
+type paths struct {
+	lumpSum  []float64
+	perRupee []float64
+}
+
+// simulate draws monthly log-normal returns with the given annual expected return
+// and volatility. SIPs are invested at the start of each month.
+func simulate(presentValue float64, months int, expectedReturn, volatility float64, runs int, seed int64) paths {
+	rng := rand.New(rand.NewSource(seed))
+	sigma := volatility / math.Sqrt(12)
+	// the drift is set so that the mean monthly growth compounds to the expected annual return
+	mu := math.Log(1+expectedReturn)/12 - sigma*sigma/2
+	p := paths{lumpSum: make([]float64, runs), perRupee: make([]float64, runs)}
+	for r := 0; r < runs; r++ {
+		lumpSum, perRupee := presentValue, 0.0
+		for m := 0; m < months; m++ {
+			growth := math.Exp(mu + sigma*rng.NormFloat64())
+			lumpSum *= growth
+			perRupee = (perRupee + 1) * growth
+		}
+		p.lumpSum[r], p.perRupee[r] = lumpSum, perRupee
+	}
+	return p
+}
+
+// successRate is the share of runs reaching the target with the monthly SIP
+func (p paths) successRate(target, sip float64) float64 {
+	var hits int
+	for r := range p.lumpSum {
+		if p.lumpSum[r]+sip*p.perRupee[r] >= target {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(p.lumpSum))
+}
+
+// sipForSuccess returns the smallest monthly SIP reaching the target in the given
+// share of runs
+func (p paths) sipForSuccess(target, share float64) float64 {
+	needed := make([]float64, len(p.lumpSum))
+	for r := range p.lumpSum {
+		needed[r] = math.Max(0, (target-p.lumpSum[r])/p.perRupee[r])
+	}
+	sort.Float64s(needed)
+	i := int(math.Ceil(share*float64(len(needed)))) - 1
+	return needed[max(i, 0)]
+}
+
+// percentile returns the corpus at the horizon below which the given share of runs end
+func (p paths) percentile(sip, share float64) float64 {
+	values := make([]float64, len(p.lumpSum))
+	for r := range p.lumpSum {
+		values[r] = p.lumpSum[r] + sip*p.perRupee[r]
+	}
+	sort.Float64s(values)
+	i := int(math.Ceil(share*float64(len(values)))) - 1
+	return values[max(i, 0)]
+}
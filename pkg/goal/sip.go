@@ -0,0 +1,98 @@
+package goal
+
+import (
+	"sort"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// minSIPMonths is the number of consecutive months with a purchase that make a SIP
+const minSIPMonths = 3
+
+// SIP is a monthly investment detected in the mutual fund transactions
+type SIP struct {
+	SchemeName string `json:"schemeName"`
+	ISIN       string `json:"isin"`
+	FolioID    string `json:"folioId"`
+	// MonthlyAmount is the smallest purchase of the latest month, which leaves out lump sums made alongside the SIP
+	MonthlyAmount   float64 `json:"monthlyAmount"`
+	Months          int     `json:"months"`
+	FirstInstalment string  `json:"firstInstalment"`
+	LastInstalment  string  `json:"lastInstalment"`
+	// Active is false when the last instalment is more than a month older than the latest transaction
+	Active bool `json:"active"`
+}
+
+// DetectSIPs finds the schemes bought in at least three consecutive months up to
+// their latest purchase. The transactions end at different dates for each user, so
+// a SIP is active when its last instalment is in the month of the latest
+// transaction or the one before.
+func DetectSIPs(resp *models.MFTransactionsResponse) []SIP {
+	sips := []SIP{}
+	if resp == nil {
+		return sips
+	}
+	var latest time.Time
+	for _, s := range resp.MFTransactions {
+		for _, t := range s.Txns {
+			if d, err := t.Time(); err == nil && d.After(latest) {
+				latest = d
+			}
+		}
+	}
+	for _, s := range resp.MFTransactions {
+		byMonth := make(map[int][]models.MFTxn)
+		for _, t := range s.Txns {
+			d, err := t.Time()
+			if err != nil || t.OrderType != models.MFOrderTypeBuy {
+				continue
+			}
+			byMonth[monthIndex(d)] = append(byMonth[monthIndex(d)], t)
+		}
+		if len(byMonth) < minSIPMonths {
+			continue
+		}
+		months := make([]int, 0, len(byMonth))
+		for m := range byMonth {
+			months = append(months, m)
+		}
+		sort.Ints(months)
+		last := months[len(months)-1]
+		first := last
+		for i := len(months) - 2; i >= 0 && months[i] == first-1; i-- {
+			first = months[i]
+		}
+		if last-first+1 < minSIPMonths {
+			continue
+		}
+		firstTxns, lastTxns := sortedByDate(byMonth[first]), byMonth[last]
+		amount := lastTxns[0].Amount
+		lastDate := lastTxns[0].Date
+		for _, t := range lastTxns {
+			amount = min(amount, t.Amount)
+			lastDate = max(lastDate, t.Date)
+		}
+		sips = append(sips, SIP{
+			SchemeName:      s.SchemeName,
+			ISIN:            s.ISIN,
+			FolioID:         s.FolioID,
+			MonthlyAmount:   models.Round(amount, 2),
+			Months:          last - first + 1,
+			FirstInstalment: firstTxns[0].Date,
+			LastInstalment:  lastDate,
+			Active:          monthIndex(latest)-last <= 1,
+		})
+	}
+	sort.SliceStable(sips, func(i, j int) bool { return sips[i].MonthlyAmount > sips[j].MonthlyAmount })
+	return sips
+}
+
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+func sortedByDate(txns []models.MFTxn) []models.MFTxn {
+	sort.Slice(txns, func(i, j int) bool { return txns[i].Date < txns[j].Date })
+	return txns
+}
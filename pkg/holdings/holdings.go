@@ -0,0 +1,118 @@
+package holdings
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Position is the holding of one ISIN reconstructed from its stock transactions
+// using the average cost method
+type Position struct {
+	ISIN          string   `json:"isin"`
+	Name          string   `json:"name"`
+	Quantity      float64  `json:"quantity"`
+	AverageCost   float64  `json:"averageCost"`
+	InvestedValue float64  `json:"investedValue"`
+	RealisedPnL   float64  `json:"realisedPnl"`
+	LastPrice     float64  `json:"lastPrice"`
+	PriceSource   string   `json:"priceSource"`
+	CurrentValue  float64  `json:"currentValue"`
+	UnrealisedPnL float64  `json:"unrealisedPnl"`
+	TxnCount      int      `json:"txnCount"`
+	FirstTxnDate  string   `json:"firstTxnDate,omitempty"`
+	LastTxnDate   string   `json:"lastTxnDate,omitempty"`
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+// Price sources of a position
+const (
+	PriceSourceHoldings    = "holdingsInfo"
+	PriceSourceTransaction = "transaction"
+	PriceSourceUnavailable = "unavailable"
+)
+
+// Reconstruct replays the BUY, SELL, BONUS and SPLIT events of each ISIN in date order.
+// Buys add to the invested value, sells realise the difference to the average cost,
+// and bonus and split units are added at zero cost, lowering the average cost.
+func Reconstruct(txns *models.StockTransactionsResponse, accounts *models.AccountDetailsBulkResponse) ([]Position, error) {
+	prices := make(map[string]models.Holding)
+	for _, h := range accounts.DematHoldings() {
+		if _, ok := prices[h.ISIN]; !ok {
+			prices[h.ISIN] = h.Holding
+		}
+	}
+
+	var positions []Position
+	for _, stock := range txns.StockTransactions {
+		sorted := make([]models.StockTxn, len(stock.Txns))
+		copy(sorted, stock.Txns)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date < sorted[j].Date })
+
+		p := Position{ISIN: stock.ISIN, Name: prices[stock.ISIN].Name(), TxnCount: len(sorted)}
+		var quantity, cost, lastTxnPrice float64
+		for _, txn := range sorted {
+			if _, err := txn.Time(); err != nil {
+				return nil, fmt.Errorf("%s: %w", stock.ISIN, err)
+			}
+			if p.FirstTxnDate == "" {
+				p.FirstTxnDate = txn.Date
+			}
+			p.LastTxnDate = txn.Date
+			if txn.Price() > 0 {
+				lastTxnPrice = txn.Price()
+			}
+			switch txn.Type {
+			case models.StockTxnTypeBuy:
+				if txn.NAV == nil {
+					p.Warnings = append(p.Warnings, fmt.Sprintf("buy of %v on %s has no price, added at zero cost", txn.Quantity, txn.Date))
+				}
+				quantity += txn.Quantity
+				cost += txn.Quantity * txn.Price()
+			case models.StockTxnTypeSell:
+				sold := txn.Quantity
+				if sold > quantity {
+					p.Warnings = append(p.Warnings, fmt.Sprintf("sell of %v on %s exceeds the %v held", txn.Quantity, txn.Date, models.Round(quantity, 4)))
+					sold = quantity
+				}
+				if txn.NAV == nil {
+					p.Warnings = append(p.Warnings, fmt.Sprintf("sell of %v on %s has no price, left out of the realised P&L", txn.Quantity, txn.Date))
+				}
+				if quantity > 0 {
+					avg := cost / quantity
+					if txn.NAV != nil {
+						p.RealisedPnL += sold * (txn.Price() - avg)
+					}
+					cost -= sold * avg
+				}
+				quantity -= sold
+			case models.StockTxnTypeBonus, models.StockTxnTypeSplit:
+				quantity += txn.Quantity
+			default:
+				p.Warnings = append(p.Warnings, fmt.Sprintf("unknown transaction type %d on %s ignored", txn.Type, txn.Date))
+			}
+		}
+
+		p.Quantity = models.Round(quantity, 4)
+		p.InvestedValue = models.Round(cost, 2)
+		if quantity > 0 {
+			p.AverageCost = models.Round(cost/quantity, 4)
+		}
+		p.RealisedPnL = models.Round(p.RealisedPnL, 2)
+		switch {
+		case prices[stock.ISIN].Price() > 0:
+			p.LastPrice, p.PriceSource = prices[stock.ISIN].Price(), PriceSourceHoldings
+		case lastTxnPrice > 0:
+			p.LastPrice, p.PriceSource = lastTxnPrice, PriceSourceTransaction
+		default:
+			p.PriceSource = PriceSourceUnavailable
+		}
+		if p.PriceSource != PriceSourceUnavailable {
+			p.CurrentValue = models.Round(quantity*p.LastPrice, 2)
+			p.UnrealisedPnL = models.Round(quantity*p.LastPrice-cost, 2)
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
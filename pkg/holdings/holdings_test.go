@@ -0,0 +1,74 @@
+package holdings
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func TestReconstructAndReconcile(t *testing.T) {
+	var txns models.StockTransactionsResponse
+	if err := json.Unmarshal([]byte(`{"stockTransactions":[
+		{"isin":"INE000000001","txns":[[2,"2024-03-01",10,130],[1,"2024-01-01",10,100],[1,"2024-02-01",10,120],[3,"2024-04-01",10]]},
+		{"isin":"INE000000002","txns":[[1,"2024-01-01",5,10],[1,"2024-01-01",5,10]]}
+	]}`), &txns); err != nil {
+		t.Fatal(err)
+	}
+	var accounts models.AccountDetailsBulkResponse
+	if err := json.Unmarshal([]byte(`{"accountDetailsMap":{
+		"a":{"accountDetails":{"accInstrumentType":"ACC_INSTRUMENT_TYPE_EQUITIES"},"equitySummary":{"holdingsInfo":[
+			{"isin":"INE000000001","units":20,"lastTradedPrice":{"units":"150"}},
+			{"isin":"INE000000003","units":7}]}},
+		"b":{"accountDetails":{"accInstrumentType":"ACC_INSTRUMENT_TYPE_EQUITIES"},"equitySummary":{"holdingsInfo":[
+			{"isin":"INE000000001","units":20}]}}
+	}}`), &accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	positions, err := Reconstruct(&txns, &accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// average cost of 110 before the sell, the bonus halves it
+	p := positions[0]
+	if p.Quantity != 20 || p.AverageCost != 55 || p.RealisedPnL != 200 || p.CurrentValue != 3000 || p.PriceSource != PriceSourceHoldings {
+		t.Errorf("position: got %+v", p)
+	}
+
+	kinds := make(map[string]int)
+	for _, m := range Reconcile(positions, &txns, &accounts).Mismatches {
+		kinds[m.Kind]++
+	}
+	want := map[string]int{
+		MismatchDuplicateTransactions: 1,
+		MismatchDuplicateHolding:      1,
+		MismatchNotInHoldings:         1,
+		MismatchNotInTransactions:     1,
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Errorf("%s: got %d mismatches, want %d", kind, kinds[kind], n)
+		}
+	}
+	if kinds[MismatchQuantity] != 0 {
+		t.Errorf("duplicated holding rows should be counted once, got %d quantity mismatches", kinds[MismatchQuantity])
+	}
+}
+
+func TestReconstructPricelessSell(t *testing.T) {
+	var txns models.StockTransactionsResponse
+	if err := json.Unmarshal([]byte(`{"stockTransactions":[
+		{"isin":"INE000000001","txns":[[1,"2024-01-01",10,100],[2,"2024-02-01",4]]}
+	]}`), &txns); err != nil {
+		t.Fatal(err)
+	}
+	positions, err := Reconstruct(&txns, &models.AccountDetailsBulkResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sale is taken out at the average cost without booking a loss
+	if p := positions[0]; p.Quantity != 6 || p.InvestedValue != 600 || p.RealisedPnL != 0 || len(p.Warnings) != 1 {
+		t.Errorf("position: got %+v", p)
+	}
+}
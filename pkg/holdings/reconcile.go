@@ -0,0 +1,187 @@
+package holdings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Kinds of reconciliation mismatches
+const (
+	// MismatchQuantity means the reconstructed quantity differs from the holdings
+	MismatchQuantity = "QUANTITY_MISMATCH"
+	// MismatchNotInHoldings means units are left after replaying the transactions but the ISIN is not held
+	MismatchNotInHoldings = "NOT_IN_HOLDINGS"
+	// MismatchNotInTransactions means the ISIN is held but has no transactions
+	MismatchNotInTransactions = "NOT_IN_TRANSACTIONS"
+	// MismatchDuplicateHolding means the same holding row appears more than once across the accounts
+	MismatchDuplicateHolding = "DUPLICATE_HOLDING_ROW"
+	// MismatchDuplicateTransactions means identical transaction rows are repeated for an ISIN
+	MismatchDuplicateTransactions = "DUPLICATE_TRANSACTION_ROWS"
+	// MismatchMissingISIN means a holding row has no ISIN and cannot be matched
+	MismatchMissingISIN = "MISSING_ISIN"
+)
+
+// Mismatch is a difference between the transactions and the holdings in accountDetailsBulkResponse
+type Mismatch struct {
+	Kind                   string   `json:"kind"`
+	ISIN                   string   `json:"isin,omitempty"`
+	AccountIDs             []string `json:"accountIds,omitempty"`
+	TransactionQuantity    *float64 `json:"transactionQuantity,omitempty"`
+	HoldingsQuantity       *float64 `json:"holdingsQuantity,omitempty"`
+	DedupedHoldingQuantity *float64 `json:"dedupedHoldingsQuantity,omitempty"`
+	Detail                 string   `json:"detail"`
+}
+
+// Reconciliation is the result of comparing positions with the holdings
+type Reconciliation struct {
+	Matched    []string   `json:"matched"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+const (
+	quantityTolerance          = 1e-6
+	usSecuritiesInstrumentType = "ACC_INSTRUMENT_TYPE_US_SECURITIES"
+)
+
+// Reconcile compares the reconstructed positions with the equity, ETF, REIT and InvIT holdings.
+// Holding rows repeated with the same units, within or across accounts, are reported and counted once
+// when comparing quantities.
+func Reconcile(positions []Position, txns *models.StockTransactionsResponse, accounts *models.AccountDetailsBulkResponse) Reconciliation {
+	result := Reconciliation{Matched: []string{}, Mismatches: []Mismatch{}}
+
+	for _, stock := range txns.StockTransactions {
+		counts := make(map[string]int)
+		var order []string
+		for _, txn := range stock.Txns {
+			key := txnKey(txn)
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+		for _, key := range order {
+			if counts[key] > 1 {
+				result.Mismatches = append(result.Mismatches, Mismatch{
+					Kind:   MismatchDuplicateTransactions,
+					ISIN:   stock.ISIN,
+					Detail: fmt.Sprintf("transaction %s appears %d times", key, counts[key]),
+				})
+			}
+		}
+	}
+
+	type held struct {
+		total, deduped float64
+		accounts       []string
+		rows           map[float64][]string
+	}
+	holdings := make(map[string]*held)
+	var heldOrder []string
+	for _, h := range accounts.DematHoldings() {
+		// fetch_stock_transactions only covers Indian stocks
+		if h.AccInstrumentType == usSecuritiesInstrumentType {
+			continue
+		}
+		if h.ISIN == "" {
+			result.Mismatches = append(result.Mismatches, Mismatch{
+				Kind:       MismatchMissingISIN,
+				AccountIDs: []string{h.AccountID},
+				Detail:     fmt.Sprintf("holding %q has no ISIN", h.Name()),
+			})
+			continue
+		}
+		entry, ok := holdings[h.ISIN]
+		if !ok {
+			entry = &held{rows: make(map[float64][]string)}
+			holdings[h.ISIN] = entry
+			heldOrder = append(heldOrder, h.ISIN)
+		}
+		qty := h.Quantity()
+		entry.total += qty
+		if len(entry.rows[qty]) == 0 {
+			entry.deduped += qty
+		}
+		entry.rows[qty] = append(entry.rows[qty], h.AccountID)
+		entry.accounts = append(entry.accounts, h.AccountID)
+	}
+	for _, isin := range heldOrder {
+		entry := holdings[isin]
+		quantities := make([]float64, 0, len(entry.rows))
+		for qty := range entry.rows {
+			quantities = append(quantities, qty)
+		}
+		sort.Float64s(quantities)
+		for _, qty := range quantities {
+			if ids := entry.rows[qty]; len(ids) > 1 {
+				result.Mismatches = append(result.Mismatches, Mismatch{
+					Kind:       MismatchDuplicateHolding,
+					ISIN:       isin,
+					AccountIDs: ids,
+					Detail:     fmt.Sprintf("holding of %v units appears %d times in accounts %s", qty, len(ids), strings.Join(ids, ", ")),
+				})
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range positions {
+		seen[p.ISIN] = true
+		txnQty := p.Quantity
+		entry, ok := holdings[p.ISIN]
+		if !ok {
+			if math.Abs(txnQty) > quantityTolerance {
+				result.Mismatches = append(result.Mismatches, Mismatch{
+					Kind:                MismatchNotInHoldings,
+					ISIN:                p.ISIN,
+					TransactionQuantity: &txnQty,
+					Detail:              fmt.Sprintf("transactions leave %v units but the ISIN is not in the holdings", txnQty),
+				})
+			} else {
+				result.Matched = append(result.Matched, p.ISIN)
+			}
+			continue
+		}
+		total, deduped := models.Round(entry.total, 4), models.Round(entry.deduped, 4)
+		if math.Abs(txnQty-total) <= quantityTolerance || math.Abs(txnQty-deduped) <= quantityTolerance {
+			result.Matched = append(result.Matched, p.ISIN)
+			continue
+		}
+		m := Mismatch{
+			Kind:                MismatchQuantity,
+			ISIN:                p.ISIN,
+			AccountIDs:          entry.accounts,
+			TransactionQuantity: &txnQty,
+			HoldingsQuantity:    &total,
+			Detail:              fmt.Sprintf("transactions leave %v units but the holdings show %v", txnQty, total),
+		}
+		if deduped != total {
+			m.DedupedHoldingQuantity = &deduped
+		}
+		result.Mismatches = append(result.Mismatches, m)
+	}
+	for _, isin := range heldOrder {
+		if seen[isin] {
+			continue
+		}
+		total := models.Round(holdings[isin].total, 4)
+		result.Mismatches = append(result.Mismatches, Mismatch{
+			Kind:             MismatchNotInTransactions,
+			ISIN:             isin,
+			AccountIDs:       holdings[isin].accounts,
+			HoldingsQuantity: &total,
+			Detail:           fmt.Sprintf("%v units are held but there are no transactions", total),
+		})
+	}
+	return result
+}
+
+func txnKey(txn models.StockTxn) string {
+	if txn.NAV == nil {
+		return fmt.Sprintf("[%d, %s, %v]", txn.Type, txn.Date, txn.Quantity)
+	}
+	return fmt.Sprintf("[%d, %s, %v, %v]", txn.Type, txn.Date, txn.Quantity, *txn.NAV)
+}
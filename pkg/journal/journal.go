@@ -0,0 +1,615 @@
+// Package journal turns the transactions and balances of a persona into a plain
+// text accounting journal for Ledger, hledger or Beancount, so that the data can
+// be replayed through those tools and their reports compared with the analytics.
+//
+// Bank rows post to Assets:Bank against Income and Expenses accounts, opened
+// with the balance implied by the currentBalance of the first row. Mutual fund
+// and stock transactions post units of a commodity named by the ISIN against
+// Equity:Transfers: the bank rows that paid for them are exported on their own,
+// so routing the cash legs through the bank accounts would count it twice. The
+// netWorthResponse values not covered by those transactions become opening
+// balances. Every ISIN gets a commodity definition and the NAVs of the
+// transactions, scheme analytics and holdings become price directives.
+package journal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Accounts shared by every persona
+const (
+	OpeningBalances = "Equity:Opening-Balances"
+	Transfers       = "Equity:Transfers"
+	CapitalGains    = "Income:Capital-Gains"
+	MutualFunds     = "Assets:Investments:Mutual-Funds"
+	Stocks          = "Assets:Investments:Stocks"
+)
+
+// Currency is the commodity of every amount that isn't a number of units
+const Currency = "INR"
+
+// attributeAccounts are the accounts of the netWorthResponse attributes
+var attributeAccounts = map[string]string{
+	"ASSET_TYPE_MUTUAL_FUND":       MutualFunds,
+	"ASSET_TYPE_EPF":               "Assets:Retirement:EPF",
+	"ASSET_TYPE_NPS":               "Assets:Retirement:NPS",
+	"ASSET_TYPE_INDIAN_SECURITIES": Stocks,
+	"ASSET_TYPE_US_SECURITIES":     "Assets:Investments:US-Securities",
+	"ASSET_TYPE_ETF":               "Assets:Investments:ETF",
+	"ASSET_TYPE_SGB":               "Assets:Investments:SGB",
+	"ASSET_TYPE_DEPOSITS":          "Assets:Deposits",
+	"ASSET_TYPE_SAVINGS_ACCOUNTS":  "Assets:Bank:Savings",
+	"LIABILITY_TYPE_HOME_LOAN":     "Liabilities:Loans:Home",
+	"LIABILITY_TYPE_VEHICLE_LOAN":  "Liabilities:Loans:Vehicle",
+	"LIABILITY_TYPE_OTHER_LOAN":    "Liabilities:Loans:Other",
+	"LIABILITY_TYPE_LOAN":          "Liabilities:Loans",
+	"LIABILITY_TYPE_CREDIT_CARD":   "Liabilities:Credit-Card",
+}
+
+// Meta is a key and value attached to a transaction, such as the JSON pointer of its source row
+type Meta struct {
+	Key   string
+	Value string
+}
+
+// Posting moves an amount of INR, or a number of units of an ISIN, into an account
+type Posting struct {
+	Account string
+	Amount  float64
+	// Commodity is the ISIN of the units, empty for INR
+	Commodity string
+	// TotalCost is what units added to the account cost in INR. They become a
+	// lot in Beancount.
+	TotalCost *float64
+	// TotalPrice is what units taken out of the account were sold for in INR.
+	// Beancount reduces the oldest lots and books the gain to CapitalGains.
+	TotalPrice *float64
+}
+
+// Transaction is a dated, balanced set of postings
+type Transaction struct {
+	Date      time.Time
+	Narration string
+	Meta      []Meta
+	Postings  []Posting
+}
+
+// Balance asserts the INR balance of an account at the end of a day
+type Balance struct {
+	Date    time.Time
+	Account string
+	Amount  float64
+}
+
+// Commodity is an ISIN and the name of its scheme or security
+type Commodity struct {
+	Symbol string
+	Name   string
+}
+
+// Price is the INR price of one unit of a commodity on a date
+type Price struct {
+	Date      time.Time
+	Commodity string
+	Value     float64
+}
+
+// Journal holds the entries of a persona in dated order
+type Journal struct {
+	// Title heads the journal, such as the persona it was built from
+	Title string
+	// Start is the date of the earliest entry, on which accounts are opened
+	Start        time.Time
+	AsOf         time.Time
+	Accounts     []string
+	Commodities  []Commodity
+	Prices       []Price
+	Transactions []Transaction
+	Balances     []Balance
+	Warnings     []string
+}
+
+// Sources are the tool responses of a persona, any of which may be nil
+type Sources struct {
+	Bank     *models.BankTransactionsResponse
+	MF       *models.MFTransactionsResponse
+	Stocks   *models.StockTransactionsResponse
+	NetWorth *models.FetchNetWorthResponse
+}
+
+// Options control how a journal is built
+type Options struct {
+	// AsOf dates the scheme and holding NAVs that have no date of their own,
+	// the latest transaction date when zero
+	AsOf time.Time
+	// Category returns the category of a bank row, such as SALARY, or "" when it
+	// has none. Categorised rows post to Income or Expenses accounts named after
+	// the category.
+	Category func(models.BankTxn) string
+}
+
+// builder collects the entries of a journal
+type builder struct {
+	j        *Journal
+	opt      Options
+	accounts map[string]bool
+	names    map[string]string
+	prices   map[string]Price
+	// held is the number of units of each ISIN, to keep sales within the holding
+	held map[string]float64
+}
+
+// Build turns the tool responses of a persona into a journal
+func Build(src Sources, opt Options) *Journal {
+	b := &builder{
+		j:        &Journal{},
+		opt:      opt,
+		accounts: map[string]bool{},
+		names:    map[string]string{},
+		prices:   map[string]Price{},
+		held:     map[string]float64{},
+	}
+	b.bank(src.Bank)
+	b.mutualFunds(src.MF)
+	b.stocks(src.Stocks, src.NetWorth)
+
+	j := b.j
+	sort.SliceStable(j.Transactions, func(i, k int) bool { return j.Transactions[i].Date.Before(j.Transactions[k].Date) })
+	for _, t := range j.Transactions {
+		if j.AsOf.Before(t.Date) {
+			j.AsOf = t.Date
+		}
+	}
+	if !opt.AsOf.IsZero() {
+		j.AsOf = opt.AsOf
+	}
+	if j.AsOf.IsZero() {
+		now := time.Now().UTC()
+		j.AsOf = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	j.Start = j.AsOf
+	if len(j.Transactions) > 0 && j.Transactions[0].Date.Before(j.Start) {
+		j.Start = j.Transactions[0].Date
+	}
+
+	b.netWorth(src)
+	for isin, name := range b.names {
+		j.Commodities = append(j.Commodities, Commodity{Symbol: isin, Name: name})
+	}
+	sort.Slice(j.Commodities, func(i, k int) bool { return j.Commodities[i].Symbol < j.Commodities[k].Symbol })
+	for _, p := range b.prices {
+		j.Prices = append(j.Prices, p)
+	}
+	sort.Slice(j.Prices, func(i, k int) bool {
+		if !j.Prices[i].Date.Equal(j.Prices[k].Date) {
+			return j.Prices[i].Date.Before(j.Prices[k].Date)
+		}
+		return j.Prices[i].Commodity < j.Prices[k].Commodity
+	})
+	sort.SliceStable(j.Balances, func(i, k int) bool { return j.Balances[i].Date.Before(j.Balances[k].Date) })
+	for account := range b.accounts {
+		j.Accounts = append(j.Accounts, account)
+	}
+	sort.Strings(j.Accounts)
+	return j
+}
+
+// add appends a transaction and records its accounts
+func (b *builder) add(t Transaction) {
+	for _, p := range t.Postings {
+		b.accounts[p.Account] = true
+	}
+	if t.sells() {
+		b.accounts[CapitalGains] = true
+	}
+	b.j.Transactions = append(b.j.Transactions, t)
+}
+
+// sells tells whether the transaction sells units, whose gain Beancount books
+func (t Transaction) sells() bool {
+	for _, p := range t.Postings {
+		if p.TotalPrice != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) warn(format string, args ...any) {
+	b.j.Warnings = append(b.j.Warnings, fmt.Sprintf(format, args...))
+}
+
+// name records the name of an ISIN unless it already has one
+func (b *builder) name(isin, name string) {
+	if b.names[isin] == "" {
+		b.names[isin] = strings.TrimSpace(name)
+	}
+}
+
+// price records the first price of an ISIN on a date
+func (b *builder) price(date time.Time, isin string, value float64) {
+	if value <= 0 {
+		return
+	}
+	b.name(isin, "")
+	key := date.Format(models.DateLayout) + " " + isin
+	if _, ok := b.prices[key]; !ok {
+		b.prices[key] = Price{Date: date, Commodity: isin, Value: value}
+	}
+}
+
+// bank posts the rows of every bank account, opened with the balance before
+// the first row and closed with an assertion of the last reported balance when
+// the rows add up to it
+func (b *builder) bank(resp *models.BankTransactionsResponse) {
+	if resp == nil {
+		return
+	}
+	flows := banking.SummarizeCashFlow(resp, time.Time{}, time.Time{}).Accounts
+	used := map[string]bool{}
+	for bi, bank := range resp.BankTransactions {
+		account := "Assets:Bank:" + component(bank.Bank)
+		for n := 2; used[account]; n++ {
+			account = fmt.Sprintf("Assets:Bank:%s-%d", component(bank.Bank), n)
+		}
+		used[account] = true
+
+		type row struct {
+			txn  int
+			date time.Time
+			t    models.BankTxn
+		}
+		var rows []row
+		for ti, t := range bank.Txns {
+			date, err := t.Time()
+			if err != nil {
+				b.warn("%s: row with date %q skipped", banking.SourcePointer(bi, ti), t.Date)
+				continue
+			}
+			rows = append(rows, row{txn: ti, date: date, t: t})
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		sort.SliceStable(rows, func(i, k int) bool { return rows[i].date.Before(rows[k].date) })
+
+		flow := flows[bi]
+		for _, brk := range flow.Breaks {
+			b.warn("%s: %s balance is %v, the rows before it add up to %v", brk.Source, account, brk.Reported, brk.Expected)
+		}
+		var balance float64
+		if flow.OpeningBalance != nil && *flow.OpeningBalance != 0 {
+			balance = *flow.OpeningBalance
+			b.add(Transaction{
+				Date:      rows[0].date,
+				Narration: "Opening balance",
+				Meta:      []Meta{{"source", fmt.Sprintf("/bankTransactions/%d", bi)}},
+				Postings: []Posting{
+					{Account: account, Amount: balance},
+					{Account: OpeningBalances, Amount: -balance},
+				},
+			})
+		}
+		for _, r := range rows {
+			amount := models.Round(float64(r.t.Sign())*r.t.AmountValue(), 2)
+			if amount == 0 {
+				continue
+			}
+			balance += amount
+			meta := []Meta{{"source", banking.SourcePointer(bi, r.txn)}}
+			if r.t.Mode != "" {
+				meta = append(meta, Meta{"mode", r.t.Mode})
+			}
+			b.add(Transaction{
+				Date:      r.date,
+				Narration: r.t.Narration,
+				Meta:      meta,
+				Postings: []Posting{
+					{Account: account, Amount: amount},
+					{Account: b.counterAccount(r.t), Amount: -amount},
+				},
+			})
+		}
+
+		last := rows[len(rows)-1]
+		reported, ok := last.t.BalanceValue()
+		switch {
+		case !ok:
+		case math.Abs(reported-balance) > 0.005:
+			b.warn("%s: %s closes at %v, the rows add up to %v, so the balance isn't asserted", banking.SourcePointer(bi, last.txn), account, reported, models.Round(balance, 2))
+		default:
+			b.j.Balances = append(b.j.Balances, Balance{Date: last.date, Account: account, Amount: reported})
+		}
+	}
+}
+
+// counterAccount is the Income or Expenses account of a bank row
+func (b *builder) counterAccount(t models.BankTxn) string {
+	side := "Expenses:"
+	if t.Sign() > 0 {
+		side = "Income:"
+	}
+	if b.opt.Category != nil {
+		if category := b.opt.Category(t); category != "" {
+			return side + component(strings.ToLower(category))
+		}
+	}
+	switch t.Type {
+	case models.BankTxnTypeInterest:
+		return "Income:Interest"
+	case models.BankTxnTypeTDS:
+		return "Expenses:Taxes:TDS"
+	case models.BankTxnTypeInstallment:
+		return "Expenses:Installments"
+	}
+	return side + "Uncategorised"
+}
+
+// mutualFunds posts the purchases and redemptions of every scheme
+func (b *builder) mutualFunds(resp *models.MFTransactionsResponse) {
+	if resp == nil {
+		return
+	}
+	for si, scheme := range resp.MFTransactions {
+		if scheme.ISIN == "" {
+			b.warn("/mfTransactions/%d: scheme %q has no ISIN and is skipped", si, scheme.SchemeName)
+			continue
+		}
+		b.name(scheme.ISIN, scheme.SchemeName)
+		for _, ti := range byDate(len(scheme.Txns), func(i int) string { return scheme.Txns[i].Date }) {
+			txn := scheme.Txns[ti]
+			source := fmt.Sprintf("/mfTransactions/%d/txns/%d", si, ti)
+			date, err := txn.Time()
+			if err != nil {
+				b.warn("%s: transaction with date %q skipped", source, txn.Date)
+				continue
+			}
+			b.price(date, scheme.ISIN, txn.Price)
+			amount := txn.Amount
+			if amount == 0 {
+				amount = txn.Units * txn.Price
+			}
+			meta := []Meta{{"source", source}}
+			if scheme.FolioID != "" {
+				meta = append(meta, Meta{"folio", scheme.FolioID})
+			}
+			switch txn.OrderType {
+			case models.MFOrderTypeBuy:
+				b.buy(date, "Purchase: "+scheme.SchemeName, meta, MutualFunds, scheme.ISIN, txn.Units, amount)
+			case models.MFOrderTypeSell:
+				b.sell(date, "Redemption: "+scheme.SchemeName, meta, MutualFunds, scheme.ISIN, txn.Units, amount)
+			default:
+				b.warn("%s: unknown order type %d skipped", source, txn.OrderType)
+			}
+		}
+	}
+}
+
+// stocks posts the trades and corporate actions of every ISIN. Trades without a
+// price use the previous price of the ISIN, or the first one when none came
+// before, or the price of the holding.
+func (b *builder) stocks(resp *models.StockTransactionsResponse, netWorth *models.FetchNetWorthResponse) {
+	if resp == nil {
+		return
+	}
+	holdings := map[string]models.Holding{}
+	if netWorth != nil {
+		for _, h := range netWorth.AccountDetailsBulkResponse.DematHoldings() {
+			if _, ok := holdings[h.ISIN]; !ok {
+				holdings[h.ISIN] = h.Holding
+			}
+		}
+	}
+	for si, stock := range resp.StockTransactions {
+		if stock.ISIN == "" {
+			b.warn("/stockTransactions/%d: transactions without an ISIN skipped", si)
+			continue
+		}
+		name := holdings[stock.ISIN].Name()
+		b.name(stock.ISIN, name)
+		if name == "" {
+			name = stock.ISIN
+		}
+		order := byDate(len(stock.Txns), func(i int) string { return stock.Txns[i].Date })
+		last := holdings[stock.ISIN].Price()
+		for _, ti := range order {
+			if price := stock.Txns[ti].Price(); price > 0 {
+				last = price
+				break
+			}
+		}
+		for _, ti := range order {
+			txn := stock.Txns[ti]
+			source := fmt.Sprintf("/stockTransactions/%d/txns/%d", si, ti)
+			date, err := txn.Time()
+			if err != nil {
+				b.warn("%s: transaction with date %q skipped", source, txn.Date)
+				continue
+			}
+			price := txn.Price()
+			if price > 0 {
+				b.price(date, stock.ISIN, price)
+				last = price
+			} else if txn.Type == models.StockTxnTypeBuy || txn.Type == models.StockTxnTypeSell {
+				b.warn("%s: no price, %v used", source, last)
+				price = last
+			}
+			meta := []Meta{{"source", source}}
+			switch txn.Type {
+			case models.StockTxnTypeBuy:
+				b.buy(date, "Buy: "+name, meta, Stocks, stock.ISIN, txn.Quantity, txn.Quantity*price)
+			case models.StockTxnTypeSell:
+				b.sell(date, "Sell: "+name, meta, Stocks, stock.ISIN, txn.Quantity, txn.Quantity*price)
+			case models.StockTxnTypeBonus:
+				b.buy(date, "Bonus: "+name, meta, Stocks, stock.ISIN, txn.Quantity, 0)
+			case models.StockTxnTypeSplit:
+				b.buy(date, "Split: "+name, meta, Stocks, stock.ISIN, txn.Quantity, 0)
+			default:
+				b.warn("%s: unknown transaction type %d skipped", source, txn.Type)
+			}
+		}
+	}
+}
+
+// byDate returns the indexes of n transactions in date order, those of a day in
+// the order they are listed
+func byDate(n int, date func(i int) string) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, k int) bool { return date(order[i]) < date(order[k]) })
+	return order
+}
+
+// buy adds units at a total cost paid from Transfers. Bonus and split units
+// cost nothing and have no cash leg.
+func (b *builder) buy(date time.Time, narration string, meta []Meta, account, isin string, units, cost float64) {
+	cost = models.Round(cost, 2)
+	b.held[account+" "+isin] += units
+	postings := []Posting{{Account: account, Amount: units, Commodity: isin, TotalCost: &cost}}
+	if cost != 0 {
+		postings = append(postings, Posting{Account: Transfers, Amount: -cost})
+	}
+	b.add(Transaction{Date: date, Narration: narration, Meta: meta, Postings: postings})
+}
+
+// sell takes units out at a total price paid into Transfers. A sale of more
+// units than are held is cut down to the holding, as the analytics do.
+func (b *builder) sell(date time.Time, narration string, meta []Meta, account, isin string, units, proceeds float64) {
+	key := account + " " + isin
+	if held := models.Round(b.held[key], 6); units > held {
+		b.warn("%s: sale of %v %s exceeds the %v held, only %v sold", meta[0].Value, units, isin, held, held)
+		if held <= 0 {
+			return
+		}
+		proceeds *= held / units
+		units = held
+	}
+	b.held[key] -= units
+	proceeds = models.Round(proceeds, 2)
+	b.add(Transaction{Date: date, Narration: narration, Meta: meta, Postings: []Posting{
+		{Account: account, Amount: -units, Commodity: isin, TotalPrice: &proceeds},
+		{Account: Transfers, Amount: proceeds},
+	}})
+}
+
+// netWorth opens the netWorthResponse values that no transactions cover on the
+// start date, and records the names and NAVs of the schemes and holdings
+func (b *builder) netWorth(src Sources) {
+	nw := src.NetWorth
+	if nw == nil {
+		return
+	}
+	if nw.MFSchemeAnalytics != nil {
+		for _, s := range nw.MFSchemeAnalytics.SchemeAnalytics {
+			isin := s.SchemeDetail.ISINNumber
+			if isin == "" {
+				continue
+			}
+			b.name(isin, s.SchemeDetail.NameData.LongName)
+			nav := s.SchemeDetail.NAV.Float()
+			if nav == 0 {
+				nav = s.EnrichedAnalytics.Analytics.SchemeDetails.NAVValue.Float()
+			}
+			b.price(b.j.AsOf, isin, nav)
+		}
+	}
+	if accounts := nw.AccountDetailsBulkResponse; accounts != nil {
+		for _, id := range accounts.AccountIDs() {
+			entry := accounts.AccountDetailsMap[id]
+			for _, summary := range []*models.AccountSummary{entry.EquitySummary, entry.ETFSummary, entry.REITSummary, entry.InvITSummary, entry.MutualFundSummary, entry.SGBSummary} {
+				if summary == nil {
+					continue
+				}
+				for _, h := range summary.HoldingsInfo {
+					if h.ISIN == "" {
+						continue
+					}
+					b.name(h.ISIN, h.Name())
+					date := b.j.AsOf
+					if d, err := time.Parse(models.DateLayout, h.LastNAVDate); err == nil {
+						date = d
+					}
+					b.price(date, h.ISIN, h.Price())
+				}
+			}
+		}
+	}
+
+	if nw.NetWorthResponse == nil {
+		return
+	}
+	covered := map[string]bool{
+		"ASSET_TYPE_SAVINGS_ACCOUNTS":  src.Bank != nil && len(src.Bank.BankTransactions) > 0,
+		"ASSET_TYPE_MUTUAL_FUND":       src.MF != nil && len(src.MF.MFTransactions) > 0,
+		"ASSET_TYPE_INDIAN_SECURITIES": src.Stocks != nil && len(src.Stocks.StockTransactions) > 0,
+	}
+	var openings []Transaction
+	for _, side := range []struct {
+		field  string
+		values []models.NetWorthValue
+		sign   float64
+	}{
+		{"assetValues", nw.NetWorthResponse.AssetValues, 1},
+		{"liabilityValues", nw.NetWorthResponse.LiabilityValues, -1},
+	} {
+		for i, v := range side.values {
+			amount := models.Round(side.sign*v.Value.Float(), 2)
+			if covered[v.NetWorthAttribute] || amount == 0 {
+				continue
+			}
+			account := attributeAccounts[v.NetWorthAttribute]
+			if account == "" {
+				account = attributeAccount(v.NetWorthAttribute)
+			}
+			openings = append(openings, Transaction{
+				Date:      b.j.Start,
+				Narration: "Opening balance: " + v.NetWorthAttribute,
+				Meta:      []Meta{{"source", fmt.Sprintf("/netWorthResponse/%s/%d", side.field, i)}},
+				Postings: []Posting{
+					{Account: account, Amount: amount},
+					{Account: OpeningBalances, Amount: -amount},
+				},
+			})
+		}
+	}
+	for _, t := range openings {
+		for _, p := range t.Postings {
+			b.accounts[p.Account] = true
+		}
+	}
+	// openings come before the transactions of the start date
+	b.j.Transactions = append(openings, b.j.Transactions...)
+}
+
+// attributeAccount names the account of an attribute missing from attributeAccounts
+func attributeAccount(attribute string) string {
+	if rest, ok := strings.CutPrefix(attribute, "LIABILITY_TYPE_"); ok {
+		return "Liabilities:" + component(strings.ToLower(rest))
+	}
+	return "Assets:" + component(strings.ToLower(strings.TrimPrefix(attribute, "ASSET_TYPE_")))
+}
+
+// component turns a name into an account name component all three tools
+// accept: words of ASCII letters and digits joined by dashes, each starting
+// with a capital
+func component(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	if len(words) == 0 {
+		return "Unknown"
+	}
+	return strings.Join(words, "-")
+}
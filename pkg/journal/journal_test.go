@@ -0,0 +1,234 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func sources(t *testing.T, bank, mf, stocks, netWorth string) Sources {
+	t.Helper()
+	var src Sources
+	for _, f := range []struct {
+		data   string
+		target any
+	}{{bank, &src.Bank}, {mf, &src.MF}, {stocks, &src.Stocks}, {netWorth, &src.NetWorth}} {
+		if f.data == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(f.data), f.target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+const (
+	testBank = `{"bankTransactions": [{"bank": "Test Bank", "txns": [
+		["200", "UPI-GROCER; MILK", "2024-01-02", 2, "UPI", "1800"],
+		["1000", "SALARY", "2024-01-01", 1, "NEFT", "2000"]
+	]}]}`
+	testMF = `{"mfTransactions": [{"isin": "INF000000001", "schemeName": "Alpha \"Growth\" Fund", "folioId": "123/45", "txns": [
+		[1, "2024-01-01", 10, 100, 1000],
+		[2, "2024-01-03", 12, 150, 1800]
+	]}]}`
+	testStocks = `{"stockTransactions": [{"isin": "INE000000001", "txns": [
+		[1, "2024-01-01", 10, 250],
+		[3, "2024-01-02", 5],
+		[2, "2024-01-03", 4]
+	]}]}`
+	testNetWorth = `{
+		"netWorthResponse": {"assetValues": [
+			{"netWorthAttribute": "ASSET_TYPE_SAVINGS_ACCOUNTS", "value": {"units": "1800"}},
+			{"netWorthAttribute": "ASSET_TYPE_EPF", "value": {"units": "50000"}}
+		], "liabilityValues": [
+			{"netWorthAttribute": "LIABILITY_TYPE_CREDIT_CARD", "value": {"units": "3000"}}
+		]},
+		"mfSchemeAnalytics": {"schemeAnalytics": [
+			{"schemeDetail": {"isinNumber": "INF000000001", "nameData": {"longName": "Alpha Fund"}, "nav": {"units": "13"}}}
+		]},
+		"accountDetailsBulkResponse": {"accountDetailsMap": {"demat1": {
+			"accountDetails": {"accInstrumentType": "ACC_INSTRUMENT_TYPE_EQUITIES"},
+			"equitySummary": {"holdingsInfo": [
+				{"isin": "INE000000001", "issuerName": "Beta Ltd", "units": 11, "lastTradedPrice": {"units": "300"}}
+			]}
+		}}}
+	}`
+)
+
+func TestBuild(t *testing.T) {
+	j := Build(sources(t, testBank, testMF, testStocks, testNetWorth), Options{})
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	if !j.Start.Equal(day(1)) || !j.AsOf.Equal(day(3)) {
+		t.Errorf("start %v, as of %v", j.Start, j.AsOf)
+	}
+	var narrations []string
+	for _, txn := range j.Transactions {
+		narrations = append(narrations, txn.Narration)
+	}
+	want := []string{
+		"Opening balance: ASSET_TYPE_EPF", "Opening balance: LIABILITY_TYPE_CREDIT_CARD",
+		"Opening balance", "SALARY", "Purchase: Alpha \"Growth\" Fund", "Buy: Beta Ltd",
+		"UPI-GROCER; MILK", "Bonus: Beta Ltd",
+		"Redemption: Alpha \"Growth\" Fund", "Sell: Beta Ltd",
+	}
+	if strings.Join(narrations, "|") != strings.Join(want, "|") {
+		t.Errorf("narrations = %q, want %q", narrations, want)
+	}
+	// the bank opens with 1000, the balance before the salary
+	if p := j.Transactions[2].Postings[0]; p.Account != "Assets:Bank:Test-Bank" || p.Amount != 1000 {
+		t.Errorf("bank opening = %+v", p)
+	}
+	if len(j.Balances) != 1 || j.Balances[0].Amount != 1800 || !j.Balances[0].Date.Equal(day(2)) {
+		t.Errorf("balances = %+v", j.Balances)
+	}
+	// the redemption of 150 units is cut down to the 100 held
+	redemption := j.Transactions[8].Postings[0]
+	if redemption.Amount != -100 || *redemption.TotalPrice != 1200 {
+		t.Errorf("redemption = %+v", redemption)
+	}
+	// the unpriced sale uses the last price
+	if sale := j.Transactions[9].Postings[0]; *sale.TotalPrice != 1000 {
+		t.Errorf("sale = %+v", sale)
+	}
+	if len(j.Warnings) != 2 {
+		t.Errorf("warnings = %q", j.Warnings)
+	}
+	if len(j.Commodities) != 2 || j.Commodities[0] != (Commodity{"INE000000001", "Beta Ltd"}) || j.Commodities[1] != (Commodity{"INF000000001", "Alpha \"Growth\" Fund"}) {
+		t.Errorf("commodities = %+v", j.Commodities)
+	}
+	prices := map[string]float64{}
+	for _, p := range j.Prices {
+		prices[p.Date.Format(models.DateLayout)+" "+p.Commodity] = p.Value
+	}
+	if prices["2024-01-03 INF000000001"] != 12 || prices["2024-01-01 INE000000001"] != 250 || len(prices) != 4 {
+		t.Errorf("prices = %v", prices)
+	}
+	checkBalanced(t, "test", j)
+
+	j = Build(sources(t, testBank, "", "", ""), Options{Category: func(t models.BankTxn) string {
+		if t.Sign() > 0 {
+			return "SALARY"
+		}
+		return "FOOD_DINING"
+	}})
+	if got := j.Accounts; strings.Join(got, " ") != "Assets:Bank:Test-Bank Equity:Opening-Balances Expenses:Food-Dining Income:Salary" {
+		t.Errorf("accounts = %v", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	j := Build(sources(t, testBank, testMF, testStocks, ""), Options{})
+	j.Title = "Persona 1"
+	for format, wants := range map[string][]string{
+		FormatLedger: {
+			"commodity \"INF000000001\"\n    note Alpha \"Growth\" Fund\n",
+			"P 2024/01/01 \"INE000000001\" 250 INR\n",
+			"2024/01/02 * UPI-GROCER, MILK\n    ; source: /bankTransactions/0/txns/0\n    ; mode: UPI\n    Assets:Bank:Test-Bank  -200 INR\n    Expenses:Uncategorised  200 INR\n",
+			"    Assets:Investments:Mutual-Funds  100 \"INF000000001\" @@ 1000 INR\n    Equity:Transfers  -1000 INR\n",
+			"    Assets:Investments:Stocks  5 \"INE000000001\" @@ 0 INR\n\n",
+			"2024/01/02 * Balance assertion\n    Assets:Bank:Test-Bank  0 INR = 1800 INR\n\n2024/01/03",
+		},
+		FormatHledger: {
+			"commodity \"INF000000001\"  ; Alpha \"Growth\" Fund\n",
+			"P 2024-01-03 \"INF000000001\" 12 INR\n",
+			"2024-01-03 * Redemption: Alpha \"Growth\" Fund\n",
+			"    Assets:Investments:Mutual-Funds  -100 \"INF000000001\" @@ 1200 INR\n    Equity:Transfers  1200 INR\n\n",
+		},
+		FormatBeancount: {
+			"option \"title\" \"Persona 1\"\noption \"operating_currency\" \"INR\"\noption \"booking_method\" \"FIFO\"\n",
+			"2024-01-01 commodity INF000000001\n  name: \"Alpha \\\"Growth\\\" Fund\"\n",
+			"2024-01-01 open Income:Capital-Gains\n",
+			"2024-01-01 price INF000000001 10 INR\n",
+			"2024-01-01 * \"Purchase: Alpha \\\"Growth\\\" Fund\"\n  source: \"/mfTransactions/0/txns/0\"\n  folio: \"123/45\"\n  Assets:Investments:Mutual-Funds  100 INF000000001 {{1000 INR}}\n",
+			"  Assets:Investments:Mutual-Funds  -100 INF000000001 {} @@ 1200 INR\n  Equity:Transfers  1200 INR\n  Income:Capital-Gains\n",
+			"2024-01-03 balance Assets:Bank:Test-Bank  1800 INR\n",
+		},
+	} {
+		var b bytes.Buffer
+		if err := Write(&b, format, j); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s journal lacks %q:\n%s", format, want, b.String())
+			}
+		}
+	}
+	if err := Write(&bytes.Buffer{}, "gnucash", j); err == nil {
+		t.Error("Write: expected an error for an unknown format")
+	}
+	if got := component("hdfc bank (joint)"); got != "Hdfc-Bank-Joint" {
+		t.Errorf("component = %s", got)
+	}
+}
+
+// checkBalanced fails for a transaction whose postings don't add up to zero INR
+// at their cost or price, as Ledger weighs them
+func checkBalanced(t *testing.T, name string, j *Journal) {
+	t.Helper()
+	for _, txn := range j.Transactions {
+		var sum float64
+		for _, p := range txn.Postings {
+			switch {
+			case p.TotalCost != nil:
+				sum += *p.TotalCost
+			case p.TotalPrice != nil:
+				sum -= *p.TotalPrice
+			case p.Commodity == "":
+				sum += p.Amount
+			default:
+				t.Errorf("%s: %s posts units without a cost or price", name, txn.Narration)
+			}
+		}
+		if math.Abs(sum) > 0.005 {
+			t.Errorf("%s: %s %s is off by %v", name, txn.Date.Format(models.DateLayout), txn.Narration, sum)
+		}
+	}
+}
+
+// TestTestDataDir builds and writes the journal of every persona in test_data_dir
+func TestTestDataDir(t *testing.T) {
+	dirs, err := os.ReadDir("../../test_data_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		var src Sources
+		for tool, target := range map[string]any{
+			"fetch_bank_transactions":  &src.Bank,
+			"fetch_mf_transactions":    &src.MF,
+			"fetch_stock_transactions": &src.Stocks,
+			"fetch_net_worth":          &src.NetWorth,
+		} {
+			data, err := os.ReadFile(filepath.Join("../../test_data_dir", dir.Name(), tool+".json"))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, target); err != nil {
+				t.Fatalf("%s/%s: %v", dir.Name(), tool, err)
+			}
+		}
+		j := Build(src, Options{})
+		checkBalanced(t, dir.Name(), j)
+		for _, format := range Formats() {
+			var b bytes.Buffer
+			if err := Write(&b, format, j); err != nil {
+				t.Errorf("%s: %v", dir.Name(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Journal formats
+const (
+	FormatLedger    = "ledger"
+	FormatHledger   = "hledger"
+	FormatBeancount = "beancount"
+)
+
+// Formats returns the journal formats
+func Formats() []string {
+	return []string{FormatLedger, FormatHledger, FormatBeancount}
+}
+
+// Supported tells whether format is a journal format
+func Supported(format string) bool {
+	return format == FormatLedger || format == FormatHledger || format == FormatBeancount
+}
+
+// Write writes the journal in a format. Ledger and hledger record sales at
+// their price without booking a gain, Beancount reduces the oldest lots.
+func Write(w io.Writer, format string, j *Journal) error {
+	var b bytes.Buffer
+	switch format {
+	case FormatLedger:
+		writeLedger(&b, j, "2006/01/02")
+	case FormatHledger:
+		writeLedger(&b, j, models.DateLayout)
+	case FormatBeancount:
+		writeBeancount(&b, j)
+	default:
+		return fmt.Errorf("unknown journal format %q, use %s", format, strings.Join(Formats(), ", "))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// header writes the title, as-of date and warnings as comments
+func header(b *bytes.Buffer, j *Journal) {
+	if j.Title != "" {
+		fmt.Fprintf(b, "; %s\n", j.Title)
+	}
+	fmt.Fprintf(b, "; as of %s\n", j.AsOf.Format(models.DateLayout))
+	for _, w := range j.Warnings {
+		fmt.Fprintf(b, "; warning: %s\n", w)
+	}
+	b.WriteString("\n")
+}
+
+// entries calls transaction and balance for the entries of the journal in date
+// order, the balances of a day after its transactions
+func entries(j *Journal, transaction func(Transaction), balance func(Balance)) {
+	i := 0
+	for _, bal := range j.Balances {
+		for ; i < len(j.Transactions) && !j.Transactions[i].Date.After(bal.Date); i++ {
+			transaction(j.Transactions[i])
+		}
+		balance(bal)
+	}
+	for ; i < len(j.Transactions); i++ {
+		transaction(j.Transactions[i])
+	}
+}
+
+// writeLedger writes the Ledger and hledger formats, which differ in their
+// dates and in how a commodity is named
+func writeLedger(b *bytes.Buffer, j *Journal, dateLayout string) {
+	header(b, j)
+	for _, c := range j.Commodities {
+		switch {
+		case c.Name == "":
+			fmt.Fprintf(b, "commodity %s\n", quote(c.Symbol))
+		case dateLayout == models.DateLayout:
+			fmt.Fprintf(b, "commodity %s  ; %s\n", quote(c.Symbol), c.Name)
+		default:
+			fmt.Fprintf(b, "commodity %s\n    note %s\n", quote(c.Symbol), c.Name)
+		}
+	}
+	if len(j.Commodities) > 0 {
+		b.WriteString("\n")
+	}
+	for _, a := range j.Accounts {
+		fmt.Fprintf(b, "account %s\n", a)
+	}
+	if len(j.Accounts) > 0 {
+		b.WriteString("\n")
+	}
+	for _, p := range j.Prices {
+		fmt.Fprintf(b, "P %s %s %s %s\n", p.Date.Format(dateLayout), quote(p.Commodity), number(p.Value, 6), Currency)
+	}
+	if len(j.Prices) > 0 {
+		b.WriteString("\n")
+	}
+
+	entries(j, func(t Transaction) {
+		fmt.Fprintf(b, "%s * %s\n", t.Date.Format(dateLayout), description(t.Narration))
+		for _, m := range t.Meta {
+			fmt.Fprintf(b, "    ; %s: %s\n", m.Key, m.Value)
+		}
+		for _, p := range t.Postings {
+			fmt.Fprintf(b, "    %s  %s", p.Account, amount(p.Amount, p.Commodity, quote))
+			switch {
+			case p.TotalCost != nil:
+				fmt.Fprintf(b, " @@ %s %s", number(*p.TotalCost, 2), Currency)
+			case p.TotalPrice != nil:
+				fmt.Fprintf(b, " @@ %s %s", number(*p.TotalPrice, 2), Currency)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}, func(bal Balance) {
+		fmt.Fprintf(b, "%s * Balance assertion\n    %s  0 %s = %s %s\n\n", bal.Date.Format(dateLayout), bal.Account, Currency, number(bal.Amount, 2), Currency)
+	})
+}
+
+// writeBeancount writes the Beancount format. Accounts and commodities are
+// declared on the date of the earliest entry and lots are booked FIFO.
+func writeBeancount(b *bytes.Buffer, j *Journal) {
+	header(b, j)
+	if j.Title != "" {
+		fmt.Fprintf(b, "option \"title\" %s\n", str(j.Title))
+	}
+	fmt.Fprintf(b, "option \"operating_currency\" \"%s\"\noption \"booking_method\" \"FIFO\"\n\n", Currency)
+
+	start := j.Start
+	if len(j.Prices) > 0 && j.Prices[0].Date.Before(start) {
+		start = j.Prices[0].Date
+	}
+	date := start.Format(models.DateLayout)
+	for _, c := range j.Commodities {
+		fmt.Fprintf(b, "%s commodity %s\n", date, c.Symbol)
+		if c.Name != "" {
+			fmt.Fprintf(b, "  name: %s\n", str(c.Name))
+		}
+	}
+	if len(j.Commodities) > 0 {
+		b.WriteString("\n")
+	}
+	for _, a := range j.Accounts {
+		fmt.Fprintf(b, "%s open %s\n", date, a)
+	}
+	if len(j.Accounts) > 0 {
+		b.WriteString("\n")
+	}
+	for _, p := range j.Prices {
+		fmt.Fprintf(b, "%s price %s %s %s\n", p.Date.Format(models.DateLayout), p.Commodity, number(p.Value, 6), Currency)
+	}
+	if len(j.Prices) > 0 {
+		b.WriteString("\n")
+	}
+
+	plain := func(s string) string { return s }
+	entries(j, func(t Transaction) {
+		fmt.Fprintf(b, "%s * %s\n", t.Date.Format(models.DateLayout), str(t.Narration))
+		for _, m := range t.Meta {
+			fmt.Fprintf(b, "  %s: %s\n", m.Key, str(m.Value))
+		}
+		for _, p := range t.Postings {
+			fmt.Fprintf(b, "  %s  %s", p.Account, amount(p.Amount, p.Commodity, plain))
+			switch {
+			case p.TotalCost != nil:
+				fmt.Fprintf(b, " {{%s %s}}", number(*p.TotalCost, 2), Currency)
+			case p.TotalPrice != nil:
+				fmt.Fprintf(b, " {} @@ %s %s", number(*p.TotalPrice, 2), Currency)
+			}
+			b.WriteString("\n")
+		}
+		if t.sells() {
+			fmt.Fprintf(b, "  %s\n", CapitalGains)
+		}
+		b.WriteString("\n")
+	}, func(bal Balance) {
+		// a balance directive holds at the start of its day
+		fmt.Fprintf(b, "%s balance %s  %s %s\n\n", bal.Date.AddDate(0, 0, 1).Format(models.DateLayout), bal.Account, number(bal.Amount, 2), Currency)
+	})
+}
+
+// amount formats INR with two decimals and units with up to six, in the
+// commodity as symbol names it
+func amount(v float64, commodity string, symbol func(string) string) string {
+	if commodity == "" {
+		return number(v, 2) + " " + Currency
+	}
+	return number(v, 6) + " " + symbol(commodity)
+}
+
+// number formats v rounded to places decimals without trailing zeros
+func number(v float64, places int) string {
+	v = models.Round(v, places)
+	if v == 0 {
+		v = 0 // no -0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// quote puts a Ledger commodity symbol in quotes, which ISINs need for their digits
+func quote(symbol string) string {
+	return `"` + symbol + `"`
+}
+
+// str is a Beancount string
+func str(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
+
+// description is a Ledger payee: on one line and without the ; that starts a comment
+func description(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, ";", ",")), " ")
+}
@@ -0,0 +1,332 @@
+package liquidity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/allocation"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Tier is how quickly an asset can be turned into cash
+type Tier string
+
+const (
+	// TierSavings is money in savings and current accounts, available the same day
+	TierSavings Tier = "SAVINGS"
+	// TierLiquidFunds are liquid, overnight and money market funds, redeemed the next business day
+	TierLiquidFunds Tier = "LIQUID_FUNDS"
+	// TierDeposits are fixed and recurring deposits, which can be broken early for a penalty
+	TierDeposits Tier = "DEPOSITS"
+	// TierMarket are equity, debt and gold investments whose value may be down when they are needed
+	TierMarket Tier = "MARKET"
+	// TierLocked are retirement savings such as EPF and NPS that can't be withdrawn at will
+	TierLocked Tier = "LOCKED"
+)
+
+// Tiers lists the tiers from the most to the least liquid
+var Tiers = []Tier{TierSavings, TierLiquidFunds, TierDeposits, TierMarket, TierLocked}
+
+var accessTimes = map[Tier]string{
+	TierSavings:     "same day",
+	TierLiquidFunds: "next business day",
+	TierDeposits:    "one to two days, with a premature withdrawal penalty before maturity",
+	TierMarket:      "two to three business days, at the market value of the day",
+	TierLocked:      "only through partial withdrawals allowed for specific purposes",
+}
+
+// emergencyTiers are the tiers counted towards the emergency fund
+var emergencyTiers = map[Tier]bool{TierSavings: true, TierLiquidFunds: true, TierDeposits: true}
+
+// Defaults of the options
+const (
+	DefaultTargetMonths            = 6
+	DefaultPrematurePenaltyPercent = 1
+)
+
+// Options are the assumptions of the analysis
+type Options struct {
+	// TargetMonths is the number of months of expenses the emergency fund should cover
+	TargetMonths float64
+	// MonthlyExpenses replaces the outflow computed from the bank transactions when set
+	MonthlyExpenses float64
+	// PrematurePenaltyPercent is the part of a deposit lost by breaking it before maturity
+	PrematurePenaltyPercent float64
+}
+
+// Asset is one holding and the tier it falls in
+type Asset struct {
+	Name      string  `json:"name"`
+	Attribute string  `json:"netWorthAttribute"`
+	Tier      Tier    `json:"tier"`
+	Value     float64 `json:"value"`
+	// AvailableValue is the cash the asset gives when liquidated today, after penalties
+	AvailableValue float64 `json:"availableValue"`
+	Source         string  `json:"source"`
+	MaturityDate   string  `json:"maturityDate,omitempty"`
+	Note           string  `json:"note,omitempty"`
+}
+
+// TierSummary is the value held in one tier
+type TierSummary struct {
+	Tier           Tier    `json:"tier"`
+	AccessTime     string  `json:"accessTime"`
+	Value          float64 `json:"value"`
+	AvailableValue float64 `json:"availableValue"`
+	// CumulativeRunwayMonths is how long this tier and the more liquid ones cover the monthly outflow
+	CumulativeRunwayMonths *float64 `json:"cumulativeRunwayMonths,omitempty"`
+}
+
+// EmergencyFund compares the savings, liquid funds and deposits with the target
+type EmergencyFund struct {
+	Value        float64  `json:"value"`
+	RunwayMonths *float64 `json:"runwayMonths,omitempty"`
+	TargetMonths float64  `json:"targetMonths"`
+	Target       *float64 `json:"target,omitempty"`
+	Shortfall    *float64 `json:"shortfall,omitempty"`
+	Surplus      *float64 `json:"surplus,omitempty"`
+}
+
+// Report is the liquidity of a user's assets against their spending
+type Report struct {
+	AsOf          string        `json:"asOf"`
+	Outflow       Outflow       `json:"monthlyOutflow"`
+	Tiers         []TierSummary `json:"tiers"`
+	EmergencyFund EmergencyFund `json:"emergencyFund"`
+	Assets        []Asset       `json:"assets"`
+	Notes         []string      `json:"notes"`
+}
+
+// Analyze classifies the assets in the net worth response by liquidity tier and
+// measures how many months of outflow each tier covers. Savings accounts and
+// deposits are listed per account from accountDetailsBulkResponse, the other
+// assets come from the asset allocation breakdown.
+func Analyze(netWorth *models.FetchNetWorthResponse, bank *models.BankTransactionsResponse, opts Options, asOf time.Time) *Report {
+	if opts.TargetMonths <= 0 {
+		opts.TargetMonths = DefaultTargetMonths
+	}
+	r := &Report{
+		AsOf:    asOf.Format(models.DateLayout),
+		Outflow: monthlyOutflow(bank),
+		Assets:  []Asset{},
+		Notes:   []string{},
+	}
+	if opts.MonthlyExpenses > 0 {
+		r.Outflow.Expenses = opts.MonthlyExpenses
+		r.Outflow.Method = OutflowGiven
+	}
+	if r.Outflow.Method == OutflowNone {
+		r.Notes = append(r.Notes, "no bank transactions to compute the monthly outflow from, pass monthly_expenses to get the runway")
+	}
+
+	r.Assets, r.Notes = assets(netWorth, opts, asOf, r.Notes)
+
+	values := make(map[Tier]float64)
+	available := make(map[Tier]float64)
+	for _, a := range r.Assets {
+		values[a.Tier] += a.Value
+		available[a.Tier] += a.AvailableValue
+	}
+	var cumulative float64
+	for _, t := range Tiers {
+		s := TierSummary{Tier: t, AccessTime: accessTimes[t], Value: models.Round(values[t], 2), AvailableValue: models.Round(available[t], 2)}
+		cumulative += available[t]
+		if t != TierLocked {
+			s.CumulativeRunwayMonths = runway(cumulative, r.Outflow.Expenses)
+		}
+		if emergencyTiers[t] {
+			r.EmergencyFund.Value += available[t]
+		}
+		r.Tiers = append(r.Tiers, s)
+	}
+
+	ef := &r.EmergencyFund
+	ef.Value = models.Round(ef.Value, 2)
+	ef.TargetMonths = opts.TargetMonths
+	ef.RunwayMonths = runway(ef.Value, r.Outflow.Expenses)
+	if r.Outflow.Expenses > 0 {
+		target := models.Round(r.Outflow.Expenses*opts.TargetMonths, 2)
+		ef.Target = &target
+		if gap := models.Round(target-ef.Value, 2); gap > 0 {
+			ef.Shortfall = &gap
+			if available[TierMarket] >= gap {
+				r.Notes = append(r.Notes, fmt.Sprintf("the emergency fund is %.0f short of %v months of expenses, moving that much from market investments to a liquid fund or savings would close it", gap, opts.TargetMonths))
+			} else {
+				r.Notes = append(r.Notes, fmt.Sprintf("the emergency fund is %.0f short of %v months of expenses", gap, opts.TargetMonths))
+			}
+		} else {
+			surplus := -gap
+			ef.Surplus = &surplus
+		}
+	}
+	return r
+}
+
+func runway(value, expenses float64) *float64 {
+	if expenses <= 0 {
+		return nil
+	}
+	months := models.Round(value/expenses, 1)
+	return &months
+}
+
+// assets lists the assets with their tier, returning the notes with any mismatch added
+func assets(netWorth *models.FetchNetWorthResponse, opts Options, asOf time.Time, notes []string) ([]Asset, []string) {
+	out := []Asset{}
+	if netWorth == nil || netWorth.NetWorthResponse == nil {
+		return out, append(notes, "no net worth data available")
+	}
+	accounts := accountAssets(netWorth.AccountDetailsBulkResponse, opts, asOf)
+	reported := make(map[string]float64)
+	for _, a := range netWorth.NetWorthResponse.AssetValues {
+		reported[a.NetWorthAttribute] += a.Value.Float()
+	}
+	for _, attribute := range []string{"ASSET_TYPE_SAVINGS_ACCOUNTS", "ASSET_TYPE_DEPOSITS"} {
+		listed := accounts[attribute]
+		if len(listed) == 0 {
+			if v := reported[attribute]; v > 0 {
+				out = append(out, wholeAttribute(attribute, v, opts))
+			}
+			continue
+		}
+		var sum float64
+		for _, a := range listed {
+			sum += a.Value
+		}
+		out = append(out, listed...)
+		// accounts can be missing from the details, the rest of the reported value is kept as a whole
+		if v, ok := reported[attribute]; ok && math.Abs(sum-v) >= 1 {
+			if v > sum {
+				notes = append(notes, fmt.Sprintf("%s is reported as %.2f in assetValues but the accounts in %s add up to %.2f, the difference is listed as accounts without details",
+					attribute, v, allocation.SourceAccounts, models.Round(sum, 2)))
+				rest := wholeAttribute(attribute, v-sum, opts)
+				rest.Name += " without account details"
+				out = append(out, rest)
+			} else {
+				notes = append(notes, fmt.Sprintf("%s is reported as %.2f in assetValues but the accounts in %s add up to %.2f, the accounts are used",
+					attribute, v, allocation.SourceAccounts, models.Round(sum, 2)))
+			}
+		}
+	}
+
+	for _, h := range allocation.Analyze(netWorth, nil).Holdings {
+		var tier Tier
+		switch {
+		case h.Attribute == "ASSET_TYPE_SAVINGS_ACCOUNTS" || h.Attribute == "ASSET_TYPE_DEPOSITS":
+			continue
+		case h.Attribute == "ASSET_TYPE_EPF" || h.Attribute == "ASSET_TYPE_NPS":
+			tier = TierLocked
+		case h.Attribute == "ASSET_TYPE_MUTUAL_FUND" && h.Bucket == allocation.BucketCash:
+			tier = TierLiquidFunds
+		default:
+			tier = TierMarket
+		}
+		out = append(out, Asset{Name: h.Name, Attribute: h.Attribute, Tier: tier, Value: h.Value, AvailableValue: h.Value, Source: h.Source})
+	}
+	order := make(map[Tier]int, len(Tiers))
+	for i, t := range Tiers {
+		order[t] = i
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Tier != out[j].Tier {
+			return order[out[i].Tier] < order[out[j].Tier]
+		}
+		return out[i].Value > out[j].Value
+	})
+	for i := range out {
+		out[i].Value = models.Round(out[i].Value, 2)
+		out[i].AvailableValue = models.Round(out[i].AvailableValue, 2)
+	}
+	return out, notes
+}
+
+// accountAssets lists the savings, current and deposit accounts keyed by their netWorthAttribute
+func accountAssets(accounts *models.AccountDetailsBulkResponse, opts Options, asOf time.Time) map[string][]Asset {
+	out := make(map[string][]Asset)
+	for _, id := range accounts.AccountIDs() {
+		entry := accounts.AccountDetailsMap[id]
+		summary, kind := entry.DepositSummary, ""
+		switch entry.AccountDetails.AccInstrumentType {
+		case "ACC_INSTRUMENT_TYPE_DEPOSIT":
+			if summary == nil {
+				continue
+			}
+			switch summary.DepositAccountType {
+			case "DEPOSIT_ACCOUNT_TYPE_SAVINGS":
+				kind = "savings account"
+			case "DEPOSIT_ACCOUNT_TYPE_CURRENT":
+				kind = "current account"
+			case "DEPOSIT_ACCOUNT_TYPE_FIXED":
+				kind = "fixed deposit"
+			default:
+				continue
+			}
+		case "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT":
+			summary, kind = entry.RecurringDepositSummary, "recurring deposit"
+		default:
+			continue
+		}
+		value := summary.Value()
+		if value <= 0 {
+			continue
+		}
+		name := kind + " " + entry.AccountDetails.MaskedAccountNumber
+		if entry.AccountDetails.FipMeta != nil && entry.AccountDetails.FipMeta.DisplayName != "" {
+			name = entry.AccountDetails.FipMeta.DisplayName + " " + name
+		}
+		a := Asset{Name: strings.TrimSpace(name), Tier: TierSavings, Value: value, AvailableValue: value, Source: "/accountDetailsBulkResponse/accountDetailsMap/" + id}
+		if !strings.HasSuffix(kind, "account") {
+			a.Tier, a.Attribute = TierDeposits, "ASSET_TYPE_DEPOSITS"
+			applyPenalty(&a, summary.MaturityDate, opts, asOf)
+		} else {
+			a.Attribute = "ASSET_TYPE_SAVINGS_ACCOUNTS"
+		}
+		out[a.Attribute] = append(out[a.Attribute], a)
+	}
+	return out
+}
+
+// applyPenalty reduces the available value of a deposit that has not matured yet
+func applyPenalty(a *Asset, maturityDate string, opts Options, asOf time.Time) {
+	maturity, ok := parseDate(maturityDate)
+	if ok {
+		a.MaturityDate = maturity.Format(models.DateLayout)
+		if !maturity.After(asOf) {
+			a.Note = "matured, available without penalty"
+			return
+		}
+	}
+	a.AvailableValue = a.Value * (1 - opts.PrematurePenaltyPercent/100)
+	if ok {
+		a.Note = fmt.Sprintf("matures in %d days, breaking it now costs about %v%%", int(maturity.Sub(asOf).Hours()/24), opts.PrematurePenaltyPercent)
+	} else {
+		a.Note = fmt.Sprintf("maturity date unknown, breaking it early costs about %v%%", opts.PrematurePenaltyPercent)
+	}
+}
+
+// wholeAttribute is a savings or deposit attribute without account details
+func wholeAttribute(attribute string, value float64, opts Options) Asset {
+	a := Asset{Attribute: attribute, Value: value, AvailableValue: value, Source: allocation.SourceNetWorth}
+	if attribute == "ASSET_TYPE_SAVINGS_ACCOUNTS" {
+		a.Name, a.Tier = "Savings accounts", TierSavings
+		return a
+	}
+	a.Name, a.Tier = "Deposits", TierDeposits
+	a.AvailableValue = value * (1 - opts.PrematurePenaltyPercent/100)
+	a.Note = fmt.Sprintf("no account details, assumed to be broken early at about %v%%", opts.PrematurePenaltyPercent)
+	return a
+}
+
+func parseDate(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(models.DateLayout, s)
+	return t, err == nil
+}
@@ -0,0 +1,78 @@
+package liquidity
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const netWorth = `{
+	"netWorthResponse": {"assetValues": [
+		{"netWorthAttribute": "ASSET_TYPE_SAVINGS_ACCOUNTS", "value": {"currencyCode": "INR", "units": "120000"}},
+		{"netWorthAttribute": "ASSET_TYPE_DEPOSITS", "value": {"currencyCode": "INR", "units": "200000"}},
+		{"netWorthAttribute": "ASSET_TYPE_MUTUAL_FUND", "value": {"currencyCode": "INR", "units": "150000"}},
+		{"netWorthAttribute": "ASSET_TYPE_EPF", "value": {"currencyCode": "INR", "units": "500000"}}
+	]},
+	"mfSchemeAnalytics": {"schemeAnalytics": [
+		{"schemeDetail": {"isinNumber": "INF000000001", "nameData": {"longName": "Alpha Overnight Fund"}, "assetClass": "CASH", "categoryName": "OVERNIGHT"},
+		 "enrichedAnalytics": {"analytics": {"schemeDetails": {"currentValue": {"currencyCode": "INR", "units": "50000"}}}}},
+		{"schemeDetail": {"isinNumber": "INF000000002", "nameData": {"longName": "Beta Flexi Cap Fund"}, "assetClass": "EQUITY", "categoryName": "FLEXI_CAP_FUND"},
+		 "enrichedAnalytics": {"analytics": {"schemeDetails": {"currentValue": {"currencyCode": "INR", "units": "100000"}}}}}
+	]},
+	"accountDetailsBulkResponse": {"accountDetailsMap": {
+		"a": {"accountDetails": {"accInstrumentType": "ACC_INSTRUMENT_TYPE_DEPOSIT", "maskedAccountNumber": "XX11"},
+		      "depositSummary": {"depositAccountType": "DEPOSIT_ACCOUNT_TYPE_SAVINGS", "currentBalance": {"currencyCode": "INR", "units": "100000"}}},
+		"b": {"accountDetails": {"accInstrumentType": "ACC_INSTRUMENT_TYPE_DEPOSIT", "maskedAccountNumber": "XX22"},
+		      "depositSummary": {"depositAccountType": "DEPOSIT_ACCOUNT_TYPE_FIXED", "currentBalance": {"currencyCode": "INR", "units": "200000"}, "maturityDate": "2026-06-30T00:00:00Z"}}
+	}}
+}`
+
+const bank = `{"bankTransactions": [{"bank": "Test Bank", "txns": [
+	["100000", "SALARY FROM ACME", "2025-01-01", 1, "NEFT", "200000"],
+	["40000", "UPI-LANDLORD-RENT", "2025-01-05", 2, "UPI", "160000"],
+	["10000", "ACH D-ALPHAMF-SIP", "2025-01-10", 2, "ACH", "150000"],
+	["60000", "UPI-GROCER", "2025-02-05", 2, "UPI", "90000"],
+	["10000", "ACH D-ALPHAMF-SIP", "2025-02-10", 2, "ACH", "80000"]
+]}]}`
+
+func TestAnalyze(t *testing.T) {
+	var nw models.FetchNetWorthResponse
+	var txns models.BankTransactionsResponse
+	if err := json.Unmarshal([]byte(netWorth), &nw); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(bank), &txns); err != nil {
+		t.Fatal(err)
+	}
+	asOf := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	r := Analyze(&nw, &txns, Options{TargetMonths: 6, PrematurePenaltyPercent: 1}, asOf)
+
+	if o := r.Outflow; o.Months != 2 || o.Total != 60000 || o.Investments != 10000 || o.Expenses != 50000 {
+		t.Errorf("outflow = %+v", o)
+	}
+	// the savings account without details is kept, the deposit loses the penalty
+	want := map[Tier]float64{TierSavings: 120000, TierLiquidFunds: 50000, TierDeposits: 198000, TierMarket: 100000, TierLocked: 500000}
+	for _, s := range r.Tiers {
+		if s.AvailableValue != want[s.Tier] {
+			t.Errorf("%s = %v, want %v", s.Tier, s.AvailableValue, want[s.Tier])
+		}
+	}
+	if r.Tiers[1].CumulativeRunwayMonths == nil || *r.Tiers[1].CumulativeRunwayMonths != 3.4 || r.Tiers[4].CumulativeRunwayMonths != nil {
+		t.Errorf("runway = %+v", r.Tiers)
+	}
+	ef := r.EmergencyFund
+	if ef.Value != 368000 || *ef.RunwayMonths != 7.4 || *ef.Target != 300000 || ef.Shortfall != nil || *ef.Surplus != 68000 {
+		t.Errorf("emergency fund = %+v", ef)
+	}
+	if r.Assets[0].Tier != TierSavings || r.Assets[len(r.Assets)-1].Tier != TierLocked {
+		t.Errorf("assets are not in tier order: %+v", r.Assets)
+	}
+
+	// deposits past maturity are available in full and given expenses replace the bank outflow
+	r = Analyze(&nw, &txns, Options{MonthlyExpenses: 100000, PrematurePenaltyPercent: 1}, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	if r.Tiers[2].AvailableValue != 200000 || r.Outflow.Method != OutflowGiven || *r.EmergencyFund.Shortfall != 230000 {
+		t.Errorf("after maturity = %+v, %+v", r.Tiers[2], r.EmergencyFund)
+	}
+}
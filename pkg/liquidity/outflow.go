@@ -0,0 +1,66 @@
+package liquidity
+
+import (
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Methods of the monthly outflow
+const (
+	OutflowBank  = "bank_transactions"
+	OutflowGiven = "monthly_expenses"
+	OutflowNone  = "none"
+)
+
+// savingsKinds are debits that move money into investments, which can be paused in an emergency
+var savingsKinds = map[string]bool{
+	banking.KindSIP:              true,
+	banking.KindRecurringDeposit: true,
+	banking.KindInvestment:       true,
+}
+
+// Outflow is the average monthly money going out of the bank accounts
+type Outflow struct {
+	Method string `json:"method"`
+	Months int    `json:"months"`
+	// Total is the average of all debits
+	Total float64 `json:"total"`
+	// Investments is the average of SIP, recurring deposit and other investment debits
+	Investments float64 `json:"investments"`
+	// Expenses is what the runway is measured in: Total less Investments, or the monthly_expenses given
+	Expenses float64 `json:"expenses"`
+}
+
+// monthlyOutflow averages the debits over the months that have transactions
+func monthlyOutflow(resp *models.BankTransactionsResponse) Outflow {
+	o := Outflow{Method: OutflowNone}
+	if resp == nil {
+		return o
+	}
+	months := make(map[string]bool)
+	var total, investments float64
+	for _, b := range resp.BankTransactions {
+		for _, t := range b.Txns {
+			if t.Sign() == 0 || len(t.Date) < 7 {
+				continue
+			}
+			months[t.Date[:7]] = true
+			if t.Sign() > 0 {
+				continue
+			}
+			total += t.AmountValue()
+			if savingsKinds[banking.KindOf(t.Narration)] {
+				investments += t.AmountValue()
+			}
+		}
+	}
+	if len(months) == 0 {
+		return o
+	}
+	n := float64(len(months))
+	o.Method, o.Months = OutflowBank, len(months)
+	o.Total = models.Round(total/n, 2)
+	o.Investments = models.Round(investments/n, 2)
+	o.Expenses = models.Round(o.Total-o.Investments, 2)
+	return o
+}
@@ -0,0 +1,85 @@
+package mfportfolio
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed categories.csv
+var categoriesCSV string
+
+// Category is a row of the category table shipped with the server
+type Category struct {
+	Name       string `json:"categoryName"`
+	Label      string `json:"label"`
+	PeerGroup  string `json:"peerGroup"`
+	AssetClass string `json:"assetClass"`
+	// MaxFunds is the number of funds of the peer group a portfolio needs
+	MaxFunds int `json:"maxFunds"`
+	// RegularExtraExpensePercent is the typical extra yearly expense ratio of a regular plan
+	RegularExtraExpensePercent float64 `json:"regularExtraExpensePercent"`
+}
+
+var categories = mustParseCategories(categoriesCSV)
+
+// parseCategories reads the category table keyed by categoryName
+func parseCategories(data string) (map[string]Category, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = 6
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("category table is empty")
+	}
+	out := make(map[string]Category, len(rows)-1)
+	// the first row is the header
+	for i, row := range rows[1:] {
+		maxFunds, err := strconv.Atoi(row[4])
+		if err != nil || maxFunds < 1 {
+			return nil, fmt.Errorf("row %d: max_funds must be a positive integer", i+2)
+		}
+		extra, err := strconv.ParseFloat(row[5], 64)
+		if err != nil || extra < 0 {
+			return nil, fmt.Errorf("row %d: regular_extra_expense_percent must be a number not below zero", i+2)
+		}
+		if _, ok := out[row[0]]; ok {
+			return nil, fmt.Errorf("row %d: duplicate category %s", i+2, row[0])
+		}
+		out[row[0]] = Category{Name: row[0], Label: row[1], PeerGroup: row[2], AssetClass: row[3], MaxFunds: maxFunds, RegularExtraExpensePercent: extra}
+	}
+	return out, nil
+}
+
+func mustParseCategories(data string) map[string]Category {
+	c, err := parseCategories(data)
+	if err != nil {
+		panic("mfportfolio: invalid categories.csv: " + err.Error())
+	}
+	return c
+}
+
+// categoryOf looks up the category of a scheme. Categories missing from the table
+// are their own peer group with one fund and the expense difference of their asset class.
+func categoryOf(categoryName, assetClass string) (Category, bool) {
+	if c, ok := categories[categoryName]; ok {
+		return c, true
+	}
+	name := categoryName
+	if name == "" {
+		name = "UNKNOWN"
+	}
+	extra := 1.0
+	switch assetClass {
+	case "DEBT", "CASH":
+		extra = 0.5
+	case "COMMODITY":
+		extra = 0.4
+	}
+	return Category{Name: categoryName, Label: strings.ToLower(strings.ReplaceAll(name, "_", " ")), PeerGroup: name, AssetClass: assetClass, MaxFunds: 1, RegularExtraExpensePercent: extra}, false
+}
@@ -0,0 +1,331 @@
+package mfportfolio
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/returns"
+)
+
+// DefaultUnderperformanceMargin is how many percentage points a fund's XIRR may
+// trail the median of its peers before it is flagged
+const DefaultUnderperformanceMargin = 1
+
+// XIRR sources of a fund
+const (
+	XIRRSourceAnalytics = "mfSchemeAnalytics"
+	XIRRSourceComputed  = "fetch_mf_transactions"
+)
+
+var (
+	regularNamePattern = regexp.MustCompile(`(?i)\bregular\b`)
+	directNamePattern  = regexp.MustCompile(`(?i)\bdirect\b`)
+)
+
+// Fund is a scheme of the portfolio, combining the rows reported for the same ISIN
+type Fund struct {
+	ISIN          string   `json:"isin"`
+	SchemeName    string   `json:"schemeName"`
+	AMC           string   `json:"amc"`
+	Category      Category `json:"category"`
+	PlanType      string   `json:"planType"`
+	CurrentValue  float64  `json:"currentValue"`
+	WeightPercent float64  `json:"weightPercent"`
+	XIRRPercent   *float64 `json:"xirrPercent"`
+	XIRRSource    string   `json:"xirrSource,omitempty"`
+	// Rows is the number of mfSchemeAnalytics entries of the ISIN
+	Rows int `json:"rows"`
+}
+
+// Duplication is a peer group holding more funds than a portfolio needs
+type Duplication struct {
+	PeerGroup     string   `json:"peerGroup"`
+	Label         string   `json:"label"`
+	MaxFunds      int      `json:"maxFunds"`
+	Funds         []string `json:"funds"`
+	ISINs         []string `json:"isins"`
+	CombinedValue float64  `json:"combinedValue"`
+	WeightPercent float64  `json:"weightPercent"`
+}
+
+// RegularPlan is a fund held in a regular plan, which pays distributor commission through a higher expense ratio
+type RegularPlan struct {
+	ISIN       string `json:"isin"`
+	SchemeName string `json:"schemeName"`
+	// Evidence lists what marks the fund as a regular plan: planType and/or schemeName
+	Evidence             []string `json:"evidence"`
+	CurrentValue         float64  `json:"currentValue"`
+	ExtraExpensePercent  float64  `json:"extraExpensePercent"`
+	EstimatedAnnualCost  float64  `json:"estimatedAnnualCost"`
+	EstimatedTenYearCost float64  `json:"estimatedTenYearCost"`
+}
+
+// Underperformer is a fund whose XIRR trails the median of its peers in the portfolio
+type Underperformer struct {
+	ISIN              string  `json:"isin"`
+	SchemeName        string  `json:"schemeName"`
+	PeerGroup         string  `json:"peerGroup"`
+	XIRRPercent       float64 `json:"xirrPercent"`
+	PeerMedianPercent float64 `json:"peerMedianXirrPercent"`
+	GapPercent        float64 `json:"gapPercent"`
+	Peers             int     `json:"peers"`
+}
+
+// Report is the analysis of a mutual fund portfolio
+type Report struct {
+	TotalValue             float64          `json:"totalValue"`
+	Funds                  []Fund           `json:"funds"`
+	Duplications           []Duplication    `json:"categoryDuplications"`
+	RegularPlans           []RegularPlan    `json:"regularPlans"`
+	RegularPlanAnnualCost  float64          `json:"regularPlanAnnualCost"`
+	UnderperformanceMargin float64          `json:"underperformanceMarginPercent"`
+	Underperformers        []Underperformer `json:"underperformers"`
+	Notes                  []string         `json:"notes"`
+}
+
+// Analyze checks the schemes in mfSchemeAnalytics for funds duplicating a
+// category, regular plans and funds trailing the median XIRR of their peers.
+// Funds without a reported XIRR use the one computed from the transactions when
+// they are available.
+func Analyze(analytics *models.MFSchemeAnalytics, txns *models.MFTransactionsResponse, margin float64) *Report {
+	r := &Report{
+		Funds:                  []Fund{},
+		Duplications:           []Duplication{},
+		RegularPlans:           []RegularPlan{},
+		UnderperformanceMargin: margin,
+		Underperformers:        []Underperformer{},
+		Notes:                  []string{},
+	}
+	if analytics == nil || len(analytics.SchemeAnalytics) == 0 {
+		r.Notes = append(r.Notes, "no mutual fund holdings available")
+		return r
+	}
+	r.Funds = funds(analytics, r)
+	fillComputedXIRR(r.Funds, txns, analytics)
+
+	for i, f := range r.Funds {
+		r.TotalValue += f.CurrentValue
+		if f.XIRRPercent == nil {
+			r.Notes = append(r.Notes, fmt.Sprintf("%s has no XIRR and is left out of the peer comparison", f.SchemeName))
+		}
+		if f.Category.Name == "" {
+			r.Notes = append(r.Notes, fmt.Sprintf("%s has no categoryName", f.SchemeName))
+		} else if _, ok := categories[f.Category.Name]; !ok {
+			r.Notes = append(r.Notes, fmt.Sprintf("category %s of %s is not in the category table, it is compared with its own category only", f.Category.Name, f.SchemeName))
+		}
+		r.Funds[i].CurrentValue = models.Round(f.CurrentValue, 2)
+	}
+	for i, f := range r.Funds {
+		if r.TotalValue > 0 {
+			r.Funds[i].WeightPercent = models.Round(f.CurrentValue/r.TotalValue*100, 2)
+		}
+	}
+	r.Duplications = duplications(r.Funds, r.TotalValue)
+	r.RegularPlans = regularPlans(r.Funds, r)
+	for _, p := range r.RegularPlans {
+		r.RegularPlanAnnualCost += p.EstimatedAnnualCost
+	}
+	r.RegularPlanAnnualCost = models.Round(r.RegularPlanAnnualCost, 2)
+	r.Underperformers = underperformers(r.Funds, margin)
+	r.TotalValue = models.Round(r.TotalValue, 2)
+	return r
+}
+
+// funds combines the analytics rows by ISIN, weighting the XIRR by current value
+func funds(analytics *models.MFSchemeAnalytics, r *Report) []Fund {
+	var out []Fund
+	index := make(map[string]int)
+	xirrWeight := make(map[string]float64)
+	xirrSum := make(map[string]float64)
+	for _, s := range analytics.SchemeAnalytics {
+		d := s.SchemeDetail
+		details := s.EnrichedAnalytics.Analytics.SchemeDetails
+		value := details.CurrentValue.Float()
+		key := d.ISINNumber
+		if key == "" {
+			key = d.NameData.LongName
+		}
+		i, ok := index[key]
+		if !ok {
+			category, _ := categoryOf(d.CategoryName, d.AssetClass)
+			i = len(out)
+			index[key] = i
+			out = append(out, Fund{ISIN: d.ISINNumber, SchemeName: d.NameData.LongName, AMC: d.AMC, Category: category, PlanType: d.PlanType})
+		}
+		out[i].CurrentValue += value
+		out[i].Rows++
+		// a zero XIRR is what the analytics report when none was computed
+		if details.XIRR != 0 {
+			xirrSum[key] += details.XIRR * value
+			xirrWeight[key] += value
+		}
+	}
+	for key, i := range index {
+		f := &out[i]
+		if f.Rows > 1 {
+			r.Notes = append(r.Notes, fmt.Sprintf("%s is reported in %d rows of mfSchemeAnalytics, they are combined", f.SchemeName, f.Rows))
+		}
+		if xirrWeight[key] > 0 {
+			xirr := models.Round(xirrSum[key]/xirrWeight[key], 2)
+			f.XIRRPercent, f.XIRRSource = &xirr, XIRRSourceAnalytics
+		}
+	}
+	sort.Strings(r.Notes)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CurrentValue > out[j].CurrentValue })
+	return out
+}
+
+// fillComputedXIRR sets the XIRR of the funds the analytics have none for from their transactions
+func fillComputedXIRR(funds []Fund, txns *models.MFTransactionsResponse, analytics *models.MFSchemeAnalytics) {
+	if txns == nil || len(txns.MFTransactions) == 0 {
+		return
+	}
+	missing := false
+	for _, f := range funds {
+		missing = missing || f.XIRRPercent == nil
+	}
+	if !missing {
+		return
+	}
+	computed, err := returns.Compute(txns, analytics, returns.Options{})
+	if err != nil {
+		return
+	}
+	byISIN := make(map[string]*float64)
+	for _, s := range computed.Schemes {
+		byISIN[s.ISIN] = s.XIRRPercent
+	}
+	for i, f := range funds {
+		if x := byISIN[f.ISIN]; f.XIRRPercent == nil && x != nil {
+			xirr := models.Round(*x, 2)
+			funds[i].XIRRPercent, funds[i].XIRRSource = &xirr, XIRRSourceComputed
+		}
+	}
+}
+
+func duplications(funds []Fund, total float64) []Duplication {
+	groups := make(map[string][]Fund)
+	var order []string
+	for _, f := range funds {
+		g := f.Category.PeerGroup
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], f)
+	}
+	out := []Duplication{}
+	for _, g := range order {
+		members := groups[g]
+		if len(members) <= members[0].Category.MaxFunds {
+			continue
+		}
+		d := Duplication{PeerGroup: g, Label: members[0].Category.Label, MaxFunds: members[0].Category.MaxFunds}
+		labels := make(map[string]bool)
+		for _, f := range members {
+			d.Funds = append(d.Funds, f.SchemeName)
+			d.ISINs = append(d.ISINs, f.ISIN)
+			d.CombinedValue += f.CurrentValue
+			labels[f.Category.Label] = true
+		}
+		if len(labels) > 1 {
+			names := make([]string, 0, len(labels))
+			for l := range labels {
+				names = append(names, l)
+			}
+			sort.Strings(names)
+			d.Label = strings.Join(names, " / ")
+		}
+		if total > 0 {
+			d.WeightPercent = models.Round(d.CombinedValue/total*100, 2)
+		}
+		d.CombinedValue = models.Round(d.CombinedValue, 2)
+		out = append(out, d)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CombinedValue > out[j].CombinedValue })
+	return out
+}
+
+func regularPlans(funds []Fund, r *Report) []RegularPlan {
+	out := []RegularPlan{}
+	for _, f := range funds {
+		var evidence []string
+		if strings.EqualFold(f.PlanType, "REGULAR") {
+			evidence = append(evidence, "planType")
+		}
+		regularName := regularNamePattern.MatchString(f.SchemeName)
+		if regularName {
+			evidence = append(evidence, "schemeName")
+		}
+		switch {
+		case regularName && strings.EqualFold(f.PlanType, "DIRECT"):
+			r.Notes = append(r.Notes, fmt.Sprintf("%s has planType DIRECT but its name says Regular", f.SchemeName))
+		case strings.EqualFold(f.PlanType, "REGULAR") && directNamePattern.MatchString(f.SchemeName):
+			r.Notes = append(r.Notes, fmt.Sprintf("%s has planType REGULAR but its name says Direct", f.SchemeName))
+		}
+		if len(evidence) == 0 {
+			continue
+		}
+		extra := f.Category.RegularExtraExpensePercent
+		annual := f.CurrentValue * extra / 100
+		out = append(out, RegularPlan{
+			ISIN:                 f.ISIN,
+			SchemeName:           f.SchemeName,
+			Evidence:             evidence,
+			CurrentValue:         f.CurrentValue,
+			ExtraExpensePercent:  extra,
+			EstimatedAnnualCost:  models.Round(annual, 2),
+			EstimatedTenYearCost: models.Round(annual*10, 2),
+		})
+	}
+	if len(out) > 0 {
+		r.Notes = append(r.Notes, "regular plan costs are estimated from typical expense ratio differences in the category table and ignore compounding; switching to the direct plan is a redemption and may incur exit load and capital gains tax")
+	}
+	return out
+}
+
+// underperformers flags funds trailing the median XIRR of the funds in their peer
+// group by more than margin percentage points. Groups need two funds with an XIRR.
+func underperformers(funds []Fund, margin float64) []Underperformer {
+	groups := make(map[string][]Fund)
+	for _, f := range funds {
+		if f.XIRRPercent != nil {
+			groups[f.Category.PeerGroup] = append(groups[f.Category.PeerGroup], f)
+		}
+	}
+	out := []Underperformer{}
+	for _, f := range funds {
+		peers := groups[f.Category.PeerGroup]
+		if f.XIRRPercent == nil || len(peers) < 2 {
+			continue
+		}
+		xirrs := make([]float64, len(peers))
+		for i, p := range peers {
+			xirrs[i] = *p.XIRRPercent
+		}
+		m := median(xirrs)
+		if gap := m - *f.XIRRPercent; gap > margin {
+			out = append(out, Underperformer{
+				ISIN:              f.ISIN,
+				SchemeName:        f.SchemeName,
+				PeerGroup:         f.Category.PeerGroup,
+				XIRRPercent:       *f.XIRRPercent,
+				PeerMedianPercent: models.Round(m, 2),
+				GapPercent:        models.Round(gap, 2),
+				Peers:             len(peers),
+			})
+		}
+	}
+	return out
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,77 @@
+package mfportfolio
+
+import (
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func scheme(isin, name, category, plan string, value, xirr float64) models.SchemeAnalytics {
+	var s models.SchemeAnalytics
+	money := models.NewMoney(value)
+	s.SchemeDetail.ISINNumber = isin
+	s.SchemeDetail.NameData.LongName = name
+	s.SchemeDetail.CategoryName = category
+	s.SchemeDetail.AssetClass = "EQUITY"
+	s.SchemeDetail.PlanType = plan
+	s.EnrichedAnalytics.Analytics.SchemeDetails.CurrentValue = &money
+	s.EnrichedAnalytics.Analytics.SchemeDetails.XIRR = xirr
+	return s
+}
+
+func TestAnalyze(t *testing.T) {
+	analytics := &models.MFSchemeAnalytics{SchemeAnalytics: []models.SchemeAnalytics{
+		scheme("INF000000001", "Alpha Bluechip Fund - Direct Plan - Growth", "LARGE_CAP_FUND", "DIRECT", 300000, 14),
+		scheme("INF000000002", "Beta Large Cap Fund - Regular Plan - Growth", "LARGE_CAP_FUND", "REGULAR", 200000, 11),
+		scheme("INF000000003", "Gamma Top 100 Fund - Direct Plan - Growth", "LARGE_CAP_FUND", "DIRECT", 100000, 13.5),
+		scheme("INF000000004", "Delta Balanced Advantage Fund - Direct Plan", "BALANCED_ADVANTAGE_FUND", "DIRECT", 150000, 10),
+		scheme("INF000000005", "Epsilon Dynamic Asset Allocation Fund - Direct", "DYNAMIC_ASSET_ALLOCATION", "DIRECT", 50000, 0),
+		scheme("INF000000005", "Epsilon Dynamic Asset Allocation Fund - Direct", "DYNAMIC_ASSET_ALLOCATION", "DIRECT", 50000, 9),
+		scheme("INF000000006", "Zeta Small Cap Fund - Direct Plan", "SMALL_CAP_FUND", "DIRECT", 100000, 0),
+	}}
+	r := Analyze(analytics, nil, DefaultUnderperformanceMargin)
+
+	if len(r.Funds) != 6 || r.TotalValue != 950000 {
+		t.Fatalf("funds = %d, total = %v", len(r.Funds), r.TotalValue)
+	}
+	if len(r.Duplications) != 2 || r.Duplications[0].PeerGroup != "LARGE_CAP" || len(r.Duplications[0].Funds) != 3 || r.Duplications[0].WeightPercent != 63.16 {
+		t.Errorf("duplications = %+v", r.Duplications)
+	}
+	if d := r.Duplications[1]; d.PeerGroup != "DYNAMIC_ASSET_ALLOCATION" || d.Label != "Balanced advantage / Dynamic asset allocation" {
+		t.Errorf("hybrid duplication = %+v", d)
+	}
+	if len(r.RegularPlans) != 1 || r.RegularPlans[0].EstimatedAnnualCost != 2000 || len(r.RegularPlans[0].Evidence) != 2 {
+		t.Errorf("regular plans = %+v", r.RegularPlans)
+	}
+	if len(r.Underperformers) != 1 || r.Underperformers[0].ISIN != "INF000000002" || r.Underperformers[0].GapPercent != 2.5 {
+		t.Errorf("underperformers = %+v", r.Underperformers)
+	}
+	for _, f := range r.Funds {
+		switch f.ISIN {
+		case "INF000000005":
+			if f.Rows != 2 || f.XIRRPercent == nil || *f.XIRRPercent != 9 {
+				t.Errorf("combined fund = %+v, want the XIRR of the row that has one", f)
+			}
+		case "INF000000006":
+			if f.XIRRPercent != nil {
+				t.Errorf("fund without XIRR = %+v", f)
+			}
+		}
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	if _, ok := categories["ELSS"]; !ok {
+		t.Errorf("shipped category table has no ELSS row")
+	}
+	for _, data := range []string{
+		"category_name,label,peer_group,asset_class,max_funds,regular_extra_expense_percent\nX,x,X,EQUITY,0,1\n",
+		"category_name,label,peer_group,asset_class,max_funds,regular_extra_expense_percent\nX,x,X,EQUITY,1,-1\n",
+		"category_name,label,peer_group,asset_class,max_funds,regular_extra_expense_percent\nX,x,X,EQUITY,1,1\nX,x,X,EQUITY,1,1\n",
+		"category_name,label\nX,x\n",
+	} {
+		if _, err := parseCategories(data); err == nil {
+			t.Errorf("parseCategories(%q) = nil error", data)
+		}
+	}
+}
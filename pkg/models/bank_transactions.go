@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bank transaction types used in the fourth field of a bank transaction
+const (
+	BankTxnTypeCredit      = 1
+	BankTxnTypeDebit       = 2
+	BankTxnTypeOpening     = 3
+	BankTxnTypeInterest    = 4
+	BankTxnTypeTDS         = 5
+	BankTxnTypeInstallment = 6
+	BankTxnTypeClosing     = 7
+	BankTxnTypeOthers      = 8
+)
+
+// BankTransactionsResponse is the payload of the fetch_bank_transactions tool
+type BankTransactionsResponse struct {
+	SchemaDescription string             `json:"schemaDescription,omitempty"`
+	BankTransactions  []BankTransactions `json:"bankTransactions,omitempty"`
+}
+
+// BankTransactions holds the transactions of one bank account
+type BankTransactions struct {
+	Bank string    `json:"bank"`
+	Txns []BankTxn `json:"txns"`
+}
+
+// BankTxn is a single bank transaction, serialised as
+// [transactionAmount, transactionNarration, transactionDate, transactionType, transactionMode, currentBalance].
+// Amounts are kept as the strings they are sent as.
+type BankTxn struct {
+	Amount         string
+	Narration      string
+	Date           string
+	Type           int
+	Mode           string
+	CurrentBalance string
+}
+
+func (t *BankTxn) UnmarshalJSON(data []byte) error {
+	return unmarshalPositional(data, 4, &t.Amount, &t.Narration, &t.Date, &t.Type, &t.Mode, &t.CurrentBalance)
+}
+
+func (t BankTxn) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{t.Amount, t.Narration, t.Date, t.Type, t.Mode, t.CurrentBalance})
+}
+
+// Time parses the transaction date
+func (t BankTxn) Time() (time.Time, error) {
+	return time.Parse(DateLayout, t.Date)
+}
+
+// AmountValue returns the transaction amount, treating an unparsable amount as zero
+func (t BankTxn) AmountValue() float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(t.Amount), 64)
+	return v
+}
+
+// BalanceValue returns the account balance after the transaction and whether it was present
+func (t BankTxn) BalanceValue() (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(t.CurrentBalance), 64)
+	return v, err == nil
+}
+
+// Sign returns 1 for transactions that add money to the account, -1 for those that
+// take money out and 0 for opening and closing balance rows. OTHERS are treated as
+// debits, which is what they are in the data files.
+func (t BankTxn) Sign() int {
+	switch t.Type {
+	case BankTxnTypeCredit, BankTxnTypeInterest:
+		return 1
+	case BankTxnTypeOpening, BankTxnTypeClosing:
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BureauDateLayout is the layout of dates in credit bureau reports, e.g. 20060110
+const BureauDateLayout = "20060102"
+
+// CreditReportResponse is the payload of the fetch_credit_report tool
+type CreditReportResponse struct {
+	CreditReports []CreditReport `json:"creditReports,omitempty"`
+}
+
+// CreditReport is one bureau report of a user. All values are strings as sent by the bureau.
+type CreditReport struct {
+	CreditReportData CreditReportData `json:"creditReportData"`
+	Vendor           string           `json:"vendor,omitempty"`
+}
+
+type CreditReportData struct {
+	UserMessage         *UserMessage        `json:"userMessage,omitempty"`
+	CreditProfileHeader CreditProfileHeader `json:"creditProfileHeader"`
+	CurrentApplication  *CurrentApplication `json:"currentApplication,omitempty"`
+	CreditAccount       CreditAccount       `json:"creditAccount"`
+	MatchResult         *MatchResult        `json:"matchResult,omitempty"`
+	TotalCapsSummary    *TotalCapsSummary   `json:"totalCapsSummary,omitempty"`
+	NonCreditCaps       *NonCreditCaps      `json:"nonCreditCaps,omitempty"`
+	Score               *BureauScore        `json:"score,omitempty"`
+	Segment             json.RawMessage     `json:"segment,omitempty"`
+	Caps                *Caps               `json:"caps,omitempty"`
+}
+
+type UserMessage struct {
+	UserMessageText string `json:"userMessageText"`
+}
+
+type CreditProfileHeader struct {
+	ReportDate string `json:"reportDate"`
+	ReportTime string `json:"reportTime,omitempty"`
+}
+
+type CurrentApplication struct {
+	CurrentApplicationDetails CurrentApplicationDetails `json:"currentApplicationDetails"`
+}
+
+type CurrentApplicationDetails struct {
+	EnquiryReason           string                  `json:"enquiryReason,omitempty"`
+	AmountFinanced          string                  `json:"amountFinanced,omitempty"`
+	DurationOfAgreement     string                  `json:"durationOfAgreement,omitempty"`
+	CurrentApplicantDetails CurrentApplicantDetails `json:"currentApplicantDetails"`
+}
+
+type CurrentApplicantDetails struct {
+	DateOfBirthApplicant string `json:"dateOfBirthApplicant,omitempty"`
+}
+
+type CreditAccount struct {
+	CreditAccountSummary CreditAccountSummary  `json:"creditAccountSummary"`
+	CreditAccountDetails []CreditAccountDetail `json:"creditAccountDetails"`
+}
+
+type CreditAccountSummary struct {
+	Account                 CreditAccountCounts     `json:"account"`
+	TotalOutstandingBalance TotalOutstandingBalance `json:"totalOutstandingBalance"`
+}
+
+type CreditAccountCounts struct {
+	CreditAccountTotal         string `json:"creditAccountTotal"`
+	CreditAccountActive        string `json:"creditAccountActive"`
+	CreditAccountDefault       string `json:"creditAccountDefault"`
+	CreditAccountClosed        string `json:"creditAccountClosed"`
+	CADSuitFiledCurrentBalance string `json:"cadSuitFiledCurrentBalance"`
+}
+
+type TotalOutstandingBalance struct {
+	OutstandingBalanceSecured             string `json:"outstandingBalanceSecured"`
+	OutstandingBalanceSecuredPercentage   string `json:"outstandingBalanceSecuredPercentage"`
+	OutstandingBalanceUnSecured           string `json:"outstandingBalanceUnSecured"`
+	OutstandingBalanceUnSecuredPercentage string `json:"outstandingBalanceUnSecuredPercentage"`
+	OutstandingBalanceAll                 string `json:"outstandingBalanceAll"`
+}
+
+// CreditAccountDetail is one tradeline of the report
+type CreditAccountDetail struct {
+	SubscriberName                    string `json:"subscriberName"`
+	PortfolioType                     string `json:"portfolioType"`
+	AccountType                       string `json:"accountType"`
+	OpenDate                          string `json:"openDate"`
+	CreditLimitAmount                 string `json:"creditLimitAmount,omitempty"`
+	HighestCreditOrOriginalLoanAmount string `json:"highestCreditOrOriginalLoanAmount"`
+	AccountStatus                     string `json:"accountStatus"`
+	PaymentRating                     string `json:"paymentRating"`
+	PaymentHistoryProfile             string `json:"paymentHistoryProfile"`
+	CurrentBalance                    string `json:"currentBalance"`
+	AmountPastDue                     string `json:"amountPastDue"`
+	DateReported                      string `json:"dateReported"`
+	DateClosed                        string `json:"dateClosed,omitempty"`
+	OccupationCode                    string `json:"occupationCode,omitempty"`
+	RateOfInterest                    string `json:"rateOfInterest,omitempty"`
+	RepaymentTenure                   string `json:"repaymentTenure,omitempty"`
+	DateOfAddition                    string `json:"dateOfAddition,omitempty"`
+	CurrencyCode                      string `json:"currencyCode,omitempty"`
+	AccountHolderTypeCode             string `json:"accountHolderTypeCode,omitempty"`
+}
+
+type MatchResult struct {
+	ExactMatch string `json:"exactMatch"`
+}
+
+type TotalCapsSummary struct {
+	TotalCapsLast7Days   string `json:"totalCapsLast7Days"`
+	TotalCapsLast30Days  string `json:"totalCapsLast30Days"`
+	TotalCapsLast90Days  string `json:"totalCapsLast90Days"`
+	TotalCapsLast180Days string `json:"totalCapsLast180Days"`
+}
+
+type NonCreditCaps struct {
+	NonCreditCapsSummary        NonCreditCapsSummary    `json:"nonCreditCapsSummary"`
+	CapsApplicationDetailsArray []CapsApplicationDetail `json:"capsApplicationDetailsArray"`
+}
+
+type NonCreditCapsSummary struct {
+	NonCreditCapsLast7Days   string `json:"nonCreditCapsLast7Days"`
+	NonCreditCapsLast30Days  string `json:"nonCreditCapsLast30Days"`
+	NonCreditCapsLast90Days  string `json:"nonCreditCapsLast90Days"`
+	NonCreditCapsLast180Days string `json:"nonCreditCapsLast180Days"`
+}
+
+// Caps holds the credit application enquiries (CAPS) made on the user
+type Caps struct {
+	CapsSummary                 CapsSummary             `json:"capsSummary"`
+	CapsApplicationDetailsArray []CapsApplicationDetail `json:"capsApplicationDetailsArray"`
+}
+
+type CapsSummary struct {
+	CapsLast7Days   string `json:"capsLast7Days"`
+	CapsLast30Days  string `json:"capsLast30Days"`
+	CapsLast90Days  string `json:"capsLast90Days"`
+	CapsLast180Days string `json:"capsLast180Days"`
+}
+
+// CapsApplicationDetail is one enquiry. The bureau sends these keys capitalised.
+type CapsApplicationDetail struct {
+	SubscriberName string `json:"SubscriberName"`
+	DateOfRequest  string `json:"DateOfRequest,omitempty"`
+	EnquiryReason  string `json:"EnquiryReason,omitempty"`
+	FinancePurpose string `json:"FinancePurpose,omitempty"`
+}
+
+type BureauScore struct {
+	BureauScore                string `json:"bureauScore"`
+	BureauScoreConfidenceLevel string `json:"bureauScoreConfidenceLevel,omitempty"`
+}
+
+// ParseBureauDate parses a YYYYMMDD bureau date
+func ParseBureauDate(s string) (time.Time, error) {
+	return time.Parse(BureauDateLayout, strings.TrimSpace(s))
+}
+
+// ParseBureauAmount parses a numeric bureau field, treating an empty or unparsable value as zero
+func ParseBureauAmount(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
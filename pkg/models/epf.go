@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EPFDateLayout is the layout of the join and exit dates in the EPF details
+const EPFDateLayout = "02-01-2006"
+
+// EPFDateNotAvailable is the exit date of an establishment the member still works for
+const EPFDateNotAvailable = "NOT AVAILABLE"
+
+// EPFDetailsResponse is the payload of the fetch_epf_details tool
+type EPFDetailsResponse struct {
+	UANAccounts []UANAccount `json:"uanAccounts,omitempty"`
+}
+
+// UANAccount is one Universal Account Number with its member ids
+type UANAccount struct {
+	PhoneNumber json.RawMessage `json:"phoneNumber,omitempty"`
+	RawDetails  EPFRawDetails   `json:"rawDetails"`
+}
+
+type EPFRawDetails struct {
+	EstDetails       []EPFEstablishment `json:"est_details,omitempty"`
+	OverallPFBalance EPFOverallBalance  `json:"overall_pf_balance"`
+}
+
+// EPFEstablishment is the member id of a UAN at one employer
+type EPFEstablishment struct {
+	EstName   string       `json:"est_name"`
+	MemberID  string       `json:"member_id"`
+	Office    string       `json:"office"`
+	DOJEPF    string       `json:"doj_epf"`
+	DOEEPF    string       `json:"doe_epf"`
+	DOEEPS    string       `json:"doe_eps"`
+	PFBalance EPFPFBalance `json:"pf_balance"`
+	// Passbook is the month by month history of the member id, an extension of
+	// the EPF details filled in by imported passbooks
+	Passbook []EPFPassbookEntry `json:"passbook,omitempty"`
+}
+
+// Passbook entry types
+const (
+	EPFEntryOpening      = "OPENING"
+	EPFEntryContribution = "CONTRIBUTION"
+	EPFEntryInterest     = "INTEREST"
+	EPFEntryTransferIn   = "TRANSFER_IN"
+	EPFEntryTransferOut  = "TRANSFER_OUT"
+	EPFEntryWithdrawal   = "WITHDRAWAL"
+)
+
+// EPFPassbookEntry is one row of a member passbook. Shares are whole rupees,
+// negative for transfers out and withdrawals.
+type EPFPassbookEntry struct {
+	// Month is the wage month in YYYY-MM format, the month up to which interest
+	// was credited for interest and opening balance entries
+	Month         string `json:"month"`
+	Date          string `json:"date"`
+	Type          string `json:"type"`
+	Particulars   string `json:"particulars,omitempty"`
+	EmployeeShare string `json:"employee_share"`
+	EmployerShare string `json:"employer_share"`
+	PensionShare  string `json:"pension_share,omitempty"`
+}
+
+type EPFPFBalance struct {
+	NetBalance    string   `json:"net_balance"`
+	EmployeeShare EPFShare `json:"employee_share"`
+	EmployerShare EPFShare `json:"employer_share"`
+}
+
+// EPFShare is the credited amount and remaining balance of the employee or employer share
+type EPFShare struct {
+	Credit  string `json:"credit,omitempty"`
+	Balance string `json:"balance,omitempty"`
+}
+
+type EPFOverallBalance struct {
+	PensionBalance     string   `json:"pension_balance"`
+	CurrentPFBalance   string   `json:"current_pf_balance"`
+	EmployeeShareTotal EPFShare `json:"employee_share_total"`
+	EmployerShareTotal EPFShare `json:"employer_share_total"`
+}
+
+// JoinDate parses the date of joining the establishment
+func (e EPFEstablishment) JoinDate() (time.Time, bool) {
+	return parseEPFDate(e.DOJEPF)
+}
+
+// ExitDate parses the date of exit from the establishment. ok is false while
+// the member still works there.
+func (e EPFEstablishment) ExitDate() (time.Time, bool) {
+	return parseEPFDate(e.DOEEPF)
+}
+
+// Time parses the date the entry was posted
+func (e EPFPassbookEntry) Time() (time.Time, bool) {
+	return parseEPFDate(e.Date)
+}
+
+func parseEPFDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, EPFDateNotAvailable) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(EPFDateLayout, s)
+	return t, err == nil
+}
+
+// ParseEPFAmount parses an EPF amount, treating an empty or unparsable value as zero
+func ParseEPFAmount(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	return v
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FinancialYear is an Indian financial year running from 1 April to 31 March
+type FinancialYear struct {
+	StartYear int
+}
+
+var financialYearPattern = regexp.MustCompile(`^(?:FY)?\s*(\d{4})(?:\s*[-/]\s*(\d{2}|\d{4}))?$`)
+
+// ParseFinancialYear parses financial years written as "2024-25", "FY2024-25", "2024-2025" or "2024"
+func ParseFinancialYear(s string) (FinancialYear, error) {
+	m := financialYearPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return FinancialYear{}, fmt.Errorf("invalid financial year %q, expected a value like 2024-25", s)
+	}
+	start, _ := strconv.Atoi(m[1])
+	if m[2] != "" {
+		end, _ := strconv.Atoi(m[2])
+		if len(m[2]) == 2 {
+			end += start / 100 * 100
+			if end < start {
+				end += 100
+			}
+		}
+		if end != start+1 {
+			return FinancialYear{}, fmt.Errorf("invalid financial year %q, years must be consecutive", s)
+		}
+	}
+	return FinancialYear{StartYear: start}, nil
+}
+
+// Start returns 1 April of the financial year
+func (fy FinancialYear) Start() time.Time {
+	return time.Date(fy.StartYear, time.April, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns 31 March of the financial year
+func (fy FinancialYear) End() time.Time {
+	return time.Date(fy.StartYear+1, time.March, 31, 0, 0, 0, 0, time.UTC)
+}
+
+// Contains reports whether t falls within the financial year
+func (fy FinancialYear) Contains(t time.Time) bool {
+	return !t.Before(fy.Start()) && !t.After(fy.End())
+}
+
+func (fy FinancialYear) String() string {
+	return fmt.Sprintf("%d-%02d", fy.StartYear, (fy.StartYear+1)%100)
+}
+
+// FinancialYearOf returns the financial year containing t
+func FinancialYearOf(t time.Time) FinancialYear {
+	if t.Month() < time.April {
+		return FinancialYear{StartYear: t.Year() - 1}
+	}
+	return FinancialYear{StartYear: t.Year()}
+}
+
+// MonthsBetween counts the whole months from a to b
+func MonthsBetween(a, b time.Time) int {
+	months := (b.Year()-a.Year())*12 + int(b.Month()-a.Month())
+	if b.Day() < a.Day() {
+		months--
+	}
+	return months
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MF order types used in the first field of a MF transaction
+const (
+	MFOrderTypeBuy  = 1
+	MFOrderTypeSell = 2
+)
+
+// MFTransactionsResponse is the payload of the fetch_mf_transactions tool
+type MFTransactionsResponse struct {
+	MFTransactions    []MFSchemeTransactions `json:"mfTransactions,omitempty"`
+	SchemaDescription string                 `json:"schemaDescription,omitempty"`
+}
+
+// MFSchemeTransactions holds the transactions of one scheme in one folio
+type MFSchemeTransactions struct {
+	ISIN       string  `json:"isin"`
+	SchemeName string  `json:"schemeName"`
+	FolioID    string  `json:"folioId"`
+	Txns       []MFTxn `json:"txns"`
+}
+
+// MFTxn is a single MF transaction, serialised as
+// [orderType, transactionDate, purchasePrice, purchaseUnits, transactionAmount]
+type MFTxn struct {
+	OrderType int
+	Date      string
+	Price     float64
+	Units     float64
+	Amount    float64
+}
+
+func (t *MFTxn) UnmarshalJSON(data []byte) error {
+	return unmarshalPositional(data, 5, &t.OrderType, &t.Date, &t.Price, &t.Units, &t.Amount)
+}
+
+func (t MFTxn) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{t.OrderType, t.Date, t.Price, t.Units, t.Amount})
+}
+
+// Time parses the transaction date
+func (t MFTxn) Time() (time.Time, error) {
+	return time.Parse(DateLayout, t.Date)
+}
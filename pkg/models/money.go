@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DateLayout is the layout of transaction dates in the tool responses
+const DateLayout = "2006-01-02"
+
+// Money is an amount split into whole units and nanos, as used across the tool responses
+type Money struct {
+	CurrencyCode string `json:"currencyCode,omitempty"`
+	Units        string `json:"units,omitempty"`
+	Nanos        int64  `json:"nanos,omitempty"`
+}
+
+// NewMoney builds an INR Money from a float amount. The nanos are read from the
+// shortest decimal of the amount, so that they carry no floating point residue.
+func NewMoney(amount float64) Money {
+	s := strconv.FormatFloat(amount, 'f', -1, 64)
+	if _, frac, _ := strings.Cut(s, "."); len(frac) > 9 {
+		s = strconv.FormatFloat(amount, 'f', 9, 64)
+	}
+	units, frac, _ := strings.Cut(s, ".")
+	nanos, _ := strconv.ParseInt((frac + "000000000")[:9], 10, 64)
+	if strings.HasPrefix(units, "-") {
+		nanos = -nanos
+		if units == "-0" {
+			units = "0"
+		}
+	}
+	return Money{CurrencyCode: "INR", Units: units, Nanos: nanos}
+}
+
+// Float returns the amount as a float, treating a nil or unparsable amount as zero
+func (m *Money) Float() float64 {
+	if m == nil {
+		return 0
+	}
+	units, _ := strconv.ParseFloat(m.Units, 64)
+	return units + float64(m.Nanos)/1e9
+}
+
+// Round rounds v to the given number of decimal places
+func Round(v float64, places int) float64 {
+	pow := math.Pow(10, float64(places))
+	return math.Round(v*pow) / pow
+}
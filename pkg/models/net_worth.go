@@ -0,0 +1,286 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// FetchNetWorthResponse is the payload of the fetch_net_worth tool
+type FetchNetWorthResponse struct {
+	NetWorthResponse           *NetWorthResponse           `json:"netWorthResponse,omitempty"`
+	MFSchemeAnalytics          *MFSchemeAnalytics          `json:"mfSchemeAnalytics,omitempty"`
+	AccountDetailsBulkResponse *AccountDetailsBulkResponse `json:"accountDetailsBulkResponse,omitempty"`
+}
+
+// ValuationDate returns the latest balanceDate of the connected accounts, the
+// day the values of the response were taken, and whether any account has one
+func (r *FetchNetWorthResponse) ValuationDate() (time.Time, bool) {
+	var latest time.Time
+	if r == nil {
+		return latest, false
+	}
+	for _, id := range r.AccountDetailsBulkResponse.AccountIDs() {
+		entry := r.AccountDetailsBulkResponse.AccountDetailsMap[id]
+		for _, s := range entry.summaries() {
+			if t, ok := parseBalanceDate(s.BalanceDate); ok && t.After(latest) {
+				latest = t
+			}
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// parseBalanceDate parses an RFC 3339 or YYYY-MM-DD date to the start of its day
+func parseBalanceDate(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse(DateLayout, s); err != nil {
+			return time.Time{}, false
+		}
+	}
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+}
+
+// NetWorthResponse holds the aggregated asset and liability values of a user
+type NetWorthResponse struct {
+	AssetValues        []NetWorthValue `json:"assetValues,omitempty"`
+	LiabilityValues    []NetWorthValue `json:"liabilityValues,omitempty"`
+	TotalNetWorthValue *Money          `json:"totalNetWorthValue,omitempty"`
+}
+
+// NetWorthValue is the value of one asset or liability type, e.g. ASSET_TYPE_MUTUAL_FUND
+type NetWorthValue struct {
+	NetWorthAttribute string `json:"netWorthAttribute"`
+	Value             *Money `json:"value"`
+}
+
+// MFSchemeAnalytics holds the per scheme analytics of a user's mutual fund holdings
+type MFSchemeAnalytics struct {
+	SchemeAnalytics []SchemeAnalytics `json:"schemeAnalytics,omitempty"`
+}
+
+type SchemeAnalytics struct {
+	SchemeDetail      SchemeDetail      `json:"schemeDetail"`
+	EnrichedAnalytics EnrichedAnalytics `json:"enrichedAnalytics"`
+}
+
+type SchemeDetail struct {
+	AMC                       string   `json:"amc,omitempty"`
+	NameData                  NameData `json:"nameData"`
+	PlanType                  string   `json:"planType,omitempty"`
+	InvestmentType            string   `json:"investmentType,omitempty"`
+	OptionType                string   `json:"optionType,omitempty"`
+	DivReinvOptionType        string   `json:"divReinvOptionType,omitempty"`
+	NAV                       *Money   `json:"nav,omitempty"`
+	AssetClass                string   `json:"assetClass,omitempty"`
+	ISINNumber                string   `json:"isinNumber"`
+	CategoryName              string   `json:"categoryName,omitempty"`
+	FundhouseDefinedRiskLevel string   `json:"fundhouseDefinedRiskLevel,omitempty"`
+}
+
+type NameData struct {
+	LongName string `json:"longName"`
+}
+
+type EnrichedAnalytics struct {
+	Analytics struct {
+		SchemeDetails SchemeDetails `json:"schemeDetails"`
+	} `json:"analytics"`
+}
+
+// SchemeDetails holds the precomputed returns of a scheme
+type SchemeDetails struct {
+	CurrentValue      *Money  `json:"currentValue,omitempty"`
+	InvestedValue     *Money  `json:"investedValue,omitempty"`
+	XIRR              float64 `json:"XIRR,omitempty"`
+	AbsoluteReturns   *Money  `json:"absoluteReturns,omitempty"`
+	RealisedReturns   *Money  `json:"realisedReturns,omitempty"`
+	UnrealisedReturns *Money  `json:"unrealisedReturns,omitempty"`
+	NAVValue          *Money  `json:"navValue,omitempty"`
+	Units             float64 `json:"units,omitempty"`
+}
+
+// AccountDetailsBulkResponse holds the connected accounts keyed by account id
+type AccountDetailsBulkResponse struct {
+	AccountDetailsMap map[string]AccountDetailsEntry `json:"accountDetailsMap,omitempty"`
+}
+
+// AccountIDs returns the ids of AccountDetailsMap in sorted order, so that accounts are iterated deterministically
+func (r *AccountDetailsBulkResponse) AccountIDs() []string {
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.AccountDetailsMap))
+	for id := range r.AccountDetailsMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DematHolding is a holding of an equity, ETF, REIT or InvIT account
+type DematHolding struct {
+	AccountID         string
+	AccInstrumentType string
+	Holding
+}
+
+// DematHoldings returns the holdings of all equity, ETF, REIT and InvIT accounts in account id order
+func (r *AccountDetailsBulkResponse) DematHoldings() []DematHolding {
+	var holdings []DematHolding
+	for _, id := range r.AccountIDs() {
+		entry := r.AccountDetailsMap[id]
+		for _, summary := range []*AccountSummary{entry.EquitySummary, entry.ETFSummary, entry.REITSummary, entry.InvITSummary} {
+			if summary == nil {
+				continue
+			}
+			for _, h := range summary.HoldingsInfo {
+				holdings = append(holdings, DematHolding{
+					AccountID:         id,
+					AccInstrumentType: entry.AccountDetails.AccInstrumentType,
+					Holding:           h,
+				})
+			}
+		}
+	}
+	return holdings
+}
+
+// AccountDetailsEntry is one connected account. Only the summary matching
+// AccountDetails.AccInstrumentType is populated.
+type AccountDetailsEntry struct {
+	AccountDetails          AccountDetails  `json:"accountDetails"`
+	DepositSummary          *AccountSummary `json:"depositSummary,omitempty"`
+	RecurringDepositSummary *AccountSummary `json:"recurringDepositSummary,omitempty"`
+	EquitySummary           *AccountSummary `json:"equitySummary,omitempty"`
+	ETFSummary              *AccountSummary `json:"etfSummary,omitempty"`
+	REITSummary             *AccountSummary `json:"reitSummary,omitempty"`
+	InvITSummary            *AccountSummary `json:"invitSummary,omitempty"`
+	MutualFundSummary       *AccountSummary `json:"mutualFundSummary,omitempty"`
+	SGBSummary              *AccountSummary `json:"sgbSummary,omitempty"`
+	NPSSummary              *AccountSummary `json:"npsSummary,omitempty"`
+	EPFSummary              *AccountSummary `json:"epfSummary,omitempty"`
+	CreditCardSummary       *AccountSummary `json:"creditCardSummary,omitempty"`
+	LoanSummary             *AccountSummary `json:"loanSummary,omitempty"`
+}
+
+// summaries returns the account summaries that are set
+func (e AccountDetailsEntry) summaries() []*AccountSummary {
+	var set []*AccountSummary
+	for _, s := range []*AccountSummary{
+		e.DepositSummary, e.RecurringDepositSummary, e.EquitySummary, e.ETFSummary, e.REITSummary, e.InvITSummary,
+		e.MutualFundSummary, e.SGBSummary, e.NPSSummary, e.EPFSummary, e.CreditCardSummary, e.LoanSummary,
+	} {
+		if s != nil {
+			set = append(set, s)
+		}
+	}
+	return set
+}
+
+type AccountDetails struct {
+	FipID               string            `json:"fipId,omitempty"`
+	MaskedAccountNumber string            `json:"maskedAccountNumber,omitempty"`
+	AccInstrumentType   string            `json:"accInstrumentType"`
+	IFSCCode            string            `json:"ifscCode,omitempty"`
+	AccountType         map[string]string `json:"accountType,omitempty"`
+	FipMeta             *FipMeta          `json:"fipMeta,omitempty"`
+}
+
+type FipMeta struct {
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Bank        string `json:"bank,omitempty"`
+}
+
+// AccountSummary is the union of the fields used by the different account summaries
+type AccountSummary struct {
+	AccountID              string    `json:"accountId,omitempty"`
+	CurrentValue           *Money    `json:"currentValue,omitempty"`
+	CurrentBalance         *Money    `json:"currentBalance,omitempty"`
+	CurrentPrincipalAmount *Money    `json:"currentPrincipalAmount,omitempty"`
+	CurrentOutstanding     *Money    `json:"currentOutstanding,omitempty"`
+	OriginalLoanAmount     *Money    `json:"originalLoanAmount,omitempty"`
+	CreditLimit            *Money    `json:"creditLimit,omitempty"`
+	AmountPastDue          *Money    `json:"amountPastDue,omitempty"`
+	BalanceDate            string    `json:"balanceDate,omitempty"`
+	MaturityDate           string    `json:"maturityDate,omitempty"`
+	OpeningDate            string    `json:"openingDate,omitempty"`
+	DepositAccountType     string    `json:"depositAccountType,omitempty"`
+	DepositAccountStatus   string    `json:"depositAccountStatus,omitempty"`
+	AccountStatus          string    `json:"accountStatus,omitempty"`
+	LoanStatus             string    `json:"loanStatus,omitempty"`
+	LoanType               string    `json:"loanType,omitempty"`
+	Branch                 string    `json:"branch,omitempty"`
+	IFSCCode               string    `json:"ifscCode,omitempty"`
+	MICRCode               string    `json:"micrCode,omitempty"`
+	HoldingsInfo           []Holding `json:"holdingsInfo,omitempty"`
+}
+
+// Value returns the value of the account, whichever amount field the summary uses
+func (s *AccountSummary) Value() float64 {
+	switch {
+	case s == nil:
+		return 0
+	case s.CurrentValue != nil:
+		return s.CurrentValue.Float()
+	case s.CurrentBalance != nil:
+		return s.CurrentBalance.Float()
+	case s.CurrentPrincipalAmount != nil:
+		return s.CurrentPrincipalAmount.Float()
+	case s.CurrentOutstanding != nil:
+		return s.CurrentOutstanding.Float()
+	}
+	return 0
+}
+
+// Holding is the union of the fields used by equity, ETF, REIT, InvIT, SGB and MF holdings
+type Holding struct {
+	ISIN             string  `json:"isin,omitempty"`
+	ISINDescription  string  `json:"isinDescription,omitempty"`
+	IssuerName       string  `json:"issuerName,omitempty"`
+	Description      string  `json:"description,omitempty"`
+	Ticker           string  `json:"ticker,omitempty"`
+	Type             string  `json:"type,omitempty"`
+	FolioNumber      string  `json:"folioNumber,omitempty"`
+	Nominee          string  `json:"nominee,omitempty"`
+	Units            float64 `json:"units,omitempty"`
+	TotalNumberUnits float64 `json:"totalNumberUnits,omitempty"`
+	LastTradedPrice  *Money  `json:"lastTradedPrice,omitempty"`
+	NAV              *Money  `json:"nav,omitempty"`
+	LastNAVDate      string  `json:"lastNavDate,omitempty"`
+	LastClosingRate  *Money  `json:"lastClosingRate,omitempty"`
+}
+
+// Quantity returns the number of units held
+func (h Holding) Quantity() float64 {
+	if h.Units != 0 {
+		return h.Units
+	}
+	return h.TotalNumberUnits
+}
+
+// Price returns the latest known price of one unit
+func (h Holding) Price() float64 {
+	switch {
+	case h.LastTradedPrice != nil:
+		return h.LastTradedPrice.Float()
+	case h.NAV != nil:
+		return h.NAV.Float()
+	case h.LastClosingRate != nil:
+		return h.LastClosingRate.Float()
+	}
+	return 0
+}
+
+// Name returns the most descriptive name available for the holding
+func (h Holding) Name() string {
+	switch {
+	case h.IssuerName != "":
+		return h.IssuerName
+	case h.ISINDescription != "":
+		return h.ISINDescription
+	}
+	return h.Description
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// unmarshalPositional decodes a JSON array into the given targets in order.
+// Trailing targets are left untouched when the array is shorter, since some
+// transaction arrays omit their optional last field.
+func unmarshalPositional(data []byte, required int, targets ...any) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) < required {
+		return fmt.Errorf("expected at least %d fields, got %d", required, len(raw))
+	}
+	for i, target := range targets {
+		if i >= len(raw) {
+			break
+		}
+		if err := json.Unmarshal(raw[i], target); err != nil {
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Stock transaction types used in the first field of a stock transaction
+const (
+	StockTxnTypeBuy   = 1
+	StockTxnTypeSell  = 2
+	StockTxnTypeBonus = 3
+	StockTxnTypeSplit = 4
+)
+
+// StockTransactionsResponse is the payload of the fetch_stock_transactions tool
+type StockTransactionsResponse struct {
+	SchemaDescription string              `json:"schemaDescription,omitempty"`
+	StockTransactions []StockTransactions `json:"stockTransactions,omitempty"`
+}
+
+// StockTransactions holds the transactions of one ISIN
+type StockTransactions struct {
+	ISIN string     `json:"isin"`
+	Txns []StockTxn `json:"txns"`
+}
+
+// StockTxn is a single stock transaction, serialised as
+// [transactionType, transactionDate, quantity, navValue]. navValue is optional.
+type StockTxn struct {
+	Type     int
+	Date     string
+	Quantity float64
+	NAV      *float64
+}
+
+func (t *StockTxn) UnmarshalJSON(data []byte) error {
+	return unmarshalPositional(data, 3, &t.Type, &t.Date, &t.Quantity, &t.NAV)
+}
+
+func (t StockTxn) MarshalJSON() ([]byte, error) {
+	if t.NAV == nil {
+		return json.Marshal([]any{t.Type, t.Date, t.Quantity})
+	}
+	return json.Marshal([]any{t.Type, t.Date, t.Quantity, *t.NAV})
+}
+
+// Time parses the transaction date
+func (t StockTxn) Time() (time.Time, error) {
+	return time.Parse(DateLayout, t.Date)
+}
+
+// Price returns the nav value of the transaction, or zero when it is not present
+func (t StockTxn) Price() float64 {
+	if t.NAV == nil {
+		return 0
+	}
+	return *t.NAV
+}
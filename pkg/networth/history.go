@@ -0,0 +1,279 @@
+package networth
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Granularity is the spacing of the points of a history
+type Granularity string
+
+const (
+	GranularityDaily     Granularity = "daily"
+	GranularityWeekly    Granularity = "weekly"
+	GranularityMonthly   Granularity = "monthly"
+	GranularityQuarterly Granularity = "quarterly"
+)
+
+// Methods used to derive the value of a point
+const (
+	MethodSnapshot       = "snapshot"
+	MethodInterpolated   = "interpolated"
+	MethodCarriedForward = "carried_forward"
+	MethodCarriedBack    = "carried_back"
+)
+
+// maxPoints bounds the size of a history so that a daily granularity over years is rejected
+const maxPoints = 500
+
+// Snapshot is the net worth of a user on a date
+type Snapshot struct {
+	Date     time.Time
+	NetWorth *models.NetWorthResponse
+}
+
+// Point is the net worth on one date of the series
+type Point struct {
+	Date             string             `json:"date"`
+	Method           string             `json:"method"`
+	NetWorth         float64            `json:"netWorth"`
+	TotalAssets      float64            `json:"totalAssets"`
+	TotalLiabilities float64            `json:"totalLiabilities"`
+	Assets           map[string]float64 `json:"assets"`
+	Liabilities      map[string]float64 `json:"liabilities"`
+}
+
+// Delta is the change between two points
+type Delta struct {
+	From             string             `json:"from"`
+	To               string             `json:"to"`
+	Change           float64            `json:"change"`
+	ChangePercent    *float64           `json:"changePercent"`
+	AssetChanges     map[string]float64 `json:"assetChanges"`
+	LiabilityChanges map[string]float64 `json:"liabilityChanges"`
+}
+
+// History is a net worth time series built from snapshots
+type History struct {
+	From          string      `json:"from"`
+	To            string      `json:"to"`
+	Granularity   Granularity `json:"granularity"`
+	SnapshotDates []string    `json:"snapshotDates"`
+	Series        []Point     `json:"series"`
+	Deltas        []Delta     `json:"deltas"`
+	Overall       *Delta      `json:"overall,omitempty"`
+	// Notes explain how the snapshots and the current net worth were combined
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ParseGranularity validates a granularity, defaulting to monthly
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(strings.ToLower(s)); g {
+	case "":
+		return GranularityMonthly, nil
+	case GranularityDaily, GranularityWeekly, GranularityMonthly, GranularityQuarterly:
+		return g, nil
+	}
+	return "", fmt.Errorf("invalid granularity %q, expected one of daily, weekly, monthly or quarterly", s)
+}
+
+func (g Granularity) step(t time.Time, n int) time.Time {
+	switch g {
+	case GranularityDaily:
+		return t.AddDate(0, 0, n)
+	case GranularityWeekly:
+		return t.AddDate(0, 0, 7*n)
+	case GranularityQuarterly:
+		return t.AddDate(0, 3*n, 0)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+// values is the net worth split by attribute
+type values struct {
+	netWorth    float64
+	assets      map[string]float64
+	liabilities map[string]float64
+}
+
+func fromResponse(nw *models.NetWorthResponse) values {
+	v := values{assets: make(map[string]float64), liabilities: make(map[string]float64)}
+	if nw == nil {
+		return v
+	}
+	add := func(a models.NetWorthValue) {
+		amount := a.Value.Float()
+		// some responses list liabilities among the assets with a negative value
+		if strings.HasPrefix(a.NetWorthAttribute, "LIABILITY_") {
+			v.liabilities[a.NetWorthAttribute] += math.Abs(amount)
+			return
+		}
+		v.assets[a.NetWorthAttribute] += amount
+	}
+	for _, a := range nw.AssetValues {
+		add(a)
+	}
+	for _, l := range nw.LiabilityValues {
+		v.liabilities[l.NetWorthAttribute] += math.Abs(l.Value.Float())
+	}
+	if nw.TotalNetWorthValue != nil {
+		v.netWorth = nw.TotalNetWorthValue.Float()
+	} else {
+		v.netWorth = sum(v.assets) - sum(v.liabilities)
+	}
+	return v
+}
+
+func interpolate(a, b values, ratio float64) values {
+	mix := func(x, y map[string]float64) map[string]float64 {
+		out := make(map[string]float64)
+		for k := range x {
+			out[k] = x[k] + (y[k]-x[k])*ratio
+		}
+		for k := range y {
+			if _, ok := x[k]; !ok {
+				out[k] = y[k] * ratio
+			}
+		}
+		return out
+	}
+	return values{
+		netWorth:    a.netWorth + (b.netWorth-a.netWorth)*ratio,
+		assets:      mix(a.assets, b.assets),
+		liabilities: mix(a.liabilities, b.liabilities),
+	}
+}
+
+// BuildHistory samples the snapshots from from to to at the given granularity.
+// Dates between two snapshots are linearly interpolated, dates outside the
+// snapshots carry the nearest snapshot. Zero from and to default to the first
+// and last snapshot.
+func BuildHistory(snapshots []Snapshot, from, to time.Time, g Granularity) (*History, error) {
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no net worth snapshots available")
+	}
+	sorted := make([]Snapshot, len(snapshots))
+	copy(sorted, snapshots)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	if from.IsZero() {
+		from = sorted[0].Date
+	}
+	if to.IsZero() {
+		to = sorted[len(sorted)-1].Date
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("to date %s is before from date %s", to.Format(models.DateLayout), from.Format(models.DateLayout))
+	}
+
+	parsed := make([]values, len(sorted))
+	h := &History{
+		From:        from.Format(models.DateLayout),
+		To:          to.Format(models.DateLayout),
+		Granularity: g,
+		Deltas:      []Delta{},
+	}
+	for i, s := range sorted {
+		parsed[i] = fromResponse(s.NetWorth)
+		h.SnapshotDates = append(h.SnapshotDates, s.Date.Format(models.DateLayout))
+	}
+
+	var dates []time.Time
+	for i := 0; ; i++ {
+		d := g.step(from, i)
+		if d.After(to) {
+			break
+		}
+		dates = append(dates, d)
+		if len(dates) > maxPoints {
+			return nil, fmt.Errorf("range has more than %d points at %s granularity, use a coarser granularity or a shorter range", maxPoints, g)
+		}
+	}
+	if last := dates[len(dates)-1]; last.Before(to) {
+		dates = append(dates, to)
+	}
+
+	for _, d := range dates {
+		v, method := valueAt(sorted, parsed, d)
+		h.Series = append(h.Series, Point{
+			Date:             d.Format(models.DateLayout),
+			Method:           method,
+			NetWorth:         models.Round(v.netWorth, 2),
+			TotalAssets:      models.Round(sum(v.assets), 2),
+			TotalLiabilities: models.Round(sum(v.liabilities), 2),
+			Assets:           roundAll(v.assets),
+			Liabilities:      roundAll(v.liabilities),
+		})
+	}
+	for i := 1; i < len(h.Series); i++ {
+		h.Deltas = append(h.Deltas, delta(h.Series[i-1], h.Series[i]))
+	}
+	if len(h.Series) > 1 {
+		overall := delta(h.Series[0], h.Series[len(h.Series)-1])
+		h.Overall = &overall
+	}
+	return h, nil
+}
+
+func valueAt(snapshots []Snapshot, parsed []values, d time.Time) (values, string) {
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Date.Before(d) })
+	switch {
+	case i < len(snapshots) && snapshots[i].Date.Equal(d):
+		return parsed[i], MethodSnapshot
+	case i == 0:
+		return parsed[0], MethodCarriedBack
+	case i == len(snapshots):
+		return parsed[i-1], MethodCarriedForward
+	}
+	prev, next := snapshots[i-1].Date, snapshots[i].Date
+	ratio := d.Sub(prev).Hours() / next.Sub(prev).Hours()
+	return interpolate(parsed[i-1], parsed[i], ratio), MethodInterpolated
+}
+
+func delta(a, b Point) Delta {
+	d := Delta{
+		From:             a.Date,
+		To:               b.Date,
+		Change:           models.Round(b.NetWorth-a.NetWorth, 2),
+		AssetChanges:     diff(a.Assets, b.Assets),
+		LiabilityChanges: diff(a.Liabilities, b.Liabilities),
+	}
+	if a.NetWorth != 0 {
+		pct := models.Round((b.NetWorth-a.NetWorth)/math.Abs(a.NetWorth)*100, 2)
+		d.ChangePercent = &pct
+	}
+	return d
+}
+
+func diff(a, b map[string]float64) map[string]float64 {
+	out := make(map[string]float64)
+	for k := range a {
+		out[k] = models.Round(b[k]-a[k], 2)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			out[k] = models.Round(b[k], 2)
+		}
+	}
+	return out
+}
+
+func sum(m map[string]float64) float64 {
+	var total float64
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
+
+func roundAll(m map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(m))
+	for k, v := range m {
+		out[k] = models.Round(v, 2)
+	}
+	return out
+}
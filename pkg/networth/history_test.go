@@ -0,0 +1,63 @@
+package networth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func netWorth(savings, loan float64) *models.NetWorthResponse {
+	total := models.NewMoney(savings - loan)
+	savingsValue, loanValue := models.NewMoney(savings), models.NewMoney(-loan)
+	return &models.NetWorthResponse{
+		// the loan is listed among the assets with a negative value, as some fixtures do
+		AssetValues: []models.NetWorthValue{
+			{NetWorthAttribute: "ASSET_TYPE_SAVINGS_ACCOUNTS", Value: &savingsValue},
+			{NetWorthAttribute: "LIABILITY_TYPE_HOME_LOAN", Value: &loanValue},
+		},
+		TotalNetWorthValue: &total,
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	snapshots := []Snapshot{
+		{Date: day(2025, 3, 1), NetWorth: netWorth(3000, 1000)},
+		{Date: day(2025, 1, 1), NetWorth: netWorth(1000, 1000)},
+	}
+	h, err := BuildHistory(snapshots, day(2024, 12, 1), day(2025, 4, 1), GranularityMonthly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		date   string
+		method string
+		worth  float64
+	}{
+		{"2024-12-01", MethodCarriedBack, 0},
+		{"2025-01-01", MethodSnapshot, 0},
+		{"2025-02-01", MethodInterpolated, 2000 * 31.0 / 59.0},
+		{"2025-03-01", MethodSnapshot, 2000},
+		{"2025-04-01", MethodCarriedForward, 2000},
+	}
+	if len(h.Series) != len(want) {
+		t.Fatalf("got %d points, want %d", len(h.Series), len(want))
+	}
+	for i, w := range want {
+		p := h.Series[i]
+		if p.Date != w.date || p.Method != w.method || p.NetWorth != models.Round(w.worth, 2) {
+			t.Errorf("point %d = %s %s %v, want %s %s %v", i, p.Date, p.Method, p.NetWorth, w.date, w.method, models.Round(w.worth, 2))
+		}
+		if p.Liabilities["LIABILITY_TYPE_HOME_LOAN"] != 1000 {
+			t.Errorf("point %d liabilities = %v, want home loan of 1000", i, p.Liabilities)
+		}
+	}
+	if h.Overall == nil || h.Overall.Change != 2000 {
+		t.Errorf("overall = %+v, want change of 2000", h.Overall)
+	}
+
+	if _, err := BuildHistory(snapshots, day(2020, 1, 1), day(2025, 1, 1), GranularityDaily); err == nil {
+		t.Error("expected an error for a daily range over too many points")
+	}
+}
@@ -0,0 +1,178 @@
+package persona
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const bankSchemaDescription = "A list of bank transactions. Each 'txns' field is a list of data arrays with schema: [transactionAmount, transactionNarration, transactionDate, transactionType (1 for CREDIT, 2 for DEBIT, 3 for OPENING, 4 for INTEREST, 5 for TDS, 6 for INSTALLMENT, 7 for CLOSING and 8 for OTHERS), transactionMode, currentBalance].\n We currently have have only last two month transaction, older transaction are coming soon..."
+
+var flowTypes = map[string]int{
+	"":            models.BankTxnTypeDebit,
+	"debit":       models.BankTxnTypeDebit,
+	"credit":      models.BankTxnTypeCredit,
+	"interest":    models.BankTxnTypeInterest,
+	"installment": models.BankTxnTypeInstallment,
+}
+
+var modePrefixes = []string{"UPI", "NEFT", "IMPS", "RTGS", "ACH"}
+
+// txn is a bank transaction before its balance is known
+type txn struct {
+	date      time.Time
+	amount    float64
+	narration string
+	typ       int
+	mode      string
+}
+
+func (t txn) sign() float64 {
+	return float64(models.BankTxn{Type: t.typ}.Sign())
+}
+
+// eachMonth calls fn with the first day of every month of the bank window
+func (g *generator) eachMonth(fn func(month time.Time)) {
+	for m := g.windowStart; !m.After(g.spec.AsOf.Time); m = m.AddDate(0, 1, 0) {
+		fn(m)
+	}
+}
+
+// inWindow reports whether a date falls in the bank window
+func (g *generator) inWindow(t time.Time) bool {
+	return !t.Before(g.windowStart) && !t.After(g.spec.AsOf.Time)
+}
+
+// bankTransactions generates the transactions of every account and works their
+// running balances back from the balance on AsOf. It returns the balance of every
+// account, including those whose transactions are not shared.
+func (g *generator) bankTransactions() (*models.BankTransactionsResponse, []float64, error) {
+	resp := &models.BankTransactionsResponse{SchemaDescription: bankSchemaDescription, BankTransactions: []models.BankTransactions{}}
+	balances := make([]float64, len(g.spec.BankAccounts))
+	payments := -1
+	for i, a := range g.spec.BankAccounts {
+		balances[i] = a.Balance
+		if !a.NoTransactions && (payments < 0 || a.Payments && !g.spec.BankAccounts[payments].Payments) {
+			payments = i
+		}
+	}
+	for i, a := range g.spec.BankAccounts {
+		if a.NoTransactions {
+			continue
+		}
+		var txns []txn
+		for _, f := range a.Flows {
+			txns = append(txns, g.flow(f)...)
+		}
+		if a.Spends != nil {
+			txns = append(txns, g.spends(*a.Spends)...)
+		}
+		if i == payments {
+			txns = append(txns, g.oneOff...)
+		}
+		sort.SliceStable(txns, func(i, j int) bool { return txns[i].date.Before(txns[j].date) })
+
+		balance := a.Balance
+		for _, t := range txns {
+			balance -= t.sign() * t.amount
+		}
+		account := models.BankTransactions{Bank: a.Bank, Txns: []models.BankTxn{}}
+		for _, t := range txns {
+			balance = models.Round(balance+t.sign()*t.amount, 2)
+			if balance < 0 {
+				return nil, nil, fmt.Errorf("bank account %s goes below zero on %s, raise its balance", a.Masked, t.date.Format(models.DateLayout))
+			}
+			account.Txns = append(account.Txns, models.BankTxn{
+				Amount:         FormatAmount(t.amount),
+				Narration:      t.narration,
+				Date:           t.date.Format(models.DateLayout),
+				Type:           t.typ,
+				Mode:           t.mode,
+				CurrentBalance: FormatAmount(balance),
+			})
+		}
+		resp.BankTransactions = append(resp.BankTransactions, account)
+	}
+	if len(resp.BankTransactions) == 0 {
+		return &models.BankTransactionsResponse{}, balances, nil
+	}
+	return resp, balances, nil
+}
+
+// flow expands a flow into its transactions in the bank window
+func (g *generator) flow(f Flow) []txn {
+	var out []txn
+	g.eachMonth(func(month time.Time) {
+		if !inMonths(f.Months, month.Month()) {
+			return
+		}
+		if f.Probability > 0 && g.rng.Float64() >= f.Probability {
+			return
+		}
+		date := dayOf(month, f.Day)
+		if !g.inWindow(date) {
+			return
+		}
+		amount := f.Amount
+		if f.JitterPercent > 0 {
+			amount = math.Round(amount * (1 + (2*g.rng.Float64()-1)*f.JitterPercent/100))
+		}
+		out = append(out, txn{date: date, amount: amount, narration: g.narration(f.Narration, date), typ: flowTypes[f.Kind], mode: modeOf(f.Mode, f.Narration)})
+	})
+	return out
+}
+
+// spends draws the random small debits of every month
+func (g *generator) spends(s Spends) []txn {
+	var out []txn
+	if len(s.Narrations) == 0 {
+		return nil
+	}
+	g.eachMonth(func(month time.Time) {
+		n := int(s.PerMonth.Min) + g.rng.Intn(int(s.PerMonth.Max-s.PerMonth.Min)+1)
+		days := time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for i := 0; i < n; i++ {
+			date := dayOf(month, 1+g.rng.Intn(days))
+			narration := s.Narrations[g.rng.Intn(len(s.Narrations))]
+			amount := math.Round(s.Amount.Min + g.rng.Float64()*(s.Amount.Max-s.Amount.Min))
+			if !g.inWindow(date) {
+				continue
+			}
+			out = append(out, txn{date: date, amount: amount, narration: g.narration(narration, date), typ: models.BankTxnTypeDebit, mode: modeOf("", narration)})
+		}
+	})
+	return out
+}
+
+// narration fills in the {MON} and {REF} placeholders
+func (g *generator) narration(s string, date time.Time) string {
+	s = strings.ReplaceAll(s, "{MON}", strings.ToUpper(date.Format("Jan")))
+	for strings.Contains(s, "{REF}") {
+		s = strings.Replace(s, "{REF}", strconv.Itoa(100000000000+g.rng.Intn(899999999999)), 1)
+	}
+	return s
+}
+
+// modeOf returns the mode given, or the one the narration starts with
+func modeOf(mode, narration string) string {
+	if mode != "" {
+		return mode
+	}
+	for _, p := range modePrefixes {
+		if strings.HasPrefix(narration, p) {
+			return p
+		}
+	}
+	return "OTHERS"
+}
+
+// FormatAmount writes an amount of the bank transactions, in rupees rounded to
+// paise and without trailing zeros
+func FormatAmount(v float64) string {
+	return strconv.FormatFloat(models.Round(v, 2), 'f', -1, 64)
+}
@@ -0,0 +1,198 @@
+package persona
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// accountType is how a kind of credit account shows up in the bureau report and the net worth
+type accountType struct {
+	code          string
+	label         string
+	portfolioType string
+	secured       bool
+	liability     string
+	loanType      string
+}
+
+var accountTypes = map[string]accountType{
+	"credit_card":    {code: "10", label: "CREDIT CARD", portfolioType: "R", liability: "LIABILITY_TYPE_CREDIT_CARD"},
+	"personal_loan":  {code: "05", label: "PERSONAL LOAN", portfolioType: "I", liability: "LIABILITY_TYPE_OTHER_LOAN", loanType: "LOAN_ACCOUNT_TYPE_PERSONAL"},
+	"home_loan":      {code: "02", label: "HOME LOAN", portfolioType: "M", secured: true, liability: "LIABILITY_TYPE_HOME_LOAN", loanType: "LOAN_ACCOUNT_TYPE_HOME"},
+	"auto_loan":      {code: "01", label: "AUTO LOAN", portfolioType: "I", secured: true, liability: "LIABILITY_TYPE_VEHICLE_LOAN", loanType: "LOAN_ACCOUNT_TYPE_VEHICLE"},
+	"consumer_loan":  {code: "06", label: "CONSUMER LOAN", portfolioType: "I", liability: "LIABILITY_TYPE_OTHER_LOAN", loanType: "LOAN_ACCOUNT_TYPE_CONSUMER"},
+	"education_loan": {code: "08", label: "EDUCATION LOAN", portfolioType: "I", liability: "LIABILITY_TYPE_OTHER_LOAN", loanType: "LOAN_ACCOUNT_TYPE_EDUCATION"},
+}
+
+const (
+	bureauStatusActive = "11"
+	bureauStatusClosed = "13"
+)
+
+// creditReport builds the bureau report. EMIs of open loans in the bank
+// window are added to the payments account.
+func (g *generator) creditReport() *models.CreditReportResponse {
+	c := g.spec.Credit
+	if c == nil {
+		return &models.CreditReportResponse{}
+	}
+	asOf := g.spec.AsOf.Time
+	reported := asOf.Format(models.BureauDateLayout)
+	data := models.CreditReportData{
+		UserMessage:         &models.UserMessage{UserMessageText: "Normal Response"},
+		CreditProfileHeader: models.CreditProfileHeader{ReportDate: reported, ReportTime: "120000"},
+		CurrentApplication: &models.CurrentApplication{CurrentApplicationDetails: models.CurrentApplicationDetails{
+			EnquiryReason:  "6",
+			AmountFinanced: "0", DurationOfAgreement: "0",
+			CurrentApplicantDetails: models.CurrentApplicantDetails{DateOfBirthApplicant: g.spec.DateOfBirth.Format(models.BureauDateLayout)},
+		}},
+		MatchResult:   &models.MatchResult{ExactMatch: "Y"},
+		CreditAccount: models.CreditAccount{CreditAccountDetails: []models.CreditAccountDetail{}},
+	}
+
+	var active, closed int
+	var secured, unsecured float64
+	for _, a := range c.Accounts {
+		t := accountTypes[a.Kind]
+		history := a.PaymentHistory
+		if history == "" {
+			history = strings.Repeat("0", 36)
+		}
+		detail := models.CreditAccountDetail{
+			SubscriberName:                    a.Subscriber,
+			PortfolioType:                     t.portfolioType,
+			AccountType:                       t.code,
+			OpenDate:                          a.Opened.Format(models.BureauDateLayout),
+			HighestCreditOrOriginalLoanAmount: Rupees(a.Amount),
+			AccountStatus:                     bureauStatusActive,
+			PaymentRating:                     "0",
+			PaymentHistoryProfile:             history,
+			CurrentBalance:                    Rupees(a.Balance),
+			AmountPastDue:                     Rupees(a.PastDue),
+			DateReported:                      reported,
+			OccupationCode:                    "S",
+			RepaymentTenure:                   strconv.Itoa(a.TenureMonths),
+			DateOfAddition:                    a.Opened.Format(models.BureauDateLayout),
+			CurrencyCode:                      "INR",
+			AccountHolderTypeCode:             "1",
+		}
+		if a.Limit > 0 {
+			detail.CreditLimitAmount = Rupees(a.Limit)
+		}
+		if a.RatePercent > 0 {
+			detail.RateOfInterest = strconv.FormatFloat(a.RatePercent, 'f', -1, 64)
+		}
+		if a.Closed != nil && !a.Closed.After(asOf) {
+			closed++
+			detail.AccountStatus = bureauStatusClosed
+			detail.DateClosed = a.Closed.Format(models.BureauDateLayout)
+			detail.CurrentBalance = "0"
+		} else {
+			active++
+			if t.secured {
+				secured += a.Balance
+			} else {
+				unsecured += a.Balance
+			}
+			g.emis(a, t)
+		}
+		data.CreditAccount.CreditAccountDetails = append(data.CreditAccount.CreditAccountDetails, detail)
+	}
+	all := secured + unsecured
+	data.CreditAccount.CreditAccountSummary = models.CreditAccountSummary{
+		Account: models.CreditAccountCounts{
+			CreditAccountTotal:         strconv.Itoa(len(c.Accounts)),
+			CreditAccountActive:        strconv.Itoa(active),
+			CreditAccountDefault:       "0",
+			CreditAccountClosed:        strconv.Itoa(closed),
+			CADSuitFiledCurrentBalance: "0",
+		},
+		TotalOutstandingBalance: models.TotalOutstandingBalance{
+			OutstandingBalanceSecured:             Rupees(secured),
+			OutstandingBalanceSecuredPercentage:   percentString(secured, all),
+			OutstandingBalanceUnSecured:           Rupees(unsecured),
+			OutstandingBalanceUnSecuredPercentage: percentString(unsecured, all),
+			OutstandingBalanceAll:                 Rupees(all),
+		},
+	}
+
+	caps := &models.Caps{CapsApplicationDetailsArray: []models.CapsApplicationDetail{}}
+	var last7, last30, last90, last180 int
+	for _, e := range c.Enquiries {
+		days := asOf.Sub(e.Date.Time).Hours() / 24
+		if days < 0 {
+			continue
+		}
+		if days <= 7 {
+			last7++
+		}
+		if days <= 30 {
+			last30++
+		}
+		if days <= 90 {
+			last90++
+		}
+		if days <= 180 {
+			last180++
+		}
+		caps.CapsApplicationDetailsArray = append(caps.CapsApplicationDetailsArray, models.CapsApplicationDetail{
+			SubscriberName: e.Subscriber,
+			DateOfRequest:  e.Date.Format(models.BureauDateLayout),
+			EnquiryReason:  e.Reason,
+			FinancePurpose: e.Reason,
+		})
+	}
+	caps.CapsSummary = models.CapsSummary{
+		CapsLast7Days: strconv.Itoa(last7), CapsLast30Days: strconv.Itoa(last30),
+		CapsLast90Days: strconv.Itoa(last90), CapsLast180Days: strconv.Itoa(last180),
+	}
+	data.Caps = caps
+	data.TotalCapsSummary = &models.TotalCapsSummary{
+		TotalCapsLast7Days: caps.CapsSummary.CapsLast7Days, TotalCapsLast30Days: caps.CapsSummary.CapsLast30Days,
+		TotalCapsLast90Days: caps.CapsSummary.CapsLast90Days, TotalCapsLast180Days: caps.CapsSummary.CapsLast180Days,
+	}
+	if c.Score > 0 {
+		data.Score = &models.BureauScore{BureauScore: strconv.Itoa(c.Score), BureauScoreConfidenceLevel: "H"}
+	}
+	vendor := c.Vendor
+	if vendor == "" {
+		vendor = "EXPERIAN"
+	}
+	return &models.CreditReportResponse{CreditReports: []models.CreditReport{{CreditReportData: data, Vendor: vendor}}}
+}
+
+// emis adds the EMI debits of an open loan, or the bill payments of a card, in the bank window
+func (g *generator) emis(a CreditAccount, t accountType) {
+	if a.EMI <= 0 {
+		return
+	}
+	day := a.EMIDay
+	if day == 0 {
+		day = 5
+	}
+	g.eachMonth(func(month time.Time) {
+		date := dayOf(month, day)
+		if !g.inWindow(date) || date.Before(a.Opened.Time) {
+			return
+		}
+		if a.Kind == "credit_card" {
+			g.oneOff = append(g.oneOff, txn{date: date, amount: a.EMI, typ: models.BankTxnTypeDebit, mode: "NEFT",
+				narration: fmt.Sprintf("NEFT DR-%s CREDIT CARD PAYMENT-%s", strings.ToUpper(a.Subscriber), a.Masked)})
+			return
+		}
+		g.oneOff = append(g.oneOff, txn{date: date, amount: a.EMI, typ: models.BankTxnTypeInstallment, mode: "ACH",
+			narration: fmt.Sprintf("ACH D-%s-%s EMI-%s", strings.ToUpper(a.Subscriber), t.label, a.Masked)})
+	})
+}
+
+func percentString(part, whole float64) string {
+	if whole == 0 {
+		return "0"
+	}
+	return strconv.Itoa(int(math.Round(part / whole * 100)))
+}
@@ -0,0 +1,57 @@
+package persona
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// deposit is a fixed or recurring deposit valued at AsOf
+type deposit struct {
+	Deposit
+	principal float64
+	value     float64
+}
+
+// deposits values every deposit with interest compounded quarterly, as banks
+// do. Recurring deposit instalments in the bank window are added to the
+// payments account.
+func (g *generator) deposits() []deposit {
+	asOf := g.spec.AsOf.Time
+	var out []deposit
+	for _, d := range g.spec.Deposits {
+		end := asOf
+		if d.Maturity.Before(asOf) {
+			end = d.Maturity.Time
+		}
+		dep := deposit{Deposit: d}
+		if d.Kind == "fixed" {
+			dep.principal = d.Principal
+			dep.value = compound(d.Principal, d.RatePercent, d.Opened.Time, end)
+		} else {
+			day := d.Opened.Day()
+			for m := d.Opened.Time; !m.After(end) && m.Before(d.Maturity.Time); m = dayOf(m.AddDate(0, 0, 1-m.Day()).AddDate(0, 1, 0), day) {
+				dep.principal += d.MonthlyInstallment
+				dep.value += compound(d.MonthlyInstallment, d.RatePercent, m, end)
+				if g.inWindow(m) {
+					g.oneOff = append(g.oneOff, txn{date: m, amount: d.MonthlyInstallment, typ: models.BankTxnTypeInstallment, mode: "OTHERS",
+						narration: fmt.Sprintf("RD INSTALLMENT-%s", d.Masked)})
+				}
+			}
+		}
+		dep.value = models.Round(dep.value, 2)
+		out = append(out, dep)
+	}
+	return out
+}
+
+// compound grows principal at an annual rate compounded quarterly from one date to another
+func compound(principal, ratePercent float64, from, to time.Time) float64 {
+	years := to.Sub(from).Hours() / 24 / 365
+	if years <= 0 {
+		return principal
+	}
+	return principal * math.Pow(1+ratePercent/100/4, 4*years)
+}
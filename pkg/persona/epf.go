@@ -0,0 +1,133 @@
+package persona
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const (
+	// employerEPFRate is the part of the employer's 12% that goes to EPF, the rest goes to EPS
+	employerEPFRate = 0.0367
+	pensionRate     = 0.0833
+	// pensionWageCeiling is the monthly wage EPS contributions are capped at
+	pensionWageCeiling = 15000
+	// inoperativeMonths is how long a member id keeps earning interest after the exit
+	inoperativeMonths = 36
+)
+
+// epfShares are the contributions and balances of one member id
+type epfShares struct {
+	employeeCredit, employerCredit   float64
+	employeeBalance, employerBalance float64
+	pension                          float64
+}
+
+// epf accumulates the monthly contributions of every establishment with the
+// declared interest credited monthly on the running balance
+func (g *generator) epf() *models.EPFDetailsResponse {
+	if g.spec.EPF == nil || len(g.spec.EPF.UANs) == 0 {
+		return &models.EPFDetailsResponse{}
+	}
+	rate := g.spec.EPF.InterestPercent
+	if rate == 0 {
+		rate = 8.25
+	}
+	asOf := g.spec.AsOf.Time
+	resp := &models.EPFDetailsResponse{}
+	for _, u := range g.spec.EPF.UANs {
+		raw := models.EPFRawDetails{}
+		var total epfShares
+		for _, e := range u.Establishments {
+			s := e.accumulate(rate/100/12, asOf)
+			total.employeeCredit += s.employeeCredit
+			total.employerCredit += s.employerCredit
+			total.employeeBalance += s.employeeBalance
+			total.employerBalance += s.employerBalance
+			total.pension += s.pension
+
+			exit := models.EPFDateNotAvailable
+			if e.Exited != nil {
+				exit = e.Exited.Format(models.EPFDateLayout)
+			}
+			raw.EstDetails = append(raw.EstDetails, models.EPFEstablishment{
+				EstName:  e.Name,
+				MemberID: e.MemberID,
+				Office:   e.Office,
+				DOJEPF:   e.Joined.Format(models.EPFDateLayout),
+				DOEEPF:   exit,
+				DOEEPS:   exit,
+				PFBalance: models.EPFPFBalance{
+					NetBalance:    Rupees(s.employeeBalance + s.employerBalance),
+					EmployeeShare: models.EPFShare{Credit: Rupees(s.employeeCredit), Balance: Rupees(s.employeeBalance)},
+					EmployerShare: models.EPFShare{Credit: Rupees(s.employerCredit), Balance: Rupees(s.employerBalance)},
+				},
+			})
+		}
+		raw.OverallPFBalance = models.EPFOverallBalance{
+			PensionBalance:     Rupees(total.pension),
+			CurrentPFBalance:   Rupees(total.employeeBalance + total.employerBalance),
+			EmployeeShareTotal: models.EPFShare{Credit: Rupees(total.employeeCredit), Balance: Rupees(total.employeeBalance)},
+			EmployerShareTotal: models.EPFShare{Credit: Rupees(total.employerCredit), Balance: Rupees(total.employerBalance)},
+		}
+		resp.UANAccounts = append(resp.UANAccounts, models.UANAccount{PhoneNumber: json.RawMessage(`{}`), RawDetails: raw})
+	}
+	return resp
+}
+
+// accumulate credits the contributions of every month of service and the
+// interest on the balance until asOf, or until the member id turns inoperative
+func (e Establishment) accumulate(monthlyRate float64, asOf time.Time) epfShares {
+	employeeRate := e.EmployeeRatePercent / 100
+	if employeeRate == 0 {
+		employeeRate = 0.12
+	}
+	end := asOf
+	if e.Exited != nil && e.Exited.Before(asOf) {
+		end = e.Exited.Time
+	}
+	interestEnd := asOf
+	if e.Exited != nil && e.Exited.AddDate(0, inoperativeMonths, 0).Before(asOf) {
+		interestEnd = e.Exited.AddDate(0, inoperativeMonths, 0)
+	}
+
+	var s epfShares
+	for m := dayOf(e.Joined.Time, 1); m.Before(interestEnd); m = m.AddDate(0, 1, 0) {
+		s.employeeBalance += s.employeeBalance * monthlyRate
+		s.employerBalance += s.employerBalance * monthlyRate
+		if m.Before(end) {
+			employee := math.Round(e.MonthlyBasic * employeeRate)
+			employer := math.Round(e.MonthlyBasic * employerEPFRate)
+			s.employeeCredit += employee
+			s.employerCredit += employer
+			s.employeeBalance += employee
+			s.employerBalance += employer
+			s.pension += math.Round(math.Min(e.MonthlyBasic, pensionWageCeiling) * pensionRate)
+		}
+	}
+	// whole rupees, so that the shares add up to the net and overall balances
+	s.employeeBalance = math.Round(math.Max(0, s.employeeBalance-e.Withdrawn/2))
+	s.employerBalance = math.Round(math.Max(0, s.employerBalance-e.Withdrawn/2))
+	return s
+}
+
+// epfBalance is the provident fund balance of all UANs, the ASSET_TYPE_EPF value
+func epfBalance(resp *models.EPFDetailsResponse) float64 {
+	var total float64
+	for _, u := range resp.UANAccounts {
+		total += models.ParseEPFAmount(u.RawDetails.OverallPFBalance.CurrentPFBalance)
+	}
+	return total
+}
+
+// Rupees formats an amount as whole rupees, as the EPFO passbook does
+func Rupees(v float64) string {
+	v = math.Round(v)
+	if v == 0 {
+		v = 0 // no -0
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
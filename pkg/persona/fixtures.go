@@ -0,0 +1,219 @@
+package persona
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Load reads the fixtures of a persona directory, so that an importer can replace
+// some of them. A missing file gives an empty response.
+func Load(dir string) (*Fixtures, error) {
+	f := &Fixtures{
+		NetWorth:          &models.FetchNetWorthResponse{},
+		CreditReport:      &models.CreditReportResponse{},
+		EPF:               &models.EPFDetailsResponse{},
+		MFTransactions:    &models.MFTransactionsResponse{},
+		BankTransactions:  &models.BankTransactionsResponse{},
+		StockTransactions: &models.StockTransactionsResponse{},
+	}
+	for tool, v := range f.Files() {
+		data, err := os.ReadFile(filepath.Join(dir, tool+".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("%s.json: %w", tool, err)
+		}
+	}
+	return f, nil
+}
+
+// WriteTools stores the fixtures of the given tools, and of any tool whose file
+// doesn't exist yet, as <dir>/<tool>.json. The other files are left untouched.
+// The rewritten files only keep the fields the models know about.
+func (f *Fixtures) WriteTools(dir string, tools ...string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for tool, v := range f.Files() {
+		path := filepath.Join(dir, tool+".json")
+		if _, err := os.Stat(path); err == nil && !slices.Contains(tools, tool) {
+			continue
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", tool, err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetBankTransactions replaces the bank transactions
+func (f *Fixtures) SetBankTransactions(accounts []models.BankTransactions) {
+	if len(accounts) == 0 {
+		f.BankTransactions = &models.BankTransactionsResponse{}
+		return
+	}
+	f.BankTransactions = &models.BankTransactionsResponse{SchemaDescription: bankSchemaDescription, BankTransactions: accounts}
+}
+
+// SetStockTransactions replaces the stock transactions
+func (f *Fixtures) SetStockTransactions(stocks []models.StockTransactions) {
+	if len(stocks) == 0 {
+		f.StockTransactions = &models.StockTransactionsResponse{}
+		return
+	}
+	f.StockTransactions = &models.StockTransactionsResponse{SchemaDescription: stockSchemaDescription, StockTransactions: stocks}
+}
+
+// SetAccounts replaces the connected accounts for which replace returns true with
+// accounts and works the net worth attributes they count towards out again from
+// the accounts held. The other attributes are kept and the total follows.
+func (f *Fixtures) SetAccounts(replace func(models.AccountDetailsEntry) bool, accounts map[string]models.AccountDetailsEntry) {
+	if f.NetWorth.AccountDetailsBulkResponse == nil {
+		f.NetWorth.AccountDetailsBulkResponse = &models.AccountDetailsBulkResponse{}
+	}
+	bulk := f.NetWorth.AccountDetailsBulkResponse
+	if bulk.AccountDetailsMap == nil {
+		bulk.AccountDetailsMap = map[string]models.AccountDetailsEntry{}
+	}
+	affected := map[string]bool{}
+	for id, entry := range bulk.AccountDetailsMap {
+		if replace(entry) {
+			affected[attributeOf(entry)] = true
+			delete(bulk.AccountDetailsMap, id)
+		}
+	}
+	for id, entry := range accounts {
+		affected[attributeOf(entry)] = true
+		bulk.AccountDetailsMap[id] = entry
+	}
+	if len(bulk.AccountDetailsMap) == 0 {
+		f.NetWorth.AccountDetailsBulkResponse = nil
+	}
+
+	values := map[string]float64{}
+	for _, entry := range bulk.AccountDetailsMap {
+		values[attributeOf(entry)] += summaryOf(entry).Value()
+	}
+	for attribute := range affected {
+		if attribute != "" {
+			f.setNetWorthValue(attribute, values[attribute])
+		}
+	}
+}
+
+// IsInstrument returns a SetAccounts filter matching the accounts of the given instrument types
+func IsInstrument(instrumentTypes ...string) func(models.AccountDetailsEntry) bool {
+	return func(entry models.AccountDetailsEntry) bool {
+		return slices.Contains(instrumentTypes, entry.AccountDetails.AccInstrumentType)
+	}
+}
+
+// attributeOf returns the net worth attribute an account counts towards
+func attributeOf(entry models.AccountDetailsEntry) string {
+	switch entry.AccountDetails.AccInstrumentType {
+	case "ACC_INSTRUMENT_TYPE_DEPOSIT":
+		if entry.DepositSummary != nil && entry.DepositSummary.DepositAccountType == "DEPOSIT_ACCOUNT_TYPE_FIXED" {
+			return "ASSET_TYPE_DEPOSITS"
+		}
+		return "ASSET_TYPE_SAVINGS_ACCOUNTS"
+	case "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT":
+		return "ASSET_TYPE_DEPOSITS"
+	case "ACC_INSTRUMENT_TYPE_MUTUAL_FUNDS":
+		return "ASSET_TYPE_MUTUAL_FUND"
+	case "ACC_INSTRUMENT_TYPE_EQUITIES", "ACC_INSTRUMENT_TYPE_REIT", "ACC_INSTRUMENT_TYPE_INVIT":
+		return "ASSET_TYPE_INDIAN_SECURITIES"
+	case "ACC_INSTRUMENT_TYPE_ETF":
+		return "ASSET_TYPE_ETF"
+	case "ACC_INSTRUMENT_TYPE_SGB":
+		return "ASSET_TYPE_SGB"
+	case "ACC_INSTRUMENT_TYPE_EPF":
+		return "ASSET_TYPE_EPF"
+	case "ACC_INSTRUMENT_TYPE_NPS":
+		return "ASSET_TYPE_NPS"
+	case "ACC_INSTRUMENT_TYPE_CREDIT_CARD":
+		return "LIABILITY_TYPE_CREDIT_CARD"
+	case "ACC_INSTRUMENT_TYPE_LOAN":
+		switch entry.AccountDetails.AccountType["loanAccountType"] {
+		case "LOAN_ACCOUNT_TYPE_HOME":
+			return "LIABILITY_TYPE_HOME_LOAN"
+		case "LOAN_ACCOUNT_TYPE_VEHICLE", "LOAN_ACCOUNT_TYPE_AUTO":
+			return "LIABILITY_TYPE_VEHICLE_LOAN"
+		}
+		return "LIABILITY_TYPE_OTHER_LOAN"
+	}
+	return ""
+}
+
+// summaryOf returns the summary of an account, whichever field holds it
+func summaryOf(entry models.AccountDetailsEntry) *models.AccountSummary {
+	for _, s := range []*models.AccountSummary{
+		entry.DepositSummary, entry.RecurringDepositSummary, entry.EquitySummary, entry.ETFSummary,
+		entry.REITSummary, entry.InvITSummary, entry.MutualFundSummary, entry.SGBSummary,
+		entry.NPSSummary, entry.EPFSummary, entry.CreditCardSummary, entry.LoanSummary,
+	} {
+		if s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// setNetWorthValue sets the value of a net worth attribute, dropping it when the
+// value is zero, and works the total out again
+func (f *Fixtures) setNetWorthValue(attribute string, v float64) {
+	if f.NetWorth.NetWorthResponse == nil {
+		f.NetWorth.NetWorthResponse = &models.NetWorthResponse{}
+	}
+	resp := f.NetWorth.NetWorthResponse
+	values, order := &resp.AssetValues, assetTypes
+	if strings.HasPrefix(attribute, "LIABILITY_") {
+		values, order = &resp.LiabilityValues, liabilityTypes
+	}
+	*values = slices.DeleteFunc(*values, func(nv models.NetWorthValue) bool { return nv.NetWorthAttribute == attribute })
+	if v = models.Round(v, 2); v > 0 {
+		// keep the attributes in the order the generator lists them, unknown ones last
+		rank := func(a string) int {
+			if i := slices.Index(order, a); i >= 0 {
+				return i
+			}
+			return len(order)
+		}
+		i := slices.IndexFunc(*values, func(nv models.NetWorthValue) bool { return rank(nv.NetWorthAttribute) > rank(attribute) })
+		if i < 0 {
+			i = len(*values)
+		}
+		*values = slices.Insert(*values, i, models.NetWorthValue{NetWorthAttribute: attribute, Value: money(v)})
+	}
+
+	var total float64
+	for _, nv := range resp.AssetValues {
+		total += nv.Value.Float()
+	}
+	for _, nv := range resp.LiabilityValues {
+		total -= nv.Value.Float()
+	}
+	resp.TotalNetWorthValue = money(total)
+}
+
+// AccountID derives a stable account id in the UUID format of the data files
+// from parts identifying the account, so that importing the same statement
+// twice gives the same ids
+func AccountID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,166 @@
+package persona
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Fixtures are the responses of the six data tools for a persona
+type Fixtures struct {
+	NetWorth          *models.FetchNetWorthResponse
+	CreditReport      *models.CreditReportResponse
+	EPF               *models.EPFDetailsResponse
+	MFTransactions    *models.MFTransactionsResponse
+	BankTransactions  *models.BankTransactionsResponse
+	StockTransactions *models.StockTransactionsResponse
+}
+
+// Files returns the fixtures keyed by the name of the data tool they answer
+func (f *Fixtures) Files() map[string]any {
+	return map[string]any{
+		"fetch_net_worth":          f.NetWorth,
+		"fetch_credit_report":      f.CreditReport,
+		"fetch_epf_details":        f.EPF,
+		"fetch_mf_transactions":    f.MFTransactions,
+		"fetch_bank_transactions":  f.BankTransactions,
+		"fetch_stock_transactions": f.StockTransactions,
+	}
+}
+
+// Write stores the fixtures as <dir>/<tool>.json
+func (f *Fixtures) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for tool, v := range f.Files() {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", tool, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tool+".json"), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generator holds the state shared by the parts of a persona while it is generated
+type generator struct {
+	spec *Spec
+	rng  *rand.Rand
+	// oneOff are the debits and credits of SIPs, redemptions, deposits and EMIs in the payments account
+	oneOff []txn
+	// windowStart is the first day of the bank transactions
+	windowStart time.Time
+}
+
+// Generate builds the fixtures of a persona. The same spec always gives the same fixtures.
+func Generate(spec *Spec) (*Fixtures, error) {
+	months := spec.BankMonths
+	if months == 0 {
+		months = 2
+	}
+	asOf := spec.AsOf.Time
+	g := &generator{
+		spec:        spec,
+		rng:         rand.New(rand.NewSource(spec.Seed)),
+		windowStart: time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1-months, 0),
+	}
+	mf, schemes, err := g.mutualFunds()
+	if err != nil {
+		return nil, err
+	}
+	stocks, equities, err := g.stocks()
+	if err != nil {
+		return nil, err
+	}
+	epf := g.epf()
+	deposits := g.deposits()
+	credit := g.creditReport()
+	bank, balances, err := g.bankTransactions()
+	if err != nil {
+		return nil, err
+	}
+	return &Fixtures{
+		NetWorth:          g.netWorth(schemes, equities, epf, deposits, balances),
+		CreditReport:      credit,
+		EPF:               epf,
+		MFTransactions:    mf,
+		BankTransactions:  bank,
+		StockTransactions: stocks,
+	}, nil
+}
+
+// prices walks a price from start on day from to asOf, one seeded log-normal step a day
+type prices struct {
+	from   time.Time
+	values []float64
+}
+
+func (g *generator) pricePath(p PricePath, from time.Time) prices {
+	days := int(g.spec.AsOf.Sub(from).Hours()/24) + 1
+	if days < 1 {
+		days = 1
+	}
+	drift := math.Log(1+p.AnnualReturnPercent/100) / 365
+	vol := p.VolatilityPercent / 100 / math.Sqrt(365)
+	values := make([]float64, days)
+	values[0] = p.Start
+	for i := 1; i < days; i++ {
+		values[i] = values[i-1] * math.Exp(drift-vol*vol/2+vol*g.rng.NormFloat64())
+	}
+	return prices{from: from, values: values}
+}
+
+// at returns the price on a date, rounded like a published NAV
+func (p prices) at(t time.Time) float64 {
+	i := int(t.Sub(p.from).Hours() / 24)
+	i = max(0, min(i, len(p.values)-1))
+	return models.Round(p.values[i], 4)
+}
+
+func (p prices) last() float64 {
+	return models.Round(p.values[len(p.values)-1], 4)
+}
+
+// id returns a random UUID-like identifier, drawn from the seeded generator
+func (g *generator) id() string {
+	b := make([]byte, 16)
+	g.rng.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// dayOf returns the given day of the month of t, clipped to the length of the month
+func dayOf(t time.Time, day int) time.Time {
+	if day < 1 {
+		day = 1
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(t.Year(), t.Month(), min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+func inMonths(months []int, m time.Month) bool {
+	if len(months) == 0 {
+		return true
+	}
+	for _, x := range months {
+		if time.Month(x) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// money converts an amount to Money by way of whole paise, so that sums carry
+// no floating point residue into the nanos
+func money(v float64) *models.Money {
+	m := models.NewMoney(models.Round(v, 2))
+	return &m
+}
@@ -0,0 +1,236 @@
+package persona
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/returns"
+)
+
+// Scheme is a mutual fund folio read from a statement
+type Scheme struct {
+	ISIN      string
+	Name      string
+	AMC       string
+	Registrar string
+	Folio     string
+	Category  string
+	// PlanType, OptionType and AssetClass default to DIRECT, GROWTH and EQUITY
+	PlanType   string
+	OptionType string
+	AssetClass string
+	// Units and NAV are the closing balance of the folio. A zero NAV falls back
+	// to the price of the last transaction.
+	Units float64
+	NAV   float64
+	// Cost is the cost of the units held when the statement gives it, otherwise
+	// it is worked out first in first out from Txns. Without either the units
+	// are taken to be worth what they cost.
+	Cost float64
+	Txns []models.MFTxn
+}
+
+// Registrar names the registrar of a folio the way the mutual fund accounts
+// do, from the name a statement gives it
+func Registrar(s string) string {
+	s = strings.ToUpper(s)
+	switch {
+	case strings.Contains(s, "CAMS"):
+		return "CAMS"
+	case strings.Contains(s, "KFIN"), strings.Contains(s, "KARVY"):
+		return "KFINTECH"
+	}
+	return s
+}
+
+// lot is a purchase of which units are still held
+type lot struct{ units, cost float64 }
+
+// lots are the purchases of a folio still held, oldest first
+type lots []lot
+
+func (l *lots) buy(units, cost float64) {
+	*l = append(*l, lot{units, cost})
+}
+
+// sell takes units out of the oldest lots and returns the cost of the units taken
+func (l *lots) sell(units float64) float64 {
+	var cost float64
+	for len(*l) > 0 && units > 1e-9 {
+		first := &(*l)[0]
+		take := math.Min(units, first.units)
+		c := first.cost * take / first.units
+		cost += c
+		first.units -= take
+		first.cost -= c
+		units -= take
+		if first.units <= 1e-9 {
+			*l = (*l)[1:]
+		}
+	}
+	return cost
+}
+
+func (l lots) cost() float64 {
+	var cost float64
+	for _, x := range l {
+		cost += x.cost
+	}
+	return cost
+}
+
+// SetMutualFunds replaces the MF transactions, the scheme analytics and the
+// mutual fund accounts with those of schemes, one account per registrar.
+// Schemes held in several folios are reported once in the analytics. XIRR is
+// worked out from the transactions and the value on asOf. All folios of a
+// scheme are valued at the NAV of the first.
+func (f *Fixtures) SetMutualFunds(schemes []Scheme, asOf time.Time) {
+	f.MFTransactions = &models.MFTransactionsResponse{}
+	analytics := map[string]*scheme{}
+	flows := map[string][]returns.CashFlow{}
+	var isins []string
+	folios := map[string]*models.AccountSummary{}
+	var registrars []string
+	for _, s := range schemes {
+		txns := append([]models.MFTxn(nil), s.Txns...)
+		sort.SliceStable(txns, func(i, j int) bool { return txns[i].Date < txns[j].Date })
+		if len(txns) > 0 {
+			f.MFTransactions.MFTransactions = append(f.MFTransactions.MFTransactions, models.MFSchemeTransactions{
+				ISIN: s.ISIN, SchemeName: s.Name, FolioID: s.Folio, Txns: txns,
+			})
+		}
+		if s.Units <= 0 {
+			continue
+		}
+
+		nav := s.NAV
+		if nav == 0 && len(txns) > 0 {
+			nav = txns[len(txns)-1].Price
+		}
+		if a, ok := analytics[s.ISIN]; ok {
+			nav = a.nav
+		}
+		var held lots
+		var realised, units float64
+		for _, t := range txns {
+			date, err := t.Time()
+			if err != nil {
+				continue
+			}
+			if t.OrderType == models.MFOrderTypeBuy {
+				held.buy(t.Units, t.Amount)
+				units += t.Units
+				flows[s.ISIN] = append(flows[s.ISIN], returns.CashFlow{Date: date, Amount: -t.Amount})
+				continue
+			}
+			realised += t.Amount - held.sell(t.Units)
+			units -= t.Units
+			flows[s.ISIN] = append(flows[s.ISIN], returns.CashFlow{Date: date, Amount: t.Amount})
+		}
+		invested := s.Cost
+		switch {
+		case invested > 0:
+		case units > 1e-9:
+			// statements trimmed to recent transactions don't explain every unit held
+			invested = held.cost() * s.Units / units
+		default:
+			invested = models.Round(s.Units*nav, 2)
+		}
+
+		a, ok := analytics[s.ISIN]
+		if !ok {
+			a = &scheme{fund: MutualFund{
+				ISIN: s.ISIN, Name: s.Name, AMC: s.AMC, Category: s.Category,
+				PlanType: s.PlanType, AssetClass: s.AssetClass,
+			}, nav: nav, optionType: s.OptionType}
+			analytics[s.ISIN] = a
+			isins = append(isins, s.ISIN)
+		}
+		a.units += s.Units
+		a.invested += invested
+		a.realised += realised
+
+		registrar := strings.ToUpper(s.Registrar)
+		if registrar == "" {
+			registrar = "CAMS"
+		}
+		summary, ok := folios[registrar]
+		if !ok {
+			summary = &models.AccountSummary{CurrentValue: money(0)}
+			folios[registrar] = summary
+			registrars = append(registrars, registrar)
+		}
+		summary.CurrentValue = money(summary.CurrentValue.Float() + models.Round(s.Units*nav, 2))
+		summary.HoldingsInfo = append(summary.HoldingsInfo, models.Holding{ISIN: s.ISIN, FolioNumber: s.Folio, Units: s.Units, NAV: money(nav)})
+	}
+	if len(f.MFTransactions.MFTransactions) > 0 {
+		f.MFTransactions.SchemaDescription = mfSchemaDescription
+	}
+
+	f.NetWorth.MFSchemeAnalytics = nil
+	if len(isins) > 0 {
+		f.NetWorth.MFSchemeAnalytics = &models.MFSchemeAnalytics{}
+	}
+	for _, isin := range isins {
+		a := analytics[isin]
+		a.units = models.Round(a.units, 3)
+		if len(flows[isin]) > 0 {
+			if rate, err := returns.XIRR(append(flows[isin], returns.CashFlow{Date: asOf, Amount: a.value()})); err == nil {
+				a.xirr = models.Round(rate*100, 2)
+			}
+		}
+		f.NetWorth.MFSchemeAnalytics.SchemeAnalytics = append(f.NetWorth.MFSchemeAnalytics.SchemeAnalytics, a.analytics())
+	}
+
+	accounts := map[string]models.AccountDetailsEntry{}
+	for i, registrar := range registrars {
+		summary := folios[registrar]
+		summary.AccountID = AccountID("mutual_funds", registrar)
+		meta, ok := rtaMeta[registrar]
+		if !ok {
+			meta = models.FipMeta{Name: registrar, DisplayName: registrar}
+		}
+		accounts[summary.AccountID] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{
+				FipID:               "fip@" + strings.ToLower(registrar),
+				MaskedAccountNumber: fmt.Sprintf("XXXXXX%d001", i+1),
+				AccInstrumentType:   "ACC_INSTRUMENT_TYPE_MUTUAL_FUNDS",
+				AccountType:         map[string]string{"mutualFundAccountType": "MUTUAL_FUND_ACCOUNT_TYPE_FOLIO"},
+				FipMeta:             &meta,
+			},
+			MutualFundSummary: summary,
+		}
+	}
+	f.SetAccounts(IsInstrument("ACC_INSTRUMENT_TYPE_MUTUAL_FUNDS"), accounts)
+}
+
+// SetEPF replaces the EPF details and the EPF account, valued at the
+// current_pf_balance of all UANs on asOf
+func (f *Fixtures) SetEPF(resp *models.EPFDetailsResponse, asOf time.Time) {
+	f.EPF = resp
+	accounts := map[string]models.AccountDetailsEntry{}
+	if balance := epfBalance(resp); balance > 0 {
+		masked := "XXXXXXXX"
+		if u := resp.UANAccounts; len(u[0].RawDetails.EstDetails) > 0 {
+			if id := u[0].RawDetails.EstDetails[0].MemberID; len(id) >= 4 {
+				masked += id[len(id)-4:]
+			}
+		}
+		id := AccountID("epf", masked)
+		accounts[id] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{
+				FipID:               "fip@epfo",
+				MaskedAccountNumber: masked,
+				AccInstrumentType:   "ACC_INSTRUMENT_TYPE_EPF",
+				AccountType:         map[string]string{"epfAccountType": "EPF_ACCOUNT_TYPE_DEFAULT_TYPE"},
+				FipMeta:             &models.FipMeta{Name: "EPFO", DisplayName: "EPFO"},
+			},
+			EPFSummary: &models.AccountSummary{AccountID: id, CurrentBalance: money(balance), BalanceDate: asOf.Add(12 * time.Hour).Format(time.RFC3339)},
+		}
+	}
+	f.SetAccounts(IsInstrument("ACC_INSTRUMENT_TYPE_EPF"), accounts)
+}
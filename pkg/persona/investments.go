@@ -0,0 +1,205 @@
+package persona
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+	"github.com/epifi/fi-mcp-lite/pkg/returns"
+)
+
+const (
+	mfSchemaDescription    = "A list of mutual fund investments. We currently support 500 transactions across all mutual funds(mutual funds with older transactions will be trimmed off, if limit exceeds). Each 'txns' field is a list of data arrays with schema: [ orderType(1 for BUY and 2 for SELL), transactionDate, purchasePrice, purchaseUnits, transactionAmount ]."
+	stockSchemaDescription = "A list of stock transactions. Each 'txns' field is a list of data arrays with schema: [transactionType (1 for BUY, 2 for SELL, 3 for BONUS, 4 for SPLIT), transactionDate, quantity, navValue]. nav value may not be present in some of the transactions"
+)
+
+// scheme is a mutual fund holding at AsOf
+type scheme struct {
+	fund     MutualFund
+	units    float64
+	nav      float64
+	invested float64
+	realised float64
+	xirr     float64
+	// optionType defaults to GROWTH
+	optionType string
+}
+
+func (s scheme) value() float64 { return models.Round(s.units*s.nav, 2) }
+
+// equity is a share holding at AsOf
+type equity struct {
+	stock    Stock
+	quantity float64
+	price    float64
+}
+
+func (e equity) value() float64 { return models.Round(e.quantity*e.price, 2) }
+
+// order is a purchase (positive amount) or a redemption (percent of the units held)
+type order struct {
+	date    time.Time
+	amount  float64
+	percent float64
+}
+
+// mutualFunds replays the orders of every fund at the NAV of its day. SIP
+// instalments, lump sums and redemptions in the bank window are also added to
+// the payments account.
+func (g *generator) mutualFunds() (*models.MFTransactionsResponse, []scheme, error) {
+	resp := &models.MFTransactionsResponse{MFTransactions: []models.MFSchemeTransactions{}, SchemaDescription: mfSchemaDescription}
+	var schemes []scheme
+	asOf := g.spec.AsOf.Time
+	for i, f := range g.spec.MutualFunds {
+		if f.Folio == "" {
+			f.Folio = fmt.Sprintf("%d/%02d", 91000000+g.rng.Intn(999999), g.rng.Intn(100))
+			g.spec.MutualFunds[i].Folio = f.Folio
+		}
+		var orders []order
+		if f.SIP != nil {
+			to := asOf
+			if !f.SIP.To.IsZero() && f.SIP.To.Before(asOf) {
+				to = f.SIP.To.Time
+			}
+			for m := dayOf(f.SIP.From.Time, f.SIP.Day); !m.After(to); m = dayOf(m.AddDate(0, 0, 1-m.Day()).AddDate(0, 1, 0), f.SIP.Day) {
+				if !m.Before(f.SIP.From.Time) && inMonths(f.SIP.Months, m.Month()) {
+					orders = append(orders, order{date: m, amount: f.SIP.Amount})
+				}
+			}
+		}
+		for _, l := range f.Lumpsums {
+			orders = append(orders, order{date: l.Date.Time, amount: l.Amount})
+		}
+		for _, r := range f.Redemptions {
+			orders = append(orders, order{date: r.Date.Time, percent: r.Percent})
+		}
+		sort.SliceStable(orders, func(i, j int) bool { return orders[i].date.Before(orders[j].date) })
+		if len(orders) == 0 || orders[0].percent > 0 {
+			return nil, nil, fmt.Errorf("mutual fund %s: the first order must be a purchase", f.ISIN)
+		}
+		path := g.pricePath(f.NAV, orders[0].date)
+
+		var held lots
+		var flows []returns.CashFlow
+		s := scheme{fund: f, nav: path.last()}
+		txns := models.MFSchemeTransactions{ISIN: f.ISIN, SchemeName: f.Name, FolioID: f.Folio}
+		for _, o := range orders {
+			if o.date.After(asOf) {
+				continue
+			}
+			nav := path.at(o.date)
+			if o.amount > 0 {
+				units := models.Round(o.amount/nav, 3)
+				held.buy(units, o.amount)
+				s.units += units
+				txns.Txns = append(txns.Txns, models.MFTxn{OrderType: models.MFOrderTypeBuy, Date: o.date.Format(models.DateLayout), Price: nav, Units: units, Amount: o.amount})
+				flows = append(flows, returns.CashFlow{Date: o.date, Amount: -o.amount})
+				g.investmentDebit(o.date, o.amount, f, "SIP")
+				continue
+			}
+			units := models.Round(s.units*o.percent/100, 3)
+			if units <= 0 {
+				continue
+			}
+			amount := models.Round(units*nav, 2)
+			s.realised += units*nav - held.sell(units)
+			s.units = models.Round(s.units-units, 3)
+			txns.Txns = append(txns.Txns, models.MFTxn{OrderType: models.MFOrderTypeSell, Date: o.date.Format(models.DateLayout), Price: nav, Units: units, Amount: amount})
+			flows = append(flows, returns.CashFlow{Date: o.date, Amount: amount})
+			g.redemptionCredit(o.date, amount, f)
+		}
+		s.invested = held.cost()
+		resp.MFTransactions = append(resp.MFTransactions, txns)
+		if s.units <= 0 {
+			continue
+		}
+		flows = append(flows, returns.CashFlow{Date: asOf, Amount: s.value()})
+		if f.ReportedXIRR != nil {
+			s.xirr = *f.ReportedXIRR
+		} else if rate, err := returns.XIRR(flows); err == nil {
+			s.xirr = models.Round(rate*100, 2)
+		}
+		schemes = append(schemes, s)
+	}
+	if len(resp.MFTransactions) == 0 {
+		return &models.MFTransactionsResponse{}, nil, nil
+	}
+	return resp, schemes, nil
+}
+
+// investmentDebit adds the bank debit of a purchase made in the bank window
+func (g *generator) investmentDebit(date time.Time, amount float64, f MutualFund, kind string) {
+	if date.Before(g.windowStart) || date.After(g.spec.AsOf.Time) {
+		return
+	}
+	if f.SIP == nil {
+		kind = "LUMPSUM"
+	}
+	g.oneOff = append(g.oneOff, txn{date: date, amount: amount, typ: models.BankTxnTypeDebit, mode: "ACH",
+		narration: fmt.Sprintf("ACH D-%sMF-%s/%s", amcCode(f), kind, f.Folio)})
+}
+
+// redemptionCredit adds the bank credit of a redemption made in the bank window
+func (g *generator) redemptionCredit(date time.Time, amount float64, f MutualFund) {
+	// redemptions are paid out a couple of days later
+	date = date.AddDate(0, 0, 2)
+	if date.Before(g.windowStart) || date.After(g.spec.AsOf.Time) {
+		return
+	}
+	g.oneOff = append(g.oneOff, txn{date: date, amount: amount, typ: models.BankTxnTypeCredit, mode: "NEFT",
+		narration: fmt.Sprintf("NEFT CR-%s MUTUAL FUND-REDEMPTION-%s", amcCode(f), f.Folio)})
+}
+
+// amcCode shortens an AMC such as SBI_MUTUAL_FUND to the SBI used in narrations
+func amcCode(f MutualFund) string {
+	name := f.AMC
+	if name == "" {
+		name = strings.Fields(f.Name)[0]
+	}
+	name = strings.TrimSuffix(strings.ToUpper(name), "_MUTUAL_FUND")
+	return strings.NewReplacer("_", "", " ", "").Replace(name)
+}
+
+// stocks replays the trades of every share at the price of its day
+func (g *generator) stocks() (*models.StockTransactionsResponse, []equity, error) {
+	resp := &models.StockTransactionsResponse{StockTransactions: []models.StockTransactions{}, SchemaDescription: stockSchemaDescription}
+	var equities []equity
+	for _, st := range g.spec.Stocks {
+		trades := append([]Trade(nil), st.Trades...)
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].Date.Before(trades[j].Date.Time) })
+		path := g.pricePath(st.Price, trades[0].Date.Time)
+		e := equity{stock: st, price: path.last()}
+		txns := models.StockTransactions{ISIN: st.ISIN}
+		for _, t := range trades {
+			row := models.StockTxn{Date: t.Date.Format(models.DateLayout), Quantity: t.Quantity}
+			price := math.Round(path.at(t.Date.Time)*100) / 100
+			switch t.Type {
+			case "buy":
+				row.Type, row.NAV = models.StockTxnTypeBuy, &price
+				e.quantity += t.Quantity
+			case "sell":
+				if t.Quantity > e.quantity {
+					return nil, nil, fmt.Errorf("stock %s: sells %v on %s but holds %v", st.ISIN, t.Quantity, row.Date, e.quantity)
+				}
+				row.Type, row.NAV = models.StockTxnTypeSell, &price
+				e.quantity -= t.Quantity
+			case "bonus":
+				row.Type = models.StockTxnTypeBonus
+				e.quantity += t.Quantity
+			}
+			txns.Txns = append(txns.Txns, row)
+		}
+		e.price = math.Round(e.price*100) / 100
+		resp.StockTransactions = append(resp.StockTransactions, txns)
+		if e.quantity > 0 {
+			equities = append(equities, e)
+		}
+	}
+	if len(resp.StockTransactions) == 0 {
+		return &models.StockTransactionsResponse{}, nil, nil
+	}
+	return resp, equities, nil
+}
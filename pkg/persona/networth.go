@@ -0,0 +1,284 @@
+package persona
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// assetTypes and liabilityTypes are the net worth attributes in the order they are listed
+var (
+	assetTypes = []string{
+		"ASSET_TYPE_MUTUAL_FUND", "ASSET_TYPE_EPF", "ASSET_TYPE_INDIAN_SECURITIES", "ASSET_TYPE_DEPOSITS",
+		"ASSET_TYPE_SAVINGS_ACCOUNTS", "ASSET_TYPE_NPS", "ASSET_TYPE_ETF", "ASSET_TYPE_SGB", "ASSET_TYPE_US_SECURITIES",
+	}
+	liabilityTypes = []string{
+		"LIABILITY_TYPE_HOME_LOAN", "LIABILITY_TYPE_VEHICLE_LOAN", "LIABILITY_TYPE_CREDIT_CARD",
+		"LIABILITY_TYPE_OTHER_LOAN", "LIABILITY_TYPE_LOAN",
+	}
+)
+
+// rtaMeta are the fip ids and names of the registrars mutual fund folios are held with
+var rtaMeta = map[string]models.FipMeta{
+	"CAMS":     {Name: "Computer Age Management Services", DisplayName: "CAMS"},
+	"KFINTECH": {Name: "KFintech", DisplayName: "KFintech"},
+}
+
+// netWorth aggregates the holdings into the asset and liability values, the
+// scheme analytics and the connected accounts. Every value is derived from the
+// same holdings, so the attributes add up to the total and to the accounts.
+func (g *generator) netWorth(schemes []scheme, equities []equity, epf *models.EPFDetailsResponse, deposits []deposit, balances []float64) *models.FetchNetWorthResponse {
+	asOf := g.spec.AsOf.Time
+	balanceDate := asOf.Add(12 * time.Hour).Format(time.RFC3339)
+	accounts := map[string]models.AccountDetailsEntry{}
+	assets := map[string]float64{}
+	liabilities := map[string]float64{}
+
+	for _, d := range deposits {
+		id := g.id()
+		summary := &models.AccountSummary{
+			AccountID:            id,
+			CurrentBalance:       money(d.value),
+			BalanceDate:          balanceDate,
+			OpeningDate:          d.Opened.Format(time.RFC3339),
+			MaturityDate:         d.Maturity.Format(time.RFC3339),
+			DepositAccountStatus: "DEPOSIT_ACCOUNT_STATUS_ACTIVE",
+		}
+		entry := models.AccountDetailsEntry{AccountDetails: bankDetails(d.FipID, d.Bank, d.DisplayName, d.Masked, "")}
+		if d.Kind == "fixed" {
+			summary.DepositAccountType = "DEPOSIT_ACCOUNT_TYPE_FIXED"
+			entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_DEPOSIT"
+			entry.AccountDetails.AccountType = map[string]string{"depositAccountType": summary.DepositAccountType}
+			entry.DepositSummary = summary
+		} else {
+			summary.CurrentPrincipalAmount = money(d.principal)
+			entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_RECURRING_DEPOSIT"
+			entry.AccountDetails.AccountType = map[string]string{"recurringDepositAccountType": "RECURRING_DEPOSIT_ACCOUNT_TYPE_RECURRING"}
+			entry.RecurringDepositSummary = summary
+		}
+		accounts[id] = entry
+		assets["ASSET_TYPE_DEPOSITS"] += models.Round(d.value, 2)
+	}
+
+	for i, a := range g.spec.BankAccounts {
+		id := g.id()
+		kind := "DEPOSIT_ACCOUNT_TYPE_SAVINGS"
+		if a.Type == "current" {
+			kind = "DEPOSIT_ACCOUNT_TYPE_CURRENT"
+		}
+		details := bankDetails(a.FipID, a.Bank, a.DisplayName, a.Masked, a.IFSC)
+		details.AccInstrumentType = "ACC_INSTRUMENT_TYPE_DEPOSIT"
+		details.AccountType = map[string]string{"depositAccountType": kind}
+		accounts[id] = models.AccountDetailsEntry{AccountDetails: details, DepositSummary: &models.AccountSummary{
+			AccountID:            id,
+			CurrentBalance:       money(balances[i]),
+			BalanceDate:          balanceDate,
+			DepositAccountType:   kind,
+			IFSCCode:             a.IFSC,
+			DepositAccountStatus: "DEPOSIT_ACCOUNT_STATUS_ACTIVE",
+		}}
+		assets["ASSET_TYPE_SAVINGS_ACCOUNTS"] += models.Round(balances[i], 2)
+	}
+
+	analytics := &models.MFSchemeAnalytics{}
+	folios := map[string]*models.AccountSummary{}
+	var rtas []string
+	for _, s := range schemes {
+		f := s.fund
+		analytics.SchemeAnalytics = append(analytics.SchemeAnalytics, s.analytics())
+		assets["ASSET_TYPE_MUTUAL_FUND"] += s.value()
+
+		rta := strings.ToUpper(f.RTA)
+		if rta == "" {
+			rta = "CAMS"
+		}
+		summary, ok := folios[rta]
+		if !ok {
+			summary = &models.AccountSummary{AccountID: g.id(), CurrentValue: money(0)}
+			folios[rta] = summary
+			rtas = append(rtas, rta)
+		}
+		summary.CurrentValue = money(summary.CurrentValue.Float() + s.value())
+		summary.HoldingsInfo = append(summary.HoldingsInfo, models.Holding{ISIN: f.ISIN, FolioNumber: f.Folio, Units: s.units, NAV: money(s.nav)})
+	}
+	for i, rta := range rtas {
+		summary := folios[rta]
+		meta := rtaMeta[rta]
+		accounts[summary.AccountID] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{
+				FipID:               "fip@" + strings.ToLower(rta),
+				MaskedAccountNumber: fmt.Sprintf("XXXXXX%d001", i+1),
+				AccInstrumentType:   "ACC_INSTRUMENT_TYPE_MUTUAL_FUNDS",
+				AccountType:         map[string]string{"mutualFundAccountType": "MUTUAL_FUND_ACCOUNT_TYPE_FOLIO"},
+				FipMeta:             &meta,
+			},
+			MutualFundSummary: summary,
+		}
+	}
+
+	if len(equities) > 0 {
+		id := g.id()
+		summary := &models.AccountSummary{AccountID: id}
+		var total float64
+		for _, e := range equities {
+			summary.HoldingsInfo = append(summary.HoldingsInfo, models.Holding{
+				ISIN:            e.stock.ISIN,
+				IssuerName:      e.stock.Name,
+				Type:            "EQUITY_HOLDING_TYPE_DEMAT",
+				Units:           e.quantity,
+				LastTradedPrice: money(e.price),
+			})
+			total += e.value()
+		}
+		summary.CurrentValue = money(total)
+		accounts[id] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{
+				FipID:               "fip@nsdl",
+				MaskedAccountNumber: "XXXXXX" + g.spec.PhoneNumber[6:],
+				AccInstrumentType:   "ACC_INSTRUMENT_TYPE_EQUITIES",
+				AccountType:         map[string]string{"equityAccountType": "EQUITY_ACCOUNT_TYPE_DEFAULT_TYPE"},
+				FipMeta:             &models.FipMeta{Name: "National Securities Depository Limited", DisplayName: "NSDL"},
+			},
+			EquitySummary: summary,
+		}
+		assets["ASSET_TYPE_INDIAN_SECURITIES"] += models.Round(total, 2)
+	}
+
+	if balance := epfBalance(epf); balance > 0 {
+		id := g.id()
+		accounts[id] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{
+				FipID:               "fip@epfo",
+				MaskedAccountNumber: "XXXXXXXX" + g.spec.PhoneNumber[6:],
+				AccInstrumentType:   "ACC_INSTRUMENT_TYPE_EPF",
+				AccountType:         map[string]string{"epfAccountType": "EPF_ACCOUNT_TYPE_DEFAULT_TYPE"},
+				FipMeta:             &models.FipMeta{Name: "EPFO", DisplayName: "EPFO"},
+			},
+			EPFSummary: &models.AccountSummary{AccountID: id, CurrentBalance: money(balance), BalanceDate: balanceDate},
+		}
+		assets["ASSET_TYPE_EPF"] += balance
+	}
+
+	if n := g.spec.NPS; n != nil && n.Value > 0 {
+		id := g.id()
+		accounts[id] = models.AccountDetailsEntry{
+			AccountDetails: models.AccountDetails{FipID: "fip@nps", MaskedAccountNumber: n.Masked, AccInstrumentType: "ACC_INSTRUMENT_TYPE_NPS"},
+			NPSSummary:     &models.AccountSummary{AccountID: id, CurrentValue: money(n.Value)},
+		}
+		assets["ASSET_TYPE_NPS"] += models.Round(n.Value, 2)
+	}
+
+	if c := g.spec.Credit; c != nil {
+		for _, a := range c.Accounts {
+			if a.Closed != nil && !a.Closed.After(asOf) || a.Balance <= 0 {
+				continue
+			}
+			t := accountTypes[a.Kind]
+			id := g.id()
+			details := models.AccountDetails{
+				FipID:               strings.ToUpper(strings.Fields(a.Subscriber)[0]) + "-FIP",
+				MaskedAccountNumber: a.Masked,
+				FipMeta:             &models.FipMeta{Name: a.Subscriber, DisplayName: a.Subscriber},
+			}
+			entry := models.AccountDetailsEntry{AccountDetails: details}
+			if a.Kind == "credit_card" {
+				entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_CREDIT_CARD"
+				entry.CreditCardSummary = &models.AccountSummary{AccountID: id, CurrentBalance: money(a.Balance), CreditLimit: money(a.Limit)}
+			} else {
+				entry.AccountDetails.AccInstrumentType = "ACC_INSTRUMENT_TYPE_LOAN"
+				entry.AccountDetails.AccountType = map[string]string{"loanAccountType": t.loanType}
+				entry.LoanSummary = &models.AccountSummary{
+					AccountID:          id,
+					CurrentOutstanding: money(a.Balance),
+					OriginalLoanAmount: money(a.Amount),
+					LoanStatus:         "LOAN_STATUS_ACTIVE",
+				}
+			}
+			accounts[id] = entry
+			liabilities[t.liability] += models.Round(a.Balance, 2)
+		}
+	}
+
+	resp := &models.NetWorthResponse{}
+	var total float64
+	for _, attribute := range assetTypes {
+		if v := models.Round(assets[attribute], 2); v > 0 {
+			resp.AssetValues = append(resp.AssetValues, models.NetWorthValue{NetWorthAttribute: attribute, Value: money(v)})
+			total += v
+		}
+	}
+	for _, attribute := range liabilityTypes {
+		if v := models.Round(liabilities[attribute], 2); v > 0 {
+			resp.LiabilityValues = append(resp.LiabilityValues, models.NetWorthValue{NetWorthAttribute: attribute, Value: money(v)})
+			total -= v
+		}
+	}
+	resp.TotalNetWorthValue = money(total)
+
+	out := &models.FetchNetWorthResponse{NetWorthResponse: resp}
+	if len(analytics.SchemeAnalytics) > 0 {
+		out.MFSchemeAnalytics = analytics
+	}
+	if len(accounts) > 0 {
+		out.AccountDetailsBulkResponse = &models.AccountDetailsBulkResponse{AccountDetailsMap: accounts}
+	}
+	return out
+}
+
+// analytics returns the precomputed returns of a scheme as fetch_net_worth reports them
+func (s scheme) analytics() models.SchemeAnalytics {
+	f := s.fund
+	planType, assetClass, optionType := f.PlanType, f.AssetClass, s.optionType
+	if planType == "" {
+		planType = "DIRECT"
+	}
+	if optionType == "" {
+		optionType = "GROWTH"
+	}
+	if assetClass == "" {
+		assetClass = "EQUITY"
+	}
+	a := models.SchemeAnalytics{SchemeDetail: models.SchemeDetail{
+		AMC:            f.AMC,
+		NameData:       models.NameData{LongName: f.Name},
+		PlanType:       planType,
+		InvestmentType: "OPEN",
+		OptionType:     optionType,
+		NAV:            money(s.nav),
+		AssetClass:     assetClass,
+		ISINNumber:     f.ISIN,
+		CategoryName:   f.Category,
+	}}
+	returns := s.value() - s.invested
+	a.EnrichedAnalytics.Analytics.SchemeDetails = models.SchemeDetails{
+		CurrentValue:      money(s.value()),
+		InvestedValue:     money(s.invested),
+		XIRR:              s.xirr,
+		AbsoluteReturns:   money(returns),
+		UnrealisedReturns: money(returns),
+		NAVValue:          money(s.nav),
+		Units:             s.units,
+	}
+	if s.realised != 0 {
+		a.EnrichedAnalytics.Analytics.SchemeDetails.RealisedReturns = money(s.realised)
+	}
+	return a
+}
+
+// bankDetails are the account details of an account held with a bank
+func bankDetails(fipID, bank, displayName, masked, ifsc string) models.AccountDetails {
+	if displayName == "" {
+		displayName = strings.Fields(bank)[0]
+	}
+	if fipID == "" {
+		fipID = strings.ToUpper(displayName) + "-FIP"
+	}
+	return models.AccountDetails{
+		FipID:               fipID,
+		MaskedAccountNumber: masked,
+		IFSCCode:            ifsc,
+		FipMeta:             &models.FipMeta{Name: bank, DisplayName: displayName, Bank: strings.ToUpper(displayName)},
+	}
+}
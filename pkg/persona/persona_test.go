@@ -0,0 +1,147 @@
+package persona
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const spec = `
+phone_number: "9000000001"
+name: Test
+seed: 7
+as_of: 2025-03-20
+date_of_birth: 1990-01-01
+bank_accounts:
+  - bank: Test Bank
+    masked_account_number: XX01
+    balance: 150000
+    payments: true
+    flows:
+      - {narration: "SALARY {MON}", amount: 100000, kind: credit, day: 1}
+    spends:
+      per_month: {min: 3, max: 5}
+      amount: {min: 100, max: 2000}
+      narrations: ["UPI-GROCER-{REF}"]
+deposits:
+  - {bank: Test Bank, masked_account_number: XX02, kind: recurring, monthly_installment: 5000, rate_percent: 7, opened: 2024-06-10, maturity: 2026-06-10}
+mutual_funds:
+  - isin: INF000000001
+    name: Alpha Flexi Cap Fund
+    amc: ALPHA_MUTUAL_FUND
+    nav: {start: 50, annual_return_percent: 12, volatility_percent: 15}
+    sip: {amount: 10000, day: 5, from: 2023-01-05}
+    redemptions:
+      - {date: 2024-09-02, percent: 30}
+stocks:
+  - isin: INE000000001
+    name: ACME LTD
+    price: {start: 100, annual_return_percent: 10, volatility_percent: 20}
+    trades:
+      - {date: 2023-05-02, type: buy, quantity: 50}
+      - {date: 2024-05-02, type: sell, quantity: 20}
+epf:
+  uans:
+    - establishments:
+        - {name: ACME LIMITED, member_id: MH0001, joined: 2020-04-01, monthly_basic: 30000}
+credit:
+  score: 780
+  accounts:
+    - {subscriber: Test Bank, kind: personal_loan, masked_account_number: XX03, opened: 2024-01-01, amount: 300000, balance: 200000, emi: 9000}
+`
+
+func generate(t *testing.T) *Fixtures {
+	t.Helper()
+	s, err := ParseSpec([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Generate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	a, _ := json.Marshal(generate(t).Files())
+	b, _ := json.Marshal(generate(t).Files())
+	if string(a) != string(b) {
+		t.Error("the same spec generated different fixtures")
+	}
+}
+
+func TestGenerateIsConsistent(t *testing.T) {
+	f := generate(t)
+
+	// every balance follows from the previous one and ends on the declared balance
+	for _, b := range f.BankTransactions.BankTransactions {
+		prev := math.NaN()
+		for i, txn := range b.Txns {
+			balance, _ := strconv.ParseFloat(txn.CurrentBalance, 64)
+			if i > 0 && math.Abs(prev+float64(txn.Sign())*txn.AmountValue()-balance) > 0.005 {
+				t.Errorf("txns/%d: balance %v does not follow from %v", i, balance, prev)
+			}
+			prev = balance
+		}
+		if prev != 150000 {
+			t.Errorf("closing balance = %v", prev)
+		}
+	}
+
+	// the attributes add up to the total
+	nw := f.NetWorth.NetWorthResponse
+	var total float64
+	for _, v := range nw.AssetValues {
+		total += v.Value.Float()
+	}
+	for _, v := range nw.LiabilityValues {
+		total -= v.Value.Float()
+	}
+	if math.Abs(total-nw.TotalNetWorthValue.Float()) > 0.005 {
+		t.Errorf("total = %v, attributes add up to %v", nw.TotalNetWorthValue.Float(), total)
+	}
+
+	// the units of the analytics are those left by the transactions
+	var units float64
+	for _, txn := range f.MFTransactions.MFTransactions[0].Txns {
+		if txn.OrderType == models.MFOrderTypeSell {
+			units -= txn.Units
+		} else {
+			units += txn.Units
+		}
+	}
+	got := f.NetWorth.MFSchemeAnalytics.SchemeAnalytics[0].EnrichedAnalytics.Analytics.SchemeDetails.Units
+	if math.Abs(got-units) > 0.001 {
+		t.Errorf("analytics units = %v, transactions leave %v", got, units)
+	}
+
+	// the EMI and RD instalments are debited from the payments account
+	var kinds []int
+	for _, txn := range f.BankTransactions.BankTransactions[0].Txns {
+		if txn.Type == models.BankTxnTypeInstallment {
+			kinds = append(kinds, int(txn.AmountValue()))
+		}
+	}
+	if want := []int{9000, 5000, 9000, 5000}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("instalments = %v, want %v", kinds, want)
+	}
+}
+
+func TestParseSpecRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseSpec([]byte("phone_number: \"9000000001\"\nas_of: 2025-01-01\nsalary: 1\n")); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestRupees(t *testing.T) {
+	for v, want := range map[float64]string{1234.5: "1235", -0.4: "0", -2.6: "-3"} {
+		if got := Rupees(v); got != want {
+			t.Errorf("Rupees(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
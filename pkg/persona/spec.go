@@ -0,0 +1,353 @@
+package persona
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Date is a YYYY-MM-DD date in a persona spec
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalYAML(node *yaml.Node) error {
+	t, err := time.Parse(models.DateLayout, node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %q is not a date in YYYY-MM-DD format", node.Line, node.Value)
+	}
+	d.Time = t
+	return nil
+}
+
+// Range is an inclusive range of values drawn from uniformly
+type Range struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// Spec describes a persona. Generate turns it into the responses of the six data tools.
+type Spec struct {
+	PhoneNumber string `yaml:"phone_number"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Seed makes the generated data reproducible
+	Seed        int64 `yaml:"seed"`
+	AsOf        Date  `yaml:"as_of"`
+	DateOfBirth Date  `yaml:"date_of_birth"`
+	// BankMonths is how many months of bank transactions up to AsOf are generated, 2 when omitted
+	BankMonths   int           `yaml:"bank_months"`
+	BankAccounts []BankAccount `yaml:"bank_accounts"`
+	Deposits     []Deposit     `yaml:"deposits"`
+	MutualFunds  []MutualFund  `yaml:"mutual_funds"`
+	Stocks       []Stock       `yaml:"stocks"`
+	EPF          *EPF          `yaml:"epf"`
+	NPS          *NPS          `yaml:"nps"`
+	Credit       *Credit       `yaml:"credit"`
+}
+
+// BankAccount is a savings or current account. Its balance on AsOf is given and
+// the balances of the generated transactions are worked back from it.
+type BankAccount struct {
+	Bank        string  `yaml:"bank"`
+	DisplayName string  `yaml:"display_name"`
+	FipID       string  `yaml:"fip_id"`
+	IFSC        string  `yaml:"ifsc"`
+	Masked      string  `yaml:"masked_account_number"`
+	Type        string  `yaml:"type"`
+	Balance     float64 `yaml:"balance"`
+	// NoTransactions leaves the account out of fetch_bank_transactions
+	NoTransactions bool `yaml:"no_transactions"`
+	// Payments marks the account SIP, recurring deposit and EMI debits are taken from. The first account when none is marked.
+	Payments bool    `yaml:"payments"`
+	Flows    []Flow  `yaml:"flows"`
+	Spends   *Spends `yaml:"spends"`
+}
+
+// Flow is a transaction repeating every month of the bank window
+type Flow struct {
+	// Narration may use {MON} for the month name and {REF} for a random reference number
+	Narration     string  `yaml:"narration"`
+	Amount        float64 `yaml:"amount"`
+	JitterPercent float64 `yaml:"jitter_percent"`
+	// Kind is credit, debit, interest or installment, debit when omitted
+	Kind string `yaml:"kind"`
+	Mode string `yaml:"mode"`
+	Day  int    `yaml:"day"`
+	// Months limits the flow to these calendar months
+	Months []int `yaml:"months"`
+	// Probability is the chance of the flow happening in a month, 1 when omitted
+	Probability float64 `yaml:"probability"`
+}
+
+// Spends are small random debits
+type Spends struct {
+	PerMonth   Range    `yaml:"per_month"`
+	Amount     Range    `yaml:"amount"`
+	Narrations []string `yaml:"narrations"`
+}
+
+// Deposit is a fixed or recurring deposit
+type Deposit struct {
+	Bank        string `yaml:"bank"`
+	DisplayName string `yaml:"display_name"`
+	FipID       string `yaml:"fip_id"`
+	Masked      string `yaml:"masked_account_number"`
+	// Kind is fixed or recurring
+	Kind               string  `yaml:"kind"`
+	Principal          float64 `yaml:"principal"`
+	MonthlyInstallment float64 `yaml:"monthly_installment"`
+	RatePercent        float64 `yaml:"rate_percent"`
+	Opened             Date    `yaml:"opened"`
+	Maturity           Date    `yaml:"maturity"`
+}
+
+// PricePath is a seeded random walk of a NAV or share price from the first transaction to AsOf
+type PricePath struct {
+	Start               float64 `yaml:"start"`
+	AnnualReturnPercent float64 `yaml:"annual_return_percent"`
+	VolatilityPercent   float64 `yaml:"volatility_percent"`
+}
+
+// MutualFund is a scheme with its orders
+type MutualFund struct {
+	ISIN         string       `yaml:"isin"`
+	Name         string       `yaml:"name"`
+	AMC          string       `yaml:"amc"`
+	Category     string       `yaml:"category"`
+	AssetClass   string       `yaml:"asset_class"`
+	PlanType     string       `yaml:"plan_type"`
+	Folio        string       `yaml:"folio"`
+	RTA          string       `yaml:"rta"`
+	NAV          PricePath    `yaml:"nav"`
+	SIP          *SIP         `yaml:"sip"`
+	Lumpsums     []Order      `yaml:"lumpsums"`
+	Redemptions  []Redemption `yaml:"redemptions"`
+	ReportedXIRR *float64     `yaml:"reported_xirr"`
+}
+
+// SIP is a monthly purchase between two dates
+type SIP struct {
+	Amount float64 `yaml:"amount"`
+	Day    int     `yaml:"day"`
+	From   Date    `yaml:"from"`
+	To     Date    `yaml:"to"`
+	// Months limits the instalments to these calendar months, e.g. [1, 2, 3] for ELSS in Q4
+	Months []int `yaml:"months"`
+}
+
+// Order is a lump sum purchase
+type Order struct {
+	Date   Date    `yaml:"date"`
+	Amount float64 `yaml:"amount"`
+}
+
+// Redemption sells a percentage of the units held on its date
+type Redemption struct {
+	Date    Date    `yaml:"date"`
+	Percent float64 `yaml:"percent"`
+}
+
+// Stock is an Indian listed share with its trades
+type Stock struct {
+	ISIN   string    `yaml:"isin"`
+	Name   string    `yaml:"name"`
+	Price  PricePath `yaml:"price"`
+	Trades []Trade   `yaml:"trades"`
+}
+
+// Trade is a buy, sell or bonus of a share
+type Trade struct {
+	Date     Date    `yaml:"date"`
+	Type     string  `yaml:"type"`
+	Quantity float64 `yaml:"quantity"`
+}
+
+// EPF are the provident fund accounts of the persona
+type EPF struct {
+	InterestPercent float64 `yaml:"interest_percent"`
+	UANs            []UAN   `yaml:"uans"`
+}
+
+// UAN is one Universal Account Number
+type UAN struct {
+	Establishments []Establishment `yaml:"establishments"`
+}
+
+// Establishment is the service at one employer
+type Establishment struct {
+	Name     string `yaml:"name"`
+	MemberID string `yaml:"member_id"`
+	Office   string `yaml:"office"`
+	Joined   Date   `yaml:"joined"`
+	// Exited is omitted for the current employer
+	Exited              *Date   `yaml:"exited"`
+	MonthlyBasic        float64 `yaml:"monthly_basic"`
+	EmployeeRatePercent float64 `yaml:"employee_rate_percent"`
+	// Withdrawn is taken out of the balance, split evenly between the shares
+	Withdrawn float64 `yaml:"withdrawn"`
+}
+
+// NPS is a National Pension System account
+type NPS struct {
+	Masked string  `yaml:"masked_account_number"`
+	Value  float64 `yaml:"value"`
+}
+
+// Credit is the bureau report of the persona. A persona without one has no credit history.
+type Credit struct {
+	Vendor    string          `yaml:"vendor"`
+	Score     int             `yaml:"score"`
+	Accounts  []CreditAccount `yaml:"accounts"`
+	Enquiries []Enquiry       `yaml:"enquiries"`
+}
+
+// CreditAccount is a loan or credit card
+type CreditAccount struct {
+	Subscriber string `yaml:"subscriber"`
+	// Kind is credit_card, personal_loan, home_loan, auto_loan, consumer_loan or education_loan
+	Kind         string  `yaml:"kind"`
+	Masked       string  `yaml:"masked_account_number"`
+	Opened       Date    `yaml:"opened"`
+	Closed       *Date   `yaml:"closed"`
+	Limit        float64 `yaml:"limit"`
+	Amount       float64 `yaml:"amount"`
+	Balance      float64 `yaml:"balance"`
+	PastDue      float64 `yaml:"past_due"`
+	RatePercent  float64 `yaml:"rate_percent"`
+	TenureMonths int     `yaml:"tenure_months"`
+	// EMI is debited every month of the bank window from the payments account. For a credit card it is the bill paid.
+	EMI    float64 `yaml:"emi"`
+	EMIDay int     `yaml:"emi_day"`
+	// PaymentHistory is the 36 character days past due profile, all zeros when omitted
+	PaymentHistory string `yaml:"payment_history"`
+}
+
+// Enquiry is a credit application made by the persona
+type Enquiry struct {
+	Subscriber string `yaml:"subscriber"`
+	Date       Date   `yaml:"date"`
+	Reason     string `yaml:"reason"`
+}
+
+var phonePattern = regexp.MustCompile(`^\d{10}$`)
+
+// LoadSpec reads and validates a persona spec
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ParseSpec decodes and validates a persona spec, rejecting unknown fields
+func ParseSpec(data []byte) (*Spec, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate checks the fields Generate relies on
+func (s *Spec) Validate() error {
+	switch {
+	case !phonePattern.MatchString(s.PhoneNumber):
+		return fmt.Errorf("phone_number must be 10 digits, got %q", s.PhoneNumber)
+	case s.AsOf.IsZero():
+		return fmt.Errorf("as_of is required")
+	case s.BankMonths < 0:
+		return fmt.Errorf("bank_months must not be negative")
+	}
+	for i, a := range s.BankAccounts {
+		if a.Bank == "" || a.Masked == "" {
+			return fmt.Errorf("bank_accounts[%d]: bank and masked_account_number are required", i)
+		}
+		if a.Type != "" && a.Type != "savings" && a.Type != "current" {
+			return fmt.Errorf("bank_accounts[%d]: type must be savings or current", i)
+		}
+		for j, f := range a.Flows {
+			switch f.Kind {
+			case "", "credit", "debit", "interest", "installment":
+			default:
+				return fmt.Errorf("bank_accounts[%d].flows[%d]: unknown kind %q", i, j, f.Kind)
+			}
+			if f.Amount <= 0 || f.Narration == "" {
+				return fmt.Errorf("bank_accounts[%d].flows[%d]: narration and a positive amount are required", i, j)
+			}
+		}
+	}
+	for i, d := range s.Deposits {
+		if d.Kind != "fixed" && d.Kind != "recurring" {
+			return fmt.Errorf("deposits[%d]: kind must be fixed or recurring", i)
+		}
+		if d.Kind == "fixed" && d.Principal <= 0 || d.Kind == "recurring" && d.MonthlyInstallment <= 0 {
+			return fmt.Errorf("deposits[%d]: a positive principal or monthly_installment is required", i)
+		}
+		if !d.Maturity.After(d.Opened.Time) {
+			return fmt.Errorf("deposits[%d]: maturity must be after opened", i)
+		}
+	}
+	for i, f := range s.MutualFunds {
+		if f.ISIN == "" || f.Name == "" || f.NAV.Start <= 0 {
+			return fmt.Errorf("mutual_funds[%d]: isin, name and a positive nav.start are required", i)
+		}
+		if f.SIP == nil && len(f.Lumpsums) == 0 {
+			return fmt.Errorf("mutual_funds[%d]: %s has no sip or lumpsums", i, f.ISIN)
+		}
+		for j, r := range f.Redemptions {
+			if r.Percent <= 0 || r.Percent > 100 {
+				return fmt.Errorf("mutual_funds[%d].redemptions[%d]: percent must be in (0, 100]", i, j)
+			}
+		}
+	}
+	for i, st := range s.Stocks {
+		if st.ISIN == "" || st.Price.Start <= 0 || len(st.Trades) == 0 {
+			return fmt.Errorf("stocks[%d]: isin, a positive price.start and trades are required", i)
+		}
+		for j, t := range st.Trades {
+			if t.Type != "buy" && t.Type != "sell" && t.Type != "bonus" {
+				return fmt.Errorf("stocks[%d].trades[%d]: type must be buy, sell or bonus", i, j)
+			}
+		}
+	}
+	if s.EPF != nil {
+		for i, u := range s.EPF.UANs {
+			for j, e := range u.Establishments {
+				if e.Name == "" || e.MonthlyBasic <= 0 {
+					return fmt.Errorf("epf.uans[%d].establishments[%d]: name and a positive monthly_basic are required", i, j)
+				}
+				if e.Exited != nil && !e.Exited.After(e.Joined.Time) {
+					return fmt.Errorf("epf.uans[%d].establishments[%d]: exited must be after joined", i, j)
+				}
+			}
+		}
+	}
+	if s.Credit != nil {
+		for i, a := range s.Credit.Accounts {
+			if _, ok := accountTypes[a.Kind]; !ok {
+				return fmt.Errorf("credit.accounts[%d]: unknown kind %q", i, a.Kind)
+			}
+			if a.PaymentHistory != "" && len(a.PaymentHistory) != 36 {
+				return fmt.Errorf("credit.accounts[%d]: payment_history must be 36 characters", i)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package returns
+
+import (
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// NAV sources reported alongside each scheme
+const (
+	NAVSourceScheme      = "schemeDetail.nav"
+	NAVSourceTransaction = "transaction"
+	NAVSourceUnavailable = "unavailable"
+)
+
+// Options controls the valuation date of Compute
+type Options struct {
+	// AsOf is the valuation date, only transactions up to it are considered. Defaults to NAVDate.
+	AsOf time.Time
+	// NAVDate is the date on which the scheme NAV in the analytics applies. Defaults
+	// to the latest transaction date; callers with the net worth response pass its
+	// ValuationDate.
+	NAVDate time.Time
+}
+
+// Summary holds the returns of a set of transactions valued on the as-of date
+type Summary struct {
+	InvestedAmount        float64  `json:"investedAmount"`
+	RedeemedAmount        float64  `json:"redeemedAmount"`
+	UnitsHeld             float64  `json:"unitsHeld"`
+	CurrentValue          float64  `json:"currentValue"`
+	AbsoluteReturn        float64  `json:"absoluteReturn"`
+	AbsoluteReturnPercent float64  `json:"absoluteReturnPercent"`
+	XIRRPercent           *float64 `json:"xirrPercent"`
+	XIRRError             string   `json:"xirrError,omitempty"`
+}
+
+// SchemeReturns holds the returns of one scheme across all its folios
+type SchemeReturns struct {
+	ISIN                string   `json:"isin"`
+	SchemeName          string   `json:"schemeName"`
+	NAV                 float64  `json:"nav"`
+	NAVSource           string   `json:"navSource"`
+	ReportedXIRRPercent *float64 `json:"reportedXirrPercent,omitempty"`
+	Summary
+}
+
+// FolioReturns holds the returns of one scheme within one folio
+type FolioReturns struct {
+	ISIN       string `json:"isin"`
+	SchemeName string `json:"schemeName"`
+	FolioID    string `json:"folioId"`
+	Summary
+}
+
+// Result holds the returns at scheme, folio and portfolio level
+type Result struct {
+	AsOf      string          `json:"asOf"`
+	Portfolio Summary         `json:"portfolio"`
+	Schemes   []SchemeReturns `json:"schemes"`
+	Folios    []FolioReturns  `json:"folios"`
+}
+
+// position accumulates the cash flows and units of a set of transactions
+type position struct {
+	flows    []CashFlow
+	units    float64
+	invested float64
+	redeemed float64
+}
+
+func (p *position) add(date time.Time, txn models.MFTxn) {
+	switch txn.OrderType {
+	case models.MFOrderTypeBuy:
+		p.units += txn.Units
+		p.invested += txn.Amount
+		p.flows = append(p.flows, CashFlow{Date: date, Amount: -txn.Amount})
+	case models.MFOrderTypeSell:
+		p.units -= txn.Units
+		p.redeemed += txn.Amount
+		p.flows = append(p.flows, CashFlow{Date: date, Amount: txn.Amount})
+	}
+}
+
+func (p *position) merge(other *position) {
+	p.flows = append(p.flows, other.flows...)
+	p.units += other.units
+	p.invested += other.invested
+	p.redeemed += other.redeemed
+}
+
+// summarize values the position at the given value on the as-of date
+func (p *position) summarize(value float64, asOf time.Time) Summary {
+	s := Summary{
+		InvestedAmount: models.Round(p.invested, 2),
+		RedeemedAmount: models.Round(p.redeemed, 2),
+		UnitsHeld:      models.Round(p.units, 4),
+		CurrentValue:   models.Round(value, 2),
+	}
+	gain := value + p.redeemed - p.invested
+	s.AbsoluteReturn = models.Round(gain, 2)
+	if p.invested > 0 {
+		s.AbsoluteReturnPercent = models.Round(gain/p.invested*100, 2)
+	}
+	flows := append([]CashFlow{}, p.flows...)
+	if value > 0 {
+		flows = append(flows, CashFlow{Date: asOf, Amount: value})
+	}
+	rate, err := XIRR(flows)
+	if err != nil {
+		s.XIRRError = err.Error()
+		return s
+	}
+	pct := models.Round(rate*100, 4)
+	s.XIRRPercent = &pct
+	return s
+}
+
+// Compute returns the XIRR and absolute returns of the MF transactions.
+// Holdings are valued at the scheme NAV from the analytics when the as-of date
+// is on or after the NAV date, and at the last transaction price on or before
+// the as-of date otherwise.
+func Compute(txns *models.MFTransactionsResponse, analytics *models.MFSchemeAnalytics, opts Options) (*Result, error) {
+	navDate := opts.NAVDate
+	if navDate.IsZero() {
+		navDate = latestDate(txns)
+	}
+	navDate = truncateDay(navDate)
+	asOf := navDate
+	if !opts.AsOf.IsZero() {
+		asOf = truncateDay(opts.AsOf)
+	}
+
+	schemeNAV := make(map[string]float64)
+	schemeXIRR := make(map[string]float64)
+	if analytics != nil {
+		for _, a := range analytics.SchemeAnalytics {
+			nav := a.SchemeDetail.NAV.Float()
+			if nav == 0 {
+				nav = a.EnrichedAnalytics.Analytics.SchemeDetails.NAVValue.Float()
+			}
+			schemeNAV[a.SchemeDetail.ISINNumber] = nav
+			schemeXIRR[a.SchemeDetail.ISINNumber] = a.EnrichedAnalytics.Analytics.SchemeDetails.XIRR
+		}
+	}
+
+	var isinOrder []string
+	schemeNames := make(map[string]string)
+	schemePositions := make(map[string]*position)
+	lastPrice := make(map[string]float64)
+	lastPriceDate := make(map[string]time.Time)
+	result := &Result{AsOf: asOf.Format(models.DateLayout)}
+	var folioPositions []*position
+
+	for _, scheme := range txns.MFTransactions {
+		folio := &position{}
+		for _, txn := range scheme.Txns {
+			date, err := txn.Time()
+			if err != nil {
+				return nil, err
+			}
+			if date.After(asOf) {
+				continue
+			}
+			folio.add(date, txn)
+			if txn.Price > 0 && !date.Before(lastPriceDate[scheme.ISIN]) {
+				lastPrice[scheme.ISIN] = txn.Price
+				lastPriceDate[scheme.ISIN] = date
+			}
+		}
+		if _, ok := schemePositions[scheme.ISIN]; !ok {
+			isinOrder = append(isinOrder, scheme.ISIN)
+			schemeNames[scheme.ISIN] = scheme.SchemeName
+			schemePositions[scheme.ISIN] = &position{}
+		}
+		schemePositions[scheme.ISIN].merge(folio)
+		folioPositions = append(folioPositions, folio)
+		result.Folios = append(result.Folios, FolioReturns{
+			ISIN:       scheme.ISIN,
+			SchemeName: scheme.SchemeName,
+			FolioID:    scheme.FolioID,
+		})
+	}
+
+	navOf := func(isin string) (float64, string) {
+		nav, hasSchemeNAV := schemeNAV[isin]
+		hasSchemeNAV = hasSchemeNAV && nav > 0
+		switch {
+		case hasSchemeNAV && !asOf.Before(navDate):
+			return nav, NAVSourceScheme
+		case lastPrice[isin] > 0:
+			return lastPrice[isin], NAVSourceTransaction
+		case hasSchemeNAV:
+			return nav, NAVSourceScheme
+		}
+		return 0, NAVSourceUnavailable
+	}
+
+	for i, scheme := range txns.MFTransactions {
+		nav, _ := navOf(scheme.ISIN)
+		folio := folioPositions[i]
+		result.Folios[i].Summary = folio.summarize(folio.units*nav, asOf)
+	}
+
+	portfolio := &position{}
+	var portfolioValue float64
+	for _, isin := range isinOrder {
+		pos := schemePositions[isin]
+		nav, source := navOf(isin)
+		value := pos.units * nav
+		scheme := SchemeReturns{
+			ISIN:       isin,
+			SchemeName: schemeNames[isin],
+			NAV:        nav,
+			NAVSource:  source,
+			Summary:    pos.summarize(value, asOf),
+		}
+		if reported, ok := schemeXIRR[isin]; ok {
+			reported := reported
+			scheme.ReportedXIRRPercent = &reported
+		}
+		result.Schemes = append(result.Schemes, scheme)
+		portfolio.merge(pos)
+		portfolioValue += value
+	}
+	result.Portfolio = portfolio.summarize(portfolioValue, asOf)
+	return result, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// latestDate returns the date of the latest transaction, zero when there is none
+func latestDate(txns *models.MFTransactionsResponse) time.Time {
+	var latest time.Time
+	for _, scheme := range txns.MFTransactions {
+		for _, txn := range scheme.Txns {
+			if date, err := txn.Time(); err == nil && date.After(latest) {
+				latest = date
+			}
+		}
+	}
+	return latest
+}
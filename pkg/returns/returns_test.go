@@ -0,0 +1,115 @@
+package returns
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(models.DateLayout, s)
+	return t
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []CashFlow
+		want  float64
+	}{
+		{
+			name:  "single year",
+			flows: []CashFlow{{date("2021-01-01"), -1000}, {date("2022-01-01"), 1100}},
+			want:  0.10,
+		},
+		{
+			name:  "loss",
+			flows: []CashFlow{{date("2021-01-01"), -1000}, {date("2022-01-01"), 900}},
+			want:  -0.10,
+		},
+		{
+			name: "multiple investments",
+			flows: []CashFlow{
+				{date("2021-01-01"), -1000},
+				{date("2022-01-01"), -1000},
+				{date("2023-01-01"), 2310},
+			},
+			want: 0.10,
+		},
+	}
+	for _, tt := range tests {
+		got, err := XIRR(tt.flows)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := XIRR([]CashFlow{{date("2021-01-01"), -1000}, {date("2022-01-01"), -1000}}); !errors.Is(err, ErrNoSolution) {
+		t.Errorf("expected ErrNoSolution for one-sided cash flows, got %v", err)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	txns := &models.MFTransactionsResponse{
+		MFTransactions: []models.MFSchemeTransactions{
+			{ISIN: "INF000000001", SchemeName: "Fund A", FolioID: "F1", Txns: []models.MFTxn{
+				{OrderType: models.MFOrderTypeBuy, Date: "2021-01-01", Price: 10, Units: 100, Amount: 1000},
+			}},
+			{ISIN: "INF000000001", SchemeName: "Fund A", FolioID: "F2", Txns: []models.MFTxn{
+				{OrderType: models.MFOrderTypeBuy, Date: "2021-01-01", Price: 10, Units: 100, Amount: 1000},
+				{OrderType: models.MFOrderTypeSell, Date: "2021-06-01", Price: 10.5, Units: 50, Amount: 525},
+			}},
+		},
+	}
+	nav := models.NewMoney(11)
+	analytics := &models.MFSchemeAnalytics{SchemeAnalytics: []models.SchemeAnalytics{
+		{SchemeDetail: models.SchemeDetail{ISINNumber: "INF000000001", NAV: &nav}},
+	}}
+
+	result, err := Compute(txns, analytics, Options{NAVDate: date("2022-01-01")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Schemes) != 1 || len(result.Folios) != 2 {
+		t.Fatalf("expected 1 scheme and 2 folios, got %d and %d", len(result.Schemes), len(result.Folios))
+	}
+	if got := result.Folios[0].CurrentValue; got != 1100 {
+		t.Errorf("folio F1 current value: got %v, want 1100", got)
+	}
+	if got := *result.Folios[0].XIRRPercent; math.Abs(got-10) > 1e-3 {
+		t.Errorf("folio F1 xirr: got %v, want 10", got)
+	}
+	scheme := result.Schemes[0]
+	if scheme.UnitsHeld != 150 || scheme.NAVSource != NAVSourceScheme {
+		t.Errorf("scheme: got %v units valued from %s", scheme.UnitsHeld, scheme.NAVSource)
+	}
+	// 150 units at 11 plus 525 redeemed against 2000 invested
+	if got := result.Portfolio.AbsoluteReturn; got != 175 {
+		t.Errorf("portfolio absolute return: got %v, want 175", got)
+	}
+
+	// valuing before the NAV date falls back to the last transaction price
+	result, err = Compute(txns, analytics, Options{AsOf: date("2021-03-01"), NAVDate: date("2022-01-01")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheme := result.Schemes[0]; scheme.NAVSource != NAVSourceTransaction || scheme.UnitsHeld != 200 || scheme.CurrentValue != 2000 {
+		t.Errorf("as-of scheme: got %+v", scheme)
+	}
+
+	// without a NAV date the holdings are valued on the latest transaction date
+	result, err = Compute(txns, analytics, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AsOf != "2021-06-01" || result.Schemes[0].NAVSource != NAVSourceScheme {
+		t.Errorf("default NAV date: got as of %s from %s", result.AsOf, result.Schemes[0].NAVSource)
+	}
+}
@@ -0,0 +1,114 @@
+package returns
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrNoSolution is returned when the cash flows do not have a rate of return,
+// e.g. when they are all of the same sign
+var ErrNoSolution = errors.New("xirr: cash flows have no solution")
+
+// CashFlow is an amount on a date. Investments are negative and redemptions
+// or the current value are positive.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+const (
+	daysInYear    = 365.0
+	maxIterations = 100
+	tolerance     = 1e-9
+)
+
+// XIRR returns the annualised internal rate of return of irregular cash flows
+// as a fraction, e.g. 0.12 for 12%. Newton's method is tried first and
+// bisection is used as a fallback when it does not converge.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoSolution
+	}
+	sorted := make([]CashFlow, len(flows))
+	copy(sorted, flows)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var hasPositive, hasNegative bool
+	for _, f := range sorted {
+		hasPositive = hasPositive || f.Amount > 0
+		hasNegative = hasNegative || f.Amount < 0
+	}
+	if !hasPositive || !hasNegative {
+		return 0, ErrNoSolution
+	}
+
+	start := sorted[0].Date
+	years := make([]float64, len(sorted))
+	for i, f := range sorted {
+		years[i] = f.Date.Sub(start).Hours() / 24 / daysInYear
+	}
+	npv := func(rate float64) float64 {
+		var sum float64
+		for i, f := range sorted {
+			sum += f.Amount / math.Pow(1+rate, years[i])
+		}
+		return sum
+	}
+	dnpv := func(rate float64) float64 {
+		var sum float64
+		for i, f := range sorted {
+			sum -= years[i] * f.Amount / math.Pow(1+rate, years[i]+1)
+		}
+		return sum
+	}
+
+	if rate, ok := newton(npv, dnpv, 0.1); ok {
+		return rate, nil
+	}
+	return bisect(npv)
+}
+
+func newton(f, df func(float64) float64, guess float64) (float64, bool) {
+	rate := guess
+	for i := 0; i < maxIterations; i++ {
+		d := df(rate)
+		if d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+			return 0, false
+		}
+		next := rate - f(rate)/d
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			return 0, false
+		}
+		if math.Abs(next-rate) < tolerance {
+			return next, true
+		}
+		rate = next
+	}
+	return 0, false
+}
+
+func bisect(f func(float64) float64) (float64, error) {
+	low, high := -0.999999, 1.0
+	// widen the upper bound until the sign changes, returns above 1e6 are treated as unsolvable
+	for f(low)*f(high) > 0 {
+		high *= 10
+		if high > 1e6 {
+			return 0, ErrNoSolution
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		mid := (low + high) / 2
+		v := f(mid)
+		if math.Abs(v) < tolerance || (high-low)/2 < tolerance {
+			return mid, nil
+		}
+		if f(low)*v < 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
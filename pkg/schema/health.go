@@ -0,0 +1,201 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxErrors is the number of errors kept per file and the number of failed
+// responses kept by a Monitor, the rest are only counted
+const maxErrors = 20
+
+// Health statuses
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// FileReport lists the schema errors of one response file
+type FileReport struct {
+	PhoneNumber string  `json:"phoneNumber"`
+	Tool        string  `json:"tool"`
+	ErrorCount  int     `json:"errorCount"`
+	Errors      []Error `json:"errors"`
+}
+
+// Report is the result of validating the response files of a test data dir
+type Report struct {
+	Dir          string       `json:"dir"`
+	CheckedAt    time.Time    `json:"checkedAt"`
+	Files        int          `json:"files"`
+	InvalidFiles []FileReport `json:"invalidFiles"`
+}
+
+// Source holds the tool responses of phone numbers, such as the data cache
+// being served
+type Source interface {
+	PhoneNumbers() []string
+	ToolData(phoneNumber, tool string) ([]byte, error)
+}
+
+// dirSource reads the tool responses from the phone number directories of dir
+type dirSource struct {
+	dir    string
+	phones []string
+}
+
+func (d dirSource) PhoneNumbers() []string { return d.phones }
+
+func (d dirSource) ToolData(phoneNumber, tool string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.dir, phoneNumber, tool+".json"))
+}
+
+// CheckDir validates the file of every tool in every phone number directory of
+// dir. A missing file is reported as an invalid one.
+func (s *Set) CheckDir(dir string) (Report, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Report{Dir: dir, CheckedAt: time.Now().UTC(), InvalidFiles: []FileReport{}}, err
+	}
+	src := dirSource{dir: dir}
+	for _, e := range entries {
+		if e.IsDir() {
+			src.phones = append(src.phones, e.Name())
+		}
+	}
+	return s.Check(dir, src), nil
+}
+
+// Check validates the response of every tool for every phone number of src,
+// which holds the files of dir. A missing response is reported as an invalid one.
+func (s *Set) Check(dir string, src Source) Report {
+	report := Report{Dir: dir, CheckedAt: time.Now().UTC(), InvalidFiles: []FileReport{}}
+	for _, phone := range src.PhoneNumbers() {
+		for _, tool := range s.Tools() {
+			report.Files++
+			var errs []Error
+			data, err := src.ToolData(phone, tool)
+			if err != nil {
+				errs = []Error{{Keyword: "file", Message: err.Error()}}
+			} else {
+				errs, _ = s.Validate(tool, data)
+			}
+			if len(errs) > 0 {
+				report.InvalidFiles = append(report.InvalidFiles, fileReport(phone, tool, errs))
+			}
+		}
+	}
+	return report
+}
+
+func fileReport(phoneNumber, tool string, errs []Error) FileReport {
+	r := FileReport{PhoneNumber: phoneNumber, Tool: tool, ErrorCount: len(errs), Errors: errs}
+	if len(errs) > maxErrors {
+		r.Errors = errs[:maxErrors]
+	}
+	return r
+}
+
+// ResponseFailure is a tool response that was withheld because it failed validation
+type ResponseFailure struct {
+	At time.Time `json:"at"`
+	FileReport
+}
+
+// Health is the state of the data reported by a Monitor
+type Health struct {
+	Status             string            `json:"status"`
+	SchemaDir          string            `json:"schemaDir"`
+	Files              Report            `json:"files"`
+	ResponseValidation bool              `json:"responseValidation"`
+	ResponsesChecked   int               `json:"responsesChecked"`
+	ResponsesFailed    int               `json:"responsesFailed"`
+	RecentFailures     []ResponseFailure `json:"recentFailures"`
+}
+
+// Monitor keeps the last check of the test data dir and, when response validation
+// is on, the responses that failed validation since the server started
+type Monitor struct {
+	set               *Set
+	validateResponses bool
+
+	mu      sync.Mutex
+	files   Report
+	checked int
+	failed  int
+	recent  []ResponseFailure
+}
+
+// NewMonitor returns a monitor validating against set. No files are checked until CheckDir or Check is called.
+func NewMonitor(set *Set, validateResponses bool) *Monitor {
+	return &Monitor{set: set, validateResponses: validateResponses, files: Report{InvalidFiles: []FileReport{}}}
+}
+
+// CheckDir validates the files of dir and keeps the report for Health
+func (m *Monitor) CheckDir(dir string) (Report, error) {
+	report, err := m.set.CheckDir(dir)
+	if err != nil {
+		return report, err
+	}
+	m.mu.Lock()
+	m.files = report
+	m.mu.Unlock()
+	return report, nil
+}
+
+// Check validates the responses of src, the files of dir, and keeps the report for Health
+func (m *Monitor) Check(dir string, src Source) Report {
+	report := m.set.Check(dir, src)
+	m.mu.Lock()
+	m.files = report
+	m.mu.Unlock()
+	return report
+}
+
+// ValidatesResponses reports whether responses are to be validated before they are sent
+func (m *Monitor) ValidatesResponses() bool {
+	return m.validateResponses
+}
+
+// CheckResponse validates the response of a tool for a phone number and records
+// it when it fails. Tools without a schema always pass.
+func (m *Monitor) CheckResponse(phoneNumber, tool string, data []byte) []Error {
+	errs, ok := m.set.Validate(tool, data)
+	if !ok {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checked++
+	if len(errs) == 0 {
+		return nil
+	}
+	m.failed++
+	m.recent = append(m.recent, ResponseFailure{At: time.Now().UTC(), FileReport: fileReport(phoneNumber, tool, errs)})
+	if len(m.recent) > maxErrors {
+		m.recent = m.recent[len(m.recent)-maxErrors:]
+	}
+	return errs
+}
+
+// Health returns the last file check and the response validation counts. The
+// status is degraded when a file or a response failed validation.
+func (m *Monitor) Health() Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := Health{
+		Status:             StatusOK,
+		SchemaDir:          m.set.Dir,
+		Files:              m.files,
+		ResponseValidation: m.validateResponses,
+		ResponsesChecked:   m.checked,
+		ResponsesFailed:    m.failed,
+		RecentFailures:     append([]ResponseFailure{}, m.recent...),
+	}
+	if len(m.files.InvalidFiles) > 0 || m.failed > 0 {
+		h.Status = StatusDegraded
+	}
+	return h
+}
@@ -0,0 +1,363 @@
+// Package schema validates the tool responses against the JSON Schemas in schemas/.
+//
+// Only the subset of JSON Schema 2020-12 used by those schemas is supported: type,
+// properties, required, additionalProperties, items, prefixItems, minItems,
+// maxItems, enum, const, pattern, minLength, maxLength, minimum, maximum, anyOf
+// and $ref to the $defs of the same schema. A schema using any other keyword is
+// rejected when it is loaded, so that no constraint is silently ignored.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error is a value that doesn't match its schema. Pointer is a JSON pointer to the value.
+type Error struct {
+	Pointer string `json:"pointer"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("#%s: %s: %s", e.Pointer, e.Keyword, e.Message)
+}
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	// always is set for the boolean schemas true and false
+	always *bool
+
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	Type                 typeList           `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	PrefixItems          []*Schema          `json:"prefixItems,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                *any               `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+
+	// annotations, not used for validation
+	SchemaURI   string          `json:"$schema,omitempty"`
+	ID          string          `json:"$id,omitempty"`
+	Comment     string          `json:"$comment,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Examples    json.RawMessage `json:"examples,omitempty"`
+
+	pattern *regexp.Regexp
+	ref     *Schema
+}
+
+// typeList is the type keyword, a single type or a list of them
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var always bool
+	if err := json.Unmarshal(data, &always); err == nil {
+		s.always = &always
+		return nil
+	}
+	// decode into an alias so that unknown keywords are rejected
+	type plain Schema
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	var p plain
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	*s = Schema(p)
+	return nil
+}
+
+// Parse reads and compiles a schema
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(&s, ""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile resolves the references against root and compiles the patterns
+func (s *Schema) compile(root *Schema, at string) error {
+	if s.always != nil {
+		return nil
+	}
+	for _, t := range s.Type {
+		if !knownTypes[t] {
+			return fmt.Errorf("%s: unknown type %q", at, t)
+		}
+	}
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok || root.Defs[name] == nil {
+			return fmt.Errorf("%s: unresolvable $ref %q", at, s.Ref)
+		}
+		s.ref = root.Defs[name]
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+		s.pattern = re
+	}
+	if s.Const != nil {
+		*s.Const = normalize(*s.Const)
+	}
+	for i := range s.Enum {
+		s.Enum[i] = normalize(s.Enum[i])
+	}
+
+	children := map[string]*Schema{"additionalProperties": s.AdditionalProperties, "items": s.Items}
+	for name, d := range s.Defs {
+		children["$defs/"+name] = d
+	}
+	for name, p := range s.Properties {
+		children["properties/"+name] = p
+	}
+	for i, p := range s.PrefixItems {
+		children["prefixItems/"+strconv.Itoa(i)] = p
+	}
+	for i, a := range s.AnyOf {
+		children["anyOf/"+strconv.Itoa(i)] = a
+	}
+	for path, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(root, at+"/"+path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a JSON document against the schema and returns every mismatch
+// in document order
+func (s *Schema) Validate(data []byte) []Error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []Error{{Keyword: "json", Message: err.Error()}}
+	}
+	if dec.More() {
+		return []Error{{Keyword: "json", Message: "unexpected data after the top-level value"}}
+	}
+	var errs []Error
+	s.validate(v, "", &errs)
+	return errs
+}
+
+func (s *Schema) validate(v any, at string, errs *[]Error) {
+	fail := func(keyword, format string, args ...any) {
+		*errs = append(*errs, Error{Pointer: at, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.always != nil {
+		if !*s.always {
+			fail("false", "no value is allowed here")
+		}
+		return
+	}
+	if s.ref != nil {
+		s.ref.validate(v, at, errs)
+	}
+	if len(s.Type) > 0 && !hasType(v, s.Type) {
+		fail("type", "expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		// the other keywords would only repeat the mismatch
+		return
+	}
+	if s.Const != nil && !reflect.DeepEqual(normalize(v), *s.Const) {
+		fail("const", "expected %s", display(*s.Const))
+	}
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = display(e)
+		}
+		fail("enum", "%s is not one of %s", display(v), strings.Join(values, ", "))
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, a := range s.AnyOf {
+			var sub []Error
+			if a.validate(v, at, &sub); len(sub) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("anyOf", "%s matches none of the %d allowed schemas", display(v), len(s.AnyOf))
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("minLength", "%q is shorter than %d characters", v, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("maxLength", "%q is longer than %d characters", v, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("pattern", "%q does not match %s", v, s.Pattern)
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("minimum", "%s is less than %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("maximum", "%s is greater than %v", v, *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("minItems", "%d items, at least %d required", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("maxItems", "%d items, at most %d allowed", len(v), *s.MaxItems)
+		}
+		for i, item := range v {
+			switch {
+			case i < len(s.PrefixItems):
+				s.PrefixItems[i].validate(item, at+"/"+strconv.Itoa(i), errs)
+			case s.Items != nil:
+				s.Items.validate(item, at+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("required", "%s is missing", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if p, ok := s.Properties[k]; ok {
+				p.validate(v[k], at+"/"+escape(k), errs)
+			} else if s.AdditionalProperties != nil {
+				if s.AdditionalProperties.always != nil && !*s.AdditionalProperties.always {
+					*errs = append(*errs, Error{Pointer: at + "/" + escape(k), Keyword: "additionalProperties", Message: k + " is not allowed here"})
+					continue
+				}
+				s.AdditionalProperties.validate(v[k], at+"/"+escape(k), errs)
+			}
+		}
+	}
+}
+
+func (s *Schema) inEnum(v any) bool {
+	v = normalize(v)
+	for _, e := range s.Enum {
+		if reflect.DeepEqual(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(v any, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if n, ok := v.(json.Number); ok {
+				f, err := n.Float64()
+				if err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case typeOf(v):
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// normalize turns numbers into float64 so that 1 and 1.0 compare equal in enum and const
+func normalize(v any) any {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
+
+func display(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(data) > 60 {
+		return string(data[:57]) + "..."
+	}
+	return string(data)
+}
+
+// escape escapes a key for use in a JSON pointer
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const txnSchema = `{
+	"type": "object",
+	"required": ["bank", "txns"],
+	"additionalProperties": false,
+	"properties": {
+		"bank": {"type": "string", "minLength": 1},
+		"txns": {"type": "array", "items": {"$ref": "#/$defs/txn"}}
+	},
+	"$defs": {
+		"txn": {
+			"type": "array",
+			"minItems": 3,
+			"maxItems": 3,
+			"prefixItems": [
+				{"type": "string", "pattern": "^[0-9]+$"},
+				{"enum": [1, 2]},
+				{"type": "integer", "minimum": 0}
+			]
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(txnSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := s.Validate([]byte(`{"bank": "Test", "txns": [["100", 1, 5], ["20", 2.0, 0]]}`)); len(errs) != 0 {
+		t.Errorf("valid document: %v", errs)
+	}
+
+	var got []string
+	for _, e := range s.Validate([]byte(`{"bank": "", "txns": [["1.5", 3, -1], ["1", 1]], "extra/key": 1}`)) {
+		got = append(got, e.Pointer+" "+e.Keyword)
+	}
+	want := []string{
+		"/bank minLength",
+		"/extra~1key additionalProperties",
+		"/txns/0/0 pattern",
+		"/txns/0/1 enum",
+		"/txns/0/2 minimum",
+		"/txns/1 minItems",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if errs := s.Validate([]byte(`{"txns": [[1, 1, 1.5]]}`)); len(errs) != 3 {
+		t.Errorf("expected required, type and integer errors, got %v", errs)
+	}
+	if errs := s.Validate([]byte(`{"bank": "x"`)); len(errs) != 1 || errs[0].Keyword != "json" {
+		t.Errorf("truncated document: %v", errs)
+	}
+}
+
+func TestParseRejectsUnsupportedSchemas(t *testing.T) {
+	for _, schema := range []string{
+		`{"type": "object", "patternProperties": {}}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"type": "decimal"}`,
+		`{"type": "string", "pattern": "("}`,
+	} {
+		if _, err := Parse([]byte(schema)); err == nil {
+			t.Errorf("%s: expected an error", schema)
+		}
+	}
+}
+
+// TestTestDataDir checks the shipped schemas against the files in test_data_dir.
+// 2525252525 has the credit report of fetch_credit_report in its net worth.
+func TestTestDataDir(t *testing.T) {
+	set, err := Load("../../schemas", []string{
+		"fetch_net_worth", "fetch_credit_report", "fetch_epf_details",
+		"fetch_mf_transactions", "fetch_bank_transactions", "fetch_stock_transactions",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := set.CheckDir("../../test_data_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files == 0 {
+		t.Fatal("no files checked")
+	}
+	for _, f := range report.InvalidFiles {
+		if f.PhoneNumber == "2525252525" && f.Tool == "fetch_net_worth" && f.ErrorCount == 1 && f.Errors[0].Pointer == "/creditReports" {
+			continue
+		}
+		t.Errorf("%s/%s.json: %d errors, first %s", f.PhoneNumber, f.Tool, f.ErrorCount, f.Errors[0])
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	s, err := Parse([]byte(txnSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMonitor(&Set{schemas: map[string]*Schema{"fetch_bank_transactions": s}}, true)
+	if errs := m.CheckResponse("9000000001", "fetch_bank_transactions", []byte(`{"bank": "Test", "txns": []}`)); len(errs) != 0 {
+		t.Errorf("valid response: %v", errs)
+	}
+	if errs := m.CheckResponse("9000000001", "fetch_bank_transactions", []byte(`{"bank": 1, "txns": []}`)); len(errs) != 1 {
+		t.Errorf("invalid response: %v", errs)
+	}
+	if errs := m.CheckResponse("9000000001", "fetch_net_worth", []byte(`not json`)); errs != nil {
+		t.Errorf("tool without schema: %v", errs)
+	}
+	h := m.Health()
+	if h.Status != StatusDegraded || h.ResponsesChecked != 2 || h.ResponsesFailed != 1 || len(h.RecentFailures) != 1 {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+// source is a Source of responses keyed by phone number and tool
+type source map[string]map[string]string
+
+func (s source) PhoneNumbers() []string {
+	var phones []string
+	for phone := range s {
+		phones = append(phones, phone)
+	}
+	return phones
+}
+
+func (s source) ToolData(phoneNumber, tool string) ([]byte, error) {
+	data, ok := s[phoneNumber][tool]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+func TestMonitorCheck(t *testing.T) {
+	s, err := Parse([]byte(txnSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMonitor(&Set{schemas: map[string]*Schema{"fetch_bank_transactions": s}}, false)
+	// the cache left out the file of the second phone number
+	report := m.Check("data", source{
+		"9000000001": {"fetch_bank_transactions": `{"bank": "Test", "txns": []}`},
+		"9000000002": {},
+	})
+	if report.Files != 2 || len(report.InvalidFiles) != 1 || report.InvalidFiles[0].PhoneNumber != "9000000002" {
+		t.Errorf("report = %+v", report)
+	}
+	if h := m.Health(); h.Status != StatusDegraded {
+		t.Errorf("health = %+v", h)
+	}
+}
@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Set holds the schema of every data tool, loaded from <dir>/<tool>.schema.json
+type Set struct {
+	Dir     string
+	schemas map[string]*Schema
+}
+
+// Load reads the schema of each tool from dir. Every tool must have one.
+func Load(dir string, tools []string) (*Set, error) {
+	set := &Set{Dir: dir, schemas: make(map[string]*Schema, len(tools))}
+	for _, tool := range tools {
+		path := filepath.Join(dir, tool+".schema.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schema: %w", err)
+		}
+		s, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.schemas[tool] = s
+	}
+	return set, nil
+}
+
+// Tools returns the tools with a schema in sorted order
+func (s *Set) Tools() []string {
+	tools := make([]string, 0, len(s.schemas))
+	for tool := range s.schemas {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// Validate checks the response of a tool. ok is false when the tool has no schema.
+func (s *Set) Validate(tool string, data []byte) (errs []Error, ok bool) {
+	schema, ok := s.schemas[tool]
+	if !ok {
+		return nil, false
+	}
+	return schema.Validate(data), true
+}
@@ -0,0 +1,15 @@
+package pkg
+
+// SnapshotsDir is the directory inside a phone number's test data holding
+// dated snapshots of tool responses, e.g. snapshots/2025-06-01/fetch_net_worth.json
+const SnapshotsDir = "snapshots"
+
+// ListSnapshotDates returns the dates of the snapshots available for a phone number in ascending order
+func ListSnapshotDates(phoneNumber string) []string {
+	return Data().SnapshotDates(phoneNumber)
+}
+
+// ReadSnapshotToolData returns the raw JSON response of a data tool in the snapshot of the given date
+func ReadSnapshotToolData(phoneNumber, date, toolName string) ([]byte, error) {
+	return Data().SnapshotToolData(phoneNumber, date, toolName)
+}
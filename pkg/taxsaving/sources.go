@@ -0,0 +1,127 @@
+package taxsaving
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+const elssCategory = "ELSS"
+
+var elssName = regexp.MustCompile(`(?i)\bELSS\b|TAX ?SAVER|LONG TERM EQUITY|TAX RELIEF`)
+
+// elssItems lists the ELSS purchases made in the year. A scheme is ELSS when its
+// category in the scheme analytics says so, or by its name when it has no analytics.
+func elssItems(resp *models.MFTransactionsResponse, netWorth *models.FetchNetWorthResponse, inYear func(time.Time) bool) []Item {
+	if resp == nil {
+		return nil
+	}
+	categories := make(map[string]string)
+	if netWorth != nil && netWorth.MFSchemeAnalytics != nil {
+		for _, s := range netWorth.MFSchemeAnalytics.SchemeAnalytics {
+			categories[s.SchemeDetail.ISINNumber] = s.SchemeDetail.CategoryName
+		}
+	}
+	var items []Item
+	for si, s := range resp.MFTransactions {
+		category, ok := categories[s.ISIN]
+		if ok && category != elssCategory || !ok && !elssName.MatchString(s.SchemeName) {
+			continue
+		}
+		for ti, t := range s.Txns {
+			date, err := t.Time()
+			if err != nil || t.OrderType != models.MFOrderTypeBuy || !inYear(date) {
+				continue
+			}
+			items = append(items, Item{
+				Instrument:  InstrumentELSS,
+				Description: s.SchemeName,
+				Date:        t.Date,
+				Amount:      models.Round(t.Amount, 2),
+				Source:      fmt.Sprintf("/mfTransactions/%d/txns/%d", si, ti),
+				Tool:        "fetch_mf_transactions",
+			})
+		}
+	}
+	return items
+}
+
+// bankPatterns detect payments into eligible instruments from bank narrations.
+// Payments to mutual funds are skipped as ELSS purchases come from the mutual
+// fund transactions, so that SIP debits are not counted twice.
+var bankPatterns = []struct {
+	instrument string
+	pattern    *regexp.Regexp
+}{
+	{InstrumentNPS, regexp.MustCompile(`(?i)\bNPS\b|NATIONAL PENSION|\bPRAN\b`)},
+	{InstrumentPPF, regexp.MustCompile(`(?i)\bPPF\b|PUBLIC PROVIDENT`)},
+	{InstrumentSSY, regexp.MustCompile(`(?i)SUKANYA|\bSSY\b`)},
+	{InstrumentNSC, regexp.MustCompile(`(?i)\bNSC\b|NATIONAL SAVINGS CERT`)},
+	{InstrumentTaxSaverFD, regexp.MustCompile(`(?i)TAX ?SAVER (FD|DEPOSIT)`)},
+	{InstrumentTuition, regexp.MustCompile(`(?i)TUITION`)},
+	{InstrumentLifeInsurance, regexp.MustCompile(`(?i)\bLIC\b|LIFE INS|SUN LIF|TERM (PLAN|INSURANCE)`)},
+}
+
+var (
+	mutualFundPattern = regexp.MustCompile(`(?i)MUTUA|MF\b|\bSIP\b`)
+	excludedPatterns  = []struct {
+		instrument string
+		pattern    *regexp.Regexp
+		reason     string
+	}{
+		{"HEALTH_INSURANCE", regexp.MustCompile(`(?i)HEALTH INS|MEDICLAIM|STAR HEALTH`), "health insurance premiums are deducted under section 80D"},
+		{"HOME_LOAN_EMI", regexp.MustCompile(`(?i)HOME LOAN|HOUSING LOAN|HOMEFIN`), "only the principal part of a home loan EMI counts towards 80C and the split is not in the bank transactions"},
+	}
+)
+
+// bankItems classifies the debits of the year by their narration
+func bankItems(resp *models.BankTransactionsResponse, inYear func(time.Time) bool) ([]Item, []Excluded) {
+	if resp == nil {
+		return nil, nil
+	}
+	var items []Item
+	var excluded []Excluded
+	for bi, b := range resp.BankTransactions {
+		for ti, t := range b.Txns {
+			date, err := t.Time()
+			if err != nil || t.Sign() >= 0 || !inYear(date) {
+				continue
+			}
+			item := Item{
+				Description: t.Narration,
+				Date:        t.Date,
+				Amount:      models.Round(t.AmountValue(), 2),
+				Source:      fmt.Sprintf("/bankTransactions/%d/txns/%d", bi, ti),
+				Tool:        "fetch_bank_transactions",
+			}
+			if e, ok := exclude(item); ok {
+				excluded = append(excluded, e)
+				continue
+			}
+			if mutualFundPattern.MatchString(t.Narration) {
+				continue
+			}
+			for _, p := range bankPatterns {
+				if p.pattern.MatchString(t.Narration) {
+					item.Instrument = p.instrument
+					items = append(items, item)
+					break
+				}
+			}
+		}
+	}
+	return items, excluded
+}
+
+// exclude returns the item as excluded when it is a tax related payment outside 80C and 80CCD(1B)
+func exclude(item Item) (Excluded, bool) {
+	for _, p := range excludedPatterns {
+		if p.pattern.MatchString(item.Description) {
+			item.Instrument = p.instrument
+			return Excluded{Item: item, Reason: p.reason}, true
+		}
+	}
+	return Excluded{}, false
+}
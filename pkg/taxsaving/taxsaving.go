@@ -0,0 +1,246 @@
+package taxsaving
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/epf"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Sections of the Income Tax Act covered by the report
+const (
+	Section80C     = "80C"
+	Section80CCD1B = "80CCD(1B)"
+)
+
+// Instruments that qualify for a deduction
+const (
+	InstrumentELSS          = "ELSS"
+	InstrumentEPF           = "EPF_EMPLOYEE_SHARE"
+	InstrumentLifeInsurance = "LIFE_INSURANCE"
+	InstrumentPPF           = "PPF"
+	InstrumentNPS           = "NPS"
+	InstrumentSSY           = "SUKANYA_SAMRIDDHI"
+	InstrumentNSC           = "NSC"
+	InstrumentTaxSaverFD    = "TAX_SAVER_FD"
+	InstrumentTuition       = "TUITION_FEES"
+)
+
+// Limit returns the deduction limit of a section in a financial year
+func Limit(section string, fy models.FinancialYear) float64 {
+	switch section {
+	case Section80C:
+		if fy.StartYear < 2014 {
+			return 100000
+		}
+		return 150000
+	case Section80CCD1B:
+		if fy.StartYear < 2015 {
+			return 0
+		}
+		return 50000
+	}
+	return 0
+}
+
+// Item is an investment or payment that qualifies for a deduction
+type Item struct {
+	Instrument  string  `json:"instrument"`
+	Description string  `json:"description"`
+	Date        string  `json:"date,omitempty"`
+	Amount      float64 `json:"amount"`
+	// Source is the JSON pointer of the row in the tool response the item comes from
+	Source string `json:"source"`
+	Tool   string `json:"tool"`
+	// Estimated is set for amounts derived from totals, such as the EPF employee share
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Excluded is a payment that looks tax related but doesn't count towards the sections covered
+type Excluded struct {
+	Item
+	Reason string `json:"reason"`
+}
+
+// Utilisation is how much of a section's limit the items use
+type Utilisation struct {
+	Section            string  `json:"section"`
+	Limit              float64 `json:"limit"`
+	Eligible           float64 `json:"eligible"`
+	Claimed            float64 `json:"claimed"`
+	UtilisationPercent float64 `json:"utilisationPercent"`
+	Headroom           float64 `json:"headroom"`
+	// Excess is the eligible amount above the limit, which gives no further deduction
+	Excess float64 `json:"excess"`
+}
+
+// Report is the use of the 80C and 80CCD(1B) deductions in a financial year
+type Report struct {
+	FinancialYear string `json:"financialYear"`
+	// AsOf is the date up to which the year is counted, the end of the year for past years
+	AsOf     string        `json:"asOf"`
+	Items    []Item        `json:"items"`
+	Excluded []Excluded    `json:"excluded"`
+	Sections []Utilisation `json:"sections"`
+	// ProjectedSections add the EPF employee share of the current employer up to the end of the year
+	ProjectedSections []Utilisation `json:"projectedSections,omitempty"`
+	Suggestions       []string      `json:"suggestions"`
+	Notes             []string      `json:"notes"`
+}
+
+// Data holds the tool responses the report is built from, any of which may be nil
+type Data struct {
+	NetWorth *models.FetchNetWorthResponse
+	MF       *models.MFTransactionsResponse
+	EPF      *models.EPFDetailsResponse
+	Bank     *models.BankTransactionsResponse
+	// EPFAsOf is the date of the EPF balances, see epf.AsOf. The end of the
+	// counted part of the year is used when it is zero.
+	EPFAsOf time.Time
+}
+
+// Build classifies the 80C and 80CCD(1B) eligible investments of the financial
+// year: ELSS purchases from the mutual fund transactions, the EPF employee share
+// and life insurance, PPF, NPS and other debits detected in the bank transactions.
+// Only the part of the year up to asOf is counted.
+func Build(d Data, fy models.FinancialYear, asOf time.Time) *Report {
+	end := fy.End()
+	if asOf.Before(end) {
+		end = asOf
+	}
+	r := &Report{
+		FinancialYear: fy.String(),
+		AsOf:          end.Format(models.DateLayout),
+		Items:         []Item{},
+		Excluded:      []Excluded{},
+		Suggestions:   []string{},
+		Notes:         []string{"80C and 80CCD(1B) deductions are only available in the old tax regime"},
+	}
+	if end.Before(fy.Start()) {
+		r.Notes = append(r.Notes, fmt.Sprintf("financial year %s has not started on %s", fy, r.AsOf))
+		r.Sections = utilisation(nil, fy)
+		return r
+	}
+	inYear := func(t time.Time) bool { return fy.Contains(t) && !t.After(end) }
+
+	r.Items = append(r.Items, elssItems(d.MF, d.NetWorth, inYear)...)
+	epfItems, projected := epfItems(d.EPF, d.EPFAsOf, fy, end)
+	r.Items = append(r.Items, epfItems...)
+	bankItems, excluded := bankItems(d.Bank, inYear)
+	r.Items = append(r.Items, bankItems...)
+	r.Excluded = append(r.Excluded, excluded...)
+
+	r.Sections = utilisation(r.Items, fy)
+	if len(projected) > 0 {
+		r.ProjectedSections = utilisation(append(append([]Item{}, r.Items...), projected...), fy)
+		r.Notes = append(r.Notes, "projectedSections assume the current employer keeps contributing to EPF at the same rate until 31 March")
+	}
+	// suggestions only make sense while the year is still open, and count on the
+	// EPF credits still to come
+	if end.Before(fy.End()) {
+		sections := r.Sections
+		if r.ProjectedSections != nil {
+			sections = r.ProjectedSections
+		}
+		for _, s := range sections {
+			if s.Headroom <= 0 {
+				continue
+			}
+			switch s.Section {
+			case Section80C:
+				r.Suggestions = append(r.Suggestions, fmt.Sprintf("%.0f of the 80C limit is unused, ELSS, PPF, life insurance premiums or a tax saver FD before 31 March would use it", s.Headroom))
+			case Section80CCD1B:
+				r.Suggestions = append(r.Suggestions, fmt.Sprintf("%.0f more in NPS Tier 1 before 31 March would use the 80CCD(1B) deduction, which is on top of 80C", s.Headroom))
+			}
+		}
+	}
+	if d.MF == nil {
+		r.Notes = append(r.Notes, "no mutual fund transactions, ELSS purchases are not included")
+	}
+	if d.EPF == nil {
+		r.Notes = append(r.Notes, "no EPF details, the EPF employee share is not included")
+	}
+	if d.Bank == nil {
+		r.Notes = append(r.Notes, "no bank transactions, insurance, PPF and NPS payments are not included")
+	}
+	return r
+}
+
+// utilisation sums the items per section. NPS goes to 80CCD(1B) first and the
+// rest counts towards 80C as 80CCD(1), which shares the 80C limit.
+func utilisation(items []Item, fy models.FinancialYear) []Utilisation {
+	var c, nps float64
+	for _, it := range items {
+		if it.Instrument == InstrumentNPS {
+			nps += it.Amount
+		} else {
+			c += it.Amount
+		}
+	}
+	npsLimit := Limit(Section80CCD1B, fy)
+	ccd1b := min(nps, npsLimit)
+	c += nps - ccd1b
+	return []Utilisation{
+		section(Section80C, Limit(Section80C, fy), c),
+		section(Section80CCD1B, npsLimit, ccd1b),
+	}
+}
+
+func section(name string, limit, eligible float64) Utilisation {
+	u := Utilisation{Section: name, Limit: limit, Eligible: models.Round(eligible, 2)}
+	u.Claimed = models.Round(min(eligible, limit), 2)
+	u.Headroom = models.Round(limit-u.Claimed, 2)
+	u.Excess = models.Round(max(eligible-limit, 0), 2)
+	if limit > 0 {
+		u.UtilisationPercent = models.Round(u.Claimed/limit*100, 2)
+	}
+	return u
+}
+
+// epfItems estimates the employee share credited in the year for every member id
+// from its average monthly credit up to asOf, the date of the balances. For the
+// current employer the months left until the end of the year are returned
+// separately as the projection.
+func epfItems(resp *models.EPFDetailsResponse, asOf time.Time, fy models.FinancialYear, end time.Time) ([]Item, []Item) {
+	if resp == nil {
+		return nil, nil
+	}
+	if asOf.IsZero() {
+		asOf = end
+	}
+	var items, projected []Item
+	// the day after the last counted day makes the month count inclusive
+	to, fyTo := end.AddDate(0, 0, 1), fy.End().AddDate(0, 0, 1)
+	for _, a := range epf.Summarize(resp, asOf).Accounts {
+		monthly := a.MonthlyEmployeeCredit()
+		if monthly == 0 {
+			continue
+		}
+		source := fmt.Sprintf("/uanAccounts/%d/rawDetails/est_details/%d/pf_balance/employee_share/credit", a.UANIndex, a.EstIndex)
+		if months := a.ServiceMonthsIn(fy.Start(), to); months > 0 {
+			items = append(items, Item{
+				Instrument:  InstrumentEPF,
+				Description: fmt.Sprintf("EPF employee share at %s, %d months at %.2f a month", a.Employer, months, monthly),
+				Amount:      models.Round(monthly*float64(months), 2),
+				Source:      source,
+				Tool:        "fetch_epf_details",
+				Estimated:   true,
+			})
+		}
+		if !a.Active {
+			continue
+		}
+		if rest := a.ServiceMonthsIn(fy.Start(), fyTo) - a.ServiceMonthsIn(fy.Start(), to); rest > 0 {
+			projected = append(projected, Item{
+				Instrument:  InstrumentEPF,
+				Description: fmt.Sprintf("EPF employee share at %s until 31 March, %d months at %.2f a month", a.Employer, rest, monthly),
+				Amount:      models.Round(monthly*float64(rest), 2),
+				Source:      source,
+				Tool:        "fetch_epf_details",
+				Estimated:   true,
+			})
+		}
+	}
+	return items, projected
+}
@@ -0,0 +1,89 @@
+package taxsaving
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+func decode[T any](t *testing.T, data string) *T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return &v
+}
+
+func TestBuild(t *testing.T) {
+	d := Data{
+		NetWorth: decode[models.FetchNetWorthResponse](t, `{"mfSchemeAnalytics":{"schemeAnalytics":[
+			{"schemeDetail":{"isinNumber":"INF000000001","categoryName":"ELSS"}},
+			{"schemeDetail":{"isinNumber":"INF000000002","categoryName":"LARGE_CAP_FUND"}}]}}`),
+		MF: decode[models.MFTransactionsResponse](t, `{"mfTransactions":[
+			{"isin":"INF000000001","schemeName":"Alpha Growth Fund","txns":[[1,"2025-01-10",50,200,10000],[1,"2024-03-10",50,200,10000],[2,"2025-02-10",50,100,5000]]},
+			{"isin":"INF000000002","schemeName":"Beta Tax Saver Named Large Cap","txns":[[1,"2025-01-10",50,200,10000]]},
+			{"isin":"INF000000003","schemeName":"Gamma ELSS Tax Saver Fund","txns":[[1,"2025-02-10",50,400,20000]]}]}`),
+		Bank: decode[models.BankTransactionsResponse](t, `{"bankTransactions":[{"bank":"Test Bank","txns":[
+			["40000","BILLPAY-NPS CONTRIBUTION-TIER1","2024-06-08",2,"OTHERS","100000"],
+			["30000","BILLPAY-NPS CONTRIBUTION-TIER1","2024-12-08",2,"OTHERS","70000"],
+			["25000","IMPS-LIC OF INDIA-PREMIUM","2024-09-01",2,"IMPS","45000"],
+			["5000","ACH D-MIRAEASSETMF-SIP/TAXSAVER/WG-ELSS1","2025-01-05",2,"ACH","40000"],
+			["30000","ACH D-HDFCHOMEFIN-HOME LOAN EMI-HL556677","2025-01-07",2,"ACH","10000"],
+			["20000","PPF DEPOSIT","2025-04-02",2,"OTHERS","0"]]}]}`),
+	}
+	fy := models.FinancialYear{StartYear: 2024}
+	r := Build(d, fy, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// ELSS by category and by name without analytics, the SIP debit, sale and
+	// purchases outside the year are not counted
+	var elss float64
+	for _, it := range r.Items {
+		if it.Instrument == InstrumentELSS {
+			elss += it.Amount
+		}
+	}
+	if elss != 30000 || len(r.Items) != 5 {
+		t.Errorf("items = %+v", r.Items)
+	}
+	// NPS beyond 50000 counts towards 80C
+	want := []Utilisation{
+		{Section: Section80C, Limit: 150000, Eligible: 75000, Claimed: 75000, UtilisationPercent: 50, Headroom: 75000},
+		{Section: Section80CCD1B, Limit: 50000, Eligible: 50000, Claimed: 50000, UtilisationPercent: 100},
+	}
+	for i, s := range r.Sections {
+		if s != want[i] {
+			t.Errorf("section %d = %+v, want %+v", i, s, want[i])
+		}
+	}
+	if len(r.Excluded) != 1 || r.Excluded[0].Instrument != "HOME_LOAN_EMI" || r.Excluded[0].Source != "/bankTransactions/0/txns/4" {
+		t.Errorf("excluded = %+v", r.Excluded)
+	}
+	if len(r.Suggestions) != 0 {
+		t.Errorf("suggestions for a past year = %v", r.Suggestions)
+	}
+
+	r = Build(d, fy, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC))
+	if r.Sections[0].Eligible != 25000 || r.Sections[1].Eligible != 40000 || len(r.Suggestions) != 2 {
+		t.Errorf("part of the year = %+v, suggestions %v", r.Sections, r.Suggestions)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	for _, tc := range []struct {
+		section string
+		year    int
+		want    float64
+	}{
+		{Section80C, 2013, 100000},
+		{Section80C, 2014, 150000},
+		{Section80CCD1B, 2014, 0},
+		{Section80CCD1B, 2015, 50000},
+	} {
+		if got := Limit(tc.section, models.FinancialYear{StartYear: tc.year}); got != tc.want {
+			t.Errorf("Limit(%s, %d) = %v, want %v", tc.section, tc.year, got, tc.want)
+		}
+	}
+}
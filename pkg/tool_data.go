@@ -0,0 +1,9 @@
+package pkg
+
+// TestDataDir is the directory holding one sub-directory of tool responses per allowed phone number
+const TestDataDir = "test_data_dir"
+
+// ReadToolData returns the raw JSON response of the given data tool for a phone number
+func ReadToolData(phoneNumber, toolName string) ([]byte, error) {
+	return Data().ToolData(phoneNumber, toolName)
+}
@@ -1,9 +1,12 @@
 package pkg
 
-// ToolInfo holds the name and description of a tool
+import "github.com/mark3labs/mcp-go/mcp"
+
+// ToolInfo holds the name, description and optional arguments of a tool
 type ToolInfo struct {
 	Name        string
 	Description string
+	Arguments   []mcp.ToolOption
 }
 
 // ToolList is the list of all tools and their descriptions
@@ -27,9 +30,24 @@ var ToolList = []ToolInfo{
 	{
 		Name:        "fetch_bank_transactions",
 		Description: "Retrieve detailed bank transactions for each bank account connected to Fi money platform.",
+		Arguments: []mcp.ToolOption{
+			mcp.WithBoolean("include_categories",
+				mcp.Description("Append to every transaction its category and the counterparty, VPA, IFSC and reference parsed from the narration."),
+			),
+		},
 	},
 	{
 		Name:        "fetch_stock_transactions",
 		Description: "Retrieve detailed indian stock transactions for all connected indian stock accounts to Fi money platform.",
 	},
 }
+
+// IsDataTool reports whether the tool is served straight from the files in test_data_dir
+func IsDataTool(name string) bool {
+	for _, tool := range ToolList {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
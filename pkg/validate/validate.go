@@ -0,0 +1,314 @@
+// Package validate checks that the tool responses of a persona agree with each other
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epifi/fi-mcp-lite/pkg/banking"
+	"github.com/epifi/fi-mcp-lite/pkg/models"
+)
+
+// Rules checked by Persona
+const (
+	RuleParse              = "parse"
+	RuleUnexpectedKey      = "unexpected_key"
+	RuleNetWorthTotal      = "net_worth_total"
+	RuleMFValue            = "mf_value"
+	RuleMFMissingAnalytics = "mf_missing_analytics"
+	RuleMFUnits            = "mf_units"
+	RuleEPFBalance         = "epf_balance"
+	RuleEPFPassbook        = "epf_passbook"
+	RuleBankBalanceChain   = "bank_balance_chain"
+)
+
+const (
+	// amountTolerance absorbs the rounding of amounts to paise or rupees
+	amountTolerance = 1
+	// unitsTolerance absorbs the rounding of MF units to three decimals
+	unitsTolerance = 0.01
+)
+
+// Violation is a broken invariant. Pointer is a JSON pointer into File.
+type Violation struct {
+	PhoneNumber string `json:"phoneNumber"`
+	File        string `json:"file"`
+	Pointer     string `json:"pointer"`
+	Rule        string `json:"rule"`
+	Message     string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s/%s#%s: %s: %s", v.PhoneNumber, v.File, v.Pointer, v.Rule, v.Message)
+}
+
+// topLevelKeys are the keys each data tool response may have
+var topLevelKeys = map[string][]string{
+	"fetch_net_worth":          {"netWorthResponse", "mfSchemeAnalytics", "accountDetailsBulkResponse"},
+	"fetch_credit_report":      {"creditReports"},
+	"fetch_epf_details":        {"uanAccounts"},
+	"fetch_mf_transactions":    {"mfTransactions", "schemaDescription"},
+	"fetch_bank_transactions":  {"bankTransactions", "schemaDescription"},
+	"fetch_stock_transactions": {"stockTransactions", "schemaDescription"},
+}
+
+// tools returns the names of the data tools whose responses are checked, in sorted order
+func tools() []string {
+	tools := make([]string, 0, len(topLevelKeys))
+	for tool := range topLevelKeys {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// persona holds the decoded responses of one persona
+type persona struct {
+	phone      string
+	violations []Violation
+
+	netWorth *models.FetchNetWorthResponse
+	epf      *models.EPFDetailsResponse
+	mf       *models.MFTransactionsResponse
+	bank     *models.BankTransactionsResponse
+	stock    *models.StockTransactionsResponse
+	credit   *models.CreditReportResponse
+}
+
+// Persona checks the responses in dir, a <test_data_dir>/<phone number> directory.
+// A missing file is not a violation, the checks that need it are skipped.
+func Persona(dir string) ([]Violation, error) {
+	p := &persona{phone: filepath.Base(dir)}
+	targets := map[string]any{
+		"fetch_net_worth":          &p.netWorth,
+		"fetch_credit_report":      &p.credit,
+		"fetch_epf_details":        &p.epf,
+		"fetch_mf_transactions":    &p.mf,
+		"fetch_bank_transactions":  &p.bank,
+		"fetch_stock_transactions": &p.stock,
+	}
+	for _, tool := range tools() {
+		data, err := os.ReadFile(filepath.Join(dir, tool+".json"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		p.decode(tool, data, targets[tool])
+	}
+	p.checkNetWorth()
+	p.checkMutualFunds()
+	p.checkEPF()
+	p.checkBank()
+	return p.violations, nil
+}
+
+// All checks every persona directory of a test data dir
+func All(testDataDir string) ([]Violation, error) {
+	entries, err := os.ReadDir(testDataDir)
+	if err != nil {
+		return nil, err
+	}
+	var all []Violation
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		violations, err := Persona(filepath.Join(testDataDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, violations...)
+	}
+	return all, nil
+}
+
+func (p *persona) add(tool, pointer, rule, format string, args ...any) {
+	p.violations = append(p.violations, Violation{
+		PhoneNumber: p.phone,
+		File:        tool + ".json",
+		Pointer:     pointer,
+		Rule:        rule,
+		Message:     fmt.Sprintf(format, args...),
+	})
+}
+
+// decode unmarshals a response into its model and reports keys that belong to other tools
+func (p *persona) decode(tool string, data []byte, target any) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		p.add(tool, "", RuleParse, "%v", err)
+		return
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		p.add(tool, "", RuleParse, "%v", err)
+		return
+	}
+	allowed := make(map[string]bool)
+	for _, k := range topLevelKeys[tool] {
+		allowed[k] = true
+	}
+	var unexpected []string
+	for k := range keys {
+		if !allowed[k] {
+			unexpected = append(unexpected, k)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, k := range unexpected {
+		p.add(tool, "/"+k, RuleUnexpectedKey, "%s is not part of the %s response", k, tool)
+	}
+}
+
+// checkNetWorth compares the total with the assets less the liabilities. Some
+// responses list liabilities among the assets with a negative value.
+func (p *persona) checkNetWorth() {
+	if p.netWorth == nil || p.netWorth.NetWorthResponse == nil || p.netWorth.NetWorthResponse.TotalNetWorthValue == nil {
+		return
+	}
+	nw := p.netWorth.NetWorthResponse
+	var computed float64
+	for _, a := range nw.AssetValues {
+		if strings.HasPrefix(a.NetWorthAttribute, "LIABILITY_") {
+			computed -= math.Abs(a.Value.Float())
+		} else {
+			computed += a.Value.Float()
+		}
+	}
+	for _, l := range nw.LiabilityValues {
+		computed -= math.Abs(l.Value.Float())
+	}
+	if total := nw.TotalNetWorthValue.Float(); math.Abs(total-computed) > amountTolerance {
+		p.add("fetch_net_worth", "/netWorthResponse/totalNetWorthValue", RuleNetWorthTotal,
+			"total is %s but assets less liabilities are %s", format(total), format(computed))
+	}
+}
+
+// checkMutualFunds compares the MF attribute with the scheme analytics, and the
+// analytics with the units left by the transactions
+func (p *persona) checkMutualFunds() {
+	analytics := make(map[string]int)
+	if p.netWorth != nil && p.netWorth.MFSchemeAnalytics != nil {
+		var value float64
+		for i, s := range p.netWorth.MFSchemeAnalytics.SchemeAnalytics {
+			analytics[s.SchemeDetail.ISINNumber] = i
+			value += s.EnrichedAnalytics.Analytics.SchemeDetails.CurrentValue.Float()
+		}
+		if i, attribute, ok := p.assetValue("ASSET_TYPE_MUTUAL_FUND"); ok && len(analytics) > 0 && math.Abs(attribute-value) > amountTolerance {
+			p.add("fetch_net_worth", fmt.Sprintf("/netWorthResponse/assetValues/%d/value", i), RuleMFValue,
+				"ASSET_TYPE_MUTUAL_FUND is %s but the schemes in mfSchemeAnalytics add up to %s", format(attribute), format(value))
+		}
+	}
+	if p.mf == nil || p.netWorth == nil {
+		return
+	}
+
+	// a scheme may be listed once per folio, the analytics are per scheme
+	units := make(map[string]float64)
+	first := make(map[string]int)
+	var isins []string
+	for i, s := range p.mf.MFTransactions {
+		if _, ok := first[s.ISIN]; !ok {
+			first[s.ISIN] = i
+			isins = append(isins, s.ISIN)
+		}
+		for _, t := range s.Txns {
+			if t.OrderType == models.MFOrderTypeSell {
+				units[s.ISIN] -= t.Units
+			} else {
+				units[s.ISIN] += t.Units
+			}
+		}
+	}
+	for _, isin := range isins {
+		held := units[isin]
+		if held <= unitsTolerance {
+			continue
+		}
+		pointer := fmt.Sprintf("/mfTransactions/%d/isin", first[isin])
+		i, ok := analytics[isin]
+		if !ok {
+			p.add("fetch_mf_transactions", pointer, RuleMFMissingAnalytics,
+				"%s holds %s units but is not in the mfSchemeAnalytics of fetch_net_worth", isin, formatUnits(held))
+			continue
+		}
+		reported := p.netWorth.MFSchemeAnalytics.SchemeAnalytics[i].EnrichedAnalytics.Analytics.SchemeDetails.Units
+		if reported != 0 && math.Abs(reported-held) > unitsTolerance {
+			p.add("fetch_net_worth", fmt.Sprintf("/mfSchemeAnalytics/schemeAnalytics/%d/enrichedAnalytics/analytics/schemeDetails/units", i), RuleMFUnits,
+				"%s has %s units but its transactions leave %s", isin, formatUnits(reported), formatUnits(held))
+		}
+	}
+}
+
+// checkEPF compares the EPF attribute with the provident fund balance of all
+// UANs, and the balance of every establishment with its passbook entries
+func (p *persona) checkEPF() {
+	if p.epf == nil || len(p.epf.UANAccounts) == 0 {
+		return
+	}
+	var balance float64
+	for i, u := range p.epf.UANAccounts {
+		balance += models.ParseEPFAmount(u.RawDetails.OverallPFBalance.CurrentPFBalance)
+		for j, e := range u.RawDetails.EstDetails {
+			if len(e.Passbook) == 0 {
+				continue
+			}
+			var employee, employer float64
+			for _, entry := range e.Passbook {
+				employee += models.ParseEPFAmount(entry.EmployeeShare)
+				employer += models.ParseEPFAmount(entry.EmployerShare)
+			}
+			b := e.PFBalance
+			if math.Abs(employee-models.ParseEPFAmount(b.EmployeeShare.Balance)) > amountTolerance || math.Abs(employer-models.ParseEPFAmount(b.EmployerShare.Balance)) > amountTolerance {
+				p.add("fetch_epf_details", fmt.Sprintf("/uanAccounts/%d/rawDetails/est_details/%d/pf_balance", i, j), RuleEPFPassbook,
+					"employee and employer shares are %s and %s but the passbook adds up to %s and %s",
+					format(models.ParseEPFAmount(b.EmployeeShare.Balance)), format(models.ParseEPFAmount(b.EmployerShare.Balance)), format(employee), format(employer))
+			}
+		}
+	}
+	if i, attribute, ok := p.assetValue("ASSET_TYPE_EPF"); ok && balance > 0 && math.Abs(attribute-balance) > amountTolerance {
+		p.add("fetch_net_worth", fmt.Sprintf("/netWorthResponse/assetValues/%d/value", i), RuleEPFBalance,
+			"ASSET_TYPE_EPF is %s but the current_pf_balance of fetch_epf_details adds up to %s", format(attribute), format(balance))
+	}
+}
+
+// checkBank reports the rows whose balance doesn't follow from the row before, in date order
+func (p *persona) checkBank() {
+	if p.bank == nil {
+		return
+	}
+	for _, a := range banking.SummarizeCashFlow(p.bank, time.Time{}, time.Time{}).Accounts {
+		for _, b := range a.Breaks {
+			p.add("fetch_bank_transactions", b.Source+"/5", RuleBankBalanceChain,
+				"balance is %s but the previous balance and this amount give %s", format(b.Reported), format(b.Expected))
+		}
+	}
+}
+
+// assetValue returns the index and value of an asset attribute
+func (p *persona) assetValue(attribute string) (int, float64, bool) {
+	if p.netWorth == nil || p.netWorth.NetWorthResponse == nil {
+		return 0, 0, false
+	}
+	for i, a := range p.netWorth.NetWorthResponse.AssetValues {
+		if a.NetWorthAttribute == attribute {
+			return i, a.Value.Float(), true
+		}
+	}
+	return 0, 0, false
+}
+
+func format(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func formatUnits(v float64) string {
+	return fmt.Sprintf("%.3f", v)
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var fixtures = map[string]string{
+	"fetch_net_worth": `{
+		"netWorthResponse": {
+			"assetValues": [
+				{"netWorthAttribute": "ASSET_TYPE_MUTUAL_FUND", "value": {"units": "1000"}},
+				{"netWorthAttribute": "ASSET_TYPE_EPF", "value": {"units": "5000"}},
+				{"netWorthAttribute": "LIABILITY_TYPE_CREDIT_CARD", "value": {"units": "-500"}}
+			],
+			"totalNetWorthValue": {"units": "6000"}
+		},
+		"mfSchemeAnalytics": {"schemeAnalytics": [
+			{"schemeDetail": {"isinNumber": "INF000000001", "nameData": {"longName": "Alpha"}},
+			 "enrichedAnalytics": {"analytics": {"schemeDetails": {"currentValue": {"units": "800"}, "units": 12}}}}
+		]},
+		"creditReports": []
+	}`,
+	"fetch_mf_transactions": `{"mfTransactions": [
+		{"isin": "INF000000001", "schemeName": "Alpha", "folioId": "1", "txns": [[1, "2024-01-01", 10, 10, 100], [2, "2024-06-01", 12, 2, 24]]},
+		{"isin": "INF000000002", "schemeName": "Beta", "folioId": "1", "txns": [[1, "2024-01-01", 10, 5, 50]]},
+		{"isin": "INF000000003", "schemeName": "Gamma", "folioId": "1", "txns": [[1, "2024-01-01", 10, 5, 50], [2, "2024-02-01", 10, 5, 50]]}
+	]}`,
+	"fetch_epf_details": `{"uanAccounts": [{"rawDetails": {
+		"est_details": [{"pf_balance": {"employee_share": {"balance": "3000"}, "employer_share": {"balance": "2000"}}, "passbook": [
+			{"month": "2024-01", "date": "15-02-2024", "type": "CONTRIBUTION", "employee_share": "3000", "employer_share": "1500"}
+		]}],
+		"overall_pf_balance": {"current_pf_balance": "5000"}
+	}}]}`,
+	"fetch_bank_transactions": `{"bankTransactions": [{"bank": "Test Bank", "txns": [
+		["1000", "SALARY", "2024-01-01", 1, "NEFT", "1000"],
+		["200", "UPI-GROCER", "2024-01-02", 2, "UPI", "800"],
+		["100", "UPI-GROCER", "2024-01-03", 2, "UPI", "650"]
+	]}]}`,
+	"fetch_stock_transactions": `{}`,
+	"fetch_credit_report":      `not json`,
+}
+
+func TestPersona(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "9000000001")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for tool, data := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, tool+".json"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	violations, err := Persona(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.File+"#"+v.Pointer+" "+v.Rule)
+	}
+	sort.Strings(got)
+	want := []string{
+		"fetch_bank_transactions.json#/bankTransactions/0/txns/2/5 bank_balance_chain",
+		"fetch_credit_report.json# parse",
+		"fetch_epf_details.json#/uanAccounts/0/rawDetails/est_details/0/pf_balance epf_passbook",
+		"fetch_mf_transactions.json#/mfTransactions/1/isin mf_missing_analytics",
+		"fetch_net_worth.json#/creditReports unexpected_key",
+		"fetch_net_worth.json#/mfSchemeAnalytics/schemeAnalytics/0/enrichedAnalytics/analytics/schemeDetails/units mf_units",
+		"fetch_net_worth.json#/netWorthResponse/assetValues/0/value mf_value",
+		"fetch_net_worth.json#/netWorthResponse/totalNetWorthValue net_worth_total",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestTestDataDir checks the personas in test_data_dir against the known drift
+// of the hand-written ones, so that no new inconsistency creeps in
+func TestTestDataDir(t *testing.T) {
+	violations, err := All("../../test_data_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int)
+	for _, v := range violations {
+		got[v.PhoneNumber+"\t"+v.File+"\t"+v.Rule]++
+	}
+
+	f, err := os.Open("testdata/known_violations.tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	known := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, "\t")
+		n, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		known[line[:i]] = n
+	}
+
+	changed := make(map[string]bool)
+	for key, n := range got {
+		if n != known[key] {
+			changed[key] = true
+			t.Errorf("%s: %d violations, %d known", strings.ReplaceAll(key, "\t", " "), n, known[key])
+		}
+	}
+	for key, n := range known {
+		if _, ok := got[key]; !ok {
+			t.Errorf("%s: no violations, %d known", strings.ReplaceAll(key, "\t", " "), n)
+		}
+	}
+	for _, v := range violations {
+		if changed[v.PhoneNumber+"\t"+v.File+"\t"+v.Rule] {
+			t.Log(v)
+		}
+	}
+}